@@ -1,28 +1,25 @@
 # coding: utf-8
 
 import os
-from ctypes import cdll, c_double, POINTER
+import subprocess
+import tempfile
 
-libpath = os.path.join(os.path.dirname(__file__), "wavelib.so")
-lib = cdll.LoadLibrary(libpath)
+import numpy as np
 
-lib.WaveSize.argtypes = [c_double]
-lib.SineWave.argtypes = [c_double, c_double, c_double, POINTER(c_double)]
-lib.SquareWave.argtypes = [c_double, c_double, c_double, POINTER(c_double)]
-lib.KarplusStrongWave.argtypes = [c_double, c_double, c_double, POINTER(c_double)]
+# The signals are created by the go program wavedump, that writes the
+# samples in a raw file of float32 values (little endian).
+here = os.path.dirname(os.path.abspath(__file__))
+exepath = os.path.join(here, "wavedump")
 
-from typing import List
-from array import array
+samplerate = 44100 # wave.DefaultSampleRate
 
-def makewave(f,a,d, wavefct) -> List[float]:
-    N = lib.WaveSize(d)
-    out = array('d', [0 for i in range(N)]) 
-    out_ptr = (c_double * len(out)).from_buffer(out)
-    wavefct(f,a,d, out_ptr)
-    return out
+def makewave(f,a,d, name) -> np.ndarray:
+    with tempfile.TemporaryDirectory() as tmpdir:
+        path = os.path.join(tmpdir, "wave.f32")
+        args = ["-wave", name, "-f", str(f), "-a", str(a), "-d", str(d), "-o", path]
+        subprocess.run([exepath] + args, check=True)
+        return np.fromfile(path, "<f4")
 
-def SineWave(f,a,d) -> List[float]: return makewave(f,a,d, lib.SineWave)
-def SquareWave(f,a,d) -> List[float]: return makewave(f,a,d, lib.SquareWave)
-def KarplusStrongWave(f,a,d) -> List[float]: return makewave(f,a,d, lib.KarplusStrongWave)
-
-samplerate = lib.WaveSize(1.) # number of samples in 1 second
+def SineWave(f,a,d) -> np.ndarray: return makewave(f,a,d, "sine")
+def SquareWave(f,a,d) -> np.ndarray: return makewave(f,a,d, "square")
+def KarplusStrongWave(f,a,d) -> np.ndarray: return makewave(f,a,d, "ks")
@@ -0,0 +1,57 @@
+package main
+
+/*
+
+This example shows how to use the go functions from another langage. The
+signals are created using the go functions of the wave package, and
+dumped in a raw file of float32 values (format .f32 of the package
+sound), that can be loaded directly with numpy:
+
+	numpy.fromfile(path, "<f4")
+
+Usage:
+
+	wavedump -wave sine -f 10 -a 10 -d 2 -o output.sine.f32
+
+The file format is selected by the extension of the output file, so that
+the signal can also be written in a WAV or FLAC file (in this case, the
+amplitude should be lower than 1).
+
+*/
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gboulant/musicall/sound"
+	"github.com/gboulant/musicall/wave"
+)
+
+const samplerate = wave.DefaultSampleRate
+
+var waves = map[string]func(f, a, d float64, r int) []float64{
+	"sine":   wave.SineWaveSignal,
+	"square": wave.SquareWaveSignal,
+	"ks":     wave.KarplusStrongSignal,
+}
+
+func main() {
+	name := flag.String("wave", "sine", "wave form (sine, square or ks)")
+	f := flag.Float64("f", 10., "frequency (Hz)")
+	a := flag.Float64("a", 1., "amplitude")
+	d := flag.Float64("d", 1., "duration (seconds)")
+	outpath := flag.String("o", "output.wave.f32", "output file (.f32, .raw, .wav, .flac, .aiff)")
+	flag.Parse()
+
+	wavefct, ok := waves[*name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "the wave %q is not defined\n", *name)
+		os.Exit(1)
+	}
+	s := wavefct(*f, *a, *d, samplerate)
+	if err := sound.SaveSamples(*outpath, s, sound.DefaultEncodeOptions(samplerate)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
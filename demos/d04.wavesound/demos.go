@@ -4,14 +4,13 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"slices"
-	"time"
 
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/sequencer"
 	"github.com/gboulant/musicall/sound"
 	"github.com/gboulant/musicall/wave"
 	"github.com/gopxl/beep"
 	"github.com/gopxl/beep/generators"
-	"github.com/gopxl/beep/speaker"
 )
 
 const sampleRate = beep.SampleRate(wave.DefaultSampleRate)
@@ -21,7 +20,7 @@ func init() {
 	// signaux ([]float64) joués par ce speaker seront considérés comme
 	// des sons avec ce sample rate. On doit donc générer des signaux
 	// avec ce sample rate.
-	err := speaker.Init(sampleRate, sampleRate.N(time.Second/10))
+	err := sound.Init(int(sampleRate))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -38,13 +37,6 @@ func decimate(samples []float64, samplerate int, step int) ([]float64, int) {
 	return wave.Decimate(samples, step), samplerate / step
 }
 
-func labeledStreamer(f float64, duration float64, name string) beep.Streamer {
-	s := wave.NewKarplusStrongSynthesizer(f, 1., int(sampleRate))
-	samples := s.Synthesize(duration)
-	label := fmt.Sprintf("note: %-4s (f=%.1f Hz)", name, f)
-	return sound.LabelledStreamer(sound.NewSound(samples), label)
-}
-
 func DEMO00_logscale() error {
 	duration := 1.0
 	streamers := make([]beep.Streamer, 0)
@@ -56,33 +48,18 @@ func DEMO00_logscale() error {
 	//
 	// Do -T-> Ré -T-> Mi -T/2-> Fa -T-> Sol -T-> La -T-> Si -T/2-> Do
 	//
-
-	fLa := 440.0 // Hz
-	lLa := math.Log2(fLa)
-	lDo := lLa - 9/12.
-	notes := []struct {
-		logf float64
-		name string
-	}{
-		{logf: lDo, name: "Do"},
-		{logf: lDo + 1/12., name: "Do#"},
-		{logf: lDo + 2/12., name: "Ré"},
-		{logf: lDo + 3/12., name: "Ré#"},
-		{logf: lDo + 4/12., name: "Mi"},
-		{logf: lDo + 5/12., name: "Fa"},
-		{logf: lDo + 6/12., name: "Fa#"},
-		{logf: lDo + 7/12., name: "Sol"},
-		{logf: lDo + 8/12., name: "Sol#"},
-		{logf: lDo + 9/12., name: "La"},
-		{logf: lDo + 10/12., name: "La#"},
-		{logf: lDo + 11/12., name: "Si"},
-		{logf: lDo + 12/12., name: "Do"},
-	}
-
-	for _, note := range notes {
-		f := math.Pow(2, note.logf)
-		s := labeledStreamer(f, duration, note.name)
-		streamers = append(streamers, s)
+	// C'est la gamme chromatique (12 demi-tons), dont les fréquences
+	// sont calculées par le package music à partir du La3 (440 Hz).
+
+	Do3 := music.Note{Octave: 3, Index: music.MustLookupIndex("Do")}
+	scale := music.NewScale(Do3, music.ChromaticPattern)
+
+	synthesizer := wave.NewKarplusStrongSynthesizer(0., 1., int(sampleRate))
+	notes := scale.Notes(1)
+	signals := scale.Render(synthesizer, duration, 1)
+	for i, note := range notes {
+		label := fmt.Sprintf("note: %-4s (f=%.1f Hz)", note.Name(), note.Frequency())
+		streamers = append(streamers, sound.LabelledStreamer(sound.NewSound(signals[i]), label))
 	}
 
 	streamer := beep.Seq(streamers...)
@@ -297,61 +274,44 @@ func DEMO05_sounds_like_a_laser() error {
 	return nil
 }
 
-func almostEqual(x, y float64, accuracy float64) bool {
-	return math.Abs(x-y) < accuracy
-}
-
+// DEMO06_musicalscale compares the frequencies of the notes of the
+// chromatic scale obtained with a chain of fifths (quintes justes, de
+// rapport 3/2, ramenées dans l'octave en divisant par 2), i.e. the
+// pythagorean tuning, with the frequencies of the tempered equal scale.
+// The notes are then played in both tuning systems.
 func DEMO06_musicalscale() error {
-	f0 := 440.   // Hz
-	f1 := 2 * f0 // octave
-
-	//accuracy := 1e-1 // 666 values
-	accuracy := 1.0 // 666 values
-
-	// near return the index of the values array containing a value near
-	// the specified value with a distance less than 1e-2. Return -1 if
-	// no element found at this distance
-	near := func(value float64, values *[]float64) int {
-		for i := range len(*values) {
-			if almostEqual((*values)[i], value, accuracy) {
-				return i
-			}
+	scale := music.NewScale(music.La3, music.ChromaticPattern)
+	notes := scale.Notes(1)
+
+	equal := music.NewEqualTemperament(music.FrequencyLa3)
+	pythagorean := music.NewPythagorean(music.La3, music.FrequencyLa3)
+
+	for i, note := range notes {
+		fp := note.FrequencyIn(pythagorean)
+		fe := note.FrequencyIn(equal)
+		msg := fmt.Sprintf("%-5s pythagore f=%7.2f  tempéré f=%7.2f  écart=%+6.2f cents", note.Name(), fp, fe, music.RatioToCents(fp/fe))
+		if i > 0 {
+			r := fp / notes[i-1].FrequencyIn(pythagorean)
+			msg += fmt.Sprintf("  r=%.6f", r)
 		}
-		return -1
-	}
-	quinte := func(f float64) float64 {
-		return 3 * f / 2
+		fmt.Println(msg)
 	}
 
-	values := make([]float64, 0)
-	values = append(values, f0, f1)
-
-	f := f0
-	ended := false
-	for !ended {
-		f = quinte(f)
-		if f > f1 {
-			// We normalize the value (by dividing by 2) to stay in the
-			// octave f0-2f0.
-			f = f / 2.
-		}
-		if i := near(f, &values); i != -1 {
-			ended = true
-		} else {
-			values = append(values, f)
+	duration := 0.5
+	synthesizer := wave.NewSineWaveSynthesizer(0., 1., int(sampleRate))
+	streamers := make([]beep.Streamer, 0)
+	for _, tuning := range []music.Tuning{pythagorean, equal} {
+		streamers = append(streamers, silence(0.5))
+		for _, note := range notes {
+			synthesizer.SetFrequency(note.FrequencyIn(tuning))
+			streamers = append(streamers, sound.NewSound(synthesizer.Synthesize(duration)))
 		}
 	}
-	slices.Sort(values)
-	for _, f := range values {
-		fmt.Printf("f=%.2f\n", f)
-	}
-	fmt.Printf("size = %d\n", len(values))
 
-	for i := 0; i < len(values)-1; i++ {
-		r := values[i+1] / values[i]
-		fmt.Printf("r=%.6f\n", r)
+	streamer := beep.Seq(streamers...)
+	if err := sound.Play(streamer); err != nil {
+		return err
 	}
-
 	return nil
 }
 
@@ -424,3 +384,97 @@ func DEMO08_sequence_smoot_signal() error {
 	}
 	return nil
 }
+
+// DEMO09_scales plays some of the scales defined in the package music,
+// all starting from the same tonic (La2).
+func DEMO09_scales() error {
+	duration := 0.4
+	La2 := music.Note{Octave: 2, Index: music.MustLookupIndex("La")}
+	scales := []struct {
+		name    string
+		pattern music.ScalePattern
+	}{
+		{"majeure", music.MajorPattern},
+		{"mineure naturelle", music.NaturalMinorPattern},
+		{"mineure harmonique", music.HarmonicMinorPattern},
+		{"pentatonique mineure", music.MinorPentatonicPattern},
+		{"blues", music.BluesPattern},
+		{"par tons", music.WholeTonePattern},
+	}
+
+	synthesizer := wave.NewKarplusStrongSynthesizer(0., 1., int(sampleRate))
+	streamers := make([]beep.Streamer, 0)
+	for _, s := range scales {
+		scale := music.NewScale(La2, s.pattern)
+		streamers = append(streamers, sound.LabelledStreamer(silence(0.5), "gamme "+s.name))
+		for _, signal := range scale.Render(synthesizer, duration, 1) {
+			streamers = append(streamers, sound.NewSound(signal))
+		}
+	}
+
+	streamer := beep.Seq(streamers...)
+	if err := sound.Play(streamer); err != nil {
+		return err
+	}
+	return nil
+}
+
+func DEMO10_stereo() error {
+	// Un son qui se déplace de la gauche vers la droite, puis les effets
+	// stéréo (largeur, ping-pong, chorus) sur un son de guitare.
+	r := int(sampleRate)
+	d := 0.5
+	streamers := []beep.Streamer{sound.LabelledStreamer(silence(0.2), "panoramique de gauche à droite")}
+	for _, pan := range []float64{-1, -0.5, 0, 0.5, 1} {
+		s := sound.NewPannedSynthesizer(wave.NewSineWaveSynthesizer(440, 0.5, r), pan)
+		streamers = append(streamers, sound.StereoSynthSound(d, s))
+	}
+
+	pluck := wave.KarplusStrongSignal(220, 0.8, 2., r)
+	dry := sound.Pan(pluck, -0.3)
+	streamers = append(streamers,
+		sound.LabelledStreamer(silence(0.5), "guitare seule"),
+		sound.NewStereoSound(dry),
+		sound.LabelledStreamer(silence(0.5), "ping-pong delay"),
+		sound.NewStereoSound(sound.PingPongDelay(dry, 0.25, 0.5, 0.6, r)),
+		sound.LabelledStreamer(silence(0.5), "chorus"),
+		sound.NewStereoSound(sound.Chorus(sound.NewStereo(pluck), 0.004, 0.8, 0.7, r)),
+		sound.LabelledStreamer(silence(0.5), "chorus élargi"),
+		sound.NewStereoSound(sound.Width(sound.Chorus(sound.NewStereo(pluck), 0.004, 0.8, 0.7, r), 1.8)),
+	)
+
+	streamer := beep.Seq(streamers...)
+	if err := sound.Play(streamer); err != nil {
+		return err
+	}
+	return nil
+}
+
+// auclairdelalune est le début de la chanson, écrit dans la notation
+// texte du package music (voir music.ParseScore).
+const auclairdelalune = `
+tempo=100
+% Au clair de la lune, mon ami Pierrot
+|: mf Do4 q Do4 Do4 Re4 | Mi4 h Re4 | Do4 q Mi4 Re4 Re4 | Do4 w :|
+% Ouvre-moi ta porte, pour l'amour de Dieu
+p Re4 q Re4 Re4 Re4 | La3 h ~ La3 h | Re4 q Do4 Si3 La3 | Sol3 w
+mf Do4 q Do4 Do4 Re4 | Mi4 h Re4 | Do4 q Mi4 Re4 Re4 | f Do4 w
+`
+
+func DEMO11_score() error {
+	// Une mélodie écrite en notation texte, jouée sur une timeline
+	score, err := music.ParseScore(auclairdelalune)
+	if err != nil {
+		return err
+	}
+	tl := sequencer.NewTimeline(int(sampleRate), sequencer.Beats)
+	tl.Tempo = score.Tempo
+	tl.SetInstrument("synth", sequencer.Synth(wave.NewRegularTriangleWaveSynthesizer(0, 0.6, int(sampleRate))))
+	tl.Score(0, "synth", score)
+	fmt.Printf("%d notes, %.1f s\n", len(score.Notes), tl.Duration())
+	streamer, err := tl.Streamer()
+	if err != nil {
+		return err
+	}
+	return sound.Play(streamer)
+}
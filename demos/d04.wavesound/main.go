@@ -14,6 +14,9 @@ func init() {
 	applet.AddApplet("D06", "echelle musicale", DEMO06_musicalscale)
 	applet.AddApplet("D07", "filtre sigmoide", DEMO07_sigmoidfilter)
 	applet.AddApplet("D08", "sequence de signaux adoucis", DEMO08_sequence_smoot_signal)
+	applet.AddApplet("D09", "gammes et modes", DEMO09_scales)
+	applet.AddApplet("D10", "stéréo: panoramique et effets", DEMO10_stereo)
+	applet.AddApplet("D11", "partition en notation texte", DEMO11_score)
 }
 
 func main() {
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"math"
 
+	"github.com/gboulant/musicall/fft"
+	"github.com/gboulant/musicall/pitch"
 	"github.com/gboulant/musicall/sound"
 	"github.com/gboulant/musicall/wave"
 )
@@ -65,6 +68,15 @@ func D02_fft_frequencyOfMaxAmplitude() error {
 		}
 	}
 	fmt.Printf("Frequency of Maximal Amplitude = %.2f\n", frequencies[imax])
+
+	// Le détecteur de hauteur (package pitch) affine la fréquence par
+	// interpolation parabolique, et retrouve la note de musique
+	detector := pitch.NewDetector(r)
+	fpeak, apeak := detector.SpectrumPeak(s)
+	fmt.Printf("Interpolated spectrum peak     = %.2f (amplitude %.2f)\n", fpeak, apeak)
+	res := detector.Detect(s)
+	fmt.Printf("Detected pitch (YIN)           = %.2f (confidence %.2f): %s %+.1f cents\n",
+		res.Frequency, res.Confidence, res.Note.Name(), res.Cents)
 	return nil
 }
 
@@ -113,3 +125,78 @@ func D03_fft_smoothboundaries() error {
 
 	return nil
 }
+
+func D04_fft_recording() error {
+	// On enregistre dans un fichier WAV le son d'une corde pincée
+	// (synthétiseur Karplus Strong), puis on relit ce fichier comme on
+	// le ferait pour un enregistrement réel, pour l'analyser et le
+	// comparer au signal synthétisé.
+	f := 110. // Hz (La1)
+	r := wave.DefaultSampleRate
+	d := 2.
+	s := wave.KarplusStrongSignal(f, 1., d, r)
+	wavpath := "output.guitar.wav"
+	if err := sound.SaveWAV(wavpath, s, sound.DefaultEncodeOptions(r)); err != nil {
+		return err
+	}
+	fmt.Printf("Sound saved to %s\n", wavpath)
+
+	recording, err := sound.LoadWAV(wavpath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recording: %d channels, %d Hz, %d bits, %.2f s\n",
+		recording.Format.Channels, recording.Format.SampleRate,
+		recording.Format.BitDepth, recording.Duration())
+	samples := recording.Mono()
+	rr := recording.Format.SampleRate
+
+	// Plot of the time series and spectrums
+	pts := wave.NewPlotter()
+	pts.AddLineSampledValues(s, r, "Synthèse")
+	pts.AddLineSampledValues(samples, rr, "Enregistrement")
+	pts.Save("output.signal.html")
+
+	psp := wave.NewPlotter()
+	frequencies, amplitudes := wave.Spectrum(s, r)
+	psp.AddLineXYValues(frequencies, amplitudes, "Synthèse")
+	frequencies, amplitudes = wave.Spectrum(samples, rr)
+	psp.AddLineXYValues(frequencies, amplitudes, "Enregistrement")
+	psp.SetXFormatter("{value}Hz")
+	psp.Save("output.fft.html")
+
+	res := pitch.Detect(samples[:rr/10], rr)
+	fmt.Printf("Detected pitch = %.2f Hz: %s %+.1f cents\n", res.Frequency, res.Note.Name(), res.Cents)
+	return nil
+}
+
+func D05_spectrogram() error {
+	// Le spectrogramme montre l'évolution du spectre dans le temps: la
+	// montée en fréquence d'un balayage, et l'extinction des harmoniques
+	// d'une corde pincée (les aigus s'éteignent plus vite que le
+	// fondamental). L'axe des fréquences est logarithmique, comme
+	// l'oreille et les notes de la gamme.
+	r := wave.DefaultSampleRate
+	sweep := wave.SweepFrequencySignal(100, 4000, 1., 3., r)
+	pluck := wave.KarplusStrongSignal(110., 1., 3., r)
+
+	p := wave.NewPlotter()
+	p.AddSpectrogram(wave.Spectrogram(sweep, r), wave.LinearFrequency, "Balayage de 100 à 4000 Hz")
+	p.AddSpectrogram(wave.Spectrogram(pluck, r), wave.LogFrequency, "Corde pincée (La1)")
+	if err := p.Save("output.spectrogram.html"); err != nil {
+		return err
+	}
+
+	// La transformée inverse reconstruit le signal (aux erreurs
+	// d'arrondi près)
+	options := fft.STFTOptions{FrameSize: 2048, Overlap: 0.75}
+	back := fft.ISTFT(fft.STFT(pluck, options), len(pluck), options)
+	maxerr := 0.
+	for i := range pluck {
+		maxerr = max(maxerr, math.Abs(back[i]-pluck[i]))
+	}
+	fmt.Printf("Reconstruction error (ISTFT) = %.2e\n", maxerr)
+
+	sound.Init(r)
+	return sound.Play(sound.NewSound(pluck))
+}
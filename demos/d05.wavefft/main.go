@@ -8,6 +8,8 @@ func init() {
 	applet.AddApplet("D01", "Signal à 2 fréquences", D01_fft)
 	applet.AddApplet("D02", "Fréquence d'amplitude Max", D02_fft_frequencyOfMaxAmplitude)
 	applet.AddApplet("D03", "Augmentation du contraste", D03_fft_smoothboundaries)
+	applet.AddApplet("D04", "Analyse d'un enregistrement WAV", D04_fft_recording)
+	applet.AddApplet("D05", "Spectrogramme d'un balayage et d'une corde", D05_spectrogram)
 }
 
 func main() {
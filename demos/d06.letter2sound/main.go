@@ -1,12 +1,30 @@
 package main
 
 import (
+	"flag"
 	"log"
 
+	"github.com/gboulant/musicall/music"
 	"github.com/gboulant/musicall/sound"
 )
 
 func program() error {
+	// Avec -score, le texte est une partition (voir music.ParseScore),
+	// par exemple "Do4 q Re4 e Mi4 e | Sol4 h", jouée avec le même
+	// synthétiseur que les lettres.
+	text := flag.String("score", "", "play a score written in text notation instead of the phrase")
+	flag.Parse()
+	if err := sound.Init(sampleRate); err != nil {
+		return err
+	}
+	if *text != "" {
+		score, err := music.ParseScore(*text)
+		if err != nil {
+			return err
+		}
+		return sound.Play(score2streamer(score))
+	}
+
 	phrase := "Salut Martin, le petit lapin"
 	//phrase := "ABCDEFGHIJKLMNOPQRSTUVWXYZ abcdefghijklmnopqrstuvwxyz"
 	//phrase := "Martin Guillaume Anne-Laure Gaelle Lucie"
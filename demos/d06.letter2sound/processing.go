@@ -12,13 +12,6 @@ import (
 
 var sampleRate = wave.DefaultSampleRate
 
-func init() {
-	err := sound.Init(sampleRate)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
 // -------------------------------------------------------------------
 // Model with a frequency value linear with the ascii code value
 
@@ -79,3 +72,27 @@ func string2streamer(phrase string) beep.Streamer {
 	}
 	return beep.Seq(streamers...)
 }
+
+// score2streamer joue les notes de la partition l'une après l'autre,
+// avec des silences entre les notes (les silences de la partition).
+func score2streamer(score music.Score) beep.Streamer {
+	streamers := make([]beep.Streamer, 0, 2*len(score.Notes))
+	var end music.Ticks
+	for _, n := range score.Notes {
+		if n.Start > end {
+			streamers = append(streamers, sound.Silence(score.Tempo.Duration(end, n.Start-end), sampleRate))
+		}
+		duration := score.Tempo.Duration(n.Start, n.Duration)
+		synthetizer.SetFrequency(n.Note.Frequency())
+		synthetizer.SetAmplitude(n.Velocity)
+		samples := synthetizer.Synthesize(duration)
+		wave.SmoothBoundaries(&samples, sampleRate, 0.1*duration)
+		label := fmt.Sprintf("%-5s f=%.1f Hz", n.Note.Name(), n.Note.Frequency())
+		streamers = append(streamers, sound.LabelledStreamer(sound.NewSound(samples), label))
+		end = n.Start + n.Duration
+	}
+	if score.Duration > end {
+		streamers = append(streamers, sound.Silence(score.Tempo.Duration(end, score.Duration-end), sampleRate))
+	}
+	return beep.Seq(streamers...)
+}
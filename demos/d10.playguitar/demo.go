@@ -1,9 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"log"
 
+	"github.com/gboulant/musicall/effects"
 	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/midi"
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/sequencer"
 	"github.com/gboulant/musicall/sound"
 	"github.com/gboulant/musicall/wave"
 	"github.com/gopxl/beep"
@@ -23,7 +28,7 @@ func init() {
 }
 
 func chord(name string) guitar.Chord {
-	return guitar.StandardChord(name)
+	return guitar.MustLookupChord(name)
 }
 
 var (
@@ -37,6 +42,52 @@ var (
 	Lam = chord("Lam")
 )
 
+// roomTail is the time given to the reverberation to fade out at the
+// end of a song (in seconds)
+const roomTail = 2.
+
+// room returns the reverberation of the room and its tail: the
+// convolution with the impulse response of the -ir option (a WAV file),
+// or a medium room.
+func room() (effects.Effect, float64, error) {
+	if *roomResponse == "" {
+		return effects.NewReverb(0.5, 0.5, 0.25, sampleRate), roomTail, nil
+	}
+	ir, err := sound.LoadImpulseResponse(*roomResponse, sampleRate)
+	if err != nil {
+		return nil, 0, err
+	}
+	tail := max(roomTail, float64(len(ir))/float64(sampleRate))
+	return effects.NewConvolver(ir, effects.DefaultBlockSize, 0.25, sampleRate), tail, nil
+}
+
+// play plays the streamer in a room, so that the guitar does not sound
+// as a dry signal, except with the -dry option. The effects (e.g. an
+// amplifier) are applied before the room.
+func play(s beep.Streamer, fx ...effects.Effect) error {
+	tail := 0.
+	if !*dry {
+		r, t, err := room()
+		if err != nil {
+			return err
+		}
+		fx, tail = append(fx, r), t
+	}
+	if len(fx) == 0 {
+		return sound.Play(s)
+	}
+	return sound.Play(effects.Stream(s, effects.Chain(fx...), tail, sampleRate))
+}
+
+// crunch returns an amplifier with a crunchy tube distortion, for the
+// rock songs
+func crunch() effects.Effect {
+	amp := effects.NewAmp(12, 0.4, sampleRate)
+	amp.Tone.Middle = effects.Const(3)
+	amp.Tone.Treble = effects.Const(-2)
+	return amp
+}
+
 // -----------------------------------------------------------
 // Technical/Exercice examples
 
@@ -58,7 +109,7 @@ func T01_play_open_strings() error {
 	)
 
 	// Then play the resulting streamer
-	return sound.Play(s)
+	return play(s)
 }
 
 // Les accords de base principaux
@@ -68,8 +119,13 @@ func T02_main_chords() error {
 	duration := 0.8 // duration of the chord
 	delay := 0.04   // delay between the string plucks
 	labelledChord := func(label string) beep.Streamer {
-		chord := guitar.StandardChord(label)
+		chord := guitar.MustLookupChord(label)
 		stream := g.Chord(chord, duration, delay)
+		// We label the chord with the name given by the music theory
+		// for the notes actually played (e.g. Fa/La for the Fa chord)
+		if c, ok := chord.Identify(); ok {
+			label = fmt.Sprintf("%-4s: %s", label, c.Name())
+		}
 		return sound.LabelledStreamer(stream, label)
 	}
 
@@ -86,7 +142,7 @@ func T02_main_chords() error {
 	)
 
 	// Then play the resulting streamer
-	return sound.Play(s)
+	return play(s)
 }
 
 // Gamme pentatonique en La
@@ -116,7 +172,67 @@ func T03_pentatonic_scale_La() error {
 	s := gamme(5)
 
 	// Then play the resulting streamer
-	return sound.Play(s)
+	return play(s)
+}
+
+// Arpèges sur une timeline: les notes sont placées à des temps absolus
+// (en temps, au tempo de la timeline) et résonnent les unes sur les
+// autres, avec une basse synthétisée jouée en même temps.
+func T04_timeline_arpeggios() error {
+	tl := sequencer.NewTimeline(sampleRate, sequencer.Beats)
+	tl.Tempo = music.MustNewTempoMap(100, music.CommonTime)
+	tl.Tempo.RampTempo(tl.Tempo.Bar(4), 70) // ritardando jusqu'à la dernière mesure
+	g := guitar.NewGuitar(sampleRate)
+	g.SpreadStrings(0.6)
+	tl.SetInstrument("guitar", sequencer.Guitar(g))
+	tl.SetInstrument("bass", sequencer.Synth(wave.NewSineWaveSynthesizer(0., 0.4, sampleRate)))
+
+	for i, c := range []guitar.Chord{Lam, Fa, Do, Sol} {
+		// La basse tient la première note de l'accord pendant la mesure
+		bar := tl.Tempo.Bar(i + 1)
+		tl.Note(bar.Beats(), music.Whole.Beats(), "bass", sequencer.Hz(c[0].Frequency()/2), 1)
+		for j, note := range c {
+			// Une corde par croche, qui sonne jusqu'à la fin de la mesure
+			start := music.Eighth.Times(j)
+			tl.Note((bar + start).Beats(), (music.Whole - start).Beats(), "guitar", note, 0.8)
+		}
+	}
+
+	s, err := tl.Streamer()
+	if err != nil {
+		return err
+	}
+	return play(s)
+}
+
+// T05_export_midi writes the chord progression of the arpeggios (T04)
+// strummed, followed by a riff, in a MIDI file with one channel per
+// string.
+func T05_export_midi() error {
+	tempo := music.MustNewTempoMap(100, music.CommonTime)
+	tempo.RampTempo(tempo.Bar(6), 70)
+	part := midi.NewGuitarPart()
+	part.PerString = true
+
+	chords, err := part.Strum([]guitar.Chord{Lam, Lam, Fa, Fa, Do, Do, Sol, Sol}, music.Half, music.ThirtySecond)
+	if err != nil {
+		return err
+	}
+	riff, err := part.Pick([]guitar.Note{
+		{StringNum: 5, FretNum: 0}, {StringNum: 5, FretNum: 3}, {StringNum: 4, FretNum: 0}, {StringNum: 4, FretNum: 2},
+		{StringNum: 3, FretNum: 0}, {StringNum: 3, FretNum: 2}, {StringNum: 2, FretNum: 1}, {StringNum: 1, FretNum: 0},
+	}, music.Eighth)
+	if err != nil {
+		return err
+	}
+	notes := append(chords, midi.Shift(riff, tempo.Bar(5))...)
+
+	filepath := "output.guitar.mid"
+	if err := midi.NewFile(tempo, notes).Save(filepath); err != nil {
+		return err
+	}
+	fmt.Printf("The MIDI file is written in %s\n", filepath)
+	return nil
 }
 
 // -----------------------------------------------------------
@@ -124,8 +240,11 @@ func T03_pentatonic_scale_La() error {
 
 // Nocking on the heaven's door
 func D01_Nocking_on_the_heavens_door() error {
-	duration := 0.8 // duration of the chord
-	delay := 0.04   // delay between the string plucks
+	// The chords are quarter notes at 75 BPM (0.8 s). Change the tempo
+	// for playing faster or slower.
+	tempo := music.MustNewTempoMap(75, music.CommonTime)
+	duration := tempo.Duration(0, music.Quarter) // duration of the chord
+	delay := 0.04                                // delay between the string plucks
 
 	g := guitar.NewGuitar(sampleRate)
 	s := beep.Seq(
@@ -150,12 +269,14 @@ func D01_Nocking_on_the_heavens_door() error {
 		g.Chord(Do, duration, delay),
 	)
 
-	return sound.Play(s)
+	return play(s)
 }
 
 func D02_U2_One() error {
-	duration := 2. // duration of the chord
-	delay := 0.1   // delay between the string plucks
+	// One chord per bar (a whole note) at 120 BPM
+	tempo := music.MustNewTempoMap(120, music.CommonTime)
+	duration := tempo.Duration(0, music.Whole) // duration of the chord
+	delay := 0.1                               // delay between the string plucks
 
 	g := guitar.NewGuitar(sampleRate)
 	s := beep.Seq(
@@ -167,7 +288,7 @@ func D02_U2_One() error {
 		g.Chord(Do, duration, delay),
 	)
 
-	return sound.Play(s)
+	return play(s)
 }
 
 // ACDC - Thunderstruck
@@ -235,7 +356,7 @@ func D03_ACDC_Thunderstruck() error {
 		riff(), riff(),
 	)
 
-	return sound.Play(s)
+	return play(s, crunch())
 }
 
 // Tostaky
@@ -276,7 +397,7 @@ func D04_NoirDesir_Tostaky() error {
 	)
 
 	// Then we play the resulting streamer
-	return sound.Play(s)
+	return play(s, crunch())
 }
 
 func D05_NoirDesir_Un_jour_en_France() error {
@@ -323,7 +444,7 @@ func D05_NoirDesir_Un_jour_en_France() error {
 	)
 
 	// Then we play the resulting streamer
-	return sound.Play(s)
+	return play(s)
 }
 
 // Le vent l'emportera, pont
@@ -345,7 +466,7 @@ func D06_NoirDesir_Le_vent_l_emportera() error {
 	)
 
 	// Then we play the resulting streamer
-	return sound.Play(s)
+	return play(s)
 }
 
 // Bloody Sunday, U2
@@ -377,7 +498,7 @@ func D07_U2_Bloody_Sunday() error {
 	)
 
 	// Then we play the resulting streamer
-	return sound.Play(s)
+	return play(s)
 }
 
 // Play a rythm Bas & Bas - Haut & Haut - Bas
@@ -406,7 +527,7 @@ func D08_Rythm_UpDown() error {
 	)
 
 	// Then we play the resulting streamer
-	return sound.Play(s)
+	return play(s)
 }
 
 func D09_Johnny_Cash_Hurt() error {
@@ -418,6 +539,23 @@ func D09_Johnny_Cash_Hurt() error {
 	ks2 := guitar.NewKarplusStrongSynthesizer(0., 1., 0.05, sampleRate)
 	g2.UseSynthesizer(ks2)
 
-	s := beep.Mix(hurtLead(g1), hurtRhythm(g2))
-	return sound.Play(s)
+	// Les deux guitares sont mixées avec un gain et une position dans
+	// l'espace stéréo. La somme dépasserait 1 sans le limiteur du bus
+	// master (beep.Mix se contente d'additionner les échantillons).
+	m := sound.NewMixer(sampleRate)
+	lead := m.AddTrack("lead", hurtLead(g1))
+	lead.Gain = -2
+	lead.Pan = 0.3
+	rhythm := m.AddTrack("rhythm", hurtRhythm(g2))
+	rhythm.Gain = -4
+	rhythm.Pan = -0.3
+	if err := play(m); err != nil {
+		return err
+	}
+
+	for _, t := range m.Tracks() {
+		fmt.Printf("%-8s peak %6.1f dB, RMS %6.1f dB\n", t.Name, t.Meter.PeakDB(), t.Meter.RMSDB())
+	}
+	fmt.Printf("%-8s peak %6.1f dB, RMS %6.1f dB\n", "master", m.Master.PeakDB(), m.Master.RMSDB())
+	return nil
 }
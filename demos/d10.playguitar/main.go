@@ -1,13 +1,24 @@
 package main
 
-import applet "github.com/gboulant/dingo-applet"
+import (
+	"flag"
+
+	applet "github.com/gboulant/dingo-applet"
+)
 
 const defaultExampleName string = "D01"
 
+var (
+	dry          = flag.Bool("dry", false, "play the guitar without the room reverberation")
+	roomResponse = flag.String("ir", "", "impulse response of the room (WAV file), instead of the default reverberation")
+)
+
 func init() {
 	applet.AddApplet("T01", "Play all open strings", T01_play_open_strings)
 	applet.AddApplet("T02", "Play the main chords", T02_main_chords)
 	applet.AddApplet("T03", "Play the pentatonic scale from La", T03_pentatonic_scale_La)
+	applet.AddApplet("T04", "Arpeggios on a timeline", T04_timeline_arpeggios)
+	applet.AddApplet("T05", "Export chords and a riff as a MIDI file", T05_export_midi)
 
 	applet.AddApplet("D01", "Nocking on the heaven's door", D01_Nocking_on_the_heavens_door)
 	applet.AddApplet("D02", "U2, One", D02_U2_One)
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/gboulant/musicall/sound"
+)
+
+// readInput reads the samples of the input file (a WAV file). If the
+// path is "-", the samples are read from the standard input, either as
+// a WAV stream or as raw PCM frames (signed 16 bits little endian,
+// mono) sampled at the specified default sample rate.
+func readInput(path string, defaultSampleRate int) (samples []float64, sampleRate int, err error) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	br := bufio.NewReader(r)
+	header, err := br.Peek(4)
+	if err == nil && string(header) == "RIFF" {
+		return readWAV(br)
+	}
+	if path != "-" {
+		return nil, 0, errors.New("the input file is not a WAV file")
+	}
+	samples, err = readPCM(br)
+	return samples, defaultSampleRate, err
+}
+
+// readWAV decodes a WAV stream and returns the samples averaged over
+// the channels (mono).
+func readWAV(r io.Reader) (samples []float64, sampleRate int, err error) {
+	recording, err := sound.DecodeWAV(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return recording.Mono(), recording.Format.SampleRate, nil
+}
+
+// readPCM reads raw PCM frames, signed 16 bits little endian, mono
+func readPCM(r io.Reader) ([]float64, error) {
+	samples := make([]float64, 0)
+	var value int16
+	for {
+		err := binary.Read(r, binary.LittleEndian, &value)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return samples, nil
+		}
+		if err != nil {
+			return samples, err
+		}
+		samples = append(samples, float64(value)/32768.)
+	}
+}
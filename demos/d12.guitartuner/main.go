@@ -0,0 +1,94 @@
+package main
+
+// The program guitartuner is a tuner for the guitar that analyzes a
+// recording (a WAV file, or PCM frames read on the standard input) and
+// reports, for each window of the signal where a note is detected, the
+// string being played and the deviation in cents from the note of the
+// open string. No sound card is needed.
+//
+// Examples:
+//
+//	guitartuner recording.wav
+//	guitartuner -tuning dadgad -window 0.2 recording.wav
+//	arecord -f S16_LE -r 44100 -c 1 -t raw | guitartuner -rate 44100 -
+//
+// Without input file, the tuner analyzes a synthesized pluck of each
+// string, slightly out of tune.
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/wave"
+)
+
+func tuningNames() string {
+	names := make([]string, len(guitar.StringTunings))
+	for i, t := range guitar.StringTunings {
+		names[i] = t.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// synthesizedInput creates a signal where each string of the tuning is
+// plucked in turn, with a deviation of a few cents.
+func synthesizedInput(tuning guitar.StringTuning, sampleRate int) []float64 {
+	samples := make([]float64, 0)
+	synthesizer := wave.NewKarplusStrongSynthesizer(0., 1., wave.SampleRate(sampleRate))
+	deviations := []float64{-25, 12, -7, 3, 30, -15}
+	for s := guitar.Mi1; s >= guitar.Mi3; s-- {
+		note, _ := tuning.OpenStringNote(s)
+		frequency := note.Frequency() * music.CentsToRatio(deviations[guitar.Mi1-s])
+		synthesizer.SetFrequency(frequency)
+		samples = append(samples, synthesizer.Synthesize(1.)...)
+	}
+	return samples
+}
+
+func program() error {
+	tuningName := flag.String("tuning", "standard", "tuning of the guitar ("+tuningNames()+")")
+	rate := flag.Int("rate", wave.DefaultSampleRate, "sample rate of the PCM frames read on the standard input")
+	window := flag.Float64("window", 0.1, "duration of the analysis window in seconds")
+	hop := flag.Float64("hop", 0.05, "time step between two windows in seconds")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [options] [file.wav | -]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	tuning, ok := guitar.LookupStringTuning(*tuningName)
+	if !ok {
+		return fmt.Errorf("the tuning %q is not defined (%s)", *tuningName, tuningNames())
+	}
+
+	var samples []float64
+	sampleRate := *rate
+	if flag.NArg() == 0 {
+		samples = synthesizedInput(tuning, sampleRate)
+	} else {
+		var err error
+		if samples, sampleRate, err = readInput(flag.Arg(0), *rate); err != nil {
+			return err
+		}
+	}
+
+	tuner := NewTuner(tuning, sampleRate)
+	tuner.Window = *window
+	tuner.Hop = *hop
+	fmt.Printf("Tuning %s: %s\n", tuning.Name, tuning)
+	for _, r := range tuner.Analyze(samples) {
+		fmt.Println(tuner.Format(r))
+	}
+	return nil
+}
+
+func main() {
+	if err := program(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/pitch"
+)
+
+// Reading is the result of the analysis of a window of the signal
+type Reading struct {
+	Time       float64             // start time of the window (in seconds)
+	Frequency  float64             // detected frequency (0 if no pitch detected)
+	Confidence float64             // confidence of the pitch detection
+	String     guitar.StringNumber // nearest open string
+	Cents      float64             // deviation from the open string note
+}
+
+// Tuner analyzes a signal in sliding windows to find the string being
+// played and its deviation from the note of the open string.
+type Tuner struct {
+	Tuning        guitar.StringTuning
+	SampleRate    int
+	Window        float64 // duration of the analysis window (in seconds)
+	Hop           float64 // time step between two windows (in seconds)
+	MinConfidence float64 // readings with a lower confidence are ignored
+	MinLevel      float64 // readings with a lower RMS level are ignored (silence)
+}
+
+func NewTuner(tuning guitar.StringTuning, sampleRate int) *Tuner {
+	return &Tuner{
+		Tuning:        tuning,
+		SampleRate:    sampleRate,
+		Window:        0.1,
+		Hop:           0.05,
+		MinConfidence: 0.8,
+		MinLevel:      0.01,
+	}
+}
+
+// Analyze returns the readings of the windows where a pitch is detected
+func (t Tuner) Analyze(samples []float64) []Reading {
+	detector := pitch.NewDetector(t.SampleRate)
+	window := int(t.Window * float64(t.SampleRate))
+	hop := max(1, int(t.Hop*float64(t.SampleRate)))
+
+	readings := make([]Reading, 0)
+	for start := 0; start+window <= len(samples); start += hop {
+		frame := samples[start : start+window]
+		if rms(frame) < t.MinLevel {
+			continue
+		}
+		res := detector.Detect(frame)
+		if res.Frequency == 0 || res.Confidence < t.MinConfidence {
+			continue
+		}
+		s, cents := t.Tuning.NearestString(res.Frequency)
+		readings = append(readings, Reading{
+			Time:       float64(start) / float64(t.SampleRate),
+			Frequency:  res.Frequency,
+			Confidence: res.Confidence,
+			String:     s,
+			Cents:      cents,
+		})
+	}
+	return readings
+}
+
+// Format returns a line of the tuner report, with a needle indicating
+// if the string is too low (<) or too high (>).
+func (t Tuner) Format(r Reading) string {
+	note, _ := t.Tuning.OpenStringNote(r.String)
+	needle := "ok"
+	if r.Cents < -5 {
+		needle = "<< monter"
+	} else if r.Cents > 5 {
+		needle = ">> baisser"
+	}
+	return fmt.Sprintf("%7.2fs  %8.2f Hz  S%d (%-4s) %+6.1f cents  %s",
+		r.Time, r.Frequency, r.String, note.Name(), r.Cents, needle)
+}
+
+func rms(samples []float64) float64 {
+	var sum float64
+	for _, v := range samples {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/wave"
+)
+
+func TestTuner_Analyze(t *testing.T) {
+	r := wave.DefaultSampleRate
+	note, _ := guitar.DropDTuning.OpenStringNote(guitar.Mi1)
+	frequency := note.Frequency() * music.CentsToRatio(-15)
+	samples := append(make([]float64, r/2), wave.SineWaveSignal(frequency, 0.5, 1., r)...)
+
+	tuner := NewTuner(guitar.DropDTuning, r)
+	readings := tuner.Analyze(samples)
+	if len(readings) == 0 {
+		t.Fatalf("no pitch detected")
+	}
+	if readings[0].Time < 0.4 {
+		t.Errorf("first reading at %.2fs (should be after the silence)", readings[0].Time)
+	}
+	for _, reading := range readings {
+		if reading.String != guitar.Mi1 {
+			t.Errorf("string is %d (should be %d)", reading.String, guitar.Mi1)
+		}
+		if math.Abs(reading.Cents+15) > 1 {
+			t.Errorf("cents is %.2f (should be %.2f)", reading.Cents, -15.)
+		}
+	}
+}
+
+func TestReadPCM(t *testing.T) {
+	var buffer bytes.Buffer
+	values := []int16{0, 16384, -32768, 32767}
+	binary.Write(&buffer, binary.LittleEndian, values)
+	buffer.WriteByte(0) // incomplete frame is ignored
+
+	samples, err := readPCM(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []float64{0, 0.5, -1, 32767. / 32768.}
+	if len(samples) != len(exp) {
+		t.Fatalf("number of samples is %d (should be %d)", len(samples), len(exp))
+	}
+	for i, v := range exp {
+		if samples[i] != v {
+			t.Errorf("sample %d is %f (should be %f)", i, samples[i], v)
+		}
+	}
+}
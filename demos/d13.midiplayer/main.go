@@ -0,0 +1,125 @@
+package main
+
+// The program midiplayer plays a Standard MIDI File (format 0 or 1) with
+// the synthesizers of the project, or renders it into an audio file
+// (whose format is given by its extension). The notes can be played by
+// a guitar, each note being mapped automatically onto a string. With
+// -live, the notes are sent as events to a pool of voices (see the
+// package live), as if they were played on a keyboard.
+//
+// Examples:
+//
+//	midiplayer song.mid
+//	midiplayer -guitar -spread 0.6 song.mid
+//	midiplayer -synth square -o output.song.flac song.mid
+//	midiplayer -live -polyphony 4 -synth sine song.mid
+//	MUSICALL_AUDIO=null midiplayer song.mid  # no sound card
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/live"
+	"github.com/gboulant/musicall/midi"
+	"github.com/gboulant/musicall/sequencer"
+	"github.com/gboulant/musicall/sound"
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+)
+
+const sampleRate = wave.DefaultSampleRate
+
+var synthesizers = map[string]func() wave.HarmonicSynthesizer{
+	"sine":   func() wave.HarmonicSynthesizer { return wave.NewSineWaveSynthesizer(0, 0.5, sampleRate) },
+	"square": func() wave.HarmonicSynthesizer { return wave.NewSquareWaveSynthesizer(0, 0.3, sampleRate) },
+	"ks":     func() wave.HarmonicSynthesizer { return wave.NewKarplusStrongSynthesizer(0, 0.8, sampleRate) },
+}
+
+func program() error {
+	useGuitar := flag.Bool("guitar", false, "play the notes with a guitar, mapped onto the strings")
+	spread := flag.Float64("spread", 0.5, "stereo spread of the guitar strings (0 to 1)")
+	synth := flag.String("synth", "ks", "synthesizer when the guitar is not used (sine, square, ks)")
+	outpath := flag.String("o", "", "render into an audio file (.wav, .flac, .aiff) instead of playing")
+	useLive := flag.Bool("live", false, "play the notes as live events with a pool of voices")
+	polyphony := flag.Int("polyphony", live.DefaultPolyphony, "maximum number of voices with -live")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [options] file.mid\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := midi.Load(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	for i, t := range f.Tracks {
+		fmt.Printf("track %d: %q, %d events\n", i+1, t.Name, len(t.Events))
+	}
+
+	if *useLive {
+		newSynthesizer, ok := synthesizers[*synth]
+		if !ok {
+			return fmt.Errorf("the synthesizer %q is not defined", *synth)
+		}
+		pool := live.NewVoicePool(sampleRate, newSynthesizer, *polyphony, live.FileEvents(f))
+		return output(pool, *outpath)
+	}
+
+	// Chaque canal a son propre synthétiseur, pour que les notes de
+	// plusieurs canaux puissent être synthétisées simultanément.
+	options := midi.Options{Channels: make(map[int]sequencer.Instrument)}
+	for channel := range 16 {
+		if channel == midi.PercussionChannel {
+			continue
+		}
+		if *useGuitar {
+			g := guitar.NewGuitar(sampleRate)
+			g.SpreadStrings(*spread)
+			options.Channels[channel] = sequencer.Guitar(g)
+		} else {
+			newSynthesizer, ok := synthesizers[*synth]
+			if !ok {
+				return fmt.Errorf("the synthesizer %q is not defined", *synth)
+			}
+			options.Channels[channel] = sequencer.Synth(newSynthesizer())
+		}
+	}
+	options.MapToGuitar = *useGuitar
+
+	tl := f.Timeline(sampleRate, options)
+	fmt.Printf("%d notes, %.1f s\n", len(tl.Events()), tl.Duration())
+	s, err := tl.Streamer()
+	if err != nil {
+		return err
+	}
+	return output(s, *outpath)
+}
+
+// output plays the streamer, or renders it into the file if outpath is
+// defined. The streamer is mixed on a master bus with a limiter.
+func output(s beep.Streamer, outpath string) error {
+	m := sound.NewMixer(sampleRate)
+	m.AddTrack("midi", s)
+	if outpath != "" {
+		options := sound.DefaultEncodeOptions(sampleRate)
+		options.Channels = 2
+		return sound.RenderFile(m, outpath, options)
+	}
+	if err := sound.Init(sampleRate); err != nil {
+		return err
+	}
+	return sound.Play(m)
+}
+
+func main() {
+	if err := program(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,185 @@
+package effects
+
+import "math"
+
+// Un ampli de guitare est simulé en trois étages: le préampli (une
+// distorsion à lampe), l'égaliseur (tone stack, les boutons bass, middle
+// et treble), et le baffle (cabinet), dont le haut-parleur filtre
+// fortement le son. Le baffle est simulé par la convolution avec sa
+// réponse impulsionnelle, enregistrée (un fichier WAV, voir
+// sound.LoadImpulseResponse) ou synthétique (voir CabinetResponse).
+
+// Frequencies of the bands of the tone stack
+const (
+	BassFrequency   = 100.  // Hz, low shelf
+	MiddleFrequency = 800.  // Hz, bell
+	TrebleFrequency = 3200. // Hz, high shelf
+)
+
+// ToneStack is the equalizer of an amplifier: the gains (in dB, 0 for a
+// flat response) of the bass, the middle and the treble.
+type ToneStack struct {
+	Bass   Param // dB
+	Middle Param // dB
+	Treble Param // dB
+
+	clock   clock
+	filters [2][3]biquad
+	gains   [3]float64 // gains of the current coefficients
+	ready   bool
+}
+
+// NewToneStack returns a tone stack with constant gains (in dB)
+func NewToneStack(bass, middle, treble float64, sampleRate int) *ToneStack {
+	return &ToneStack{Bass: Const(bass), Middle: Const(middle), Treble: Const(treble), clock: newClock(sampleRate)}
+}
+
+func (s *ToneStack) Process(frame [2]float64) [2]float64 {
+	t := s.clock.tick()
+	gains := [3]float64{s.Bass(t), s.Middle(t), s.Treble(t)}
+	if !s.ready || gains != s.gains {
+		// Les coefficients ne sont recalculés que si les gains changent
+		rate := s.clock.sampleRate
+		for c := range 2 {
+			s.filters[c][0].lowShelf(BassFrequency, gains[0], rate)
+			s.filters[c][1].peaking(MiddleFrequency, 0.7, gains[1], rate)
+			s.filters[c][2].highShelf(TrebleFrequency, gains[2], rate)
+		}
+		s.gains, s.ready = gains, true
+	}
+	for c := range 2 {
+		for i := range s.filters[c] {
+			frame[c] = s.filters[c][i].process(frame[c])
+		}
+	}
+	return frame
+}
+
+func (s *ToneStack) Reset() {
+	s.clock.frame = 0
+	for c := range 2 {
+		for i := range s.filters[c] {
+			s.filters[c][i].reset()
+		}
+	}
+}
+
+// -------------------------------------------------------------
+// Cabinet simulates the loudspeaker cabinet of an amplifier by the
+// convolution of the signal with its impulse response (computed
+// directly, then the response should be short, a few thousands of
+// samples).
+type Cabinet struct {
+	Mix Param
+
+	clock    clock
+	response []float64
+	lines    [2]*delayLine
+}
+
+// NewCabinet returns a cabinet with the impulse response. The response
+// must have the sample rate of the signal.
+func NewCabinet(response []float64, mix float64, sampleRate int) *Cabinet {
+	c := &Cabinet{response: response, Mix: Const(mix), clock: newClock(sampleRate)}
+	c.lines = [2]*delayLine{newDelayLine(len(response) + 1), newDelayLine(len(response) + 1)}
+	return c
+}
+
+func (c *Cabinet) Process(frame [2]float64) [2]float64 {
+	t := c.clock.tick()
+	m := c.Mix(t)
+	var out [2]float64
+	for ch, line := range c.lines {
+		line.write(frame[ch])
+		// y[n] = somme des h[k]*x[n-k], x[n-k] étant lu au retard k+1
+		n := len(line.buffer)
+		pos := line.pos
+		wet := 0.
+		for k, h := range c.response {
+			wet += h * line.buffer[(pos-1-k+2*n)%n]
+		}
+		out[ch] = mix(frame[ch], wet, m)
+	}
+	return out
+}
+
+func (c *Cabinet) Reset() {
+	c.clock.frame = 0
+	for _, line := range c.lines {
+		line.reset()
+	}
+}
+
+// CabinetResponse returns a synthetic impulse response of a guitar
+// cabinet (a 12 inches loudspeaker) of 20 ms: a band-pass from about 80
+// Hz to 5 kHz, with the resonance of the cone around 2.5 kHz. It is used
+// when no recorded response is available.
+func CabinetResponse(sampleRate int) []float64 {
+	rate := newClock(sampleRate).sampleRate
+	var filters [4]biquad
+	filters[0].highPass(80, 0.7, rate)
+	filters[1].peaking(2500, 1.5, 6, rate)
+	filters[2].lowPass(5000, 0.7, rate)
+	filters[3].lowPass(5000, 0.7, rate)
+	response := make([]float64, int(0.02*rate))
+	for i := range response {
+		x := 0.
+		if i == 0 {
+			x = 1
+		}
+		for j := range filters {
+			x = filters[j].process(x)
+		}
+		response[i] = x
+	}
+	// La réponse est normalisée en énergie, pour que le baffle ne change
+	// pas le volume perçu.
+	energy := 0.
+	for _, h := range response {
+		energy += h * h
+	}
+	if energy > 0 {
+		for i := range response {
+			response[i] /= math.Sqrt(energy)
+		}
+	}
+	return response
+}
+
+// -------------------------------------------------------------
+// Amp is a guitar amplifier: a preamplifier (a tube distortion), a tone
+// stack and a cabinet, processed in this order. Each stage can be
+// changed, the cabinet can be nil (a direct output).
+type Amp struct {
+	Preamp  *Distortion
+	Tone    *ToneStack
+	Cabinet *Cabinet
+}
+
+// NewAmp returns an amplifier with a tube preamplifier of the specified
+// drive, a flat tone stack, and the synthetic cabinet. The level is the
+// output gain.
+func NewAmp(drive, level float64, sampleRate int) *Amp {
+	return &Amp{
+		Preamp:  NewDistortion(Tube, drive, level, 1, sampleRate),
+		Tone:    NewToneStack(0, 0, 0, sampleRate),
+		Cabinet: NewCabinet(CabinetResponse(sampleRate), 1, sampleRate),
+	}
+}
+
+func (a *Amp) Process(frame [2]float64) [2]float64 {
+	frame = a.Preamp.Process(frame)
+	frame = a.Tone.Process(frame)
+	if a.Cabinet != nil {
+		frame = a.Cabinet.Process(frame)
+	}
+	return frame
+}
+
+func (a *Amp) Reset() {
+	a.Preamp.Reset()
+	a.Tone.Reset()
+	if a.Cabinet != nil {
+		a.Cabinet.Reset()
+	}
+}
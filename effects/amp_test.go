@@ -0,0 +1,90 @@
+package effects
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/wave"
+)
+
+const ampRate = 44100
+
+func TestToneStack(t *testing.T) {
+	s := wave.SineWaveSignal(1000, 0.5, 1, ampRate)
+
+	// A flat tone stack keeps the signal
+	out := Apply(NewToneStack(0, 0, 0, ampRate), s)
+	for i := range s {
+		if math.Abs(out[i]-s[i]) > 1e-9 {
+			t.Fatalf("sample %d is %v (should be %v)", i, out[i], s[i])
+		}
+	}
+
+	tests := []struct {
+		bass, middle, treble float64
+		freq                 float64
+		want                 float64 // dB
+	}{
+		{12, 0, 0, 30, 12},
+		{0, -6, 0, MiddleFrequency, -6},
+		{0, 0, 9, 15000, 9},
+		{12, 0, 9, MiddleFrequency, 0},
+	}
+	for _, tt := range tests {
+		s := wave.SineWaveSignal(tt.freq, 0.5, 1, ampRate)
+		out := Apply(NewToneStack(tt.bass, tt.middle, tt.treble, ampRate), s)
+		a := amplitude(out[ampRate/2:], tt.freq, ampRate)
+		if db := 20 * math.Log10(a/0.5); math.Abs(db-tt.want) > 1 {
+			t.Errorf("gain at %v Hz is %.1f dB (should be %v dB)", tt.freq, db, tt.want)
+		}
+	}
+}
+
+func TestCabinet(t *testing.T) {
+	// The output of an impulse is the response
+	response := []float64{0.5, 0.25, -0.125}
+	c := NewCabinet(response, 1, sampleRate)
+	out := Apply(c, impulse(10))
+	for i, want := range []float64{0.5, 0.25, -0.125, 0, 0} {
+		if out[i] != want {
+			t.Errorf("sample %d is %v (should be %v)", i, out[i], want)
+		}
+	}
+
+	// The synthetic response is a band-pass
+	ir := CabinetResponse(ampRate)
+	if e := energy(ir); math.Abs(e-1) > 1e-9 {
+		t.Errorf("energy of the response is %v (should be 1)", e)
+	}
+	level := func(freq float64) float64 {
+		c := NewCabinet(ir, 1, ampRate)
+		out := Apply(c, wave.SineWaveSignal(freq, 0.5, 1, ampRate))
+		return amplitude(out[ampRate/2:], freq, ampRate)
+	}
+	low, mid, high := level(30), level(2500), level(12000)
+	if low > mid/4 || high > mid/4 {
+		t.Errorf("levels at 30, 2500 and 12000 Hz are %v, %v and %v (should be a band-pass)", low, mid, high)
+	}
+}
+
+func TestAmp(t *testing.T) {
+	a := NewAmp(20, 0.5, ampRate)
+	s := wave.SineWaveSignal(110, 0.5, 1, ampRate)
+	out := Apply(a, s)
+	for i, x := range out {
+		if math.IsNaN(x) || math.Abs(x) > 2 {
+			t.Fatalf("sample %d is %v", i, x)
+		}
+	}
+	if e := energy(out); e == 0 {
+		t.Errorf("the amplifier is silent")
+	}
+
+	// Without cabinet
+	a.Cabinet = nil
+	a.Reset()
+	direct := Apply(a, s)
+	if energy(direct) == energy(out) {
+		t.Errorf("the cabinet should change the signal")
+	}
+}
@@ -0,0 +1,168 @@
+package effects
+
+import "github.com/gboulant/musicall/wave"
+
+// La convolution par une réponse impulsionnelle (celle d'une salle, d'un
+// baffle) reproduit exactement le filtrage qu'elle a enregistré. Calculée
+// directement, elle coûte len(réponse) multiplications par échantillon,
+// ce qui est trop pour une réverbération de plusieurs secondes. Elle est
+// donc calculée dans le domaine fréquentiel:
+//
+//   - hors ligne (Convolve), par une seule FFT de la taille du résultat,
+//   - en flux (Convolver), par la convolution partitionnée uniforme
+//     (uniformly partitioned overlap-save): la réponse est découpée en
+//     partitions de B échantillons, dont les spectres (FFT de taille 2B)
+//     sont calculés une fois pour toutes. À chaque bloc de B échantillons
+//     du signal, le spectre du bloc est ajouté à une ligne à retard
+//     fréquentielle, et la sortie est la FFT inverse de la somme des
+//     produits des spectres des blocs passés par ceux des partitions. La
+//     latence est d'un bloc.
+
+// DefaultBlockSize is the size of the partitions of a Convolver
+const DefaultBlockSize = 512
+
+// Convolve returns the convolution of the signal with the impulse
+// response (len(signal)+len(response)-1 samples), computed with a FFT.
+func Convolve(signal, response []float64) []float64 {
+	if len(signal) == 0 || len(response) == 0 {
+		return nil
+	}
+	size := len(signal) + len(response) - 1
+	n := wave.NextPowerOfTwo(size)
+	s := wave.RealFFT(padded(signal, n))
+	h := wave.RealFFT(padded(response, n))
+	for i := range s {
+		s[i] *= h[i]
+	}
+	return wave.RealIFFT(s)[:size]
+}
+
+// padded returns the samples completed with zeros up to n samples
+func padded(samples []float64, n int) []float64 {
+	p := make([]float64, n)
+	copy(p, samples)
+	return p
+}
+
+// Convolver convolves a stream with an impulse response (a room reverb
+// or a cabinet), with a uniformly partitioned convolution. The latency
+// is the block size, it is removed by Apply and Stream.
+type Convolver struct {
+	Mix Param
+
+	clock      clock
+	block      int
+	partitions [2][][]complex128 // spectra of the partitions of the responses
+	history    [2][][]complex128 // spectra of the last blocks of input (circular)
+	head       int               // index in history of the last block
+	input      [2][]float64      // previous and current blocks of input
+	output     [2][]float64      // block of output being streamed
+	dry        [2]*delayLine     // input delayed by the latency
+	pos        int               // position in the current block
+}
+
+// NewConvolver returns a convolver with the same impulse response for
+// the two channels (e.g. loaded with sound.LoadImpulseResponse), at the
+// sample rate of the signal. The block size is rounded to a power of
+// two (DefaultBlockSize if it is not positive): a small block reduces
+// the latency, a large block reduces the computation.
+func NewConvolver(response []float64, blockSize int, mix float64, sampleRate int) *Convolver {
+	return NewStereoConvolver(response, response, blockSize, mix, sampleRate)
+}
+
+// NewStereoConvolver returns a convolver with an impulse response for
+// each channel (a stereo room reverb), see NewConvolver.
+func NewStereoConvolver(left, right []float64, blockSize int, mix float64, sampleRate int) *Convolver {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	b := wave.NextPowerOfTwo(blockSize)
+	c := &Convolver{Mix: Const(mix), clock: newClock(sampleRate), block: b}
+	c.partitions[0] = partitionSpectra(left, b)
+	c.partitions[1] = partitionSpectra(right, b)
+	n := max(len(c.partitions[0]), len(c.partitions[1]))
+	for ch := range 2 {
+		c.history[ch] = make([][]complex128, n)
+		for i := range c.history[ch] {
+			c.history[ch][i] = make([]complex128, 2*b)
+		}
+		c.input[ch] = make([]float64, 2*b)
+		c.output[ch] = make([]float64, b)
+		c.dry[ch] = newDelayLine(b + 2)
+	}
+	return c
+}
+
+// partitionSpectra returns the spectra (FFT of size 2b) of the
+// partitions of b samples of the response
+func partitionSpectra(response []float64, b int) [][]complex128 {
+	var spectra [][]complex128
+	for start := 0; start < len(response); start += b {
+		end := min(start+b, len(response))
+		spectra = append(spectra, wave.RealFFT(padded(response[start:end], 2*b)))
+	}
+	if len(spectra) == 0 {
+		spectra = append(spectra, make([]complex128, 2*b))
+	}
+	return spectra
+}
+
+// Latency returns the block size
+func (c *Convolver) Latency() int {
+	return c.block
+}
+
+func (c *Convolver) Process(frame [2]float64) [2]float64 {
+	t := c.clock.tick()
+	m := c.Mix(t)
+	var out [2]float64
+	for ch := range 2 {
+		c.input[ch][c.block+c.pos] = frame[ch]
+		c.dry[ch].write(frame[ch])
+		dry := c.dry[ch].read(float64(c.block + 1))
+		out[ch] = mix(dry, c.output[ch][c.pos], m)
+	}
+	c.pos++
+	if c.pos == c.block {
+		c.head = (c.head + 1) % len(c.history[0])
+		for ch := range 2 {
+			c.convolveBlock(ch)
+		}
+		c.pos = 0
+	}
+	return out
+}
+
+// convolveBlock computes the next block of output of the channel, from
+// the last two blocks of input (overlap-save)
+func (c *Convolver) convolveBlock(ch int) {
+	b := c.block
+	copy(c.history[ch][c.head], wave.RealFFT(c.input[ch]))
+
+	n := len(c.history[ch])
+	sum := make([]complex128, 2*b)
+	for p, h := range c.partitions[ch] {
+		x := c.history[ch][(c.head-p+n)%n]
+		for k := range sum {
+			sum[k] += x[k] * h[k]
+		}
+	}
+	// La première moitié de la FFT inverse est faussée par la convolution
+	// circulaire: seule la seconde moitié est gardée.
+	y := wave.RealIFFT(sum)
+	copy(c.output[ch], y[b:])
+	copy(c.input[ch][:b], c.input[ch][b:])
+}
+
+func (c *Convolver) Reset() {
+	c.clock.frame = 0
+	c.head, c.pos = 0, 0
+	for ch := range 2 {
+		for _, x := range c.history[ch] {
+			clear(x)
+		}
+		clear(c.input[ch])
+		clear(c.output[ch])
+		c.dry[ch].reset()
+	}
+}
@@ -0,0 +1,95 @@
+package effects
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// directConvolution is the reference: the convolution computed directly
+func directConvolution(signal, response []float64) []float64 {
+	out := make([]float64, len(signal)+len(response)-1)
+	for i, x := range signal {
+		for k, h := range response {
+			out[i+k] += x * h
+		}
+	}
+	return out
+}
+
+func randomSignal(n int, seed int64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = 2*r.Float64() - 1
+	}
+	return s
+}
+
+func TestConvolve(t *testing.T) {
+	signal := randomSignal(1000, 1)
+	response := randomSignal(300, 2)
+	got := Convolve(signal, response)
+	want := directConvolution(signal, response)
+	if len(got) != len(want) {
+		t.Fatalf("len is %d (should be %d)", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("sample %d is %v (should be %v)", i, got[i], want[i])
+		}
+	}
+	if out := Convolve(nil, response); out != nil {
+		t.Errorf("convolution of an empty signal is %v (should be nil)", out)
+	}
+}
+
+func TestConvolver(t *testing.T) {
+	// The partitioned convolution is the convolution, without latency
+	// (removed by Apply), for a response of several partitions that is
+	// not a multiple of the block size
+	signal := randomSignal(2000, 3)
+	response := randomSignal(700, 4)
+	c := NewConvolver(response, 100, 1, sampleRate)
+	if c.Latency() != 128 {
+		t.Errorf("latency is %d (should be 128)", c.Latency())
+	}
+	got := Apply(c, signal)
+	want := directConvolution(signal, response)
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("sample %d is %v (should be %v)", i, got[i], want[i])
+		}
+	}
+
+	// Dry signal only
+	c = NewConvolver(response, 64, 0, sampleRate)
+	got = Apply(c, signal)
+	for i := range got {
+		if got[i] != signal[i] {
+			t.Fatalf("sample %d is %v (should be %v)", i, got[i], signal[i])
+		}
+	}
+}
+
+func TestStereoConvolver(t *testing.T) {
+	// Each channel has its own response: a delay of 3 samples on the
+	// left, and a gain of 0.5 on the right
+	c := NewStereoConvolver([]float64{0, 0, 0, 1}, []float64{0.5}, 16, 1, sampleRate)
+	left, right := ApplyStereo(c, impulse(64), impulse(64))
+	if math.Abs(left[3]-1) > 1e-12 || math.Abs(energy(left)-1) > 1e-12 {
+		t.Errorf("left channel is %v (should be the impulse at 3)", left[:8])
+	}
+	if math.Abs(right[0]-0.5) > 1e-12 || math.Abs(energy(right)-0.25) > 1e-12 {
+		t.Errorf("right channel is %v (should be the impulse of 0.5)", right[:8])
+	}
+
+	// The reset clears the state
+	c.Reset()
+	again, _ := ApplyStereo(c, impulse(64), impulse(64))
+	for i := range again {
+		if again[i] != left[i] {
+			t.Fatalf("sample %d is %v after reset (should be %v)", i, again[i], left[i])
+		}
+	}
+}
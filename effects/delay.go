@@ -0,0 +1,162 @@
+package effects
+
+import "math"
+
+// maxDelayTime is the minimal capacity (in seconds) of the delay lines
+// of the delays, so that their time can be automated.
+const maxDelayTime = 2.
+
+// Delay is a feedback delay: the signal is repeated after the delay
+// time, each repetition being attenuated by the feedback gain. The two
+// channels have their own delay line.
+type Delay struct {
+	Time     Param // delay between two repetitions, in seconds
+	Feedback Param // gain from one repetition to the next one (below 1)
+	Mix      Param
+
+	clock clock
+	lines [2]*delayLine
+}
+
+// NewDelay returns a feedback delay with constant parameters. The delay
+// time can be automated up to twice the initial time (or 2 s).
+func NewDelay(time, feedback, mix float64, sampleRate int) *Delay {
+	d := &Delay{Time: Const(time), Feedback: Const(feedback), Mix: Const(mix), clock: newClock(sampleRate)}
+	size := int(math.Max(2*time, maxDelayTime)*d.clock.sampleRate) + 2
+	d.lines = [2]*delayLine{newDelayLine(size), newDelayLine(size)}
+	return d
+}
+
+func (d *Delay) Process(frame [2]float64) [2]float64 {
+	t := d.clock.tick()
+	delay := d.Time(t) * d.clock.sampleRate
+	feedback := clamp(d.Feedback(t), 0, 0.99)
+	m := d.Mix(t)
+	var out [2]float64
+	for c, line := range d.lines {
+		wet := line.read(delay)
+		line.write(frame[c] + feedback*wet)
+		out[c] = mix(frame[c], wet, m)
+	}
+	return out
+}
+
+func (d *Delay) Reset() {
+	d.clock.frame = 0
+	for _, line := range d.lines {
+		line.reset()
+	}
+}
+
+// -------------------------------------------------------------
+// PingPong is a feedback delay whose repetitions bounce alternately on
+// the left and on the right channel.
+//
+// Les deux lignes à retard sont croisées: la sortie de la ligne de
+// gauche alimente la ligne de droite, et inversement. L'entrée (le
+// mélange mono du signal) n'alimente que la ligne de gauche, de sorte
+// que le premier écho est à gauche, le second à droite, etc.
+type PingPong struct {
+	Time     Param // delay between two repetitions, in seconds
+	Feedback Param // gain from one repetition to the next one (below 1)
+	Mix      Param
+
+	clock clock
+	lines [2]*delayLine
+}
+
+// NewPingPong returns a ping-pong delay with constant parameters. The
+// delay time can be automated up to twice the initial time (or 2 s).
+func NewPingPong(time, feedback, mix float64, sampleRate int) *PingPong {
+	p := &PingPong{Time: Const(time), Feedback: Const(feedback), Mix: Const(mix), clock: newClock(sampleRate)}
+	size := int(math.Max(2*time, maxDelayTime)*p.clock.sampleRate) + 2
+	p.lines = [2]*delayLine{newDelayLine(size), newDelayLine(size)}
+	return p
+}
+
+func (p *PingPong) Process(frame [2]float64) [2]float64 {
+	t := p.clock.tick()
+	delay := math.Max(1, math.Round(p.Time(t)*p.clock.sampleRate))
+	feedback := clamp(p.Feedback(t), 0, 0.99)
+	m := p.Mix(t)
+	left, right := p.lines[0].read(delay), p.lines[1].read(delay)
+	p.lines[0].write((frame[0]+frame[1])/2 + feedback*right)
+	p.lines[1].write(feedback * left)
+	return [2]float64{mix(frame[0], left, m), mix(frame[1], right, m)}
+}
+
+func (p *PingPong) Reset() {
+	p.clock.frame = 0
+	for _, line := range p.lines {
+		line.reset()
+	}
+}
+
+// -------------------------------------------------------------
+// Tap is a repetition of a multi-tap echo: its delay (in seconds), its
+// gain, and its position in the stereo field (from -1 on the left to 1
+// on the right).
+type Tap struct {
+	Time float64
+	Gain float64
+	Pan  float64
+}
+
+// Echo is a multi-tap echo: the signal is repeated at the times of the
+// taps, without feedback (each repetition is defined by its tap).
+type Echo struct {
+	Taps []Tap
+	Mix  Param
+
+	clock clock
+	line  *delayLine // mono mix of the input
+}
+
+// NewEcho returns an echo with the specified taps
+func NewEcho(taps []Tap, mix float64, sampleRate int) *Echo {
+	e := &Echo{Taps: taps, Mix: Const(mix), clock: newClock(sampleRate)}
+	longest := 0.
+	for _, tap := range taps {
+		longest = math.Max(longest, tap.Time)
+	}
+	e.line = newDelayLine(int(longest*e.clock.sampleRate) + 2)
+	return e
+}
+
+// NewRepeatEcho returns an echo of n repetitions separated by the delay
+// time, each one attenuated by the decay gain, alternately on the left
+// and on the right with the specified spread (0 for all the repetitions
+// at the center).
+func NewRepeatEcho(n int, time, decay, spread, mix float64, sampleRate int) *Echo {
+	taps := make([]Tap, n)
+	gain := 1.
+	for i := range taps {
+		gain *= decay
+		pan := spread
+		if i%2 == 0 {
+			pan = -spread
+		}
+		taps[i] = Tap{Time: time * float64(i+1), Gain: gain, Pan: pan}
+	}
+	return NewEcho(taps, mix, sampleRate)
+}
+
+func (e *Echo) Process(frame [2]float64) [2]float64 {
+	t := e.clock.tick()
+	e.line.write((frame[0] + frame[1]) / 2)
+	var wet [2]float64
+	for _, tap := range e.Taps {
+		x := tap.Gain * e.line.read(tap.Time*e.clock.sampleRate+1)
+		// Balance: le canal opposé au panoramique est atténué
+		pan := clamp(tap.Pan, -1, 1)
+		wet[0] += x * math.Min(1, 1-pan)
+		wet[1] += x * math.Min(1, 1+pan)
+	}
+	m := e.Mix(t)
+	return [2]float64{mix(frame[0], wet[0], m), mix(frame[1], wet[1], m)}
+}
+
+func (e *Echo) Reset() {
+	e.clock.frame = 0
+	e.line.reset()
+}
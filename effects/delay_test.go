@@ -0,0 +1,82 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDelay(t *testing.T) {
+	d := NewDelay(0.1, 0.5, 1, sampleRate)
+	out := Apply(d, impulse(sampleRate))
+	step := int(0.1 * sampleRate)
+	for k, want := range []float64{0, 1, 0.5, 0.25, 0.125} {
+		if got := out[k*step]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("repetition %d is %v (should be %v)", k, got, want)
+		}
+	}
+
+	// Dry signal only
+	d = NewDelay(0.1, 0.5, 0, sampleRate)
+	in := impulse(sampleRate)
+	out = Apply(d, in)
+	for i := range in {
+		if out[i] != in[i] {
+			t.Fatalf("sample %d is %v (should be %v)", i, out[i], in[i])
+		}
+	}
+}
+
+func TestDelay_Reset(t *testing.T) {
+	d := NewDelay(0.1, 0.5, 0.5, sampleRate)
+	first := Apply(d, impulse(sampleRate))
+	d.Reset()
+	second := Apply(d, impulse(sampleRate))
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("sample %d is %v after reset (should be %v)", i, second[i], first[i])
+		}
+	}
+}
+
+func TestDelay_Automation(t *testing.T) {
+	// The delay time changes from 0.1 s to 0.2 s after 0.5 s
+	d := NewDelay(0.1, 0, 1, sampleRate)
+	d.Time = Ramp(0.1, 0.2, 0.5, 0.5)
+	in := make([]float64, sampleRate)
+	in[int(0.6*sampleRate)] = 1
+	out := Apply(d, in)
+	if got := out[int(0.8*sampleRate)]; got != 1 {
+		t.Errorf("delayed sample is %v (should be 1)", got)
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	// The repetitions bounce from the left to the right channel
+	p := NewPingPong(0.1, 0.5, 1, sampleRate)
+	left, right := ApplyStereo(p, impulse(sampleRate), impulse(sampleRate))
+	step := int(0.1 * sampleRate)
+	wants := [][2]float64{{0, 0}, {1, 0}, {0, 0.5}, {0.25, 0}, {0, 0.125}}
+	for k, want := range wants {
+		got := [2]float64{left[k*step], right[k*step]}
+		if math.Abs(got[0]-want[0]) > 1e-9 || math.Abs(got[1]-want[1]) > 1e-9 {
+			t.Errorf("repetition %d is %v (should be %v)", k, got, want)
+		}
+	}
+}
+
+func TestEcho(t *testing.T) {
+	e := NewRepeatEcho(3, 0.1, 0.5, 1, 1, sampleRate)
+	left, right := ApplyStereo(e, impulse(sampleRate), impulse(sampleRate))
+	step := int(0.1 * sampleRate)
+	// The repetitions alternate between the left and the right channel
+	wants := [][2]float64{{0, 0}, {0.5, 0}, {0, 0.25}, {0.125, 0}}
+	for k, want := range wants {
+		got := [2]float64{left[k*step], right[k*step]}
+		if math.Abs(got[0]-want[0]) > 1e-9 || math.Abs(got[1]-want[1]) > 1e-9 {
+			t.Errorf("repetition %d is %v (should be %v)", k, got, want)
+		}
+	}
+	if e := energy(left[4*step:]) + energy(right[4*step:]); e != 0 {
+		t.Errorf("energy after the last tap is %v (should be 0)", e)
+	}
+}
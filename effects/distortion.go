@@ -0,0 +1,127 @@
+package effects
+
+import "math"
+
+// Curve is the transfer function of a distortion (a waveshaper): it
+// maps an input sample (amplified by the drive) to an output sample
+// between -1 and 1.
+type Curve func(x float64) float64
+
+// Curves of the distortions. The soft clip (an overdrive) rounds the
+// peaks, the hard clip cuts them. The tube curve is asymmetric as the
+// transfer of a triode, and adds even harmonics. The fuzz curve is an
+// extreme and asymmetric clip, that makes an almost square signal.
+var (
+	SoftClip Curve = math.Tanh
+	HardClip Curve = func(x float64) float64 { return clamp(x, -1, 1) }
+	Tube     Curve = tube
+	Fuzz     Curve = fuzz
+)
+
+// tubeBias shifts the operating point of the tube curve
+const tubeBias = 0.4
+
+func tube(x float64) float64 {
+	// La tangente hyperbolique décalée sature plus tôt pour les valeurs
+	// positives: l'asymétrie produit les harmoniques paires. Chaque
+	// alternance est ramenée entre -1 et 1, et la composante continue est
+	// retirée après la distorsion.
+	y := math.Tanh(x+tubeBias) - math.Tanh(tubeBias)
+	if y >= 0 {
+		return y / (1 - math.Tanh(tubeBias))
+	}
+	return y / (1 + math.Tanh(tubeBias))
+}
+
+func fuzz(x float64) float64 {
+	if x >= 0 {
+		return 1 - math.Exp(-4*x)
+	}
+	return -0.8 * (1 - math.Exp(5*x))
+}
+
+// DefaultOversampling is the oversampling factor of the distortions
+const DefaultOversampling = 4
+
+// Distortion is a waveshaping distortion: the signal, amplified by the
+// drive, goes through the curve, and the output is attenuated by the
+// level. The curve creates harmonics that can be above the Nyquist
+// frequency and fold back in the audible spectrum (aliasing): the
+// distortion is computed at a multiple of the sample rate (the
+// oversampling), then filtered before going back to the sample rate.
+type Distortion struct {
+	Curve Curve
+	Drive Param // gain before the curve (1 for a clean signal, 10 to 100 for a distortion)
+	Level Param // gain after the curve
+	Mix   Param
+
+	clock        clock
+	oversampling int
+	last         [2]float64   // last input, for the interpolation
+	filters      [2][2]biquad // anti-aliasing filters, at the oversampled rate
+	dc           [2]dcBlocker
+}
+
+// NewDistortion returns a distortion with constant parameters and the
+// default oversampling.
+func NewDistortion(curve Curve, drive, level, mix float64, sampleRate int) *Distortion {
+	return NewOversampledDistortion(curve, drive, level, mix, DefaultOversampling, sampleRate)
+}
+
+// NewOversampledDistortion returns a distortion computed at oversampling
+// times the sample rate (1 for no oversampling).
+func NewOversampledDistortion(curve Curve, drive, level, mix float64, oversampling int, sampleRate int) *Distortion {
+	d := &Distortion{
+		Curve:        curve,
+		Drive:        Const(drive),
+		Level:        Const(level),
+		Mix:          Const(mix),
+		clock:        newClock(sampleRate),
+		oversampling: max(oversampling, 1),
+	}
+	rate := d.clock.sampleRate * float64(d.oversampling)
+	for c := range 2 {
+		// Deux filtres de Butterworth en cascade, coupant un peu sous la
+		// fréquence de Nyquist du signal d'origine.
+		d.filters[c][0].lowPass(0.45*d.clock.sampleRate, 0.54, rate)
+		d.filters[c][1].lowPass(0.45*d.clock.sampleRate, 1.31, rate)
+		d.dc[c] = newDCBlocker(d.clock.sampleRate)
+	}
+	return d
+}
+
+func (d *Distortion) Process(frame [2]float64) [2]float64 {
+	t := d.clock.tick()
+	drive, level, m := d.Drive(t), d.Level(t), d.Mix(t)
+	n := d.oversampling
+	var out [2]float64
+	for c := range 2 {
+		var y float64
+		if n == 1 {
+			y = d.Curve(drive * frame[c])
+		} else {
+			// Les échantillons intermédiaires sont interpolés linéairement
+			// entre l'échantillon précédent et le courant, distordus, puis
+			// filtrés. On garde le dernier (la décimation).
+			for k := 1; k <= n; k++ {
+				x := d.last[c] + (frame[c]-d.last[c])*float64(k)/float64(n)
+				y = d.Curve(drive * x)
+				y = d.filters[c][0].process(y)
+				y = d.filters[c][1].process(y)
+			}
+		}
+		d.last[c] = frame[c]
+		out[c] = mix(frame[c], level*d.dc[c].process(y), m)
+	}
+	return out
+}
+
+func (d *Distortion) Reset() {
+	d.clock.frame = 0
+	d.last = [2]float64{}
+	for c := range 2 {
+		d.filters[c][0].reset()
+		d.filters[c][1].reset()
+		d.dc[c].reset()
+	}
+}
@@ -0,0 +1,94 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCurves(t *testing.T) {
+	curves := map[string]Curve{"soft": SoftClip, "hard": HardClip, "tube": Tube, "fuzz": Fuzz}
+	for name, curve := range curves {
+		if y := curve(0); y != 0 {
+			t.Errorf("%s(0) is %v (should be 0)", name, y)
+		}
+		previous := curve(-100)
+		for x := -100.; x <= 100; x += 0.01 {
+			y := curve(x)
+			if y < -1 || y > 1 {
+				t.Fatalf("%s(%v) is %v (should be between -1 and 1)", name, x, y)
+			}
+			if y < previous {
+				t.Fatalf("%s is not increasing at %v", name, x)
+			}
+			previous = y
+		}
+	}
+	if y := HardClip(0.5); y != 0.5 {
+		t.Errorf("hard(0.5) is %v (should be 0.5)", y)
+	}
+}
+
+func TestDistortion(t *testing.T) {
+	const f = 200.
+	s := sine(f, 1) // amplitude 0.5
+
+	// A clean drive with a hard clip keeps the signal (after the DC
+	// blocker, which has a small effect at 200 Hz)
+	d := NewOversampledDistortion(HardClip, 1, 1, 1, 1, sampleRate)
+	out := Apply(d, s)
+	if a := amplitude(out[sampleRate/2:], f, sampleRate); math.Abs(a-0.5) > 0.01 {
+		t.Errorf("clean amplitude is %v (should be 0.5)", a)
+	}
+
+	// The saturation adds odd harmonics
+	d = NewDistortion(HardClip, 20, 1, 1, sampleRate)
+	out = Apply(d, s)
+	if h3 := amplitude(out[sampleRate/2:], 3*f, sampleRate); h3 < 0.1 {
+		t.Errorf("third harmonic is %v (should be above 0.1)", h3)
+	}
+
+	// The tube curve is asymmetric, and adds even harmonics
+	soft := Apply(NewDistortion(SoftClip, 5, 1, 1, sampleRate), s)
+	tube := Apply(NewDistortion(Tube, 5, 1, 1, sampleRate), s)
+	hs := amplitude(soft[sampleRate/2:], 2*f, sampleRate)
+	ht := amplitude(tube[sampleRate/2:], 2*f, sampleRate)
+	if hs > 1e-3 || ht < 0.01 {
+		t.Errorf("second harmonic is %v with soft clip, %v with tube (should be 0 and above 0.01)", hs, ht)
+	}
+
+	// Dry signal only
+	d = NewDistortion(Fuzz, 50, 1, 0, sampleRate)
+	out = Apply(d, s)
+	for i := range s {
+		if out[i] != s[i] {
+			t.Fatalf("sample %d is %v (should be %v)", i, out[i], s[i])
+		}
+	}
+}
+
+func TestDistortion_Oversampling(t *testing.T) {
+	// The harmonic 5 of 1100 Hz (5500 Hz) is above the Nyquist frequency
+	// (4000 Hz), and folds back at 8000-5500 = 2500 Hz. The oversampling
+	// reduces this aliasing.
+	const f = 1100.
+	s := sine(f, 1)
+	aliased := Apply(NewOversampledDistortion(HardClip, 10, 1, 1, 1, sampleRate), s)
+	filtered := Apply(NewOversampledDistortion(HardClip, 10, 1, 1, 8, sampleRate), s)
+	a1 := amplitude(aliased[sampleRate/2:], 2500, sampleRate)
+	a8 := amplitude(filtered[sampleRate/2:], 2500, sampleRate)
+	if a8 > a1/4 {
+		t.Errorf("aliasing is %v with oversampling (should be below %v)", a8, a1/4)
+	}
+}
+
+func TestDistortion_Reset(t *testing.T) {
+	d := NewDistortion(Tube, 10, 0.5, 1, sampleRate)
+	first := Apply(d, sine(300, 0.5))
+	d.Reset()
+	second := Apply(d, sine(300, 0.5))
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("sample %d is %v after reset (should be %v)", i, second[i], first[i])
+		}
+	}
+}
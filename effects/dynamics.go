@@ -0,0 +1,410 @@
+package effects
+
+import "math"
+
+// Les processeurs de dynamique modifient le gain du signal en fonction
+// de son niveau: le compresseur et le limiteur réduisent les niveaux
+// forts, l'expandeur et le noise gate réduisent les niveaux faibles. Le
+// niveau est mesuré sur un signal de contrôle (key), qui est le signal
+// lui-même, ou un autre signal (sidechain, voir ProcessKeyed). Le gain
+// est calculé en dB par une courbe statique (seuil, ratio, coude), puis
+// lissé par les temps d'attaque et de relâchement.
+//
+// Avec une anticipation (lookahead), le signal est retardé, de sorte que
+// le gain est réduit avant l'arrivée des pics. Ce retard (la latence)
+// est compensé par Apply et Stream.
+
+// Keyed is an effect whose processing is driven by a control signal,
+// the key (the side chain), that can be another signal than the
+// processed one. Process(frame) is ProcessKeyed(frame, frame).
+type Keyed interface {
+	Effect
+	ProcessKeyed(frame, key [2]float64) [2]float64
+}
+
+// Default parameters of the dynamics processors
+const (
+	DefaultKnee      = 6.    // dB
+	DefaultLookahead = 0.005 // seconds
+	DefaultRange     = 80.   // dB
+	minLevel         = -200. // dB, level of the silence
+
+	// detectorRelease is the decay time of the level detector, long
+	// enough to hold the level between the peaks of a low note
+	detectorRelease = 0.02 // seconds
+)
+
+// levelDB returns the level in dB of a peak value
+func levelDB(peak float64) float64 {
+	if peak == 0 {
+		return minLevel
+	}
+	return math.Max(20*math.Log10(peak), minLevel)
+}
+
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// timeCoefficient returns the coefficient of a one pole smoothing with
+// the time constant (in seconds), 0 for an immediate change
+func timeCoefficient(seconds, sampleRate float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (seconds * sampleRate))
+}
+
+// dynamics is the state shared by the dynamics processors: the clock,
+// the smoothed gain (in dB), and the delay lines of the lookahead.
+type dynamics struct {
+	clock     clock
+	gain      float64 // dB
+	envelope  float64 // level of the key
+	decay     float64 // coefficient of the decay of the envelope
+	lookahead int     // frames
+	lines     [2]*delayLine
+}
+
+func newDynamics(sampleRate int) dynamics {
+	d := dynamics{clock: newClock(sampleRate), lines: [2]*delayLine{newDelayLine(2), newDelayLine(2)}}
+	d.decay = timeCoefficient(detectorRelease, d.clock.sampleRate)
+	return d
+}
+
+// detect returns the level of the key in dB: its peak envelope, that
+// follows the peaks immediately and decays between them
+func (d *dynamics) detect(key [2]float64) float64 {
+	peak := math.Max(math.Abs(key[0]), math.Abs(key[1]))
+	d.envelope = math.Max(peak, d.envelope*d.decay)
+	return levelDB(d.envelope)
+}
+
+// setLookahead changes the lookahead (in seconds) and clears the state
+func (d *dynamics) setLookahead(seconds float64) {
+	d.lookahead = max(int(seconds*d.clock.sampleRate), 0)
+	d.lines = [2]*delayLine{newDelayLine(d.lookahead + 2), newDelayLine(d.lookahead + 2)}
+	d.reset()
+}
+
+// delay returns the frame of the lookahead time ago
+func (d *dynamics) delay(frame [2]float64) [2]float64 {
+	for c, line := range d.lines {
+		line.write(frame[c])
+		frame[c] = line.read(float64(d.lookahead + 1))
+	}
+	return frame
+}
+
+// smooth moves the gain to the target (in dB) with the attack time when
+// the gain decreases, and with the release time when it increases
+func (d *dynamics) smooth(target, attack, release float64) float64 {
+	k := timeCoefficient(release, d.clock.sampleRate)
+	if target < d.gain {
+		k = timeCoefficient(attack, d.clock.sampleRate)
+	}
+	d.gain = target + k*(d.gain-target)
+	return d.gain
+}
+
+// apply applies the gain (in dB) to the frame
+func apply(frame [2]float64, db float64) [2]float64 {
+	g := dbToGain(db)
+	return [2]float64{frame[0] * g, frame[1] * g}
+}
+
+func (d *dynamics) Latency() int {
+	return d.lookahead
+}
+
+// GainReduction returns the current gain reduction in dB (a positive
+// value, 0 when the processor does not act)
+func (d *dynamics) GainReduction() float64 {
+	return -d.gain
+}
+
+func (d *dynamics) reset() {
+	d.clock.frame = 0
+	d.gain, d.envelope = 0, 0
+	for _, line := range d.lines {
+		line.reset()
+	}
+}
+
+// -------------------------------------------------------------
+// Compressor reduces the level of the signal above the threshold: an
+// excess of level of ratio dB above the threshold gives an excess of 1
+// dB. The knee makes the transition progressive around the threshold.
+type Compressor struct {
+	Threshold Param // dB
+	Ratio     Param // 4 for a compression of 4:1
+	Knee      Param // width of the knee in dB (0 for a hard knee)
+	Attack    Param // seconds
+	Release   Param // seconds
+	Makeup    Param // gain in dB applied after the compression
+
+	dynamics
+}
+
+// NewCompressor returns a compressor with a knee of 6 dB, without makeup
+// gain and without lookahead (see SetLookahead).
+func NewCompressor(threshold, ratio, attack, release float64, sampleRate int) *Compressor {
+	return &Compressor{
+		Threshold: Const(threshold),
+		Ratio:     Const(ratio),
+		Knee:      Const(DefaultKnee),
+		Attack:    Const(attack),
+		Release:   Const(release),
+		Makeup:    Const(0),
+		dynamics:  newDynamics(sampleRate),
+	}
+}
+
+// compress is the static curve of the compressor: the gain in dB for the
+// level x in dB
+func compress(x, threshold, ratio, knee float64) float64 {
+	ratio = math.Max(ratio, 1)
+	over := x - threshold
+	switch {
+	case 2*over < -knee:
+		return 0
+	case knee > 0 && 2*math.Abs(over) <= knee:
+		return (1/ratio - 1) * (over + knee/2) * (over + knee/2) / (2 * knee)
+	}
+	return (1/ratio - 1) * over
+}
+
+// SetLookahead sets the time (in seconds) the signal is delayed, so that
+// the compression starts before the peaks
+func (c *Compressor) SetLookahead(seconds float64) {
+	c.setLookahead(seconds)
+}
+
+func (c *Compressor) Process(frame [2]float64) [2]float64 {
+	return c.ProcessKeyed(frame, frame)
+}
+
+func (c *Compressor) ProcessKeyed(frame, key [2]float64) [2]float64 {
+	t := c.clock.tick()
+	target := compress(c.detect(key), c.Threshold(t), c.Ratio(t), c.Knee(t))
+	gain := c.smooth(target, c.Attack(t), c.Release(t))
+	return apply(c.delay(frame), gain+c.Makeup(t))
+}
+
+func (c *Compressor) Reset() {
+	c.reset()
+}
+
+// -------------------------------------------------------------
+// Expander reduces the level of the signal below the threshold (a
+// downward expander): a lack of level of 1 dB below the threshold gives
+// a lack of ratio dB. The reduction is limited by the range.
+type Expander struct {
+	Threshold Param // dB
+	Ratio     Param // 2 for an expansion of 1:2
+	Knee      Param // width of the knee in dB (0 for a hard knee)
+	Range     Param // maximal reduction in dB
+	Attack    Param // seconds
+	Release   Param // seconds
+
+	dynamics
+}
+
+// NewExpander returns an expander with a knee of 6 dB, a range of 80 dB
+// and without lookahead (see SetLookahead).
+func NewExpander(threshold, ratio, attack, release float64, sampleRate int) *Expander {
+	return &Expander{
+		Threshold: Const(threshold),
+		Ratio:     Const(ratio),
+		Knee:      Const(DefaultKnee),
+		Range:     Const(DefaultRange),
+		Attack:    Const(attack),
+		Release:   Const(release),
+		dynamics:  newDynamics(sampleRate),
+	}
+}
+
+// expand is the static curve of the expander: the gain in dB for the
+// level x in dB
+func expand(x, threshold, ratio, knee float64) float64 {
+	ratio = math.Max(ratio, 1)
+	under := x - threshold
+	switch {
+	case 2*under > knee:
+		return 0
+	case knee > 0 && 2*math.Abs(under) <= knee:
+		return -(ratio - 1) * (under - knee/2) * (under - knee/2) / (2 * knee)
+	}
+	return (ratio - 1) * under
+}
+
+// SetLookahead sets the time (in seconds) the signal is delayed, so that
+// the expander opens before the attacks
+func (e *Expander) SetLookahead(seconds float64) {
+	e.setLookahead(seconds)
+}
+
+func (e *Expander) Process(frame [2]float64) [2]float64 {
+	return e.ProcessKeyed(frame, frame)
+}
+
+func (e *Expander) ProcessKeyed(frame, key [2]float64) [2]float64 {
+	t := e.clock.tick()
+	target := math.Max(expand(e.detect(key), e.Threshold(t), e.Ratio(t), e.Knee(t)), -e.Range(t))
+	// Pour un expandeur, l'attaque est l'ouverture (le gain remonte) et
+	// le relâchement la fermeture.
+	gain := e.smooth(target, e.Release(t), e.Attack(t))
+	return apply(e.delay(frame), gain)
+}
+
+func (e *Expander) Reset() {
+	e.reset()
+}
+
+// -------------------------------------------------------------
+// Gate is a noise gate: the signal below the threshold is muted (reduced
+// by the range). The gate opens in the attack time, stays open during
+// the hold time after the level went below the threshold, and closes in
+// the release time.
+type Gate struct {
+	Threshold Param // dB
+	Range     Param // reduction in dB when the gate is closed
+	Attack    Param // seconds
+	Hold      Param // seconds
+	Release   Param // seconds
+
+	dynamics
+	held float64 // time the level went below the threshold, in seconds
+}
+
+// NewGate returns a noise gate with a range of 80 dB, a hold time of 50
+// ms, and without lookahead (see SetLookahead).
+func NewGate(threshold, attack, release float64, sampleRate int) *Gate {
+	return &Gate{
+		Threshold: Const(threshold),
+		Range:     Const(DefaultRange),
+		Attack:    Const(attack),
+		Hold:      Const(0.05),
+		Release:   Const(release),
+		dynamics:  newDynamics(sampleRate),
+		held:      math.Inf(-1),
+	}
+}
+
+// SetLookahead sets the time (in seconds) the signal is delayed, so that
+// the gate opens before the attacks
+func (g *Gate) SetLookahead(seconds float64) {
+	g.setLookahead(seconds)
+	g.held = math.Inf(-1)
+}
+
+func (g *Gate) Process(frame [2]float64) [2]float64 {
+	return g.ProcessKeyed(frame, frame)
+}
+
+func (g *Gate) ProcessKeyed(frame, key [2]float64) [2]float64 {
+	t := g.clock.tick()
+	if g.detect(key) >= g.Threshold(t) {
+		g.held = t
+	}
+	target := -g.Range(t)
+	if t-g.held <= g.Hold(t) {
+		target = 0
+	}
+	gain := g.smooth(target, g.Release(t), g.Attack(t))
+	return apply(g.delay(frame), gain)
+}
+
+func (g *Gate) Reset() {
+	g.reset()
+	g.held = math.Inf(-1)
+}
+
+// -------------------------------------------------------------
+// Limiter is a brick-wall limiter: the output never exceeds the ceiling.
+// The gain is reduced progressively during the lookahead before a peak
+// (the signal is delayed by the lookahead), then released.
+//
+// Le gain cible (plafond/pic) est d'abord remplacé par son minimum sur
+// la fenêtre d'anticipation, puis moyenné sur cette même fenêtre: la
+// moyenne de valeurs toutes inférieures au gain cible de l'échantillon
+// retardé lui est inférieure, ce qui garantit le plafond sans
+// discontinuité du gain.
+type Limiter struct {
+	Ceiling Param // dB
+	Release Param // seconds
+
+	dynamics
+	targets []float64 // target gains of the lookahead window (linear)
+	held    []float64 // minimums of the target gains over the window
+	pos     int
+	sum     float64 // sum of held
+	level   float64 // current linear gain
+}
+
+// NewLimiter returns a brick-wall limiter with the ceiling (in dB), the
+// release time and the lookahead (in seconds).
+func NewLimiter(ceiling, release, lookahead float64, sampleRate int) *Limiter {
+	l := &Limiter{Ceiling: Const(ceiling), Release: Const(release), dynamics: newDynamics(sampleRate)}
+	l.setLookahead(lookahead)
+	n := l.lookahead + 1
+	l.targets = make([]float64, n)
+	l.held = make([]float64, n)
+	l.Reset()
+	return l
+}
+
+func (l *Limiter) Process(frame [2]float64) [2]float64 {
+	return l.ProcessKeyed(frame, frame)
+}
+
+func (l *Limiter) ProcessKeyed(frame, key [2]float64) [2]float64 {
+	t := l.clock.tick()
+	ceiling := dbToGain(l.Ceiling(t))
+	target := 1.
+	if peak := math.Max(math.Abs(key[0]), math.Abs(key[1])); peak > ceiling {
+		target = ceiling / peak
+	}
+
+	n := len(l.targets)
+	l.targets[l.pos] = target
+	held := target
+	for _, g := range l.targets {
+		held = math.Min(held, g)
+	}
+	l.sum += held - l.held[l.pos]
+	l.held[l.pos] = held
+	l.pos = (l.pos + 1) % n
+	if l.pos == 0 {
+		// La somme glissante est recalculée à chaque tour, pour éviter
+		// l'accumulation des erreurs d'arrondi.
+		l.sum = 0
+		for _, g := range l.held {
+			l.sum += g
+		}
+	}
+	smoothed := math.Min(l.sum/float64(n), 1)
+
+	release := timeCoefficient(l.Release(t), l.clock.sampleRate)
+	l.level = math.Min(smoothed, 1-(1-l.level)*release)
+	// Le gain ne dépasse pas le gain cible de la trame retardée (la plus
+	// ancienne de la fenêtre), malgré les erreurs d'arrondi de la moyenne
+	l.level = math.Min(l.level, l.targets[l.pos])
+	l.gain = 20 * math.Log10(l.level)
+
+	frame = l.delay(frame)
+	for c := range frame {
+		frame[c] = clamp(frame[c]*l.level, -ceiling, ceiling)
+	}
+	return frame
+}
+
+func (l *Limiter) Reset() {
+	l.reset()
+	for i := range l.targets {
+		l.targets[i], l.held[i] = 1, 1
+	}
+	l.pos = 0
+	l.sum = float64(len(l.held))
+	l.level = 1
+}
@@ -0,0 +1,224 @@
+package effects
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/wave"
+)
+
+func peak(samples []float64) float64 {
+	p := 0.
+	for _, x := range samples {
+		p = math.Max(p, math.Abs(x))
+	}
+	return p
+}
+
+func toDB(x float64) float64 {
+	return 20 * math.Log10(x)
+}
+
+func TestStaticCurves(t *testing.T) {
+	tests := []struct {
+		name string
+		gain float64
+		want float64
+	}{
+		{"compress below", compress(-30, -20, 4, 0), 0},
+		{"compress above", compress(-8, -20, 4, 0), -9},
+		{"compress knee start", compress(-23, -20, 4, 6), 0},
+		{"compress knee end", compress(-17, -20, 4, 6), -2.25},
+		{"compress knee middle", compress(-20, -20, 4, 6), -0.5625},
+		{"expand above", expand(-10, -20, 2, 0), 0},
+		{"expand below", expand(-30, -20, 2, 0), -10},
+		{"expand knee start", expand(-17, -20, 3, 6), 0},
+		{"expand knee end", expand(-23, -20, 3, 6), -6},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.gain-tt.want) > 1e-9 {
+			t.Errorf("%s: gain is %v (should be %v)", tt.name, tt.gain, tt.want)
+		}
+	}
+}
+
+func TestCompressor(t *testing.T) {
+	// A sine at -6 dB, compressed 4:1 above -18 dB, goes out at -15 dB
+	s := wave.SineWaveSignal(200, 0.5, 1, sampleRate)
+	c := NewCompressor(-18, 4, 0.001, 0.1, sampleRate)
+	c.Knee = Const(0)
+	out := Apply(c, s)
+	if p := toDB(peak(out[sampleRate/2:])); math.Abs(p+15) > 1 {
+		t.Errorf("level is %.1f dB (should be -15 dB)", p)
+	}
+	if r := c.GainReduction(); r < 8 || r > 10 {
+		t.Errorf("gain reduction is %.1f dB (should be about 9 dB)", r)
+	}
+
+	// The makeup gain restores the level
+	c = NewCompressor(-18, 4, 0.001, 0.1, sampleRate)
+	c.Knee = Const(0)
+	c.Makeup = Const(9)
+	out = Apply(c, s)
+	if p := toDB(peak(out[sampleRate/2:])); math.Abs(p+6) > 1 {
+		t.Errorf("level with makeup is %.1f dB (should be -6 dB)", p)
+	}
+
+	// Below the threshold, the signal is unchanged
+	c = NewCompressor(0, 4, 0.001, 0.1, sampleRate)
+	out = Apply(c, s)
+	for i := range s {
+		if out[i] != s[i] {
+			t.Fatalf("sample %d is %v (should be %v)", i, out[i], s[i])
+		}
+	}
+}
+
+func TestCompressor_Lookahead(t *testing.T) {
+	// The lookahead delays the signal, but Apply removes the latency
+	c := NewCompressor(-6, 10, 0.002, 0.1, sampleRate)
+	c.SetLookahead(DefaultLookahead)
+	if l := c.Latency(); l != int(DefaultLookahead*sampleRate) {
+		t.Errorf("latency is %d (should be %d)", l, int(DefaultLookahead*sampleRate))
+	}
+	in := make([]float64, sampleRate)
+	for i := sampleRate / 2; i < len(in); i++ {
+		in[i] = 1
+	}
+	out := Apply(c, in)
+	if out[sampleRate/2-1] != 0 {
+		t.Errorf("sample before the step is %v (should be 0)", out[sampleRate/2-1])
+	}
+	// Thanks to the lookahead, the gain is already reduced at the step
+	if out[sampleRate/2] > 0.9 {
+		t.Errorf("sample of the step is %v (should be reduced)", out[sampleRate/2])
+	}
+}
+
+func TestCompressor_Sidechain(t *testing.T) {
+	// Ducking: the music is compressed when the voice (the key) speaks
+	music := wave.SineWaveSignal(200, 0.5, 1, sampleRate)
+	voice := make([]float64, sampleRate/2)
+	for i := range voice {
+		voice[i] = 0.8
+	}
+	c := NewCompressor(-20, 10, 0.001, 0.05, sampleRate)
+	out := ApplyKeyed(c, music, voice)
+	during := peak(out[sampleRate/4 : sampleRate/2])
+	after := peak(out[3*sampleRate/4:])
+	if during > 0.2 || math.Abs(after-0.5) > 0.01 {
+		t.Errorf("level is %v during the voice and %v after (should be below 0.2 and 0.5)", during, after)
+	}
+}
+
+func TestExpander(t *testing.T) {
+	// A sine at -26 dB, expanded 1:2 below -20 dB, goes out at -32 dB
+	s := wave.SineWaveSignal(200, 0.05, 1, sampleRate)
+	e := NewExpander(-20, 2, 0.001, 0.001, sampleRate)
+	e.Knee = Const(0)
+	out := Apply(e, s)
+	if p := toDB(peak(out[sampleRate/2:])); math.Abs(p+32) > 1 {
+		t.Errorf("level is %.1f dB (should be -32 dB)", p)
+	}
+
+	// The range limits the reduction
+	e = NewExpander(-20, 10, 0.001, 0.001, sampleRate)
+	e.Range = Const(12)
+	out = Apply(e, s)
+	if p := toDB(peak(out[sampleRate/2:])); math.Abs(p+38) > 1 {
+		t.Errorf("level with a range of 12 dB is %.1f dB (should be -38 dB)", p)
+	}
+}
+
+func TestGate(t *testing.T) {
+	// A note followed by a noise
+	in := wave.SineWaveSignal(200, 0.5, 1, sampleRate)
+	for i := sampleRate / 2; i < len(in); i++ {
+		in[i] *= 0.002
+	}
+	g := NewGate(-40, 0.001, 0.01, sampleRate)
+	out := Apply(g, in)
+	if p := peak(out[sampleRate/4 : sampleRate/2]); math.Abs(p-0.5) > 0.01 {
+		t.Errorf("level of the note is %v (should be 0.5)", p)
+	}
+	if p := peak(out[3*sampleRate/4:]); p > 1e-6 {
+		t.Errorf("level of the noise is %v (should be 0)", p)
+	}
+
+	// The gate stays open during the hold time
+	g.Reset()
+	g.Hold = Const(0.2)
+	out = Apply(g, in)
+	if p := peak(out[sampleRate/2 : sampleRate/2+sampleRate/10]); p < 0.0009 {
+		t.Errorf("level during the hold is %v (should be 0.001)", p)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	// A loud signal with a peak
+	in := wave.SineWaveSignal(200, 0.6, 1, sampleRate)
+	in[sampleRate/2] = 3
+	l := NewLimiter(-1, 0.05, DefaultLookahead, sampleRate)
+	out := Apply(l, in)
+	ceiling := math.Pow(10, -1./20)
+	if p := peak(out); p > ceiling+1e-12 {
+		t.Errorf("peak is %v (should be below %v)", p, ceiling)
+	}
+	// Far from the peak, the signal is unchanged
+	for i := range sampleRate / 4 {
+		if out[i] != in[i] {
+			t.Fatalf("sample %d is %v (should be %v)", i, out[i], in[i])
+		}
+	}
+	if r := l.GainReduction(); r > 0.01 {
+		t.Errorf("gain reduction at the end is %v (should be 0)", r)
+	}
+}
+
+func TestLimiter_Stream(t *testing.T) {
+	// The latency is removed by Stream: the samples are aligned
+	s := wave.SineWaveSignal(200, 2, 0.5, sampleRate)
+	l := NewLimiter(0, 0.05, DefaultLookahead, sampleRate)
+	buffer := make([][2]float64, len(s))
+	for i, x := range s {
+		buffer[i] = [2]float64{x, x}
+	}
+	source := &sliceStreamer{frames: buffer}
+	// The short read at the end of the source is passed through: the
+	// stream is read until it is over
+	out := make([][2]float64, 2*len(s))
+	stream := Stream(source, l, 0, sampleRate)
+	n := 0
+	for {
+		m, ok := stream.Stream(out[n:])
+		n += m
+		if !ok {
+			break
+		}
+	}
+	if n != len(s) {
+		t.Fatalf("len is %d (should be %d)", n, len(s))
+	}
+	for i := range n {
+		if math.Abs(out[i][0]) > 1 {
+			t.Fatalf("sample %d is %v (should be below 1)", i, out[i][0])
+		}
+		if math.Abs(s[i]) > 0 && math.Signbit(out[i][0]) != math.Signbit(s[i]) {
+			t.Fatalf("sample %d is %v (should have the sign of %v)", i, out[i][0], s[i])
+		}
+	}
+}
+
+type sliceStreamer struct {
+	frames [][2]float64
+}
+
+func (s *sliceStreamer) Stream(samples [][2]float64) (int, bool) {
+	n := copy(samples, s.frames)
+	s.frames = s.frames[n:]
+	return n, n > 0
+}
+
+func (s *sliceStreamer) Err() error {
+	return nil
+}
@@ -0,0 +1,361 @@
+package effects
+
+// This package implements the audio effects: the delays (feedback delay
+// and multi-tap echo), the reverberation, the modulation effects
+// (chorus, flanger and phaser), the distortions and the amplifier of
+// the electric guitar, and the dynamics processors (compressor,
+// expander, noise gate and limiter). An effect processes a stereo signal
+// frame by frame, and keeps its state (delay lines, filters) from one
+// frame to the next one, so that it can be applied to a whole signal
+// ([]float64, see Apply) or to a stream (beep.Streamer, see Stream).
+//
+// The parameters of the effects are functions of the time (see Param),
+// so that they can be automated: a constant, a ramp, an oscillation, or
+// an envelope defined by points. Each effect has a wet/dry mix: 0 gives
+// the input signal only (dry), 1 gives the processed signal only (wet).
+//
+// Ce package n'utilise pas le package sound, qui l'utilise (limiteur
+// par défaut de la lecture et de l'export).
+
+import (
+	"math"
+
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+)
+
+// Effect is an audio effect processing a stereo signal frame by frame
+type Effect interface {
+	// Process returns the output frame for the input frame (left and
+	// right samples).
+	Process(frame [2]float64) [2]float64
+	// Reset clears the state of the effect (delay lines, filters and
+	// time), as if no frame had been processed.
+	Reset()
+}
+
+// -------------------------------------------------------------
+// Param is a parameter of an effect, that can change with the time t
+// (in seconds from the first processed frame).
+type Param func(t float64) float64
+
+// Const returns a constant parameter
+func Const(v float64) Param {
+	return func(float64) float64 { return v }
+}
+
+// Ramp returns a parameter that goes linearly from the value from to the
+// value to, between the times start and end (in seconds). It is
+// constant before and after.
+func Ramp(from, to, start, end float64) Param {
+	return func(t float64) float64 {
+		switch {
+		case t <= start:
+			return from
+		case t >= end:
+			return to
+		}
+		return from + (to-from)*(t-start)/(end-start)
+	}
+}
+
+// LFO returns a parameter that oscillates around the center value, with
+// the specified depth (amplitude) and rate (frequency in Hz), as a low
+// frequency oscillator.
+func LFO(center, depth, rate float64) Param {
+	return func(t float64) float64 {
+		return center + depth*math.Sin(2*math.Pi*rate*t)
+	}
+}
+
+// Point is a point of an envelope: the value of a parameter at a time
+type Point struct {
+	Time  float64
+	Value float64
+}
+
+// Envelope returns a parameter defined by points sorted by time, and
+// interpolated linearly between them (constant before the first point
+// and after the last one).
+func Envelope(points ...Point) Param {
+	return func(t float64) float64 {
+		if len(points) == 0 {
+			return 0
+		}
+		if t <= points[0].Time {
+			return points[0].Value
+		}
+		for i := 1; i < len(points); i++ {
+			if t < points[i].Time {
+				p, q := points[i-1], points[i]
+				return p.Value + (q.Value-p.Value)*(t-p.Time)/(q.Time-p.Time)
+			}
+		}
+		return points[len(points)-1].Value
+	}
+}
+
+// clock counts the processed frames, to evaluate the parameters
+type clock struct {
+	sampleRate float64
+	frame      int64
+}
+
+func newClock(sampleRate int) clock {
+	return clock{sampleRate: float64(wave.SampleRate(sampleRate))}
+}
+
+// tick returns the time of the current frame and moves to the next one
+func (c *clock) tick() float64 {
+	t := float64(c.frame) / c.sampleRate
+	c.frame++
+	return t
+}
+
+// mix returns the wet/dry mix of the two samples
+func mix(dry, wet, m float64) float64 {
+	return (1-m)*dry + m*wet
+}
+
+func clamp(v, low, high float64) float64 {
+	return math.Max(low, math.Min(v, high))
+}
+
+// -------------------------------------------------------------
+// delayLine is a circular buffer that reads the past samples at a
+// fractional delay (with a linear interpolation).
+type delayLine struct {
+	buffer []float64
+	pos    int // position of the next written sample
+}
+
+func newDelayLine(size int) *delayLine {
+	return &delayLine{buffer: make([]float64, max(size, 2))}
+}
+
+func (d *delayLine) write(x float64) {
+	d.buffer[d.pos] = x
+	d.pos = (d.pos + 1) % len(d.buffer)
+}
+
+// read returns the sample written delay samples before the next one (a
+// delay of 1 is the last written sample).
+func (d *delayLine) read(delay float64) float64 {
+	delay = clamp(delay, 1, float64(len(d.buffer)-1))
+	k := int(math.Floor(delay))
+	frac := delay - float64(k)
+	n := len(d.buffer)
+	a := d.buffer[(d.pos-k+n)%n]
+	b := d.buffer[(d.pos-k-1+n)%n]
+	return (1-frac)*a + frac*b
+}
+
+func (d *delayLine) reset() {
+	clear(d.buffer)
+	d.pos = 0
+}
+
+// -------------------------------------------------------------
+// chain is a sequence of effects
+type chain []Effect
+
+// Chain returns the effect that applies the effects one after the
+// other (the output of an effect is the input of the next one).
+func Chain(effects ...Effect) Effect {
+	return chain(effects)
+}
+
+func (c chain) Process(frame [2]float64) [2]float64 {
+	for _, e := range c {
+		frame = e.Process(frame)
+	}
+	return frame
+}
+
+func (c chain) Reset() {
+	for _, e := range c {
+		e.Reset()
+	}
+}
+
+func (c chain) Latency() int {
+	n := 0
+	for _, e := range c {
+		n += Latency(e)
+	}
+	return n
+}
+
+// Latency returns the delay (in frames) that the effect adds to the
+// signal, i.e. the lookahead of the dynamics processors (0 for the
+// other effects). Apply and Stream remove this delay.
+func Latency(e Effect) int {
+	if l, ok := e.(interface{ Latency() int }); ok {
+		return l.Latency()
+	}
+	return 0
+}
+
+// -------------------------------------------------------------
+// Apply processes a mono signal with the effect and returns the result
+// (the left channel of the output), with the same number of samples
+// (the latency of the effect is removed). The state of the effect is
+// kept: call Reset before applying it to another signal.
+func Apply(e Effect, samples []float64) []float64 {
+	out, _ := ApplyStereo(e, samples, samples)
+	return out
+}
+
+// ApplyStereo processes a stereo signal with the effect (see Apply)
+func ApplyStereo(e Effect, left, right []float64) ([]float64, []float64) {
+	return applyKeyed(e, left, right, nil, nil)
+}
+
+// ApplyKeyed processes a mono signal with the effect driven by the key
+// signal (the side chain, see Keyed). The key is completed with silence
+// if it is shorter than the signal.
+func ApplyKeyed(e Keyed, samples, key []float64) []float64 {
+	out, _ := applyKeyed(e, samples, samples, key, key)
+	return out
+}
+
+func applyKeyed(e Effect, left, right, keyLeft, keyRight []float64) ([]float64, []float64) {
+	keyed, _ := e.(Keyed)
+	n := min(len(left), len(right))
+	latency := Latency(e)
+	outL := make([]float64, n)
+	outR := make([]float64, n)
+	for i := range n + latency {
+		var frame, key [2]float64
+		if i < n {
+			frame = [2]float64{left[i], right[i]}
+		}
+		if keyLeft != nil {
+			if i < len(keyLeft) {
+				key[0] = keyLeft[i]
+			}
+			if i < len(keyRight) {
+				key[1] = keyRight[i]
+			}
+			frame = keyed.ProcessKeyed(frame, key)
+		} else {
+			frame = e.Process(frame)
+		}
+		if i >= latency {
+			outL[i-latency], outR[i-latency] = frame[0], frame[1]
+		}
+	}
+	return outL, outR
+}
+
+// Stream returns a streamer that processes the streamer with the
+// effect. When the streamer is over, the effect is fed with silence
+// during the tail time (in seconds), so that the echoes or the
+// reverberation can fade out. The latency of the effect is removed.
+func Stream(s beep.Streamer, e Effect, tail float64, sampleRate int) beep.Streamer {
+	latency := Latency(e)
+	return &streamer{
+		streamer: s,
+		effect:   e,
+		tail:     int(tail*float64(wave.SampleRate(sampleRate))) + latency,
+		skip:     latency,
+	}
+}
+
+// KeyedStream returns a streamer that processes the streamer with the
+// effect driven by the key streamer (the side chain, see Keyed), as
+// Stream. The key is completed with silence if it is shorter.
+func KeyedStream(s, key beep.Streamer, e Keyed, tail float64, sampleRate int) beep.Streamer {
+	st := Stream(s, e, tail, sampleRate).(*streamer)
+	st.key = key
+	return st
+}
+
+type streamer struct {
+	streamer beep.Streamer
+	effect   Effect
+	tail     int // number of frames of the tail not yet streamed
+	skip     int // number of frames of the latency not yet removed
+	done     bool
+
+	key     beep.Streamer // side chain
+	keyDone bool
+	keys    [][2]float64
+}
+
+// keyFrames returns the next n frames of the key
+func (s *streamer) keyFrames(n int) [][2]float64 {
+	if cap(s.keys) < n {
+		s.keys = make([][2]float64, n)
+	}
+	keys := s.keys[:n]
+	m := 0
+	if !s.keyDone {
+		var ok bool
+		m, ok = s.key.Stream(keys)
+		if !ok {
+			s.keyDone = true
+		}
+	}
+	clear(keys[m:])
+	return keys
+}
+
+// Stream implements the beep.Streamer interface. A short read of the
+// streamer (e.g. a live source waiting for its events) is passed
+// through: the streamer is over only when it returns false.
+func (s *streamer) Stream(samples [][2]float64) (int, bool) {
+	n := 0
+	for n < len(samples) {
+		frames := samples[n:]
+		m := 0
+		short := false
+		if !s.done {
+			var ok bool
+			m, ok = s.streamer.Stream(frames)
+			if !ok {
+				s.done = true
+			} else if m < len(frames) {
+				short = true
+			}
+		}
+		for m < len(frames) && s.done && s.tail > 0 {
+			frames[m] = [2]float64{}
+			m++
+			s.tail--
+		}
+		if m == 0 {
+			break
+		}
+
+		var keys [][2]float64
+		if s.key != nil {
+			keys = s.keyFrames(m)
+		}
+		// Les premières trames (la latence de l'effet) sont retirées
+		k := 0
+		for i := range m {
+			var frame [2]float64
+			if keys != nil {
+				frame = s.effect.(Keyed).ProcessKeyed(frames[i], keys[i])
+			} else {
+				frame = s.effect.Process(frames[i])
+			}
+			if s.skip > 0 {
+				s.skip--
+				continue
+			}
+			frames[k] = frame
+			k++
+		}
+		n += k
+		if short {
+			break
+		}
+	}
+	return n, n > 0 || !s.done
+}
+
+func (s *streamer) Err() error {
+	return s.streamer.Err()
+}
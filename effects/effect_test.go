@@ -0,0 +1,143 @@
+package effects
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gopxl/beep"
+)
+
+const sampleRate = 8000
+
+// impulse returns a signal of n samples, 1 at the beginning and 0 after
+func impulse(n int) []float64 {
+	s := make([]float64, n)
+	s[0] = 1
+	return s
+}
+
+func energy(samples []float64) float64 {
+	e := 0.
+	for _, x := range samples {
+		e += x * x
+	}
+	return e
+}
+
+func TestParams(t *testing.T) {
+	ramp := Ramp(0, 1, 1, 3)
+	tests := []struct {
+		p    Param
+		t    float64
+		want float64
+	}{
+		{Const(0.3), 12, 0.3},
+		{ramp, 0, 0},
+		{ramp, 2, 0.5},
+		{ramp, 5, 1},
+		{LFO(1, 0.5, 1), 0.25, 1.5},
+		{LFO(1, 0.5, 1), 0.75, 0.5},
+		{Envelope(Point{1, 0}, Point{2, 1}, Point{4, 0}), 0, 0},
+		{Envelope(Point{1, 0}, Point{2, 1}, Point{4, 0}), 3, 0.5},
+		{Envelope(Point{1, 0}, Point{2, 1}, Point{4, 0}), 5, 0},
+		{Envelope(), 1, 0},
+	}
+	for i, tt := range tests {
+		if got := tt.p(tt.t); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("param #%d at %v is %v (should be %v)", i, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestDelayLine(t *testing.T) {
+	d := newDelayLine(8)
+	for i := 1; i <= 5; i++ {
+		d.write(float64(i))
+	}
+	if got := d.read(1); got != 5 {
+		t.Errorf("read(1) is %v (should be 5)", got)
+	}
+	if got := d.read(3); got != 3 {
+		t.Errorf("read(3) is %v (should be 3)", got)
+	}
+	if got := d.read(2.5); got != 3.5 {
+		t.Errorf("read(2.5) is %v (should be 3.5)", got)
+	}
+	d.reset()
+	if got := d.read(1); got != 0 {
+		t.Errorf("read(1) after reset is %v (should be 0)", got)
+	}
+}
+
+func TestChain(t *testing.T) {
+	// Two delays of 10 ms in a chain make a delay of 20 ms
+	d1 := NewDelay(0.01, 0, 1, sampleRate)
+	d2 := NewDelay(0.01, 0, 1, sampleRate)
+	out := Apply(Chain(d1, d2), impulse(400))
+	want := int(0.02 * sampleRate)
+	for i, x := range out {
+		if i == want && x != 1 || i != want && x != 0 {
+			t.Errorf("sample %d is %v", i, x)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	samples := make([][2]float64, 100)
+	samples[0] = [2]float64{1, 1}
+	source := beep.StreamerFunc(func(buffer [][2]float64) (int, bool) {
+		n := copy(buffer, samples)
+		samples = samples[n:]
+		return n, n > 0
+	})
+	s := Stream(source, NewDelay(0.05, 0, 1, sampleRate), 0.1, sampleRate)
+
+	var out [][2]float64
+	buffer := make([][2]float64, 64)
+	for {
+		n, ok := s.Stream(buffer)
+		out = append(out, buffer[:n]...)
+		if !ok {
+			break
+		}
+	}
+	// The tail is added after the end of the source
+	if want := 100 + int(0.1*sampleRate); len(out) != want {
+		t.Errorf("len is %d (should be %d)", len(out), want)
+	}
+	if echo := out[int(0.05*sampleRate)]; echo != [2]float64{1, 1} {
+		t.Errorf("echo is %v (should be [1 1])", echo)
+	}
+}
+
+func TestStream_Short(t *testing.T) {
+	// The source returns no samples for the first calls (a live source
+	// waiting for its events): the stream goes on
+	wait := 3
+	samples := make([][2]float64, 100)
+	source := beep.StreamerFunc(func(buffer [][2]float64) (int, bool) {
+		if wait > 0 {
+			wait--
+			return 0, true
+		}
+		n := copy(buffer, samples)
+		samples = samples[n:]
+		return n, n > 0
+	})
+	s := Stream(source, NewLimiter(0, 0.1, 0.001, sampleRate), 0, sampleRate)
+	buffer := make([][2]float64, 64)
+	if n, ok := s.Stream(buffer); n != 0 || !ok {
+		t.Errorf("the waiting stream returns (%d, %v) (should be (0, true))", n, ok)
+	}
+	total := 0
+	for {
+		n, ok := s.Stream(buffer)
+		total += n
+		if !ok {
+			break
+		}
+	}
+	if total != 100 {
+		t.Errorf("len is %d (should be 100)", total)
+	}
+}
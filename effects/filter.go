@@ -0,0 +1,112 @@
+package effects
+
+import "math"
+
+// Les filtres du second ordre (biquads) suivent les formules du "Audio
+// EQ Cookbook" de Robert Bristow-Johnson. Ils servent à l'égaliseur de
+// l'ampli (tone stack) et au filtre anti-repliement du suréchantillonnage
+// de la distorsion.
+
+// biquad is a second order filter (transposed direct form II)
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+// set defines the coefficients, normalized by a0
+func (f *biquad) set(b0, b1, b2, a0, a1, a2 float64) {
+	f.b0, f.b1, f.b2 = b0/a0, b1/a0, b2/a0
+	f.a1, f.a2 = a1/a0, a2/a0
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+func (f *biquad) reset() {
+	f.z1, f.z2 = 0, 0
+}
+
+// omega returns the cosine and the sine of the normalized frequency
+func omega(freq, sampleRate float64) (float64, float64) {
+	w := 2 * math.Pi * clamp(freq, 1, 0.49*sampleRate) / sampleRate
+	return math.Cos(w), math.Sin(w)
+}
+
+// lowPass defines a low-pass filter of cutoff frequency freq
+func (f *biquad) lowPass(freq, q, sampleRate float64) {
+	cos, sin := omega(freq, sampleRate)
+	alpha := sin / (2 * q)
+	f.set((1-cos)/2, 1-cos, (1-cos)/2, 1+alpha, -2*cos, 1-alpha)
+}
+
+// highPass defines a high-pass filter of cutoff frequency freq
+func (f *biquad) highPass(freq, q, sampleRate float64) {
+	cos, sin := omega(freq, sampleRate)
+	alpha := sin / (2 * q)
+	f.set((1+cos)/2, -(1 + cos), (1+cos)/2, 1+alpha, -2*cos, 1-alpha)
+}
+
+// peaking defines a bell filter of gain db (in dB) around freq
+func (f *biquad) peaking(freq, q, db, sampleRate float64) {
+	cos, sin := omega(freq, sampleRate)
+	a := math.Pow(10, db/40)
+	alpha := sin / (2 * q)
+	f.set(1+alpha*a, -2*cos, 1-alpha*a, 1+alpha/a, -2*cos, 1-alpha/a)
+}
+
+// lowShelf defines a filter of gain db (in dB) below freq
+func (f *biquad) lowShelf(freq, db, sampleRate float64) {
+	cos, sin := omega(freq, sampleRate)
+	a := math.Pow(10, db/40)
+	alpha := sin / math.Sqrt2 // shelf slope of 1
+	sq := 2 * math.Sqrt(a) * alpha
+	f.set(
+		a*((a+1)-(a-1)*cos+sq),
+		2*a*((a-1)-(a+1)*cos),
+		a*((a+1)-(a-1)*cos-sq),
+		(a+1)+(a-1)*cos+sq,
+		-2*((a-1)+(a+1)*cos),
+		(a+1)+(a-1)*cos-sq,
+	)
+}
+
+// highShelf defines a filter of gain db (in dB) above freq
+func (f *biquad) highShelf(freq, db, sampleRate float64) {
+	cos, sin := omega(freq, sampleRate)
+	a := math.Pow(10, db/40)
+	alpha := sin / math.Sqrt2
+	sq := 2 * math.Sqrt(a) * alpha
+	f.set(
+		a*((a+1)+(a-1)*cos+sq),
+		-2*a*((a-1)+(a+1)*cos),
+		a*((a+1)+(a-1)*cos-sq),
+		(a+1)-(a-1)*cos+sq,
+		2*((a-1)-(a+1)*cos),
+		(a+1)-(a-1)*cos-sq,
+	)
+}
+
+// dcBlocker removes the constant component of a signal (a one pole
+// high-pass filter at about 10 Hz), that an asymmetric distortion adds.
+type dcBlocker struct {
+	x1, y1 float64
+	r      float64
+}
+
+func newDCBlocker(sampleRate float64) dcBlocker {
+	return dcBlocker{r: 1 - 2*math.Pi*10/sampleRate}
+}
+
+func (d *dcBlocker) process(x float64) float64 {
+	y := x - d.x1 + d.r*d.y1
+	d.x1, d.y1 = x, y
+	return y
+}
+
+func (d *dcBlocker) reset() {
+	d.x1, d.y1 = 0, 0
+}
@@ -0,0 +1,73 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+// amplitude returns the amplitude of the component of frequency f of
+// the samples (a correlation with a sine and a cosine)
+func amplitude(samples []float64, f, sampleRate float64) float64 {
+	re, im := 0., 0.
+	for i, x := range samples {
+		w := 2 * math.Pi * f * float64(i) / sampleRate
+		re += x * math.Cos(w)
+		im += x * math.Sin(w)
+	}
+	return 2 * math.Hypot(re, im) / float64(len(samples))
+}
+
+// gain returns the gain of the filter at the frequency f
+func gain(f *biquad, freq, sampleRate float64) float64 {
+	n := int(sampleRate)
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = f.process(math.Sin(2 * math.Pi * freq * float64(i) / sampleRate))
+	}
+	// Le régime transitoire est ignoré
+	return amplitude(out[n/2:], freq, sampleRate)
+}
+
+// butterworthQ is the quality factor of a Butterworth filter (1/√2), whose
+// gain at the cutoff frequency is -3 dB
+var butterworthQ = 1 / math.Sqrt2
+
+func TestBiquad(t *testing.T) {
+	const rate = 44100.
+	db := func(g float64) float64 { return math.Pow(10, g/20) }
+	tests := []struct {
+		name string
+		set  func(f *biquad)
+		freq float64
+		want float64
+	}{
+		{"low-pass pass band", func(f *biquad) { f.lowPass(1000, butterworthQ, rate) }, 100, 1},
+		{"low-pass cutoff", func(f *biquad) { f.lowPass(1000, butterworthQ, rate) }, 1000, butterworthQ},
+		{"high-pass pass band", func(f *biquad) { f.highPass(100, butterworthQ, rate) }, 5000, 1},
+		{"high-pass cutoff", func(f *biquad) { f.highPass(100, butterworthQ, rate) }, 100, butterworthQ},
+		{"peaking", func(f *biquad) { f.peaking(800, 0.7, 6, rate) }, 800, db(6)},
+		{"peaking flat", func(f *biquad) { f.peaking(800, 0.7, 0, rate) }, 800, 1},
+		{"low shelf", func(f *biquad) { f.lowShelf(100, -12, rate) }, 20, db(-12)},
+		{"low shelf above", func(f *biquad) { f.lowShelf(100, -12, rate) }, 5000, 1},
+		{"high shelf", func(f *biquad) { f.highShelf(3200, 9, rate) }, 15000, db(9)},
+		{"high shelf below", func(f *biquad) { f.highShelf(3200, 9, rate) }, 100, 1},
+	}
+	for _, tt := range tests {
+		var f biquad
+		tt.set(&f)
+		if got := gain(&f, tt.freq, rate); math.Abs(got-tt.want) > 0.02*tt.want {
+			t.Errorf("%s: gain is %.3f (should be %.3f)", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDCBlocker(t *testing.T) {
+	d := newDCBlocker(sampleRate)
+	var y float64
+	for range sampleRate {
+		y = d.process(0.5)
+	}
+	if math.Abs(y) > 1e-3 {
+		t.Errorf("output of a constant is %v (should be 0)", y)
+	}
+}
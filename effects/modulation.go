@@ -0,0 +1,202 @@
+package effects
+
+import "math"
+
+// Les effets de modulation mélangent le signal avec une copie retardée
+// (chorus, flanger) ou déphasée (phaser), le retard ou le déphasage
+// étant modulé par un oscillateur basse fréquence (LFO). Le LFO du canal
+// droit est en quadrature avec celui du canal gauche, ce qui élargit
+// l'image stéréo.
+
+// lfo returns the value (from -1 to 1) of a LFO at the phase (in
+// cycles) for the channel ch: the right channel is in quadrature with
+// the left channel.
+func lfo(phase float64, ch int) float64 {
+	return math.Sin(2*math.Pi*phase + float64(ch)*math.Pi/2)
+}
+
+// Chorus thickens the signal as if several instruments played it: the
+// signal is mixed with a copy delayed by about 20 ms, the delay slowly
+// oscillating with the depth.
+type Chorus struct {
+	Delay Param // base delay, in seconds
+	Depth Param // amplitude of the delay oscillation, in seconds
+	Rate  Param // frequency of the delay oscillation, in Hz
+	Mix   Param
+
+	clock clock
+	lines [2]*delayLine
+	phase float64 // phase of the LFO, in cycles (the rate can change)
+}
+
+// Default parameters of the modulation effects
+const (
+	ChorusDelay  = 0.020 // seconds
+	FlangerDelay = 0.002 // seconds
+	maxModDelay  = 0.050 // capacity of the delay lines, in seconds
+)
+
+// NewChorus returns a chorus with a base delay of 20 ms, and the depth
+// (in seconds, e.g. 0.003) and rate (in Hz, e.g. 0.8) of the oscillation.
+func NewChorus(depth, rate, mix float64, sampleRate int) *Chorus {
+	c := &Chorus{Delay: Const(ChorusDelay), Depth: Const(depth), Rate: Const(rate), Mix: Const(mix), clock: newClock(sampleRate)}
+	// La ligne peut contenir le retard maximal même si le retard de base
+	// est augmenté de la profondeur (comme le fait sound.Chorus)
+	size := int(math.Max(maxModDelay, 2*(ChorusDelay+depth))*c.clock.sampleRate) + 2
+	c.lines = [2]*delayLine{newDelayLine(size), newDelayLine(size)}
+	return c
+}
+
+func (c *Chorus) Process(frame [2]float64) [2]float64 {
+	t := c.clock.tick()
+	m := c.Mix(t)
+	var out [2]float64
+	for ch, line := range c.lines {
+		line.write(frame[ch])
+		delay := c.Delay(t) + c.Depth(t)*lfo(c.phase, ch)
+		wet := line.read(delay*c.clock.sampleRate + 1)
+		out[ch] = mix(frame[ch], wet, m)
+	}
+	c.phase = math.Mod(c.phase+c.Rate(t)/c.clock.sampleRate, 1)
+	return out
+}
+
+func (c *Chorus) Reset() {
+	c.clock.frame = 0
+	c.phase = 0
+	for _, line := range c.lines {
+		line.reset()
+	}
+}
+
+// -------------------------------------------------------------
+// Flanger is a chorus with a very short delay (a few milliseconds) and a
+// feedback: the comb filter it makes sweeps the spectrum, with the
+// characteristic "jet plane" sound.
+type Flanger struct {
+	Delay    Param // base delay, in seconds
+	Depth    Param // amplitude of the delay oscillation, in seconds
+	Rate     Param // frequency of the delay oscillation, in Hz
+	Feedback Param // from -1 to 1 (excluded)
+	Mix      Param
+
+	clock clock
+	lines [2]*delayLine
+	phase float64
+}
+
+// NewFlanger returns a flanger with a base delay of 2 ms, and the depth
+// (in seconds, e.g. 0.0015), the rate (in Hz, e.g. 0.25) and the
+// feedback of the oscillation.
+func NewFlanger(depth, rate, feedback, mix float64, sampleRate int) *Flanger {
+	f := &Flanger{
+		Delay:    Const(FlangerDelay),
+		Depth:    Const(depth),
+		Rate:     Const(rate),
+		Feedback: Const(feedback),
+		Mix:      Const(mix),
+		clock:    newClock(sampleRate),
+	}
+	size := int(maxModDelay*f.clock.sampleRate) + 2
+	f.lines = [2]*delayLine{newDelayLine(size), newDelayLine(size)}
+	return f
+}
+
+func (f *Flanger) Process(frame [2]float64) [2]float64 {
+	t := f.clock.tick()
+	feedback := clamp(f.Feedback(t), -0.95, 0.95)
+	m := f.Mix(t)
+	var out [2]float64
+	for ch, line := range f.lines {
+		delay := f.Delay(t) + f.Depth(t)*lfo(f.phase, ch)
+		wet := line.read(delay * f.clock.sampleRate)
+		line.write(frame[ch] + feedback*wet)
+		out[ch] = mix(frame[ch], wet, m)
+	}
+	f.phase = math.Mod(f.phase+f.Rate(t)/f.clock.sampleRate, 1)
+	return out
+}
+
+func (f *Flanger) Reset() {
+	f.clock.frame = 0
+	f.phase = 0
+	for _, line := range f.lines {
+		line.reset()
+	}
+}
+
+// -------------------------------------------------------------
+// Phaser shifts the phase of the signal with a series of all-pass
+// filters, whose frequency is swept by a LFO between a minimal and a
+// maximal frequency. Mixed with the signal, it makes moving notches in
+// the spectrum.
+type Phaser struct {
+	MinFreq  Param // Hz
+	MaxFreq  Param // Hz
+	Rate     Param // frequency of the sweep, in Hz
+	Feedback Param // from -1 to 1 (excluded)
+	Mix      Param
+
+	clock  clock
+	stages [2][]float64 // states of the all-pass filters
+	last   [2]float64   // last output, for the feedback
+	phase  float64
+}
+
+// DefaultPhaserStages is the number of all-pass filters of a phaser (it
+// makes half as many notches).
+const DefaultPhaserStages = 4
+
+// NewPhaser returns a phaser sweeping between the frequencies low and
+// high (in Hz, e.g. 200 and 2000) at the rate (in Hz, e.g. 0.5).
+func NewPhaser(low, high, rate, feedback, mix float64, sampleRate int) *Phaser {
+	p := &Phaser{
+		MinFreq:  Const(low),
+		MaxFreq:  Const(high),
+		Rate:     Const(rate),
+		Feedback: Const(feedback),
+		Mix:      Const(mix),
+		clock:    newClock(sampleRate),
+	}
+	for ch := range p.stages {
+		p.stages[ch] = make([]float64, DefaultPhaserStages)
+	}
+	return p
+}
+
+func (p *Phaser) Process(frame [2]float64) [2]float64 {
+	t := p.clock.tick()
+	low := math.Max(p.MinFreq(t), 1)
+	high := math.Max(p.MaxFreq(t), low)
+	feedback := clamp(p.Feedback(t), -0.95, 0.95)
+	m := p.Mix(t)
+	var out [2]float64
+	for ch := range p.stages {
+		// La fréquence est balayée sur une échelle logarithmique (de
+		// façon régulière à l'oreille).
+		sweep := (1 + lfo(p.phase, ch)) / 2
+		freq := low * math.Pow(high/low, sweep)
+		w := math.Tan(math.Pi * clamp(freq, 1, 0.49*p.clock.sampleRate) / p.clock.sampleRate)
+		a := (w - 1) / (w + 1) // coefficient of the first order all-pass
+
+		x := frame[ch] + feedback*p.last[ch]
+		for i, z := range p.stages[ch] {
+			y := a*x + z
+			p.stages[ch][i] = x - a*y
+			x = y
+		}
+		p.last[ch] = x
+		out[ch] = mix(frame[ch], x, m)
+	}
+	p.phase = math.Mod(p.phase+p.Rate(t)/p.clock.sampleRate, 1)
+	return out
+}
+
+func (p *Phaser) Reset() {
+	p.clock.frame = 0
+	p.phase = 0
+	for ch := range p.stages {
+		clear(p.stages[ch])
+		p.last[ch] = 0
+	}
+}
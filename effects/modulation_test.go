@@ -0,0 +1,86 @@
+package effects
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/wave"
+)
+
+func sine(f float64, seconds float64) []float64 {
+	return wave.SineWaveSignal(f, 0.5, seconds, sampleRate)
+}
+
+func TestChorus(t *testing.T) {
+	c := NewChorus(0.003, 1, 1, sampleRate)
+	in := impulse(sampleRate)
+	out := Apply(c, in)
+	// Wet only: the impulse comes out around the base delay
+	peak := 0
+	for i, x := range out {
+		if math.Abs(x) > math.Abs(out[peak]) {
+			peak = i
+		}
+	}
+	base := int(ChorusDelay * sampleRate)
+	if peak < base-30 || peak > base+30 {
+		t.Errorf("peak is at %d (should be around %d)", peak, base)
+	}
+
+	// The delay oscillates: the left and right channels differ
+	c = NewChorus(0.003, 1, 0.5, sampleRate)
+	s := sine(440, 1)
+	left, right := ApplyStereo(c, s, s)
+	if d := energy(subtract(left, right)); d == 0 {
+		t.Errorf("the left and right channels are identical")
+	}
+}
+
+func TestFlanger(t *testing.T) {
+	f := NewFlanger(0.0015, 0.25, 0.7, 0.5, sampleRate)
+	out := Apply(f, sine(440, 1))
+	for i, x := range out {
+		if math.IsNaN(x) || math.Abs(x) > 2 {
+			t.Fatalf("sample %d is %v", i, x)
+		}
+	}
+	// No modulation, no feedback, half mix: a delay of 2 ms makes a
+	// notch at 1/(2*0.002) = 250 Hz
+	f = NewFlanger(0, 0, 0, 0.5, sampleRate)
+	out = Apply(f, sine(250, 1))
+	if e := energy(out[sampleRate/2:]); e > 1e-6 {
+		t.Errorf("energy at the notch is %v (should be 0)", e)
+	}
+}
+
+func TestPhaser(t *testing.T) {
+	// The all-pass filters keep the energy of the signal (wet only)
+	p := NewPhaser(200, 2000, 0.5, 0, 1, sampleRate)
+	s := sine(440, 2)
+	out := Apply(p, s)
+	ein, eout := energy(s[sampleRate:]), energy(out[sampleRate:])
+	if math.Abs(eout-ein)/ein > 0.05 {
+		t.Errorf("energy is %v (should be %v)", eout, ein)
+	}
+
+	// Mixed with the signal, the phaser makes notches: the energy changes
+	p = NewPhaser(200, 2000, 0.5, 0, 0.5, sampleRate)
+	out = Apply(p, s)
+	if e := energy(out[sampleRate:]); e >= 0.95*ein {
+		t.Errorf("energy is %v (should be below %v)", e, ein)
+	}
+
+	p.Reset()
+	again := Apply(p, s)
+	if again[100] != out[100] {
+		t.Errorf("sample after reset is %v (should be %v)", again[100], out[100])
+	}
+}
+
+func subtract(a, b []float64) []float64 {
+	d := make([]float64, len(a))
+	for i := range a {
+		d[i] = a[i] - b[i]
+	}
+	return d
+}
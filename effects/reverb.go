@@ -0,0 +1,135 @@
+package effects
+
+// La réverbération suit l'algorithme Freeverb (Jezar at Dreampoint), une
+// variante de la réverbération de Schroeder: 8 filtres en peigne
+// parallèles, dont la boucle de rétroaction est amortie par un filtre
+// passe-bas (l'absorption des aigus par les murs), suivis de 4 filtres
+// passe-tout en série qui diffusent les échos. Le canal droit utilise
+// des longueurs un peu plus grandes (stereo spread), ce qui décorrèle
+// les deux canaux.
+
+// Tunings of Freeverb, in samples at 44100 Hz
+var (
+	freeverbCombs     = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	freeverbAllpasses = []int{556, 441, 341, 225}
+)
+
+const (
+	freeverbSpread    = 23
+	freeverbGain      = 0.015
+	freeverbScaleRoom = 0.28
+	freeverbRoom      = 0.7
+	freeverbScaleDamp = 0.4
+	freeverbAllpassFB = 0.5
+	freeverbRate      = 44100.
+)
+
+// comb is a feedback comb filter with a low-pass filter in its loop
+type comb struct {
+	buffer []float64
+	pos    int
+	store  float64 // state of the low-pass filter
+}
+
+func (c *comb) process(x, feedback, damp float64) float64 {
+	out := c.buffer[c.pos]
+	c.store = out*(1-damp) + c.store*damp
+	c.buffer[c.pos] = x + c.store*feedback
+	c.pos = (c.pos + 1) % len(c.buffer)
+	return out
+}
+
+// allpass is the all-pass filter of Freeverb (a Schroeder all-pass)
+type allpass struct {
+	buffer []float64
+	pos    int
+}
+
+func (a *allpass) process(x float64) float64 {
+	delayed := a.buffer[a.pos]
+	a.buffer[a.pos] = x + delayed*freeverbAllpassFB
+	a.pos = (a.pos + 1) % len(a.buffer)
+	return delayed - x
+}
+
+// Reverb is a reverberation (Freeverb): the signal is followed by a
+// dense series of echoes that fade out, as in a room.
+type Reverb struct {
+	RoomSize Param // size of the room, from 0 to 1 (the decay time)
+	Damping  Param // absorption of the high frequencies, from 0 to 1
+	Width    Param // stereo width of the reverberation, from 0 to 1
+	Mix      Param
+
+	clock     clock
+	combs     [2][]*comb
+	allpasses [2][]*allpass
+}
+
+// NewReverb returns a reverberation with constant parameters. A room
+// size of 0.5 and a damping of 0.5 sound like a medium room, a room size
+// of 0.9 like a hall.
+func NewReverb(roomSize, damping, mix float64, sampleRate int) *Reverb {
+	r := &Reverb{
+		RoomSize: Const(roomSize),
+		Damping:  Const(damping),
+		Width:    Const(1),
+		Mix:      Const(mix),
+		clock:    newClock(sampleRate),
+	}
+	scale := r.clock.sampleRate / freeverbRate
+	size := func(n int, c int) int {
+		return max(1, int(float64(n+c*freeverbSpread)*scale))
+	}
+	for c := range 2 {
+		for _, n := range freeverbCombs {
+			r.combs[c] = append(r.combs[c], &comb{buffer: make([]float64, size(n, c))})
+		}
+		for _, n := range freeverbAllpasses {
+			r.allpasses[c] = append(r.allpasses[c], &allpass{buffer: make([]float64, size(n, c))})
+		}
+	}
+	return r
+}
+
+func (r *Reverb) Process(frame [2]float64) [2]float64 {
+	t := r.clock.tick()
+	feedback := clamp(r.RoomSize(t), 0, 1)*freeverbScaleRoom + freeverbRoom
+	damp := clamp(r.Damping(t), 0, 1) * freeverbScaleDamp
+	width := clamp(r.Width(t), 0, 1)
+	input := (frame[0] + frame[1]) * freeverbGain
+
+	var wet [2]float64
+	for c := range 2 {
+		for _, f := range r.combs[c] {
+			wet[c] += f.process(input, feedback, damp)
+		}
+		for _, f := range r.allpasses[c] {
+			wet[c] = f.process(wet[c])
+		}
+	}
+	// Le mélange des deux canaux réduit la largeur stéréo
+	wet1 := (1 + width) / 2
+	wet2 := (1 - width) / 2
+	m := r.Mix(t)
+	// The reverberation is quiet: it is amplified (as the wet gain of
+	// Freeverb) so that a mix of 0.5 sounds balanced.
+	const gain = 3
+	return [2]float64{
+		mix(frame[0], gain*(wet[0]*wet1+wet[1]*wet2), m),
+		mix(frame[1], gain*(wet[1]*wet1+wet[0]*wet2), m),
+	}
+}
+
+func (r *Reverb) Reset() {
+	r.clock.frame = 0
+	for c := range 2 {
+		for _, f := range r.combs[c] {
+			clear(f.buffer)
+			f.pos, f.store = 0, 0
+		}
+		for _, f := range r.allpasses[c] {
+			clear(f.buffer)
+			f.pos = 0
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package effects
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReverb(t *testing.T) {
+	r := NewReverb(0.5, 0.5, 1, sampleRate)
+	left, right := ApplyStereo(r, impulse(4*sampleRate), impulse(4*sampleRate))
+
+	// The reverberation starts after the shortest comb
+	if e := energy(left[:50]); e != 0 {
+		t.Errorf("energy before the first echo is %v (should be 0)", e)
+	}
+	// The reverberation decays, and the channels are decorrelated
+	first := energy(left[:sampleRate])
+	last := energy(left[3*sampleRate:])
+	if first == 0 || last >= first/100 {
+		t.Errorf("energy goes from %v to %v (should decay)", first, last)
+	}
+	same := true
+	for i := range left {
+		same = same && left[i] == right[i]
+	}
+	if same {
+		t.Errorf("the left and right channels are identical")
+	}
+	for i, x := range left {
+		if math.IsNaN(x) || math.Abs(x) > 1 {
+			t.Fatalf("sample %d is %v", i, x)
+		}
+	}
+}
+
+func TestReverb_RoomSize(t *testing.T) {
+	// A larger room has a longer reverberation
+	small := Apply(NewReverb(0.2, 0.5, 1, sampleRate), impulse(4*sampleRate))
+	large := Apply(NewReverb(0.9, 0.5, 1, sampleRate), impulse(4*sampleRate))
+	if es, el := energy(small[2*sampleRate:]), energy(large[2*sampleRate:]); es >= el {
+		t.Errorf("tail energy of the small room is %v (should be below %v)", es, el)
+	}
+}
+
+func TestReverb_Reset(t *testing.T) {
+	r := NewReverb(0.5, 0.5, 0.3, sampleRate)
+	first := Apply(r, impulse(sampleRate))
+	r.Reset()
+	second := Apply(r, impulse(sampleRate))
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("sample %d is %v after reset (should be %v)", i, second[i], first[i])
+		}
+	}
+}
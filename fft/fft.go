@@ -0,0 +1,214 @@
+// Package fft computes the discrete Fourier transforms of the signals:
+// the complex transform and its inverse, the transform of the real
+// signals (one-sided), for any size, the window functions, and the
+// spectrum of a signal with its options (window, scaling, zero-padding).
+//
+// The sizes that are powers of two are computed with the iterative
+// radix-2 algorithm (Cooley-Tukey). The other sizes are computed with
+// the Bluestein algorithm, that writes the transform as a convolution,
+// itself computed with radix-2 transforms of a larger size: the cost is
+// still O(n log n).
+package fft
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// IsPowerOfTwo returns true if n is a power of two (1, 2, 4, 8, ...)
+func IsPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// NextPowerOfTwo returns the smallest power of two greater or equal to
+// n, i.e. the size of the fastest transform of a signal of n samples
+// padded with zeros.
+func NextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
+
+// ZeroPad returns the samples completed with zeros up to n samples (the
+// samples are copied, and truncated if there are more than n).
+func ZeroPad(samples []float64, n int) []float64 {
+	padded := make([]float64, n)
+	copy(padded, samples)
+	return padded
+}
+
+// PadToPowerOfTwo returns the samples completed with zeros up to the
+// next power of two
+func PadToPowerOfTwo(samples []float64) []float64 {
+	return ZeroPad(samples, NextPowerOfTwo(len(samples)))
+}
+
+// -------------------------------------------------------------
+// FFT returns the discrete Fourier transform of the values, of any size:
+//
+//	X[k] = sum of x[j] exp(-2iπjk/n)
+func FFT(values []complex128) []complex128 {
+	out := make([]complex128, len(values))
+	copy(out, values)
+	transform(out)
+	return out
+}
+
+// IFFT returns the inverse discrete Fourier transform of the values,
+// normalized by 1/n so that IFFT(FFT(x)) is x.
+func IFFT(values []complex128) []complex128 {
+	// La transformée inverse est la conjuguée de la transformée de la
+	// conjuguée, divisée par n.
+	n := len(values)
+	out := make([]complex128, n)
+	for i, v := range values {
+		out[i] = cmplx.Conj(v)
+	}
+	transform(out)
+	for i, v := range out {
+		out[i] = cmplx.Conj(v) / complex(float64(n), 0)
+	}
+	return out
+}
+
+// transform computes the FFT in place
+func transform(values []complex128) {
+	switch n := len(values); {
+	case n <= 1:
+	case IsPowerOfTwo(n):
+		radix2(values)
+	default:
+		bluestein(values)
+	}
+}
+
+// radix2 computes the FFT in place, the size being a power of two: the
+// values are sorted in the bit-reversed order, then combined by
+// butterflies of increasing sizes.
+func radix2(values []complex128) {
+	n := len(values)
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range values {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	twiddles := make([]complex128, n/2)
+	for k := range twiddles {
+		twiddles[k] = cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n))
+	}
+	for size := 2; size <= n; size *= 2 {
+		half := size / 2
+		step := n / size
+		for start := 0; start < n; start += size {
+			for k := range half {
+				e := twiddles[k*step] * values[start+k+half]
+				values[start+k], values[start+k+half] = values[start+k]+e, values[start+k]-e
+			}
+		}
+	}
+}
+
+// bluestein computes the FFT in place, for any size. With jk = (j² + k²
+// - (k-j)²)/2, the transform is the convolution of x[j]w[j] with the
+// conjugate of w, multiplied by w[k], where w[j] = exp(-iπj²/n) is a
+// chirp. The convolution is computed with radix-2 transforms.
+func bluestein(values []complex128) {
+	n := len(values)
+	m := NextPowerOfTwo(2*n - 1)
+
+	chirp := make([]complex128, n)
+	for j := range chirp {
+		// j² est réduit modulo 2n pour garder la précision de l'angle
+		j2 := (j * j) % (2 * n)
+		chirp[j] = cmplx.Rect(1, -math.Pi*float64(j2)/float64(n))
+	}
+
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for j, v := range values {
+		a[j] = v * chirp[j]
+	}
+	b[0] = cmplx.Conj(chirp[0])
+	for j := 1; j < n; j++ {
+		b[j] = cmplx.Conj(chirp[j])
+		b[m-j] = b[j]
+	}
+
+	radix2(a)
+	radix2(b)
+	for i := range a {
+		a[i] *= b[i]
+	}
+	c := IFFT(a)
+	for k := range values {
+		values[k] = c[k] * chirp[k]
+	}
+}
+
+// -------------------------------------------------------------
+// RFFT returns the transform of real samples. The transform of a real
+// signal is symmetric (X[n-k] is the conjugate of X[k]): only the n/2+1
+// first values are returned (the one-sided transform).
+func RFFT(samples []float64) []complex128 {
+	values := make([]complex128, len(samples))
+	for i, x := range samples {
+		values[i] = complex(x, 0)
+	}
+	transform(values)
+	return values[:len(samples)/2+1]
+}
+
+// IRFFT returns the real signal of n samples whose one-sided transform
+// is the values (see RFFT), i.e. the inverse of RFFT. The values beyond
+// n/2+1 are ignored, the missing ones are zeros.
+func IRFFT(values []complex128, n int) []float64 {
+	full := make([]complex128, n)
+	for k := 0; k <= n/2 && k < len(values); k++ {
+		full[k] = values[k]
+		if k > 0 {
+			full[n-k] = cmplx.Conj(values[k])
+		}
+	}
+	inverse := IFFT(full)
+	samples := make([]float64, n)
+	for i, v := range inverse {
+		samples[i] = real(v)
+	}
+	return samples
+}
+
+// Freq returns the frequencies of the values of a transform of size n,
+// for samples separated by d seconds (the inverse of the sample rate),
+// as numpy.fft.fftfreq:
+//
+//	f = [0, 1, ...,   n/2-1,     -n/2, ..., -1] / (d*n)   if n is even
+//	f = [0, 1, ..., (n-1)/2, -(n-1)/2, ..., -1] / (d*n)   if n is odd
+func Freq(n int, d float64) []float64 {
+	f := make([]float64, n)
+	dn := d * float64(n)
+	limit := (n - 1) / 2
+	for i := 0; i <= limit; i++ {
+		f[i] = float64(i) / dn
+	}
+	for i := limit + 1; i < n; i++ {
+		f[i] = -float64(n-i) / dn
+	}
+	return f
+}
+
+// RFreq returns the frequencies of the values of a one-sided transform
+// (see RFFT) of n samples separated by d seconds: from 0 to the Nyquist
+// frequency.
+func RFreq(n int, d float64) []float64 {
+	f := make([]float64, n/2+1)
+	for i := range f {
+		f[i] = float64(i) / (d * float64(n))
+	}
+	return f
+}
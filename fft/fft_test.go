@@ -0,0 +1,119 @@
+package fft
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// dft is the direct computation of the discrete Fourier transform
+func dft(values []complex128) []complex128 {
+	n := len(values)
+	out := make([]complex128, n)
+	for k := range out {
+		for j, v := range values {
+			out[k] += v * cmplx.Rect(1, -2*math.Pi*float64(j*k%n)/float64(n))
+		}
+	}
+	return out
+}
+
+func testValues(n int) []complex128 {
+	values := make([]complex128, n)
+	for i := range values {
+		values[i] = complex(math.Sin(float64(i)), math.Cos(float64(3*i)))
+	}
+	return values
+}
+
+func TestFFT(t *testing.T) {
+	// Powers of two (radix-2), and any other sizes (Bluestein)
+	for _, n := range []int{1, 2, 8, 64, 3, 10, 12, 97, 1000} {
+		values := testValues(n)
+		freqs := FFT(values)
+		exp := dft(values)
+		for k := range n {
+			if cmplx.Abs(freqs[k]-exp[k]) > 1e-9*float64(n) {
+				t.Errorf("size %d: frequency %d is %v (should be %v)", n, k, freqs[k], exp[k])
+				break
+			}
+		}
+		inverse := IFFT(freqs)
+		for i := range n {
+			if cmplx.Abs(inverse[i]-values[i]) > 1e-12*float64(n) {
+				t.Errorf("size %d: value %d is %v (should be %v)", n, i, inverse[i], values[i])
+				break
+			}
+		}
+	}
+	if len(FFT(nil)) != 0 {
+		t.Errorf("the FFT of no values should be empty")
+	}
+}
+
+func TestRFFT(t *testing.T) {
+	for _, n := range []int{16, 15} {
+		samples := make([]float64, n)
+		for i := range samples {
+			samples[i] = math.Sin(float64(i)) + 0.5*math.Cos(float64(5*i))
+		}
+		freqs := RFFT(samples)
+		if len(freqs) != n/2+1 {
+			t.Errorf("size %d: the number of frequencies is %d (should be %d)", n, len(freqs), n/2+1)
+		}
+		exp := dft(toComplex(samples))
+		for k := range freqs {
+			if cmplx.Abs(freqs[k]-exp[k]) > 1e-9 {
+				t.Errorf("size %d: frequency %d is %v (should be %v)", n, k, freqs[k], exp[k])
+			}
+		}
+		back := IRFFT(freqs, n)
+		for i := range samples {
+			if math.Abs(back[i]-samples[i]) > 1e-12 {
+				t.Errorf("size %d: sample %d is %v (should be %v)", n, i, back[i], samples[i])
+			}
+		}
+	}
+}
+
+func TestFreq(t *testing.T) {
+	f := Freq(10, 0.01)
+	exp := []float64{0, 10, 20, 30, 40, -50, -40, -30, -20, -10}
+	for i, v := range exp {
+		if math.Abs(f[i]-v) > 1e-9 {
+			t.Errorf("frequency %d is %.6f (should be %.6f)", i, f[i], v)
+		}
+	}
+	f = RFreq(10, 0.01)
+	exp = []float64{0, 10, 20, 30, 40, 50}
+	if len(f) != len(exp) {
+		t.Errorf("the number of frequencies is %d (should be %d)", len(f), len(exp))
+	}
+	for i, v := range exp {
+		if math.Abs(f[i]-v) > 1e-9 {
+			t.Errorf("frequency %d is %.6f (should be %.6f)", i, f[i], v)
+		}
+	}
+}
+
+func TestZeroPad(t *testing.T) {
+	tests := map[int]int{0: 1, 1: 1, 2: 2, 3: 4, 1000: 1024, 1024: 1024, 88200: 131072}
+	for n, exp := range tests {
+		if p := NextPowerOfTwo(n); p != exp {
+			t.Errorf("next power of two of %d is %d (should be %d)", n, p, exp)
+		}
+		if IsPowerOfTwo(n) != (n == exp) {
+			t.Errorf("IsPowerOfTwo(%d) is %v", n, IsPowerOfTwo(n))
+		}
+	}
+	padded := PadToPowerOfTwo([]float64{1, 2, 3})
+	exp := []float64{1, 2, 3, 0}
+	if len(padded) != len(exp) {
+		t.Fatalf("the size is %d (should be %d)", len(padded), len(exp))
+	}
+	for i, v := range exp {
+		if padded[i] != v {
+			t.Errorf("sample %d is %v (should be %v)", i, padded[i], v)
+		}
+	}
+}
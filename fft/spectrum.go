@@ -0,0 +1,106 @@
+package fft
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Scaling is the unit of the values of a spectrum
+type Scaling int
+
+const (
+	// Amplitude is the amplitude of the sinusoids: a sine of amplitude a
+	// (at the frequency of a bin) gives the value a.
+	Amplitude Scaling = iota
+	// Power is the power of the sinusoids (the square of their RMS
+	// value): a sine of amplitude a gives a²/2, a constant c gives c².
+	// The powers of the two-sided spectrum are split between the
+	// positive and the negative frequencies.
+	Power
+	// Decibels is the amplitude in dB relative to 1 (dBFS): a sine of
+	// amplitude 1 gives 0 dB. The null amplitudes give MinDecibels.
+	Decibels
+)
+
+// MinDecibels is the value in dB of the null amplitudes
+const MinDecibels = -300.
+
+// SpectrumOptions are the options of the computation of a spectrum
+type SpectrumOptions struct {
+	Window   Window  // window applied to the samples (nil for none)
+	Scaling  Scaling // unit of the values (Amplitude by default)
+	Size     int     // size of the transform, with zero-padding (0 for the number of samples)
+	TwoSided bool    // all the frequencies, the negative ones included
+}
+
+// Spectrum returns the spectrum of the samples, taken at the specified
+// sample rate: the frequencies and their values. The spectrum is
+// one-sided by default, from 0 to the Nyquist frequency (the spectrum of
+// a real signal is symmetric). The values are corrected from the
+// coherent gain of the window, so that the amplitude of a sinusoid does
+// not depend on the window or on the zero-padding.
+func Spectrum(samples []float64, sampleRate int, options SpectrumOptions) (frequencies, values []float64) {
+	n := len(samples)
+	if n == 0 {
+		return nil, nil
+	}
+	size := max(options.Size, n)
+	windowed := ApplyWindow(samples, options.Window)
+	norm := float64(n)
+	if options.Window != nil {
+		norm *= CoherentGain(options.Window(n))
+	}
+
+	d := 1 / float64(sampleRate)
+	var transform []complex128
+	if options.TwoSided {
+		transform = FFT(toComplex(ZeroPad(windowed, size)))
+		frequencies = Freq(size, d)
+	} else {
+		transform = RFFT(ZeroPad(windowed, size))
+		frequencies = RFreq(size, d)
+	}
+
+	values = make([]float64, len(transform))
+	for k, x := range transform {
+		values[k] = scale(cmplx.Abs(x)/norm, k, size, options.TwoSided, options.Scaling)
+	}
+	return frequencies, values
+}
+
+// scale returns the value of the bin k of a transform of the specified
+// size, whose normalized modulus is a
+func scale(a float64, k, size int, twoSided bool, scaling Scaling) float64 {
+	// Dans le spectre unilatéral, l'amplitude d'une fréquence est la
+	// somme de celles des fréquences positive et négative (sauf pour la
+	// fréquence nulle et la fréquence de Nyquist, qui sont seules).
+	doubled := !twoSided && k > 0 && !(size%2 == 0 && k == size/2)
+	if doubled {
+		a *= 2
+	}
+	switch scaling {
+	case Power:
+		// La puissance d'une sinusoïde est a²/2, celle d'une composante
+		// seule (constante, Nyquist, ou chaque côté du spectre
+		// bilatéral) est a².
+		if doubled {
+			return a * a / 2
+		}
+		return a * a
+	case Decibels:
+		if a > 0 {
+			return math.Max(20*math.Log10(a), MinDecibels)
+		}
+		return MinDecibels
+	default:
+		return a
+	}
+}
+
+func toComplex(samples []float64) []complex128 {
+	values := make([]complex128, len(samples))
+	for i, x := range samples {
+		values[i] = complex(x, 0)
+	}
+	return values
+}
@@ -0,0 +1,119 @@
+package fft
+
+import (
+	"math"
+	"testing"
+)
+
+func sine(f, a float64, n, sampleRate int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = a * math.Sin(2*math.Pi*f*float64(i)/float64(sampleRate))
+	}
+	return s
+}
+
+// peak returns the index of the maximal value
+func peak(values []float64) int {
+	imax := 0
+	for i, v := range values {
+		if v > values[imax] {
+			imax = i
+		}
+	}
+	return imax
+}
+
+func TestSpectrum(t *testing.T) {
+	// 1000 samples at 1000 Hz: the bins are at each Hz, a sine at 100 Hz
+	// falls on a bin
+	r, n := 1000, 1000
+	s := sine(100, 0.5, n, r)
+
+	frequencies, values := Spectrum(s, r, SpectrumOptions{})
+	if len(frequencies) != n/2+1 || len(values) != n/2+1 {
+		t.Errorf("the size of the spectrum is %d (should be %d)", len(values), n/2+1)
+	}
+	if f := frequencies[len(frequencies)-1]; f != 500 {
+		t.Errorf("the last frequency is %v (should be 500)", f)
+	}
+	i := peak(values)
+	if frequencies[i] != 100 || math.Abs(values[i]-0.5) > 1e-9 {
+		t.Errorf("the peak is %v at %v Hz (should be 0.5 at 100 Hz)", values[i], frequencies[i])
+	}
+
+	// The amplitude does not depend on the window (the Kaiser window
+	// has a small leakage from the negative frequency), the scaling
+	// gives the power and the dB
+	for _, window := range []Window{Hann, Hamming, Blackman, FlatTop, Kaiser(6)} {
+		_, values = Spectrum(s, r, SpectrumOptions{Window: window})
+		if math.Abs(values[peak(values)]-0.5) > 1e-5 {
+			t.Errorf("the peak with a window is %v (should be 0.5)", values[peak(values)])
+		}
+	}
+	_, values = Spectrum(s, r, SpectrumOptions{Window: Hann, Scaling: Power})
+	if math.Abs(values[peak(values)]-0.125) > 1e-9 {
+		t.Errorf("the power of the peak is %v (should be 0.125)", values[peak(values)])
+	}
+	_, values = Spectrum(s, r, SpectrumOptions{Window: Hann, Scaling: Decibels})
+	if exp := 20 * math.Log10(0.5); math.Abs(values[peak(values)]-exp) > 1e-9 {
+		t.Errorf("the peak in dB is %v (should be %v)", values[peak(values)], exp)
+	}
+	if values[300] != MinDecibels && values[300] > -200 {
+		t.Errorf("the value far from the peak is %v dB (should be very low)", values[300])
+	}
+
+	// The two-sided spectrum has all the frequencies and half the
+	// amplitude on each side
+	frequencies, values = Spectrum(s, r, SpectrumOptions{TwoSided: true})
+	if len(values) != n {
+		t.Errorf("the size of the two-sided spectrum is %d (should be %d)", len(values), n)
+	}
+	if math.Abs(values[100]-0.25) > 1e-9 || math.Abs(values[n-100]-0.25) > 1e-9 || frequencies[n-100] != -100 {
+		t.Errorf("the two-sided peaks are %v and %v at %v Hz (should be 0.25)", values[100], values[n-100], frequencies[n-100])
+	}
+
+	// A constant is not doubled
+	_, values = Spectrum([]float64{1, 1, 1, 1, 1, 1, 1, 1}, 8, SpectrumOptions{})
+	if math.Abs(values[0]-1) > 1e-12 {
+		t.Errorf("the constant component is %v (should be 1)", values[0])
+	}
+
+	// The power of a constant is its square, the powers of the
+	// two-sided spectrum add up to the power of the signal
+	_, values = Spectrum([]float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}, 8, SpectrumOptions{Scaling: Power})
+	if math.Abs(values[0]-0.25) > 1e-12 {
+		t.Errorf("the power of the constant component is %v (should be 0.25)", values[0])
+	}
+	_, values = Spectrum(s, r, SpectrumOptions{Scaling: Power, TwoSided: true})
+	if math.Abs(values[100]+values[n-100]-0.125) > 1e-9 {
+		t.Errorf("the power of the two-sided peaks is %v (should be 0.125)", values[100]+values[n-100])
+	}
+}
+
+func TestSpectrum_Size(t *testing.T) {
+	// The zero-padding interpolates the spectrum: the flat-top window
+	// gives the amplitude of a sinusoid between two bins (its main lobe
+	// is flat, the peak can be at a neighbouring frequency)
+	r, n := 1000, 1000
+	s := sine(100.5, 1, n, r)
+	frequencies, values := Spectrum(s, r, SpectrumOptions{Window: FlatTop, Size: 4 * n})
+	if len(values) != 2*n+1 {
+		t.Errorf("the size of the spectrum is %d (should be %d)", len(values), 2*n+1)
+	}
+	i := peak(values)
+	if math.Abs(frequencies[i]-100.5) > 0.5 {
+		t.Errorf("the frequency of the peak is %v (should be 100.5)", frequencies[i])
+	}
+	if math.Abs(values[i]-1) > 1e-3 {
+		t.Errorf("the amplitude of the peak is %v (should be 1)", values[i])
+	}
+	// Without window, the amplitude between two bins is underestimated
+	_, values = Spectrum(s, r, SpectrumOptions{})
+	if a := values[peak(values)]; a > 0.7 {
+		t.Errorf("the amplitude without window is %v (should be about 0.64)", a)
+	}
+	if f, v := Spectrum(nil, r, SpectrumOptions{}); f != nil || v != nil {
+		t.Errorf("the spectrum of no samples should be empty")
+	}
+}
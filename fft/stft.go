@@ -0,0 +1,141 @@
+package fft
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// La transformée de Fourier à court terme (STFT) découpe le signal en
+// trames de FrameSize échantillons, espacées de Hop échantillons (elles
+// se recouvrent si Hop < FrameSize), pondère chaque trame par la fenêtre
+// et en calcule la transformée: on obtient l'évolution du spectre dans
+// le temps (le spectrogramme). Le signal est complété par FrameSize/2
+// zéros au début, pour que la trame k soit centrée sur l'échantillon
+// k*Hop. La transformée inverse (ISTFT) additionne les trames inverses
+// pondérées par la fenêtre (overlap-add), divisées par la somme des
+// carrés des fenêtres: le signal est reconstruit exactement dès que les
+// fenêtres se recouvrent.
+
+// Default parameters of the short-time transform
+const (
+	DefaultFrameSize = 1024
+	DefaultOverlap   = 0.75
+)
+
+// STFTOptions are the parameters of the short-time Fourier transform
+type STFTOptions struct {
+	FrameSize int     // samples in a frame (DefaultFrameSize if 0)
+	Hop       int     // samples between two frames (0 to derive it from the overlap)
+	Overlap   float64 // fraction of a frame covered by the next one, if Hop is 0 (DefaultOverlap if 0)
+	Window    Window  // window of the frames (Hann if nil)
+}
+
+// normalized returns the options with the default values
+func (o STFTOptions) normalized() STFTOptions {
+	if o.FrameSize <= 0 {
+		o.FrameSize = DefaultFrameSize
+	}
+	if o.Hop <= 0 {
+		if o.Overlap <= 0 || o.Overlap >= 1 {
+			o.Overlap = DefaultOverlap
+		}
+		o.Hop = max(int(math.Round(float64(o.FrameSize)*(1-o.Overlap))), 1)
+	}
+	if o.Window == nil {
+		o.Window = Hann
+	}
+	return o
+}
+
+// STFT returns the short-time Fourier transform of the samples: a
+// matrix of frames (time) by frequencies, each frame being the one-sided
+// transform (see RFFT) of FrameSize/2+1 values. The frame k is centered
+// on the sample k*Hop.
+func STFT(samples []float64, options STFTOptions) [][]complex128 {
+	o := options.normalized()
+	if len(samples) == 0 {
+		return nil
+	}
+	offset := o.FrameSize / 2
+	count := (len(samples)+o.Hop-1)/o.Hop + 1
+	padded := ZeroPad(nil, offset+(count-1)*o.Hop+o.FrameSize)
+	copy(padded[offset:], samples)
+
+	window := o.Window(o.FrameSize)
+	frames := make([][]complex128, count)
+	frame := make([]float64, o.FrameSize)
+	for k := range frames {
+		start := k * o.Hop
+		for i, w := range window {
+			frame[i] = padded[start+i] * w
+		}
+		frames[k] = RFFT(frame)
+	}
+	return frames
+}
+
+// ISTFT returns the n samples whose short-time transform is the frames
+// (see STFT), with the same options. The frames can have been modified
+// (a filtering, a denoising): the result is then the signal whose
+// transform is the closest to the frames in the least squares sense
+// (Griffin and Lim).
+func ISTFT(frames [][]complex128, n int, options STFTOptions) []float64 {
+	o := options.normalized()
+	offset := o.FrameSize / 2
+	size := max(offset+(len(frames)-1)*o.Hop+o.FrameSize, offset+n)
+	sum := make([]float64, size)
+	weights := make([]float64, size)
+
+	window := o.Window(o.FrameSize)
+	for k, values := range frames {
+		frame := IRFFT(values, o.FrameSize)
+		start := k * o.Hop
+		for i, w := range window {
+			sum[start+i] += frame[i] * w
+			weights[start+i] += w * w
+		}
+	}
+
+	samples := make([]float64, n)
+	for i := range samples {
+		// Les échantillons que ne couvre aucune fenêtre restent nuls
+		if w := weights[offset+i]; w > 1e-10 {
+			samples[i] = sum[offset+i] / w
+		}
+	}
+	return samples
+}
+
+// Spectrogram is the evolution of the spectrum of a signal in time: the
+// values of the frequencies (in the unit of the scaling) for each frame.
+type Spectrogram struct {
+	Times       []float64   // centers of the frames (s)
+	Frequencies []float64   // from 0 to the Nyquist frequency (Hz)
+	Values      [][]float64 // values of the frequencies of each frame
+	Scaling     Scaling
+}
+
+// NewSpectrogram returns the spectrogram of the samples, taken at the
+// specified sample rate, computed with the short-time transform (see
+// STFT). The values are scaled as those of Spectrum: a sinusoid of
+// amplitude a has the value a in each frame, whatever the window.
+func NewSpectrogram(samples []float64, sampleRate int, options STFTOptions, scaling Scaling) *Spectrogram {
+	o := options.normalized()
+	frames := STFT(samples, o)
+	norm := float64(o.FrameSize) * CoherentGain(o.Window(o.FrameSize))
+
+	s := &Spectrogram{
+		Times:       make([]float64, len(frames)),
+		Frequencies: RFreq(o.FrameSize, 1/float64(sampleRate)),
+		Values:      make([][]float64, len(frames)),
+		Scaling:     scaling,
+	}
+	for k, frame := range frames {
+		s.Times[k] = float64(k*o.Hop) / float64(sampleRate)
+		s.Values[k] = make([]float64, len(frame))
+		for i, x := range frame {
+			s.Values[k][i] = scale(cmplx.Abs(x)/norm, i, o.FrameSize, false, scaling)
+		}
+	}
+	return s
+}
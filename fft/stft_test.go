@@ -0,0 +1,92 @@
+package fft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSTFT(t *testing.T) {
+	r, n := 8000, 5000
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = math.Sin(2*math.Pi*440*float64(i)/float64(r)) + 0.3*math.Sin(float64(i*i)/1e4)
+	}
+
+	// The signal is reconstructed by the inverse transform, for any
+	// overlapping frames
+	tests := []STFTOptions{
+		{},
+		{FrameSize: 512, Overlap: 0.5},
+		{FrameSize: 256, Hop: 100, Window: Hamming},
+		{FrameSize: 1000, Hop: 250, Window: Blackman},
+		{FrameSize: 300, Hop: 300, Window: Rectangular},
+	}
+	for _, options := range tests {
+		frames := STFT(s, options)
+		o := options.normalized()
+		if exp := (n+o.Hop-1)/o.Hop + 1; len(frames) != exp {
+			t.Errorf("%+v: the number of frames is %d (should be %d)", options, len(frames), exp)
+		}
+		if len(frames[0]) != o.FrameSize/2+1 {
+			t.Errorf("%+v: the size of the frames is %d (should be %d)", options, len(frames[0]), o.FrameSize/2+1)
+		}
+		back := ISTFT(frames, n, options)
+		for i := range s {
+			if math.Abs(back[i]-s[i]) > 1e-9 {
+				t.Errorf("%+v: sample %d is %v (should be %v)", options, i, back[i], s[i])
+				break
+			}
+		}
+	}
+
+	if frames := STFT(nil, STFTOptions{}); frames != nil {
+		t.Errorf("the transform of no samples should be empty")
+	}
+	o := STFTOptions{FrameSize: 100}.normalized()
+	if o.Hop != 25 || o.Window == nil {
+		t.Errorf("the default hop is %d (should be 25)", o.Hop)
+	}
+}
+
+func TestSpectrogram(t *testing.T) {
+	// A sweep from 500 to 3000 Hz in 1 s: the frequency of the peak of
+	// each frame is the frequency of the sweep at the time of the frame
+	r := 8000
+	f0, f1 := 500., 3000.
+	s := make([]float64, r)
+	for i := range s {
+		x := float64(i) / float64(r)
+		s[i] = 0.5 * math.Sin(2*math.Pi*(f0*x+(f1-f0)*x*x/2))
+	}
+	sg := NewSpectrogram(s, r, STFTOptions{FrameSize: 512, Hop: 128}, Amplitude)
+	if len(sg.Times) != len(sg.Values) || len(sg.Frequencies) != 257 {
+		t.Fatalf("the size of the spectrogram is %dx%d", len(sg.Values), len(sg.Frequencies))
+	}
+	if step := sg.Frequencies[1] - sg.Frequencies[0]; step != 15.625 {
+		t.Errorf("the frequency step is %v (should be 15.625)", step)
+	}
+	for k := 8; k < len(sg.Times)-8; k += 8 {
+		exp := f0 + (f1-f0)*sg.Times[k]
+		i := peak(sg.Values[k])
+		if math.Abs(sg.Frequencies[i]-exp) > 2*15.625 {
+			t.Errorf("the peak at %.3fs is at %v Hz (should be %v Hz)", sg.Times[k], sg.Frequencies[i], exp)
+		}
+	}
+
+	// A sinusoid has its amplitude in each frame (but the first and the
+	// last ones, that are partly out of the signal)
+	for i := range s {
+		s[i] = 0.5 * math.Sin(2*math.Pi*1000*float64(i)/float64(r))
+	}
+	sg = NewSpectrogram(s, r, STFTOptions{FrameSize: 512}, Decibels)
+	exp := 20 * math.Log10(0.5)
+	for k, time := range sg.Times {
+		if center := int(time * float64(r)); center < 256 || center+256 > len(s) {
+			continue
+		}
+		i := peak(sg.Values[k])
+		if sg.Frequencies[i] != 1000 || math.Abs(sg.Values[k][i]-exp) > 1e-6 {
+			t.Errorf("the peak at %.3fs is %v dB at %v Hz (should be %v dB at 1000 Hz)", sg.Times[k], sg.Values[k][i], sg.Frequencies[i], exp)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package fft
+
+import "math"
+
+// Window returns the n weights of a window function, that reduces the
+// spectral leakage of a transform (the spreading of a frequency on the
+// neighbouring bins, due to the discontinuity at the boundaries of the
+// signal). The windows are periodic (DFT-even): the window of size n is
+// the symmetric window of size n+1 without its last point, as needed by
+// the spectral analysis and the short-time transform.
+type Window func(n int) []float64
+
+// The windows differ by the width of their main lobe (the frequency
+// resolution) and the level of their side lobes (the leakage). The
+// flat-top window has a very wide lobe but an accurate amplitude, even
+// between two bins.
+var (
+	Rectangular Window = func(n int) []float64 { return cosineSum(n, 1) }
+	Hann        Window = func(n int) []float64 { return cosineSum(n, 0.5, 0.5) }
+	Hamming     Window = func(n int) []float64 { return cosineSum(n, 0.54, 0.46) }
+	Blackman    Window = func(n int) []float64 { return cosineSum(n, 0.42, 0.5, 0.08) }
+	FlatTop     Window = func(n int) []float64 {
+		return cosineSum(n, 0.21557895, 0.41663158, 0.277263158, 0.083578947, 0.006947368)
+	}
+)
+
+// cosineSum returns the window sum of a[j] (-1)^j cos(2πjk/n)
+func cosineSum(n int, a ...float64) []float64 {
+	w := make([]float64, n)
+	for k := range w {
+		sign := 1.
+		for j, aj := range a {
+			w[k] += sign * aj * math.Cos(2*math.Pi*float64(j*k)/float64(n))
+			sign = -sign
+		}
+	}
+	return w
+}
+
+// Kaiser returns the Kaiser window of parameter beta, that sets the
+// trade-off between the width of the main lobe and the level of the
+// side lobes (0 is the rectangular window, 8.6 is close to Blackman).
+func Kaiser(beta float64) Window {
+	return func(n int) []float64 {
+		w := make([]float64, n)
+		for k := range w {
+			x := 2*float64(k)/float64(n) - 1
+			w[k] = besselI0(beta*math.Sqrt(1-x*x)) / besselI0(beta)
+		}
+		return w
+	}
+}
+
+// besselI0 is the modified Bessel function of the first kind of order
+// 0, computed with its series
+func besselI0(x float64) float64 {
+	sum, term := 1., 1.
+	for k := 1; k < 500; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < 1e-16*sum {
+			break
+		}
+	}
+	return sum
+}
+
+// ApplyWindow returns the samples weighted by the window (a nil window is
+// the rectangular window)
+func ApplyWindow(samples []float64, window Window) []float64 {
+	out := make([]float64, len(samples))
+	copy(out, samples)
+	if window == nil {
+		return out
+	}
+	for i, w := range window(len(samples)) {
+		out[i] *= w
+	}
+	return out
+}
+
+// CoherentGain returns the mean of the weights of the window: the
+// amplitude of a sinusoid in the spectrum of the windowed signal is
+// multiplied by this gain.
+func CoherentGain(weights []float64) float64 {
+	if len(weights) == 0 {
+		return 0
+	}
+	sum := 0.
+	for _, w := range weights {
+		sum += w
+	}
+	return sum / float64(len(weights))
+}
@@ -0,0 +1,76 @@
+package fft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWindow(t *testing.T) {
+	n := 64
+	windows := map[string]Window{
+		"rectangular": Rectangular,
+		"hann":        Hann,
+		"hamming":     Hamming,
+		"blackman":    Blackman,
+		"flattop":     FlatTop,
+		"kaiser":      Kaiser(8.6),
+	}
+	// Coherent gains of the windows (the mean of the weights)
+	gains := map[string]float64{
+		"rectangular": 1,
+		"hann":        0.5,
+		"hamming":     0.54,
+		"blackman":    0.42,
+		"flattop":     0.21557895,
+	}
+	for name, window := range windows {
+		w := window(n)
+		if len(w) != n {
+			t.Errorf("the size of the %s window is %d (should be %d)", name, len(w), n)
+			continue
+		}
+		// The windows are periodic: symmetric around n/2, and maximal
+		// at the center
+		for k := 1; k < n; k++ {
+			if math.Abs(w[k]-w[n-k]) > 1e-12 {
+				t.Errorf("the %s window is not symmetric at %d (%v, %v)", name, k, w[k], w[n-k])
+				break
+			}
+		}
+		if math.Abs(w[n/2]-1) > 1e-6 {
+			t.Errorf("the center of the %s window is %v (should be 1)", name, w[n/2])
+		}
+		if g, ok := gains[name]; ok && math.Abs(CoherentGain(w)-g) > 1e-9 {
+			t.Errorf("the gain of the %s window is %v (should be %v)", name, CoherentGain(w), g)
+		}
+	}
+
+	// Kaiser with beta 0 is the rectangular window
+	for k, v := range Kaiser(0)(n) {
+		if math.Abs(v-1) > 1e-12 {
+			t.Errorf("weight %d of the Kaiser window is %v (should be 1)", k, v)
+		}
+	}
+	if v := besselI0(1); math.Abs(v-1.2660658777520082) > 1e-12 {
+		t.Errorf("I0(1) is %v (should be 1.2660658777520082)", v)
+	}
+}
+
+func TestApplyWindow(t *testing.T) {
+	samples := []float64{1, 1, 1, 1}
+	windowed := ApplyWindow(samples, Hann)
+	exp := []float64{0, 0.5, 1, 0.5}
+	for i, v := range exp {
+		if math.Abs(windowed[i]-v) > 1e-12 {
+			t.Errorf("sample %d is %v (should be %v)", i, windowed[i], v)
+		}
+	}
+	if samples[0] != 1 {
+		t.Errorf("the samples should not be modified")
+	}
+	for i, v := range ApplyWindow(samples, nil) {
+		if v != samples[i] {
+			t.Errorf("sample %d is %v (should be %v)", i, v, samples[i])
+		}
+	}
+}
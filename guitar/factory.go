@@ -1,6 +1,12 @@
 package guitar
 
-import "github.com/gboulant/musicall"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gboulant/musicall"
+	"github.com/gboulant/musicall/music"
+)
 
 // ----------------------------------------------------------------------
 // Définition des accord principaux
@@ -9,6 +15,24 @@ import "github.com/gboulant/musicall"
 // plucked in the order defined by the list.
 type Chord []Note
 
+// MusicNotes returns the music notes played by this chord, in the order
+// of the plucks.
+func (c Chord) MusicNotes() []music.Note {
+	notes := make([]music.Note, len(c))
+	for i, n := range c {
+		notes[i] = n.MusicNote()
+	}
+	return notes
+}
+
+// Identify returns the chord of the music theory (see music.Chord) that
+// corresponds to the notes of this guitar chord, or false if the notes
+// don't make a known chord. For example the standard chord "Fa" of
+// this package, whose bass is the La1 string, is identified as "Fa/La".
+func (c Chord) Identify() (music.Chord, bool) {
+	return music.IdentifyChord(c.MusicNotes())
+}
+
 // Reverse can be used to create a chord as the reverse of the input
 // chord. For example, if you define a chord as the standard chord,
 // plucking the string from the top to the bottom (down chord), then the
@@ -23,8 +47,13 @@ func Reverse(notes Chord) Chord {
 	return r
 }
 
-// StandardChords is a table of the most standard chords, identified
-// with the french naming convention (Do, Re, Mi, etc.).
+// ErrUnknownChord is the error returned (wrapped) when a chord name is
+// not defined.
+var ErrUnknownChord = errors.New("unknown chord")
+
+// LookupChord returns a chord from the table of the most standard
+// chords, identified with the french naming convention (Do, Re, Mi,
+// etc.). The error wraps ErrUnknownChord if the name is not defined.
 //
 // The first index of a note is the string number to pluck and the
 // second is the fret number to press: Note{string number to pluck, fret
@@ -39,10 +68,35 @@ func Reverse(notes Chord) Chord {
 // "don't press any fret (play the open string)". If a string number
 // does not appear in a chord list, it means that the string must not be
 // plucked.
-func StandardChord(name string) Chord {
+func LookupChord(name string) (Chord, error) {
 	chord, ok := standardChords[name]
 	if !ok {
-		musicall.LogError("err: (StandardChord) no chord with name %s\n", name)
+		return nil, fmt.Errorf("%w: no chord with name %q", ErrUnknownChord, name)
+	}
+	return chord, nil
+}
+
+// MustLookupChord is like LookupChord but panics if the chord is not
+// defined. It simplifies the programs where the names are constants.
+func MustLookupChord(name string) Chord {
+	chord, err := LookupChord(name)
+	if err != nil {
+		panic(err)
+	}
+	return chord
+}
+
+// StandardChord returns the standard chord of the specified name. As
+// before the function LookupChord, the error is reported with
+// musicall.LogError (that exits the process by default), and a nil
+// chord is returned if LogError returns.
+//
+// Deprecated: use LookupChord, or MustLookupChord for the constant
+// names.
+func StandardChord(name string) Chord {
+	chord, err := LookupChord(name)
+	if err != nil {
+		musicall.LogError("err: (StandardChord) %v\n", err)
 	}
 	return chord
 }
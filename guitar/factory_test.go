@@ -0,0 +1,86 @@
+package guitar
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gboulant/musicall"
+	"github.com/gboulant/musicall/music"
+)
+
+// TestStandardChord_Theory checks that the notes of the standard chords
+// are the notes defined by the music theory for the chord of same name.
+func TestStandardChord_Theory(t *testing.T) {
+	for name, chord := range standardChords {
+		t.Run(name, func(t *testing.T) {
+			theory, err := music.ParseChord(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			identified, ok := chord.Identify()
+			if !ok {
+				t.Fatalf("the chord %s is not identified", name)
+			}
+			if !slices.Equal(identified.PitchClasses(), theory.PitchClasses()) {
+				t.Errorf("the chord %s plays the notes of %s", name, identified.Name())
+			}
+			if identified.Root != theory.Root {
+				t.Errorf("the root of the chord %s is %s", name, identified.Name())
+			}
+		})
+	}
+}
+
+func TestChord_Identify(t *testing.T) {
+	tests := []struct {
+		chord Chord
+		want  string
+	}{
+		{StandardChord("Do"), "Do"},
+		{StandardChord("Fa"), "Fa/La"},
+		{StandardChord("Mim"), "Mim"},
+		{PowerChord(Mi1, 5), "La5"},
+		{Chord{{La1, 0}, {Re2, 2}, {Sol2, 0}, {Si2, 1}, {Mi3, 0}}, "Lam7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			c, ok := tt.chord.Identify()
+			if !ok {
+				t.Fatalf("the chord is not identified (should be %s)", tt.want)
+			}
+			if c.Name() != tt.want {
+				t.Errorf("the chord is %s (should be %s)", c.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupChord(t *testing.T) {
+	c, err := LookupChord("Lam")
+	if err != nil || len(c) != 5 {
+		t.Errorf("chord Lam is %v, %v (should have 5 notes)", c, err)
+	}
+	if _, err := LookupChord("Lam7b5"); !errors.Is(err, ErrUnknownChord) {
+		t.Errorf("error is %v (should be ErrUnknownChord)", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustLookupChord should panic on an unknown chord")
+		}
+	}()
+	MustLookupChord("Lam7b5")
+}
+
+func TestStandardChord_LogError(t *testing.T) {
+	// The deprecated StandardChord reports the errors with LogError
+	defer func(f func(string, ...any)) { musicall.LogError = f }(musicall.LogError)
+	logged := 0
+	musicall.LogError = func(format string, v ...any) { logged++ }
+	if c := StandardChord("Lam"); len(c) != 5 || logged != 0 {
+		t.Errorf("chord Lam is %v with %d errors (should have 5 notes without error)", c, logged)
+	}
+	if c := StandardChord("Lam7b5"); c != nil || logged != 1 {
+		t.Errorf("chord Lam7b5 is %v with %d errors (should be nil with 1 error)", c, logged)
+	}
+}
@@ -0,0 +1,82 @@
+package guitar
+
+import (
+	"github.com/gboulant/musicall/music"
+)
+
+// MaxFret is the highest fret considered for playing a note
+const MaxFret FretNumber = 19
+
+// Positions returns the positions (string and fret) where the music
+// note can be played on the guitar, from the bass string to the high
+// string. A note out of the range of the guitar has no position.
+func Positions(n music.Note) []Note {
+	key := n.MIDINumber()
+	positions := make([]Note, 0)
+	for s := Mi1; s >= Mi3; s-- {
+		open, err := OpenStringNote(s)
+		if err != nil {
+			continue
+		}
+		fret := FretNumber(key - open.MIDINumber())
+		if fret >= 0 && fret <= MaxFret {
+			positions = append(positions, Note{StringNum: s, FretNum: fret})
+		}
+	}
+	return positions
+}
+
+// StringMapper maps the notes of a melody (or of a polyphonic part, as
+// read from a MIDI file) onto the guitar strings. A string plays only
+// one note at a time: a note is played on a string that is free at its
+// start time, with the lowest fret. If all the possible strings are
+// busy, the string that gets free first is used (its note is cut, as
+// on a real guitar).
+type StringMapper struct {
+	busy map[StringNumber]float64 // end time of the note of each string
+}
+
+// NewStringMapper returns a mapper whose strings are all free
+func NewStringMapper() *StringMapper {
+	return &StringMapper{busy: make(map[StringNumber]float64)}
+}
+
+// Map returns the guitar note playing the music note from the time
+// start to the time end (in any unit, e.g. seconds). The notes must be
+// mapped in the order of their start time. A note out of the range of
+// the guitar is transposed by octaves into this range.
+func (m *StringMapper) Map(n music.Note, start, end float64) Note {
+	// Transposition par octaves dans la tessiture de la guitare
+	low := MustOpenStringNote(Mi1).MIDINumber()
+	high := MustOpenStringNote(Mi3).MIDINumber() + int(MaxFret)
+	key := n.MIDINumber()
+	for key < low {
+		key += int(music.Octave)
+	}
+	for key > high {
+		key -= int(music.Octave)
+	}
+
+	var best Note
+	bestFree := false
+	better := func(p Note, free bool) bool {
+		if best.StringNum == 0 {
+			return true
+		}
+		if free != bestFree {
+			return free
+		}
+		if !free && m.busy[p.StringNum] != m.busy[best.StringNum] {
+			return m.busy[p.StringNum] < m.busy[best.StringNum]
+		}
+		return p.FretNum < best.FretNum
+	}
+	for _, p := range Positions(music.NoteFromMIDINumber(key)) {
+		free := m.busy[p.StringNum] <= start
+		if better(p, free) {
+			best, bestFree = p, free
+		}
+	}
+	m.busy[best.StringNum] = end
+	return best
+}
@@ -0,0 +1,61 @@
+package guitar
+
+import (
+	"testing"
+
+	"github.com/gboulant/musicall/music"
+)
+
+func TestPositions(t *testing.T) {
+	la2 := music.Note{Octave: 2, Index: music.Label2Index("La")}
+	exp := []Note{{Mi1, 17}, {La1, 12}, {Re2, 7}, {Sol2, 2}}
+	positions := Positions(la2)
+	if len(positions) != len(exp) {
+		t.Fatalf("positions are %v (should be %v)", positions, exp)
+	}
+	for i, p := range positions {
+		if p != exp[i] {
+			t.Errorf("position %d is %v (should be %v)", i, p, exp[i])
+		}
+		if p.MusicNote() != la2 {
+			t.Errorf("position %v plays %s (should be La2)", p, p.Name())
+		}
+	}
+
+	if p := Positions(music.Note{Octave: 0, Index: 0}); len(p) != 0 {
+		t.Errorf("Do0 should have no position (%v)", p)
+	}
+}
+
+func TestStringMapper(t *testing.T) {
+	m := NewStringMapper()
+	mi2 := music.Note{Octave: 2, Index: music.Label2Index("Mi")}
+
+	// The lowest fret on a free string
+	if n := m.Map(mi2, 0, 1); n != (Note{Re2, 2}) {
+		t.Errorf("first Mi2 is %v (should be {4 2})", n)
+	}
+	// The string Re2 is busy, the next lowest fret is on La1
+	if n := m.Map(mi2, 0.5, 1); n != (Note{La1, 7}) {
+		t.Errorf("second Mi2 is %v (should be {5 7})", n)
+	}
+	if n := m.Map(mi2, 0.5, 2); n != (Note{Mi1, 12}) {
+		t.Errorf("third Mi2 is %v (should be {6 12})", n)
+	}
+	// All the strings are busy: the first free one is cut
+	if n := m.Map(mi2, 0.6, 2); n != (Note{Re2, 2}) {
+		t.Errorf("fourth Mi2 is %v (should be {4 2})", n)
+	}
+	// The strings are free again
+	if n := m.Map(mi2, 3, 4); n != (Note{Re2, 2}) {
+		t.Errorf("fifth Mi2 is %v (should be {4 2})", n)
+	}
+
+	// Out of range notes are transposed by octaves
+	if n := m.Map(music.Note{Octave: 0, Index: music.Label2Index("Mi")}, 5, 6); n != (Note{Mi1, 0}) {
+		t.Errorf("Mi0 is %v (should be {6 0})", n)
+	}
+	if n := m.Map(music.Note{Octave: 7, Index: music.Label2Index("Do")}, 5, 6); n.MusicNote() != (music.Note{Octave: 4, Index: 0}) {
+		t.Errorf("Do7 is %v (should be played as Do4)", n.Name())
+	}
+}
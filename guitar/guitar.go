@@ -1,6 +1,10 @@
 package guitar
 
 import (
+	"math"
+
+	"github.com/gboulant/musicall"
+	"github.com/gboulant/musicall/music"
 	"github.com/gboulant/musicall/sound"
 	"github.com/gboulant/musicall/wave"
 	"github.com/gopxl/beep"
@@ -8,11 +12,13 @@ import (
 
 type Guitar struct {
 	synthesizer wave.HarmonicSynthesizer
+	tuning      music.Tuning
+	spread      float64
 }
 
 func NewGuitar(sampleRate int) *Guitar {
 	f := 0. // no specific frequency at initialize step
-	a := 1. // DO NOT set a>1, it will be truncated by the speaker
+	a := 1. // DO NOT set a>1, it will be truncated by the speaker (or use a sound.Mixer)
 	r := wave.SampleRate(sampleRate)
 	s := wave.NewKarplusStrongSynthesizer(f, a, r)
 	g := Guitar{synthesizer: s}
@@ -23,11 +29,59 @@ func (g *Guitar) UseSynthesizer(s wave.HarmonicSynthesizer) {
 	g.synthesizer = s
 }
 
+// UseTuning specifies the tuning system used to compute the frequencies
+// of the notes played by this guitar. By default (or if t is nil), the
+// guitar uses the current tuning of the music package (see
+// music.SetTuning).
+func (g *Guitar) UseTuning(t music.Tuning) {
+	g.tuning = t
+}
+
+// SpreadStrings spreads the strings across the stereo field, as heard
+// by the guitarist: the bass string Mi1 on the left at the position
+// -spread, and the high string Mi3 on the right at the position +spread
+// (see sound.PanGains). The spread is between 0 and 1. By default (or
+// if spread is 0), the guitar is mono and all the strings are played
+// identically on the two channels.
+func (g *Guitar) SpreadStrings(spread float64) {
+	g.spread = math.Max(0, math.Min(1, spread))
+}
+
+// StringPan returns the position of the string in the stereo field,
+// from -1 (left) to +1 (right), according to the spread of the strings
+// (see SpreadStrings).
+func (g Guitar) StringPan(s StringNumber) float64 {
+	// Les cordes sont réparties régulièrement de Mi1 (6) à Mi3 (1)
+	return g.spread * float64(Mi1+Mi3-2*s) / float64(Mi1-Mi3)
+}
+
+// Pluck plucks the string of the note at its fret. If the string
+// number is not defined, the error is reported with musicall.LogError
+// (see Note.Check), and the string is silent if LogError returns.
 func (g Guitar) Pluck(note Note, duration float64) beep.Streamer {
-	frequency := note.Frequency()
+	n, err := note.ToMusicNote()
+	if err != nil {
+		musicall.LogError("err: (Pluck) %v\n", err)
+		return g.Silence(duration)
+	}
+	frequency := n.Frequency()
+	if g.tuning != nil {
+		frequency = n.FrequencyIn(g.tuning)
+	}
 	g.synthesizer.SetFrequency(frequency)
 	samples := g.synthesizer.Synthesize(duration)
-	return sound.NewSound(samples)
+	if g.spread == 0 {
+		return sound.NewSound(samples)
+	}
+	return sound.NewStereoSound(sound.Pan(samples, g.StringPan(note.StringNum)))
+}
+
+// PluckFrequency plucks a string at the specified frequency, whatever
+// the string and the fret that could play it. The sound is at the center
+// of the stereo field.
+func (g Guitar) PluckFrequency(frequency float64, duration float64) beep.Streamer {
+	g.synthesizer.SetFrequency(frequency)
+	return sound.NewSound(g.synthesizer.Synthesize(duration))
 }
 
 func (g Guitar) Chord(notes []Note, duration float64, delay float64) beep.Streamer {
@@ -1,6 +1,7 @@
 package guitar
 
 import (
+	"math"
 	"testing"
 
 	"github.com/gboulant/musicall/sound"
@@ -11,6 +12,9 @@ import (
 const sampleRate = wave.DefaultSampleRate
 
 func init() {
+	// Les sons des tests sont rendus en mémoire, sans carte son (utiliser
+	// MUSICALL_AUDIO=speaker pour les écouter)
+	sound.SetBackend(sound.NewNullBackend())
 	sound.Init(sampleRate)
 }
 
@@ -89,3 +93,41 @@ func TestGuitar_Reverse(t *testing.T) {
 	}
 
 }
+
+func TestGuitar_SpreadStrings(t *testing.T) {
+	g := NewGuitar(sampleRate)
+	if p := g.StringPan(Mi1); p != 0 {
+		t.Errorf("pan of Mi1 is %v (should be 0)", p)
+	}
+
+	g.SpreadStrings(0.8)
+	tests := []struct {
+		s   StringNumber
+		pan float64
+	}{
+		{Mi1, -0.8},
+		{La1, -0.48},
+		{Sol2, 0.16},
+		{Mi3, 0.8},
+	}
+	for _, tt := range tests {
+		if p := g.StringPan(tt.s); math.Abs(p-tt.pan) > 1e-12 {
+			t.Errorf("pan of %d is %v (should be %v)", tt.s, p, tt.pan)
+		}
+	}
+
+	// The bass string is louder on the left channel
+	rec, err := sound.RenderDuration(g.Pluck(Note{Mi1, 0}, 0.2), sampleRate, 0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left, right float64
+	for i := range rec.Frames() {
+		left += rec.Channels[0][i] * rec.Channels[0][i]
+		right += rec.Channels[1][i] * rec.Channels[1][i]
+	}
+	gl, gr := sound.PanGains(-0.8)
+	if math.Abs(right/left-(gr*gr)/(gl*gl)) > 1e-9 {
+		t.Errorf("power ratio is %v (should be %v)", right/left, (gr*gr)/(gl*gl))
+	}
+}
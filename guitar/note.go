@@ -1,6 +1,9 @@
 package guitar
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/gboulant/musicall"
 	"github.com/gboulant/musicall/music"
 )
@@ -27,23 +30,39 @@ const (
 // 2^5/12 ~ 1.334, soit environ 4/3, et 2⁴/12 ~ 1.259, soit environ
 // 5/4.
 
-// noteOfOpenString returns the music.Note played when we pluck the
-// specified open string (without pressing any fret).
-func noteOfOpenString(stringNum StringNumber) music.Note {
+// ErrUnknownString is the error returned (wrapped) when a string number
+// is not defined (the strings are numbered from 1 to 6).
+var ErrUnknownString = errors.New("unknown string number")
+
+// OpenStringNote returns the music.Note played when we pluck the
+// specified open string (without pressing any fret), in the standard
+// tuning. The error wraps ErrUnknownString if the string number is not
+// defined.
+func OpenStringNote(stringNum StringNumber) (music.Note, error) {
 	note, ok := openStringNotes[stringNum]
 	if !ok {
-		musicall.LogError("err: (noteOfOpenString) the string number %d is not defined\n", stringNum)
+		return music.Note{}, fmt.Errorf("%w: the string number %d is not defined", ErrUnknownString, stringNum)
+	}
+	return note, nil
+}
+
+// MustOpenStringNote is like OpenStringNote but panics if the string
+// number is not defined.
+func MustOpenStringNote(stringNum StringNumber) music.Note {
+	note, err := OpenStringNote(stringNum)
+	if err != nil {
+		panic(err)
 	}
 	return note
 }
 
 var openStringNotes map[StringNumber]music.Note = map[StringNumber]music.Note{
-	Mi3:  {Octave: 3, Index: music.Label2Index("Mi")},
-	Si2:  {Octave: 2, Index: music.Label2Index("Si")},
-	Sol2: {Octave: 2, Index: music.Label2Index("Sol")},
-	Re2:  {Octave: 2, Index: music.Label2Index("Re")},
-	La1:  {Octave: 1, Index: music.Label2Index("La")},
-	Mi1:  {Octave: 1, Index: music.Label2Index("Mi")},
+	Mi3:  {Octave: 3, Index: music.MustLookupIndex("Mi")},
+	Si2:  {Octave: 2, Index: music.MustLookupIndex("Si")},
+	Sol2: {Octave: 2, Index: music.MustLookupIndex("Sol")},
+	Re2:  {Octave: 2, Index: music.MustLookupIndex("Re")},
+	La1:  {Octave: 1, Index: music.MustLookupIndex("La")},
+	Mi1:  {Octave: 1, Index: music.MustLookupIndex("Mi")},
 }
 
 // Note defines the musical note from a guitar point of view, i.e. by
@@ -59,13 +78,55 @@ type Note struct {
 	FretNum   FretNumber
 }
 
-// MusicNote returns the music note corresponding to this guitar note. A music
-// note is defined in terms of an octave number and an index in this octave.
-func (n Note) MusicNote() music.Note {
+// Check returns an error if the note can not be played: its string
+// number is not defined (the error wraps ErrUnknownString) or its fret
+// number is negative. The notes read from an input (a file, a user
+// interface) should be checked before being played.
+func (n Note) Check() error {
+	if _, err := OpenStringNote(n.StringNum); err != nil {
+		return err
+	}
+	if n.FretNum < 0 {
+		return fmt.Errorf("the fret number %d is negative", n.FretNum)
+	}
+	return nil
+}
+
+// ToMusicNote returns the music note corresponding to this guitar note.
+// A music note is defined in terms of an octave number and an index in
+// this octave. The error wraps ErrUnknownString if the string number
+// is not defined.
+func (n Note) ToMusicNote() (music.Note, error) {
 	// 1. On récupère la note de la corde à vide
-	note := noteOfOpenString(n.StringNum)
+	note, err := OpenStringNote(n.StringNum)
+	if err != nil {
+		return music.Note{}, err
+	}
 	// 2. On ajoute l'intervalle de la frette (nb de demi-tons)
 	note.Add(music.Interval(n.FretNum))
+	return note, nil
+}
+
+// MustMusicNote is like ToMusicNote but panics if the string number is
+// not defined. It simplifies the programs where the notes are
+// constants.
+func (n Note) MustMusicNote() music.Note {
+	note, err := n.ToMusicNote()
+	if err != nil {
+		panic(err)
+	}
+	return note
+}
+
+// MusicNote returns the music note corresponding to this guitar note,
+// as ToMusicNote. If the string number is not defined, the error is
+// reported with musicall.LogError (that exits the process by default),
+// and the zero note is returned if LogError returns.
+func (n Note) MusicNote() music.Note {
+	note, err := n.ToMusicNote()
+	if err != nil {
+		musicall.LogError("err: (MusicNote) %v\n", err)
+	}
 	return note
 }
 
@@ -76,6 +137,12 @@ func (n Note) Frequency() float64 {
 	return n.MusicNote().Frequency()
 }
 
+// FrequencyIn returns the frequency of this note in the specified
+// tuning system (see music.Tuning).
+func (n Note) FrequencyIn(t music.Tuning) float64 {
+	return n.MusicNote().FrequencyIn(t)
+}
+
 // Name return the name of this note (Do, Ré, Mi, etc.).
 func (n Note) Name() string {
 	return n.MusicNote().Name()
@@ -1,8 +1,10 @@
 package guitar
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/gboulant/musicall"
 	"github.com/gboulant/musicall/music"
 )
 
@@ -35,3 +37,70 @@ func TestNote_Frequency(t *testing.T) {
 		})
 	}
 }
+
+func TestNote_FrequencyIn(t *testing.T) {
+	defer music.SetTuning(music.CurrentTuning())
+
+	n := Note{StringNum: La1, FretNum: 0}
+	tuning := music.NewEqualTemperament(432)
+	want := 432. / 4.
+	if got := n.FrequencyIn(tuning); got != want {
+		t.Errorf("Note.FrequencyIn() = %v, want %v", got, want)
+	}
+
+	// The tuning of the music package is used by Note.Frequency
+	music.SetTuning(tuning)
+	if got := n.Frequency(); got != want {
+		t.Errorf("Note.Frequency() = %v, want %v", got, want)
+	}
+}
+
+func TestOpenStringNote(t *testing.T) {
+	n, err := OpenStringNote(La1)
+	if err != nil || n.Name() != "La1" {
+		t.Errorf("open string La1 is %v, %v (should be La1)", n.Name(), err)
+	}
+	if _, err := OpenStringNote(7); !errors.Is(err, ErrUnknownString) {
+		t.Errorf("error is %v (should be ErrUnknownString)", err)
+	}
+}
+
+func TestNote_Check(t *testing.T) {
+	if err := (Note{StringNum: Mi1, FretNum: 3}).Check(); err != nil {
+		t.Errorf("the note should be valid: %v", err)
+	}
+	if err := (Note{StringNum: 0, FretNum: 3}).Check(); !errors.Is(err, ErrUnknownString) {
+		t.Errorf("error is %v (should be ErrUnknownString)", err)
+	}
+	if err := (Note{StringNum: Mi1, FretNum: -1}).Check(); err == nil {
+		t.Errorf("a negative fret should be an error")
+	}
+}
+
+func TestNote_ToMusicNote(t *testing.T) {
+	if n, err := (Note{StringNum: La1, FretNum: 3}).ToMusicNote(); err != nil || n.Name() != "Do2" {
+		t.Errorf("note is %v, %v (should be Do2)", n.Name(), err)
+	}
+	if _, err := (Note{StringNum: 9}).ToMusicNote(); !errors.Is(err, ErrUnknownString) {
+		t.Errorf("error is %v (should be ErrUnknownString)", err)
+	}
+
+	// MusicNote and Pluck report the error with LogError
+	defer func(f func(string, ...any)) { musicall.LogError = f }(musicall.LogError)
+	logged := 0
+	musicall.LogError = func(format string, v ...any) { logged++ }
+	if n := (Note{StringNum: 9}).MusicNote(); n != (music.Note{}) || logged != 1 {
+		t.Errorf("note is %v with %d errors (should be the zero note with 1 error)", n, logged)
+	}
+	g := NewGuitar(sampleRate)
+	if s := g.Pluck(Note{StringNum: 0}, 0.1); s == nil || logged != 2 {
+		t.Errorf("pluck of an unknown string reports %d errors (should be 2)", logged)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustMusicNote should panic on an unknown string")
+		}
+	}()
+	Note{StringNum: 9}.MustMusicNote()
+}
@@ -0,0 +1,97 @@
+package guitar
+
+import (
+	"math"
+	"strings"
+
+	"github.com/gboulant/musicall/music"
+)
+
+// StringTuning defines the notes of the six open strings of the guitar
+// (l'accordage). The standard tuning is Mi1 La1 Re2 Sol2 Si2 Mi3, but
+// many alternate tunings are used, for example to play in open chords
+// (the open strings make a chord) or to extend the range in the bass.
+type StringTuning struct {
+	Name  string
+	Notes map[StringNumber]music.Note
+}
+
+// newStringTuning creates a tuning from the notes of the open strings,
+// given from the string 6 (the bass string) to the string 1, as usual
+// when naming a tuning (e.g. DADGAD).
+func newStringTuning(name string, notes ...music.Note) StringTuning {
+	t := StringTuning{Name: name, Notes: make(map[StringNumber]music.Note)}
+	for i, note := range notes {
+		t.Notes[Mi1-StringNumber(i)] = note
+	}
+	return t
+}
+
+func openNote(label string, octave int) music.Note {
+	return music.Note{Octave: octave, Index: music.MustLookupIndex(label)}
+}
+
+var (
+	StandardTuning     = StringTuning{Name: "standard", Notes: openStringNotes}
+	DropDTuning        = newStringTuning("dropd", openNote("Re", 1), openNote("La", 1), openNote("Re", 2), openNote("Sol", 2), openNote("Si", 2), openNote("Mi", 3))
+	HalfStepDownTuning = newStringTuning("halfstepdown", openNote("Re#", 1), openNote("Sol#", 1), openNote("Do#", 2), openNote("Fa#", 2), openNote("La#", 2), openNote("Re#", 3))
+	DADGADTuning       = newStringTuning("dadgad", openNote("Re", 1), openNote("La", 1), openNote("Re", 2), openNote("Sol", 2), openNote("La", 2), openNote("Re", 3))
+	OpenGTuning        = newStringTuning("openg", openNote("Re", 1), openNote("Sol", 1), openNote("Re", 2), openNote("Sol", 2), openNote("Si", 2), openNote("Re", 3))
+	OpenDTuning        = newStringTuning("opend", openNote("Re", 1), openNote("La", 1), openNote("Re", 2), openNote("Fa#", 2), openNote("La", 2), openNote("Re", 3))
+	OpenETuning        = newStringTuning("opene", openNote("Mi", 1), openNote("Si", 1), openNote("Mi", 2), openNote("Sol#", 2), openNote("Si", 2), openNote("Mi", 3))
+)
+
+// StringTunings is the list of the predefined tunings
+var StringTunings []StringTuning = []StringTuning{
+	StandardTuning,
+	DropDTuning,
+	HalfStepDownTuning,
+	DADGADTuning,
+	OpenGTuning,
+	OpenDTuning,
+	OpenETuning,
+}
+
+// LookupStringTuning returns the predefined tuning of the specified
+// name (the case is ignored), or false if the name is not defined.
+func LookupStringTuning(name string) (StringTuning, bool) {
+	for _, t := range StringTunings {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return StringTuning{}, false
+}
+
+// OpenStringNote returns the note of the specified open string in this
+// tuning, or false if the string number is not defined.
+func (t StringTuning) OpenStringNote(stringNum StringNumber) (music.Note, bool) {
+	note, ok := t.Notes[stringNum]
+	return note, ok
+}
+
+// String returns the names of the open strings, from the string 6 to the
+// string 1, e.g. "Mi1 La1 Ré2 Sol2 Si2 Mi3" for the standard tuning.
+func (t StringTuning) String() string {
+	names := make([]string, 0, len(t.Notes))
+	for s := Mi1; s >= Mi3; s-- {
+		if note, ok := t.Notes[s]; ok {
+			names = append(names, note.Name())
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// NearestString returns the open string whose note is the nearest from
+// the specified frequency, and the deviation in cents of the frequency
+// from this note (positive if the string is too high, negative if it
+// is too low). This is the function of a tuner.
+func (t StringTuning) NearestString(frequency float64) (stringNum StringNumber, cents float64) {
+	for s, note := range t.Notes {
+		c := music.RatioToCents(frequency / note.Frequency())
+		if stringNum == 0 || math.Abs(c) < math.Abs(cents) {
+			stringNum, cents = s, c
+		}
+	}
+	return stringNum, cents
+}
@@ -0,0 +1,66 @@
+package guitar
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/music"
+)
+
+func TestStringTuning_String(t *testing.T) {
+	tests := []struct {
+		tuning StringTuning
+		want   string
+	}{
+		{StandardTuning, "Mi1 La1 Ré2 Sol2 Si2 Mi3"},
+		{DropDTuning, "Ré1 La1 Ré2 Sol2 Si2 Mi3"},
+		{DADGADTuning, "Ré1 La1 Ré2 Sol2 La2 Ré3"},
+		{HalfStepDownTuning, "Ré#1 Sol#1 Do#2 Fa#2 La#2 Ré#3"},
+	}
+	for _, tt := range tests {
+		if got := tt.tuning.String(); got != tt.want {
+			t.Errorf("tuning %s is %q (should be %q)", tt.tuning.Name, got, tt.want)
+		}
+	}
+}
+
+func TestLookupStringTuning(t *testing.T) {
+	tuning, ok := LookupStringTuning("DADGAD")
+	if !ok {
+		t.Fatalf("the tuning DADGAD should be defined")
+	}
+	note, ok := tuning.OpenStringNote(Si2)
+	if !ok || note.Name() != "La2" {
+		t.Errorf("string 2 in DADGAD is %s (should be La2)", note.Name())
+	}
+	if _, ok := LookupStringTuning("unknown"); ok {
+		t.Errorf("the tuning unknown should not be defined")
+	}
+	if _, ok := StandardTuning.OpenStringNote(7); ok {
+		t.Errorf("the string 7 should not be defined")
+	}
+}
+
+func TestStringTuning_NearestString(t *testing.T) {
+	tests := []struct {
+		tuning    StringTuning
+		frequency float64
+		stringNum StringNumber
+		cents     float64
+	}{
+		{StandardTuning, Note{StringNum: La1, FretNum: 0}.Frequency(), La1, 0.},
+		{StandardTuning, 440. * music.CentsToRatio(-2400.+20.), La1, 20.},
+		{StandardTuning, Note{StringNum: Mi1, FretNum: 0}.Frequency() * music.CentsToRatio(-30.), Mi1, -30.},
+		{DropDTuning, Note{StringNum: Mi1, FretNum: 0}.Frequency() * music.CentsToRatio(-210.), Mi1, -10.},
+		{StandardTuning, Note{StringNum: Si2, FretNum: 0}.Frequency() * music.CentsToRatio(5.), Si2, 5.},
+	}
+	for _, tt := range tests {
+		s, cents := tt.tuning.NearestString(tt.frequency)
+		if s != tt.stringNum {
+			t.Errorf("string for %.2f Hz is %d (should be %d)", tt.frequency, s, tt.stringNum)
+		}
+		if math.Abs(cents-tt.cents) > 1e-6 {
+			t.Errorf("cents for %.2f Hz is %.3f (should be %.3f)", tt.frequency, cents, tt.cents)
+		}
+	}
+}
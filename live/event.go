@@ -0,0 +1,129 @@
+package live
+
+// This package plays the notes received while the sound is streamed,
+// as on a keyboard: the note on and note off events (as the messages of
+// a MIDI keyboard) are sent on a Go channel, and played by a pool of
+// voices (see VoicePool). The events can come from a virtual port (see
+// VirtualPort), fed by a program or by the user, or from a MIDI file
+// (see FileEvents), so that the pool can be used without any hardware.
+
+import (
+	"sync"
+
+	"github.com/gboulant/musicall/midi"
+	"github.com/gboulant/musicall/music"
+)
+
+// Event is a note on or note off event. Kind is midi.NoteOn or
+// midi.NoteOff, Key is the MIDI key number (60 is the Do3) and Velocity
+// is the intensity of the note, from 1 to 127 (a note on with a
+// velocity of 0 is a note off, as in the MIDI messages).
+//
+// Time is the time of the event in seconds, from the start of the
+// stream of the voice pool. An event whose time is passed (e.g. 0) is
+// played as soon as it is received.
+type Event struct {
+	Kind     byte
+	Channel  int
+	Key      int
+	Velocity int
+	Time     float64
+}
+
+// IsNoteOn returns true if the event starts a note
+func (e Event) IsNoteOn() bool {
+	return e.Kind == midi.NoteOn && e.Velocity > 0
+}
+
+// NoteOn returns an event that starts the note now
+func NoteOn(key int, velocity int) Event {
+	return Event{Kind: midi.NoteOn, Key: key, Velocity: velocity}
+}
+
+// NoteOff returns an event that stops the note now
+func NoteOff(key int) Event {
+	return Event{Kind: midi.NoteOff, Key: key}
+}
+
+// -------------------------------------------------------------
+// VirtualPort is a local source of events, that a program (e.g. a user
+// interface, or a test) uses as a MIDI keyboard. The events are sent on
+// the channel returned by Events, to be played by a voice pool.
+type VirtualPort struct {
+	events chan Event
+	once   sync.Once
+}
+
+// NewVirtualPort returns a port whose channel can hold the specified
+// number of events not yet read by the voice pool. The sending functions
+// block when the channel is full.
+func NewVirtualPort(buffer int) *VirtualPort {
+	return &VirtualPort{events: make(chan Event, max(buffer, 0))}
+}
+
+// Events returns the channel of the events sent to the port
+func (p *VirtualPort) Events() <-chan Event {
+	return p.events
+}
+
+// Send sends an event
+func (p *VirtualPort) Send(e Event) {
+	p.events <- e
+}
+
+// NoteOn starts the note of the specified MIDI key
+func (p *VirtualPort) NoteOn(key int, velocity int) {
+	p.Send(NoteOn(key, velocity))
+}
+
+// NoteOff stops the note of the specified MIDI key
+func (p *VirtualPort) NoteOff(key int) {
+	p.Send(NoteOff(key))
+}
+
+// Play starts the music note (see NoteOn)
+func (p *VirtualPort) Play(n music.Note, velocity int) {
+	p.NoteOn(n.MIDINumber(), velocity)
+}
+
+// Stop stops the music note (see NoteOff)
+func (p *VirtualPort) Stop(n music.Note) {
+	p.NoteOff(n.MIDINumber())
+}
+
+// Close closes the port. The voice pool reading its events ends when
+// its last notes are released.
+func (p *VirtualPort) Close() {
+	p.once.Do(func() { close(p.events) })
+}
+
+// -------------------------------------------------------------
+// FileEvents returns a channel that receives the note events of the MIDI
+// file, timed with its tempo map, and that is closed after the last
+// event. The notes of the percussion channel are ignored. The events are
+// sent by a goroutine as soon as the channel can receive them: their
+// time is used by the voice pool to play them at the right sample.
+func FileEvents(f *midi.File) <-chan Event {
+	tempo := f.TempoMap()
+	notes := f.Notes()
+	events := make([]Event, 0, 2*len(notes))
+	for _, n := range notes {
+		if n.Channel == midi.PercussionChannel {
+			continue
+		}
+		events = append(events,
+			Event{Kind: midi.NoteOn, Channel: n.Channel, Key: n.Key, Velocity: n.Velocity, Time: tempo.Seconds(n.Start)},
+			Event{Kind: midi.NoteOff, Channel: n.Channel, Key: n.Key, Time: tempo.Seconds(n.Start + n.Duration)},
+		)
+	}
+	sortEvents(events)
+
+	ch := make(chan Event, 64)
+	go func() {
+		defer close(ch)
+		for _, e := range events {
+			ch <- e
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,52 @@
+package live
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/midi"
+	"github.com/gboulant/musicall/music"
+)
+
+func TestFileEvents(t *testing.T) {
+	notes := midi.Melody([]music.Note{music.La3, music.La3, music.NoteFromMIDINumber(72)}, music.Quarter)
+	drum := midi.Note{Track: 1, Channel: midi.PercussionChannel, Key: 36, Velocity: 100, Duration: music.Quarter}
+	f := midi.NewFile(music.MustNewTempoMap(60, music.CommonTime), append(notes, drum))
+
+	var events []Event
+	for e := range FileEvents(f) {
+		events = append(events, e)
+	}
+	if len(events) != 6 {
+		t.Fatalf("number of events is %d (should be 6)", len(events))
+	}
+	exp := []struct {
+		on   bool
+		key  int
+		time float64
+	}{
+		{true, 69, 0}, {false, 69, 1}, {true, 69, 1}, {false, 69, 2}, {true, 72, 2}, {false, 72, 3},
+	}
+	for i, e := range exp {
+		got := events[i]
+		if got.IsNoteOn() != e.on || got.Key != e.key || math.Abs(got.Time-e.time) > 1e-9 {
+			t.Errorf("event %d is %+v (should be %+v)", i, got, e)
+		}
+	}
+}
+
+func TestVirtualPort(t *testing.T) {
+	port := NewVirtualPort(4)
+	port.Play(music.La3, 100)
+	port.Stop(music.La3)
+	port.Close()
+	port.Close() // closing twice is allowed
+
+	var events []Event
+	for e := range port.Events() {
+		events = append(events, e)
+	}
+	if len(events) != 2 || !events[0].IsNoteOn() || events[1].IsNoteOn() || events[1].Key != 69 {
+		t.Errorf("events are %+v (should be a note on and a note off of the key 69)", events)
+	}
+}
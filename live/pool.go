@@ -0,0 +1,321 @@
+package live
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/gboulant/musicall/midi"
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/wave"
+)
+
+// Default parameters of a voice pool
+const (
+	DefaultPolyphony = 16
+	DefaultAttack    = 0.005 // seconds
+	DefaultRelease   = 0.3   // seconds
+
+	// DefaultMaxDuration is the default maximal duration of a note,
+	// release included (see VoicePool.MaxDuration).
+	DefaultMaxDuration = 8 // seconds
+
+	// stealFade is the fade out of a stolen voice, short enough to free
+	// the voice quickly, long enough to avoid a click.
+	stealFade = 0.005
+)
+
+// SynthesizerFactory creates a new synthesizer for each voice of a pool
+// (the synthesizers are not shared, since their frequency changes with
+// the note).
+type SynthesizerFactory func() wave.HarmonicSynthesizer
+
+// VoicePool is a streamer that plays the events received on a channel
+// with a pool of voices, each one synthesizing a note. A note is held
+// until its note off event (with a short attack at its start), then it
+// fades out during the release time (its release tail).
+//
+// The number of voices playing at the same time is limited by the
+// polyphony: when a note starts and all the voices are busy, a voice is
+// stolen, the releasing voice with the lowest level or, if all the
+// voices are held, the oldest one.
+//
+// The pool streams silence while it waits for events: it is a single
+// continuous streamer, as a live instrument. It ends when its channel of
+// events is closed and all its notes are finished.
+//
+// Les synthétiseurs produisent des signaux d'une durée fixée: chaque
+// note est donc synthétisée une seule fois, sur la durée MaxDuration,
+// ce qui garantit la continuité du signal même pour les synthétiseurs
+// non déterministes (Karplus-Strong part d'un bruit aléatoire). Une
+// note tenue plus longtemps est relâchée pour s'éteindre à la fin du
+// signal synthétisé.
+type VoicePool struct {
+	Attack      float64      // seconds
+	Release     float64      // seconds
+	MaxDuration float64      // maximal duration of a note in seconds
+	Gain        float64      // gain of the voices (1 for a velocity of 127)
+	Tuning      music.Tuning // tuning of the keys, the current tuning if nil
+
+	sampleRate int
+	factory    SynthesizerFactory
+	polyphony  int
+	events     <-chan Event
+	closed     bool
+	pending    []Event // events received, not yet played
+
+	mu     sync.Mutex
+	voices []*voice
+	stolen []*voice // voices fading out after being stolen
+	clock  int64    // number of frames streamed
+}
+
+type voice struct {
+	channel, key int
+	start        int64 // clock at the note on
+	held         bool
+	samples      []float64 // whole signal, synthesized at the note on
+	pos          int       // position of the next sample
+	end          int       // position where a held voice is released
+	gain         float64
+	level        float64 // envelope
+	attack       float64 // increment of the level while the note is held
+	release      float64 // decrement of the level after the note off
+}
+
+// NewVoicePool returns a pool playing the events of the channel with at
+// most polyphony voices (DefaultPolyphony if polyphony is not
+// positive), created by the factory.
+func NewVoicePool(sampleRate int, factory SynthesizerFactory, polyphony int, events <-chan Event) *VoicePool {
+	if polyphony <= 0 {
+		polyphony = DefaultPolyphony
+	}
+	return &VoicePool{
+		Attack:      DefaultAttack,
+		Release:     DefaultRelease,
+		MaxDuration: DefaultMaxDuration,
+		Gain:        1,
+		sampleRate:  wave.SampleRate(sampleRate),
+		factory:     factory,
+		polyphony:   polyphony,
+		events:      events,
+	}
+}
+
+// Active returns the number of voices playing a note (held or
+// releasing), the stolen voices being excluded.
+func (p *VoicePool) Active() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.voices)
+}
+
+// Held returns the keys of the notes being held
+func (p *VoicePool) Held() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var keys []int
+	for _, v := range p.voices {
+		if v.held {
+			keys = append(keys, v.key)
+		}
+	}
+	return keys
+}
+
+// Time returns the time in seconds of the next frame to be streamed
+func (p *VoicePool) Time() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return float64(p.clock) / float64(p.sampleRate)
+}
+
+// sortEvents sorts the events by time, the note off events being
+// before the note on events of the same time.
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Time != events[j].Time {
+			return events[i].Time < events[j].Time
+		}
+		return !events[i].IsNoteOn() && events[j].IsNoteOn()
+	})
+}
+
+// receive reads the events available on the channel, without waiting
+func (p *VoicePool) receive() {
+	received := false
+loop:
+	for !p.closed {
+		select {
+		case e, ok := <-p.events:
+			if !ok {
+				p.closed = true
+				break loop
+			}
+			p.pending = append(p.pending, e)
+			received = true
+		default:
+			break loop
+		}
+	}
+	if received {
+		sortEvents(p.pending)
+	}
+}
+
+func (p *VoicePool) frequency(key int) float64 {
+	n := music.NoteFromMIDINumber(key)
+	if p.Tuning != nil {
+		return n.FrequencyIn(p.Tuning)
+	}
+	return n.Frequency()
+}
+
+func (p *VoicePool) fade(seconds float64, level float64) float64 {
+	frames := max(seconds*float64(p.sampleRate), 1)
+	return level / frames
+}
+
+// noteOn starts a voice, stealing one if needed
+func (p *VoicePool) noteOn(e Event) {
+	// Une touche déjà tenue est relâchée avant d'être rejouée
+	p.noteOff(e)
+	if len(p.voices) >= p.polyphony {
+		p.steal()
+	}
+	synth := p.factory()
+	synth.SetFrequency(p.frequency(e.Key))
+	samples := synth.Synthesize(p.MaxDuration)
+	v := &voice{
+		channel: e.Channel,
+		key:     e.Key,
+		start:   p.clock,
+		held:    true,
+		samples: samples,
+		end:     len(samples) - min(int(p.Release*float64(p.sampleRate)), len(samples)),
+		gain:    p.Gain * float64(min(e.Velocity, 127)) / 127,
+		attack:  p.fade(p.Attack, 1),
+	}
+	if p.Attack <= 0 {
+		v.level = 1
+	}
+	p.voices = append(p.voices, v)
+}
+
+// noteOff releases the voices holding the key
+func (p *VoicePool) noteOff(e Event) {
+	for _, v := range p.voices {
+		if v.held && v.key == e.Key && v.channel == e.Channel {
+			v.held = false
+			v.release = p.fade(p.Release, max(v.level, 1e-9))
+		}
+	}
+}
+
+// steal frees a voice: the releasing voice with the lowest level, else
+// the oldest held voice.
+func (p *VoicePool) steal() {
+	best := -1
+	for i, v := range p.voices {
+		if best < 0 {
+			best = i
+			continue
+		}
+		b := p.voices[best]
+		switch {
+		case !v.held && b.held:
+			best = i
+		case v.held == b.held && !v.held && v.level < b.level:
+			best = i
+		case v.held == b.held && v.held && v.start < b.start:
+			best = i
+		}
+	}
+	v := p.voices[best]
+	p.voices = append(p.voices[:best], p.voices[best+1:]...)
+	v.held = false
+	v.release = p.fade(stealFade, max(v.level, 1e-9))
+	p.stolen = append(p.stolen, v)
+}
+
+// next returns the next sample of the voice, and false when the voice
+// is finished (its release is over, or its signal is consumed).
+func (v *voice) next() (float64, bool) {
+	remaining := len(v.samples) - v.pos
+	if remaining <= 0 {
+		return 0, false
+	}
+	// La voix est relâchée (ou son relâchement accéléré) pour que le
+	// niveau atteigne 0 à la fin du signal, sans clic.
+	if (v.held && v.pos >= v.end) || (!v.held && v.level > float64(remaining)*v.release) {
+		v.held = false
+		v.release = v.level / float64(remaining)
+	}
+	s := v.samples[v.pos] * v.gain * v.level
+	v.pos++
+	if v.held {
+		v.level = math.Min(v.level+v.attack, 1)
+		return s, true
+	}
+	v.level -= v.release
+	return s, v.level > 0
+}
+
+// Stream streams the voices, and plays the events at their time
+func (p *VoicePool) Stream(samples [][2]float64) (int, bool) {
+	p.receive()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.idle() {
+		return 0, false
+	}
+
+	rate := float64(p.sampleRate)
+	for i := range samples {
+		for len(p.pending) > 0 && p.pending[0].Time*rate <= float64(p.clock) {
+			e := p.pending[0]
+			p.pending = p.pending[1:]
+			if e.IsNoteOn() {
+				p.noteOn(e)
+			} else if e.Kind == midi.NoteOff || e.Kind == midi.NoteOn {
+				p.noteOff(e)
+			}
+		}
+		if p.idle() {
+			return i, true
+		}
+
+		var s float64
+		p.voices = streamVoices(p.voices, &s)
+		p.stolen = streamVoices(p.stolen, &s)
+		samples[i] = [2]float64{s, s}
+		p.clock++
+	}
+	return len(samples), true
+}
+
+// streamVoices adds the next sample of the voices to s, and returns the
+// voices that are not finished.
+func streamVoices(voices []*voice, s *float64) []*voice {
+	n := 0
+	for _, v := range voices {
+		x, ok := v.next()
+		*s += x
+		if ok {
+			voices[n] = v
+			n++
+		}
+	}
+	clear(voices[n:])
+	return voices[:n]
+}
+
+// idle returns true when the pool is over: no more events and no voice
+func (p *VoicePool) idle() bool {
+	return p.closed && len(p.pending) == 0 && len(p.voices) == 0 && len(p.stolen) == 0
+}
+
+// Err returns nil (a voice pool can not fail)
+func (p *VoicePool) Err() error {
+	return nil
+}
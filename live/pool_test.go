@@ -0,0 +1,219 @@
+package live
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/wave"
+)
+
+const sampleRate = 8000
+
+func sine() wave.HarmonicSynthesizer {
+	return wave.NewSineWaveSynthesizer(0, 1, sampleRate)
+}
+
+// stream streams the pool during the specified duration, and returns
+// the left channel.
+func stream(p *VoicePool, seconds float64) []float64 {
+	buffer := make([][2]float64, int(seconds*sampleRate))
+	n, _ := p.Stream(buffer)
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = buffer[i][0]
+	}
+	return samples
+}
+
+func TestVoicePool_Continuity(t *testing.T) {
+	port := NewVirtualPort(8)
+	p := NewVoicePool(sampleRate, sine, 4, port.Events())
+	p.Attack = 0
+	port.NoteOn(69, 127)
+
+	// The note is held across several synthesized chunks
+	var got []float64
+	for range 30 {
+		got = append(got, stream(p, 0.05)...)
+	}
+	exp := wave.NewSineWaveSynthesizer(music.La3.Frequency(), 1, sampleRate).Synthesize(1.5)
+	for i := range exp {
+		if math.Abs(got[i]-exp[i]) > 1e-9 {
+			t.Fatalf("sample %d is %v (should be %v)", i, got[i], exp[i])
+		}
+	}
+	if keys := p.Held(); !slices.Equal(keys, []int{69}) {
+		t.Errorf("held keys are %v (should be [69])", keys)
+	}
+}
+
+// noise is a non deterministic synthesizer (as Karplus-Strong), that
+// records the signals it synthesizes.
+type noise struct {
+	wave.HarmonicSynthesizer
+	signals [][]float64
+}
+
+func (n *noise) Synthesize(duration float64) []float64 {
+	samples := make([]float64, int(duration*float64(n.SampleRate())))
+	for i := range samples {
+		samples[i] = 2*rand.Float64() - 1
+	}
+	n.signals = append(n.signals, samples)
+	return samples
+}
+
+func TestVoicePool_Noise(t *testing.T) {
+	// A non deterministic note is synthesized once, and played without
+	// discontinuity
+	port := NewVirtualPort(8)
+	synth := &noise{HarmonicSynthesizer: sine()}
+	p := NewVoicePool(sampleRate, func() wave.HarmonicSynthesizer { return synth }, 4, port.Events())
+	p.Attack = 0
+	port.NoteOn(69, 127)
+	var got []float64
+	for range 30 {
+		got = append(got, stream(p, 0.05)...)
+	}
+	if len(synth.signals) != 1 {
+		t.Fatalf("number of signals synthesized is %d (should be 1)", len(synth.signals))
+	}
+	for i := range got {
+		if got[i] != synth.signals[0][i] {
+			t.Fatalf("sample %d is %v (should be %v)", i, got[i], synth.signals[0][i])
+		}
+	}
+}
+
+func TestVoicePool_MaxDuration(t *testing.T) {
+	// A note held longer than the maximal duration fades out at the end
+	// of its signal
+	port := NewVirtualPort(8)
+	p := NewVoicePool(sampleRate, sine, 4, port.Events())
+	p.Attack = 0
+	p.Release = 0.1
+	p.MaxDuration = 0.5
+	port.NoteOn(69, 127)
+	samples := stream(p, 1)
+	if p.Active() != 0 {
+		t.Errorf("number of active voices is %d (should be 0)", p.Active())
+	}
+	if m := peak(samples[int(0.3*sampleRate):int(0.4*sampleRate)]); math.Abs(m-1) > 1e-2 {
+		t.Errorf("peak before the release is %v (should be 1)", m)
+	}
+	if m := peak(samples[int(0.49*sampleRate):]); m > 0.11 {
+		t.Errorf("peak at the end of the signal is %v (should be faded out)", m)
+	}
+}
+
+// peak returns the maximal absolute value of the samples
+func peak(samples []float64) float64 {
+	p := 0.
+	for _, v := range samples {
+		p = math.Max(p, math.Abs(v))
+	}
+	return p
+}
+
+func TestVoicePool_Release(t *testing.T) {
+	port := NewVirtualPort(8)
+	p := NewVoicePool(sampleRate, sine, 4, port.Events())
+	p.Release = 0.1
+	port.NoteOn(60, 64)
+	stream(p, 0.1)
+	port.NoteOff(60)
+	tail := stream(p, 0.2)
+	if p.Active() != 0 {
+		t.Errorf("number of active voices is %d (should be 0)", p.Active())
+	}
+	// The tail lasts the release time, then the pool is silent
+	last := 0
+	for i, s := range tail {
+		if s != 0 {
+			last = i
+		}
+	}
+	if exp := int(0.1 * sampleRate); math.Abs(float64(last-exp)) > 2 {
+		t.Errorf("release tail lasts %d frames (should be %d)", last, exp)
+	}
+	peak := 0.
+	for _, s := range tail[:10] {
+		peak = max(peak, math.Abs(s))
+	}
+	if peak < 0.3 || peak > 64./127 {
+		t.Errorf("level at the note off is %v (should be the velocity 64/127)", peak)
+	}
+}
+
+func TestVoicePool_Stealing(t *testing.T) {
+	port := NewVirtualPort(8)
+	p := NewVoicePool(sampleRate, sine, 2, port.Events())
+	port.NoteOn(60, 100)
+	stream(p, 0.01)
+	port.NoteOn(64, 100)
+	stream(p, 0.01)
+	port.NoteOn(67, 100)
+	stream(p, 0.01)
+	if p.Active() != 2 {
+		t.Errorf("number of active voices is %d (should be 2)", p.Active())
+	}
+	if keys := p.Held(); !slices.Equal(keys, []int{64, 67}) {
+		t.Errorf("held keys are %v (should be [64 67], the oldest being stolen)", keys)
+	}
+
+	// A releasing voice is stolen before the held ones
+	port.NoteOff(67)
+	stream(p, 0.01)
+	port.NoteOn(72, 100)
+	stream(p, 0.01)
+	if keys := p.Held(); !slices.Equal(keys, []int{64, 72}) {
+		t.Errorf("held keys are %v (should be [64 72])", keys)
+	}
+}
+
+func TestVoicePool_Retrigger(t *testing.T) {
+	port := NewVirtualPort(8)
+	p := NewVoicePool(sampleRate, sine, 4, port.Events())
+	port.NoteOn(60, 100)
+	stream(p, 0.01)
+	port.NoteOn(60, 100)
+	stream(p, 0.01)
+	if keys := p.Held(); !slices.Equal(keys, []int{60}) {
+		t.Errorf("held keys are %v (should be [60])", keys)
+	}
+	if p.Active() != 2 {
+		t.Errorf("number of active voices is %d (should be 2, the first one releasing)", p.Active())
+	}
+}
+
+func TestVoicePool_Timed(t *testing.T) {
+	events := make(chan Event, 4)
+	events <- Event{Kind: 0x90, Key: 69, Velocity: 127, Time: 0.25}
+	events <- Event{Kind: 0x90, Key: 69, Velocity: 0, Time: 0.5} // note off
+	close(events)
+	p := NewVoicePool(sampleRate, sine, 4, events)
+	p.Release = 0.05
+
+	// The stream waits for the first event, then ends after the release
+	var samples []float64
+	for {
+		s := stream(p, 0.1)
+		if len(s) == 0 {
+			break
+		}
+		samples = append(samples, s...)
+	}
+	first := slices.IndexFunc(samples, func(s float64) bool { return s != 0 })
+	if first < int(0.25*sampleRate) || first > int(0.25*sampleRate)+1 {
+		t.Errorf("first sample of the note is %d (should be %d)", first, int(0.25*sampleRate))
+	}
+	if d := float64(len(samples)) / sampleRate; math.Abs(d-0.55) > 0.01 {
+		t.Errorf("duration of the stream is %v s (should be 0.55)", d)
+	}
+	if n, ok := p.Stream(make([][2]float64, 16)); n != 0 || ok {
+		t.Errorf("the stream should be over")
+	}
+}
@@ -0,0 +1,130 @@
+package midi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Save writes the MIDI file at the specified path
+func (f *File) Save(path string) error {
+	w, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Encode(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Encode writes the MIDI file to the stream. The events of each track
+// are written in the order of their time (the events at the same time
+// keep their order), a track name event is added if the track has a
+// name, and the end of track event is added after the last event.
+func (f *File) Encode(w io.Writer) error {
+	if f.Division <= 0 || f.Division >= 0x8000 {
+		return fmt.Errorf("the time division %d is not valid", f.Division)
+	}
+	if f.Format == 0 && len(f.Tracks) != 1 {
+		return errors.New("a MIDI file of format 0 must have a single track")
+	}
+	bw := bufio.NewWriter(w)
+	var header [6]byte
+	binary.BigEndian.PutUint16(header[0:2], uint16(f.Format))
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(f.Tracks)))
+	binary.BigEndian.PutUint16(header[4:6], uint16(f.Division))
+	writeChunk(bw, "MThd", header[:])
+	for i, t := range f.Tracks {
+		content, err := encodeTrack(t)
+		if err != nil {
+			return fmt.Errorf("track %d: %w", i+1, err)
+		}
+		writeChunk(bw, "MTrk", content)
+	}
+	return bw.Flush()
+}
+
+func writeChunk(w *bufio.Writer, id string, content []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(content)))
+	w.WriteString(id)
+	w.Write(length[:])
+	w.Write(content)
+}
+
+// appendVarLen appends a variable length quantity (see readVarLen)
+func appendVarLen(data []byte, v uint32) []byte {
+	var buf [4]byte
+	n := 0
+	for {
+		buf[n] = byte(v & 0x7F)
+		n++
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		b := buf[i]
+		if i > 0 {
+			b |= 0x80
+		}
+		data = append(data, b)
+	}
+	return data
+}
+
+func encodeTrack(t Track) ([]byte, error) {
+	events := make([]Event, 0, len(t.Events)+1)
+	hasName := false
+	for _, e := range t.Events {
+		if e.IsMeta() && e.Meta == MetaEndOfTrack {
+			continue // added at the end
+		}
+		hasName = hasName || (e.IsMeta() && e.Meta == MetaTrackName)
+		events = append(events, e)
+	}
+	if t.Name != "" && !hasName {
+		name := Event{Kind: 0xFF, Meta: MetaTrackName, Data: []byte(t.Name)}
+		events = append([]Event{name}, events...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Tick < events[j].Tick })
+
+	var data []byte
+	var tick int64
+	for _, e := range events {
+		if e.Tick < 0 {
+			return nil, fmt.Errorf("negative time %d", e.Tick)
+		}
+		if e.Tick-tick > 0x0FFFFFFF {
+			return nil, fmt.Errorf("delay of %d ticks too long", e.Tick-tick)
+		}
+		data = appendVarLen(data, uint32(e.Tick-tick))
+		tick = e.Tick
+		if e.IsMeta() {
+			data = append(data, 0xFF, e.Meta)
+			data = appendVarLen(data, uint32(len(e.Data)))
+			data = append(data, e.Data...)
+			continue
+		}
+		if e.Kind < NoteOff || e.Kind&0x0F != 0 || e.Channel < 0 || e.Channel > 15 {
+			return nil, fmt.Errorf("event 0x%02x on channel %d is not a channel message", e.Kind, e.Channel)
+		}
+		if len(e.Data) != dataLength(e.Kind) {
+			return nil, fmt.Errorf("event 0x%02x at tick %d has %d data bytes (should be %d)",
+				e.Kind, e.Tick, len(e.Data), dataLength(e.Kind))
+		}
+		data = append(data, e.Kind|byte(e.Channel))
+		for _, b := range e.Data {
+			data = append(data, b&0x7F)
+		}
+	}
+	data = appendVarLen(data, 0)
+	return append(data, 0xFF, MetaEndOfTrack, 0), nil
+}
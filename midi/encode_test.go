@@ -0,0 +1,68 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFile_Encode(t *testing.T) {
+	f, err := Decode(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Format != f.Format || g.Division != f.Division || len(g.Tracks) != len(f.Tracks) {
+		t.Fatalf("header is format %d, division %d, %d tracks (should be %d, %d, %d)",
+			g.Format, g.Division, len(g.Tracks), f.Format, f.Division, len(f.Tracks))
+	}
+	for i := range f.Tracks {
+		if g.Tracks[i].Name != f.Tracks[i].Name {
+			t.Errorf("name of track %d is %q (should be %q)", i, g.Tracks[i].Name, f.Tracks[i].Name)
+		}
+		if len(g.Tracks[i].Events) != len(f.Tracks[i].Events) {
+			t.Errorf("track %d has %d events (should be %d)", i, len(g.Tracks[i].Events), len(f.Tracks[i].Events))
+			continue
+		}
+		for j, e := range f.Tracks[i].Events {
+			got := g.Tracks[i].Events[j]
+			if got.Tick != e.Tick || got.Kind != e.Kind || got.Channel != e.Channel || got.Meta != e.Meta || !bytes.Equal(got.Data, e.Data) {
+				t.Errorf("event %d of track %d is %+v (should be %+v)", j, i, got, e)
+			}
+		}
+	}
+}
+
+func TestFile_EncodeErrors(t *testing.T) {
+	tests := map[string]*File{
+		"division": {Format: 1, Division: 0},
+		"format0":  {Format: 0, Division: 96, Tracks: make([]Track, 2)},
+		"negative": {Format: 0, Division: 96, Tracks: []Track{{Events: []Event{{Tick: -1, Kind: NoteOn, Data: []byte{60, 64}}}}}},
+		"data":     {Format: 0, Division: 96, Tracks: []Track{{Events: []Event{{Kind: NoteOn, Data: []byte{60}}}}}},
+		"kind":     {Format: 0, Division: 96, Tracks: []Track{{Events: []Event{{Kind: 0x12, Data: []byte{60, 64}}}}}},
+	}
+	for name, f := range tests {
+		if err := f.Encode(&bytes.Buffer{}); err == nil {
+			t.Errorf("encoding %s should fail", name)
+		}
+	}
+}
+
+func TestAppendVarLen(t *testing.T) {
+	for _, v := range []uint32{0, 1, 127, 128, 8192, 16383, 16384, 0x0FFFFFFF} {
+		data := appendVarLen(nil, v)
+		got, pos, err := readVarLen(data, 0)
+		if err != nil || got != v || pos != len(data) {
+			t.Errorf("value read from % x is %d (should be %d)", data, got, v)
+		}
+	}
+	if data := appendVarLen(nil, 128); !bytes.Equal(data, []byte{0x81, 0x00}) {
+		t.Errorf("128 is encoded as % x (should be 81 00)", data)
+	}
+}
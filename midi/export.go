@@ -0,0 +1,260 @@
+package midi
+
+// Export of the parts generated by this project (melodies, guitar
+// chords and picking) as MIDI files, to open them in a sequencer or a
+// notation editor. The notes are first built as a list of Note (see
+// Melody and GuitarPart), then gathered in a file with the tempo map of
+// the piece (see NewFile).
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/music"
+)
+
+// DefaultVelocity is the velocity of the exported notes when it is not
+// specified.
+const DefaultVelocity = 100
+
+// AcousticGuitar is the General MIDI program of the acoustic guitar
+// (steel strings), numbered from 0.
+const AcousticGuitar = 25
+
+// rampStep is the interval of the tempo events that approximate a
+// tempo ramp, a MIDI file having only sudden tempo changes.
+const rampStep = music.Sixteenth
+
+// Melody returns the notes of a melody, played one after the other from
+// the time 0, on the channel 0 of the track 1. The durations of the
+// notes are the specified note values, repeated if there are less
+// values than notes (e.g. a single value for notes of equal durations).
+func Melody(notes []music.Note, values ...music.Ticks) []Note {
+	if len(values) == 0 {
+		values = []music.Ticks{music.Quarter}
+	}
+	part := make([]Note, len(notes))
+	var at music.Ticks
+	for i, n := range notes {
+		value := values[i%len(values)]
+		part[i] = Note{Track: 1, Key: n.MIDINumber(), Velocity: DefaultVelocity, Start: at, Duration: value}
+		at += value
+	}
+	return part
+}
+
+// GuitarPart defines how the guitar notes are exported: the program and
+// the velocity of the notes, and the channels. With PerString, each
+// string has its own channel (the string n on the channel n-1), so that
+// the strings can be edited separately. The notes are on the track 1.
+type GuitarPart struct {
+	Program   int
+	Velocity  int
+	PerString bool
+}
+
+// NewGuitarPart returns a part for the acoustic guitar, with all the
+// strings on the channel 0.
+func NewGuitarPart() GuitarPart {
+	return GuitarPart{Program: AcousticGuitar, Velocity: DefaultVelocity}
+}
+
+func (p GuitarPart) note(n guitar.Note, start, duration music.Ticks) (Note, error) {
+	note, err := n.ToMusicNote()
+	if err != nil {
+		return Note{}, err
+	}
+	channel := 0
+	if p.PerString {
+		channel = int(n.StringNum) - 1
+	}
+	velocity := p.Velocity
+	if velocity <= 0 {
+		velocity = DefaultVelocity
+	}
+	return Note{
+		Track:    1,
+		Channel:  channel,
+		Program:  p.Program,
+		Key:      note.MIDINumber(),
+		Velocity: velocity,
+		Start:    start,
+		Duration: duration,
+	}, nil
+}
+
+// Strum returns the notes of a chord progression, each chord lasting the
+// specified note value. As with guitar.Guitar.Chord, the strings are
+// plucked in the order of the chord with the specified delay, and all
+// ring until the end of the chord. The error wraps
+// guitar.ErrUnknownString if a string number is not defined.
+func (p GuitarPart) Strum(chords []guitar.Chord, value music.Ticks, delay music.Ticks) ([]Note, error) {
+	var part []Note
+	for i, c := range chords {
+		start := value.Times(i)
+		for j, n := range c {
+			offset := delay.Times(j)
+			if offset >= value {
+				break
+			}
+			note, err := p.note(n, start+offset, value-offset)
+			if err != nil {
+				return nil, err
+			}
+			part = append(part, note)
+		}
+	}
+	return part, nil
+}
+
+// Pick returns the notes of guitar notes played one after the other
+// (e.g. a riff written as a tab), with the durations of the specified
+// note values, repeated as for Melody. The error wraps
+// guitar.ErrUnknownString if a string number is not defined.
+func (p GuitarPart) Pick(notes []guitar.Note, values ...music.Ticks) ([]Note, error) {
+	if len(values) == 0 {
+		values = []music.Ticks{music.Quarter}
+	}
+	part := make([]Note, len(notes))
+	var at music.Ticks
+	for i, n := range notes {
+		value := values[i%len(values)]
+		note, err := p.note(n, at, value)
+		if err != nil {
+			return nil, err
+		}
+		part[i] = note
+		at += value
+	}
+	return part, nil
+}
+
+// Shift returns the notes delayed by the specified duration, e.g. to
+// put the parts one after the other.
+func Shift(notes []Note, offset music.Ticks) []Note {
+	shifted := make([]Note, len(notes))
+	for i, n := range notes {
+		n.Start += offset
+		shifted[i] = n
+	}
+	return shifted
+}
+
+// NewFile returns a MIDI file of format 1 with the notes, in ticks of
+// the package music (music.TicksPerQuarter). The track 0 holds the tempo
+// and the time signature events of the tempo map (a tempo ramp is
+// approximated by a tempo change every sixteenth note), and the notes
+// are put in the track of their field Track. A program change is added
+// before a note whose program differs from the previous note of its
+// channel.
+func NewFile(tempo *music.TempoMap, notes []Note) *File {
+	ntracks := 1
+	for _, n := range notes {
+		ntracks = max(ntracks, n.Track+1)
+	}
+	f := &File{Format: 1, Division: music.TicksPerQuarter, Tracks: make([]Track, ntracks)}
+	if tempo != nil {
+		f.Tracks[0].Events = tempoEvents(tempo)
+	}
+
+	sorted := make([]Note, len(notes))
+	copy(sorted, notes)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	// Les note off sont placés avant les note on du même instant, pour
+	// que deux notes successives de même hauteur restent distinctes.
+	type timed struct {
+		event Event
+		order int
+	}
+	events := make([][]timed, ntracks)
+	programs := make(map[[2]int]int) // by track and channel
+	for _, n := range sorted {
+		if n.Track < 0 || n.Duration <= 0 {
+			continue
+		}
+		channel := byte(min(max(n.Channel, 0), 15))
+		key := byte(min(max(n.Key, 0), 127))
+		velocity := byte(min(max(n.Velocity, 1), 127))
+		start, end := int64(n.Start), int64(n.Start+n.Duration)
+		tc := [2]int{n.Track, int(channel)}
+		if p, ok := programs[tc]; !ok || p != n.Program {
+			programs[tc] = n.Program
+			events[n.Track] = append(events[n.Track], timed{Event{
+				Tick: start, Kind: ProgramChange, Channel: int(channel),
+				Data: []byte{byte(min(max(n.Program, 0), 127))},
+			}, 1})
+		}
+		events[n.Track] = append(events[n.Track],
+			timed{Event{Tick: start, Kind: NoteOn, Channel: int(channel), Data: []byte{key, velocity}}, 2},
+			timed{Event{Tick: end, Kind: NoteOff, Channel: int(channel), Data: []byte{key, 0}}, 0},
+		)
+	}
+	for i, te := range events {
+		sort.SliceStable(te, func(a, b int) bool {
+			if te[a].event.Tick != te[b].event.Tick {
+				return te[a].event.Tick < te[b].event.Tick
+			}
+			return te[a].order < te[b].order
+		})
+		for _, e := range te {
+			f.Tracks[i].Events = append(f.Tracks[i].Events, e.event)
+		}
+	}
+	return f
+}
+
+// tempoEvents returns the meta events of the tempo map
+func tempoEvents(m *music.TempoMap) []Event {
+	var events []Event
+	for _, s := range m.TimeSignatures() {
+		// Numérateur, dénominateur en puissance de 2, nombre de clocks
+		// MIDI par battement de métronome, et nombre de triples croches
+		// par noire.
+		unit := byte(bits.Len(uint(max(s.Signature.Unit, 1))) - 1)
+		clocks := byte(min(24*4/max(s.Signature.Unit, 1), 127))
+		events = append(events, Event{
+			Tick: int64(s.At), Kind: 0xFF, Meta: MetaTimeSignature,
+			Data: []byte{byte(s.Signature.Beats), unit, clocks, 8},
+		})
+	}
+
+	tempos := m.Tempos()
+	for i, c := range tempos {
+		if c.Ramp && i > 0 {
+			// La rampe est découpée en paliers dont le tempo donne la
+			// même durée que la rampe sur le palier.
+			for at := tempos[i-1].At; at < c.At; at += rampStep {
+				end := min(at+rampStep, c.At)
+				bpm := 60 * (end - at).Beats() / (m.Seconds(end) - m.Seconds(at))
+				events = append(events, tempoEvent(at, bpm))
+			}
+		}
+		events = append(events, tempoEvent(c.At, c.BPM))
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Tick < events[j].Tick })
+
+	// Un seul changement de tempo par instant (le dernier)
+	var cleaned []Event
+	for _, e := range events {
+		if n := len(cleaned); n > 0 && e.Meta == MetaTempo {
+			if last := cleaned[n-1]; last.Meta == MetaTempo && last.Tick == e.Tick {
+				cleaned[n-1] = e
+				continue
+			}
+		}
+		cleaned = append(cleaned, e)
+	}
+	return cleaned
+}
+
+// tempoEvent returns a tempo meta event (microseconds per quarter note)
+func tempoEvent(at music.Ticks, bpm float64) Event {
+	us := min(max(int(math.Round(60e6/bpm)), 1), 0xFFFFFF)
+	return Event{
+		Tick: int64(at), Kind: 0xFF, Meta: MetaTempo,
+		Data: []byte{byte(us >> 16), byte(us >> 8), byte(us)},
+	}
+}
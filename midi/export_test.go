@@ -0,0 +1,152 @@
+package midi
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/music"
+)
+
+// roundTrip encodes and decodes the file
+func roundTrip(t *testing.T, f *File) *File {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestMelody(t *testing.T) {
+	notes := []music.Note{music.NoteFromMIDINumber(60), music.NoteFromMIDINumber(62), music.NoteFromMIDINumber(64), music.NoteFromMIDINumber(60)}
+	part := Melody(notes, music.Quarter, music.Eighth)
+	exp := []Note{
+		{Track: 1, Key: 60, Velocity: DefaultVelocity, Start: 0, Duration: music.Quarter},
+		{Track: 1, Key: 62, Velocity: DefaultVelocity, Start: music.Quarter, Duration: music.Eighth},
+		{Track: 1, Key: 64, Velocity: DefaultVelocity, Start: music.Quarter.Dotted(), Duration: music.Quarter},
+		{Track: 1, Key: 60, Velocity: DefaultVelocity, Start: music.Quarter.Dotted() + music.Quarter, Duration: music.Eighth},
+	}
+	for i := range exp {
+		if part[i] != exp[i] {
+			t.Errorf("note %d is %+v (should be %+v)", i, part[i], exp[i])
+		}
+	}
+
+	tempo := music.MustNewTempoMap(90, music.WaltzTime)
+	g := roundTrip(t, NewFile(tempo, part))
+	if g.Division != music.TicksPerQuarter || len(g.Tracks) != 2 {
+		t.Fatalf("division is %d, %d tracks (should be %d, 2)", g.Division, len(g.Tracks), music.TicksPerQuarter)
+	}
+	got := g.Notes()
+	if len(got) != len(exp) {
+		t.Fatalf("notes are %v (should be %v)", got, exp)
+	}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Errorf("note %d read back is %+v (should be %+v)", i, got[i], exp[i])
+		}
+	}
+	m := g.TempoMap()
+	if bpm := m.TempoAt(0); math.Abs(bpm-90) > 1e-3 {
+		t.Errorf("tempo is %v (should be 90)", bpm)
+	}
+	if s := m.TimeSignatureAt(0); s != music.WaltzTime {
+		t.Errorf("signature is %v (should be 3/4)", s)
+	}
+}
+
+func TestGuitarPart_Strum(t *testing.T) {
+	chord := guitar.Chord{{StringNum: 5, FretNum: 3}, {StringNum: 4, FretNum: 2}, {StringNum: 3, FretNum: 0}}
+	p := NewGuitarPart()
+	p.PerString = true
+	part, err := p.Strum([]guitar.Chord{chord, chord}, music.Half, music.Sixteenth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(part) != 6 {
+		t.Fatalf("number of notes is %d (should be 6)", len(part))
+	}
+	exp := []Note{
+		{Track: 1, Channel: 4, Program: AcousticGuitar, Key: 48, Velocity: DefaultVelocity, Start: 0, Duration: music.Half},
+		{Track: 1, Channel: 3, Program: AcousticGuitar, Key: 52, Velocity: DefaultVelocity, Start: music.Sixteenth, Duration: music.Half - music.Sixteenth},
+		{Track: 1, Channel: 2, Program: AcousticGuitar, Key: 55, Velocity: DefaultVelocity, Start: music.Eighth, Duration: music.Half - music.Eighth},
+	}
+	for i := range exp {
+		if part[i] != exp[i] {
+			t.Errorf("note %d is %+v (should be %+v)", i, part[i], exp[i])
+		}
+		next := exp[i]
+		next.Start += music.Half
+		if part[i+3] != next {
+			t.Errorf("note %d is %+v (should be %+v)", i+3, part[i+3], next)
+		}
+	}
+
+	// The same key played again on the same string makes two notes
+	got := roundTrip(t, NewFile(music.MustNewTempoMap(100, music.CommonTime), part)).Notes()
+	if len(got) != len(part) {
+		t.Fatalf("notes read back are %v (should be %v)", got, part)
+	}
+	for i := range part {
+		if got[i] != part[i] {
+			t.Errorf("note %d read back is %+v (should be %+v)", i, got[i], part[i])
+		}
+	}
+}
+
+func TestGuitarPart_UnknownString(t *testing.T) {
+	// A string number read from the user data is an error, not a panic
+	p := NewGuitarPart()
+	if _, err := p.Strum([]guitar.Chord{{{StringNum: 7, FretNum: 0}}}, music.Half, 0); !errors.Is(err, guitar.ErrUnknownString) {
+		t.Errorf("error is %v (should be ErrUnknownString)", err)
+	}
+	if _, err := p.Pick([]guitar.Note{{StringNum: 0, FretNum: 3}}); !errors.Is(err, guitar.ErrUnknownString) {
+		t.Errorf("error is %v (should be ErrUnknownString)", err)
+	}
+}
+
+func TestGuitarPart_Pick(t *testing.T) {
+	riff := []guitar.Note{{StringNum: 6, FretNum: 0}, {StringNum: 6, FretNum: 3}, {StringNum: 5, FretNum: 0}}
+	picked, err := NewGuitarPart().Pick(riff, music.Eighth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := Shift(picked, music.Whole)
+	for i, n := range part {
+		if n.Channel != 0 || n.Start != music.Whole+music.Eighth.Times(i) || n.Duration != music.Eighth {
+			t.Errorf("note %d is %+v", i, n)
+		}
+	}
+	if part[1].Key != 43 {
+		t.Errorf("key is %d (should be 43)", part[1].Key)
+	}
+}
+
+func TestNewFile_TempoRamp(t *testing.T) {
+	tempo := music.MustNewTempoMap(120, music.CommonTime)
+	tempo.SetTimeSignature(3, music.TimeSignature{Beats: 6, Unit: 8})
+	tempo.SetTempo(tempo.Bar(2), 100)
+	tempo.RampTempo(tempo.Bar(4), 60)
+	f := NewFile(tempo, nil)
+	m := roundTrip(t, f).TempoMap()
+
+	for _, at := range []music.Ticks{0, tempo.Bar(2), tempo.Bar(3), tempo.Bar(4), tempo.Bar(5)} {
+		exp := tempo.Seconds(at)
+		if s := m.Seconds(at); math.Abs(s-exp) > 1e-4 {
+			t.Errorf("time at %v is %v s (should be %v)", tempo.Position(at), s, exp)
+		}
+	}
+	if s := m.TimeSignatureAt(tempo.Bar(3)); s != (music.TimeSignature{Beats: 6, Unit: 8}) {
+		t.Errorf("signature of bar 3 is %v (should be 6/8)", s)
+	}
+	if bpm := m.TempoAt(tempo.Bar(4)); math.Abs(bpm-60) > 1e-3 {
+		t.Errorf("tempo after the ramp is %v (should be 60)", bpm)
+	}
+}
@@ -0,0 +1,148 @@
+package midi
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gboulant/musicall/music"
+)
+
+// DefaultTempo is the tempo of a MIDI file without tempo event (BPM)
+const DefaultTempo = 120.
+
+// Note is a note of a MIDI file, converted to the music time (see
+// music.Ticks and music.TempoMap).
+type Note struct {
+	Track    int
+	Channel  int
+	Program  int // program (instrument) of the channel when the note starts
+	Key      int // MIDI key number (60 is the Do3, 69 the La3)
+	Velocity int // from 1 to 127
+	Start    music.Ticks
+	Duration music.Ticks
+}
+
+// MusicNote returns the music note of the MIDI key
+func (n Note) MusicNote() music.Note {
+	return music.NoteFromMIDINumber(n.Key)
+}
+
+// ticks converts a time of the file in ticks of the package music
+func (f *File) ticks(tick int64) music.Ticks {
+	return music.Ticks(math.Round(float64(tick) * music.TicksPerQuarter / float64(f.Division)))
+}
+
+// events returns the events of all the tracks, sorted by time, with the
+// index of their track.
+func (f *File) events() ([]Event, []int) {
+	var events []Event
+	var tracks []int
+	for i, t := range f.Tracks {
+		events = append(events, t.Events...)
+		for range t.Events {
+			tracks = append(tracks, i)
+		}
+	}
+	index := make([]int, len(events))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		return events[index[i]].Tick < events[index[j]].Tick
+	})
+	sorted := make([]Event, len(events))
+	sortedTracks := make([]int, len(events))
+	for i, k := range index {
+		sorted[i] = events[k]
+		sortedTracks[i] = tracks[k]
+	}
+	return sorted, sortedTracks
+}
+
+// maxUnitExponent is the largest denominator of a time signature
+// event, as a power of 2 (see music.MaxTimeSignatureUnit)
+const maxUnitExponent = 6
+
+// TempoMap returns the tempo map defined by the tempo and time
+// signature events of all the tracks. The invalid time signatures are
+// skipped.
+func (f *File) TempoMap() *music.TempoMap {
+	m := music.MustNewTempoMap(DefaultTempo, music.CommonTime)
+	events, _ := f.events()
+	for _, e := range events {
+		if !e.IsMeta() {
+			continue
+		}
+		at := f.ticks(e.Tick)
+		switch {
+		case e.Meta == MetaTempo && len(e.Data) == 3:
+			// Microseconds per quarter note
+			us := int(e.Data[0])<<16 | int(e.Data[1])<<8 | int(e.Data[2])
+			if us > 0 {
+				m.SetTempo(at, 60e6/float64(us))
+			}
+		case e.Meta == MetaTimeSignature && len(e.Data) >= 2:
+			// Numerator, and denominator as a power of 2. A signature
+			// change is applied from the bar where it occurs. The invalid
+			// signatures (of a corrupted file) are skipped.
+			signature := music.TimeSignature{Beats: int(e.Data[0])}
+			if e.Data[1] <= maxUnitExponent {
+				signature.Unit = 1 << e.Data[1]
+			}
+			if signature.Check() == nil {
+				m.SetTimeSignature(m.Position(at).Bar, signature)
+			}
+		}
+	}
+	return m
+}
+
+// Notes returns the notes of all the tracks, sorted by start time. The
+// note off events (or note on events with a velocity of 0) are matched
+// with the first note on event of the same key and channel that is not
+// yet stopped. The notes that are never stopped end at the last event
+// of the file.
+func (f *File) Notes() []Note {
+	type key struct{ channel, key int }
+	var notes []Note
+	open := make(map[key][]int) // index of the notes started and not stopped
+	var programs [16]int
+	var last int64
+
+	events, tracks := f.events()
+	for i, e := range events {
+		last = max(last, e.Tick)
+		if e.IsMeta() {
+			continue
+		}
+		switch {
+		case e.Kind == ProgramChange:
+			programs[e.Channel] = int(e.Data[0])
+		case e.Kind == NoteOn && e.Data[1] > 0:
+			k := key{e.Channel, int(e.Data[0])}
+			open[k] = append(open[k], len(notes))
+			notes = append(notes, Note{
+				Track:    tracks[i],
+				Channel:  e.Channel,
+				Program:  programs[e.Channel],
+				Key:      int(e.Data[0]),
+				Velocity: int(e.Data[1]),
+				Start:    f.ticks(e.Tick),
+			})
+		case e.Kind == NoteOff || e.Kind == NoteOn:
+			k := key{e.Channel, int(e.Data[0])}
+			if len(open[k]) == 0 {
+				continue // note off without note on
+			}
+			n := &notes[open[k][0]]
+			open[k] = open[k][1:]
+			n.Duration = f.ticks(e.Tick) - n.Start
+		}
+	}
+	for _, indexes := range open {
+		for _, i := range indexes {
+			notes[i].Duration = f.ticks(last) - notes[i].Start
+		}
+	}
+	return notes
+}
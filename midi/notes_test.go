@@ -0,0 +1,91 @@
+package midi
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/music"
+)
+
+func TestFile_TempoMap(t *testing.T) {
+	f, err := Decode(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := f.TempoMap()
+	if s := m.TimeSignatureAt(0); s != music.WaltzTime {
+		t.Errorf("signature is %v (should be 3/4)", s)
+	}
+	if bpm := m.TempoAt(music.Half.Dotted()); bpm != 60 {
+		t.Errorf("tempo of the second bar is %v (should be 60)", bpm)
+	}
+	// One bar of 3 beats at 120 BPM, then one beat at 60 BPM
+	if s := m.Seconds(music.Whole); math.Abs(s-2.5) > 1e-12 {
+		t.Errorf("time of the fifth beat is %v s (should be 2.5)", s)
+	}
+}
+
+func TestFile_TempoMapCorrupted(t *testing.T) {
+	// Time signatures with a denominator of 2^13 and 2^64, and with no
+	// beat: they are skipped
+	tempo := []byte{
+		0x00, 0xFF, 0x58, 0x04, 0x03, 0x0D, 0x18, 0x08,
+		0x00, 0xFF, 0x58, 0x04, 0x03, 0x40, 0x18, 0x08,
+		0x00, 0xFF, 0x58, 0x04, 0x00, 0x02, 0x18, 0x08,
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	f, err := Decode(bytes.NewReader(smf(0, 480, tempo)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := f.TempoMap()
+	if s := m.TimeSignatureAt(0); s != music.CommonTime {
+		t.Errorf("signature is %v (should be 4/4)", s)
+	}
+	if p := m.Position(music.Whole); p != (music.Position{Bar: 2, Beat: 1}) {
+		t.Errorf("position is %v (should be 2.1.000)", p)
+	}
+}
+
+func TestFile_Notes(t *testing.T) {
+	f, err := Decode(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := f.Notes()
+	exp := []Note{
+		{Track: 1, Channel: 0, Program: 25, Key: 60, Velocity: 100, Start: 0, Duration: music.Quarter},
+		{Track: 1, Channel: 0, Program: 25, Key: 64, Velocity: 80, Start: 0, Duration: music.Half},
+		{Track: 1, Channel: 1, Program: 0, Key: 67, Velocity: 127, Start: music.Half.Dotted(), Duration: music.Quarter},
+	}
+	if len(notes) != len(exp) {
+		t.Fatalf("notes are %v (should be %v)", notes, exp)
+	}
+	for i := range exp {
+		if notes[i] != exp[i] {
+			t.Errorf("note %d is %+v (should be %+v)", i, notes[i], exp[i])
+		}
+	}
+	if name := notes[1].MusicNote().Name(); name != "Mi3" {
+		t.Errorf("note name is %s (should be Mi3)", name)
+	}
+}
+
+func TestFile_NotesNotStopped(t *testing.T) {
+	// Format 0, a division of 96, and a note that is never stopped
+	data := smf(0, 96, []byte{
+		0x00, 0x90, 69, 64,
+		0x60, 0x90, 71, 64,
+		0x60, 0x80, 71, 64,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	f, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := f.Notes()
+	if len(notes) != 2 || notes[0].Duration != music.Half || notes[1].Start != music.Quarter {
+		t.Errorf("notes are %+v", notes)
+	}
+}
@@ -0,0 +1,71 @@
+package midi
+
+import (
+	"fmt"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/sequencer"
+)
+
+// PercussionChannel is the channel of the percussions in the General
+// MIDI convention (the channel 10, numbered 9 from 0). Its keys are not
+// pitches but instruments, and it is not played by default.
+const PercussionChannel = 9
+
+// Options defines how the notes of a MIDI file are played. The
+// instrument of a note is the instrument of its program if it is
+// defined, else the instrument of its channel, else the default
+// instrument. The notes without instrument are not played.
+type Options struct {
+	Programs map[int]sequencer.Instrument // by program number (0 to 127)
+	Channels map[int]sequencer.Instrument // by channel (0 to 15)
+	Default  sequencer.Instrument
+
+	// MapToGuitar maps the notes onto the guitar strings (see
+	// guitar.StringMapper), so that a guitar instrument plucks each note
+	// on a string (with the stereo spread of the guitar). The notes of
+	// each channel are mapped independently.
+	MapToGuitar bool
+}
+
+func (o Options) instrument(n Note) (sequencer.Instrument, string) {
+	if i, ok := o.Programs[n.Program]; ok {
+		return i, fmt.Sprintf("program %d", n.Program)
+	}
+	if i, ok := o.Channels[n.Channel]; ok {
+		return i, fmt.Sprintf("channel %d", n.Channel)
+	}
+	if o.Default != nil && n.Channel != PercussionChannel {
+		return o.Default, "default"
+	}
+	return nil, ""
+}
+
+// Timeline returns a timeline (see sequencer.Timeline) with the notes
+// of the file, played by the instruments defined by the options. The
+// times of the timeline are in beats, converted to seconds with the
+// tempo map of the file.
+func (f *File) Timeline(sampleRate int, options Options) *sequencer.Timeline {
+	tl := sequencer.NewTimeline(sampleRate, sequencer.Beats)
+	tl.Tempo = f.TempoMap()
+	mappers := make(map[int]*guitar.StringMapper)
+	for _, n := range f.Notes() {
+		instrument, name := options.instrument(n)
+		if instrument == nil {
+			continue
+		}
+		tl.SetInstrument(name, instrument)
+
+		var pitch sequencer.Pitch = n.MusicNote()
+		if options.MapToGuitar {
+			m, ok := mappers[n.Channel]
+			if !ok {
+				m = guitar.NewStringMapper()
+				mappers[n.Channel] = m
+			}
+			pitch = m.Map(n.MusicNote(), tl.Tempo.Seconds(n.Start), tl.Tempo.Seconds(n.Start+n.Duration))
+		}
+		tl.Note(n.Start.Beats(), n.Duration.Beats(), name, pitch, float64(n.Velocity)/127)
+	}
+	return tl
+}
@@ -0,0 +1,86 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/sequencer"
+	"github.com/gboulant/musicall/sound"
+	"github.com/gopxl/beep"
+)
+
+const sampleRate = 8000
+
+// recorder is an instrument that records the notes it plays
+type recorder struct {
+	pitches    []sequencer.Pitch
+	velocities []float64
+}
+
+func (r *recorder) Play(pitch sequencer.Pitch, velocity float64, duration float64) beep.Streamer {
+	r.pitches = append(r.pitches, pitch)
+	r.velocities = append(r.velocities, velocity)
+	return sound.Silence(duration, sampleRate)
+}
+
+func TestFile_Timeline(t *testing.T) {
+	f, err := Decode(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lead := &recorder{}
+	other := &recorder{}
+	tl := f.Timeline(sampleRate, Options{
+		Programs: map[int]sequencer.Instrument{25: lead},
+		Default:  other,
+	})
+	// Two beats at 120 BPM (1 s), then one at 60 BPM (1 s)
+	if d := tl.Duration(); d != 2.5 {
+		t.Errorf("duration is %v (should be 2.5)", d)
+	}
+	rec, err := tl.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != 2.5*sampleRate {
+		t.Errorf("number of frames is %d (should be %d)", rec.Frames(), int(2.5*sampleRate))
+	}
+	if len(lead.pitches) != 2 || len(other.pitches) != 1 {
+		t.Fatalf("lead played %d notes, other %d (should be 2 and 1)", len(lead.pitches), len(other.pitches))
+	}
+	if v := other.velocities[0]; v != 1 {
+		t.Errorf("velocity is %v (should be 1)", v)
+	}
+
+	// Without instrument, the notes are not played
+	if tl := f.Timeline(sampleRate, Options{}); len(tl.Events()) != 0 {
+		t.Errorf("the notes without instrument should not be played")
+	}
+}
+
+func TestFile_TimelineGuitar(t *testing.T) {
+	// Two Mi2 (key 52) at the same time are played on two strings
+	data := smf(0, 96, []byte{
+		0x00, 0x90, 52, 100,
+		0x00, 0x90, 52, 100,
+		0x60, 0x80, 52, 0,
+		0x00, 0x80, 52, 0,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	f, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &recorder{}
+	tl := f.Timeline(sampleRate, Options{Default: r, MapToGuitar: true})
+	if _, err := tl.Render(); err != nil {
+		t.Fatal(err)
+	}
+	exp := []guitar.Note{{StringNum: guitar.Re2, FretNum: 2}, {StringNum: guitar.La1, FretNum: 7}}
+	for i, p := range r.pitches {
+		if p != exp[i] {
+			t.Errorf("note %d is %v (should be %v)", i, p, exp[i])
+		}
+	}
+}
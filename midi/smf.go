@@ -0,0 +1,256 @@
+package midi
+
+// This package reads (and writes) the Standard MIDI Files (SMF), so that
+// the songs written with a sequencer or a notation editor can be played
+// with the synthesizers of this project.
+//
+// A MIDI file is made of chunks: a header chunk "MThd" (the format, the
+// number of tracks, and the division, i.e. the number of ticks per
+// quarter note), followed by the track chunks "MTrk". A track is a
+// sequence of events, each one preceded by its delay (delta time) from
+// the previous event, in ticks. The events are:
+//
+// - the channel messages (note on, note off, program change, etc.), on
+//   one of the 16 channels,
+// - the meta events (tempo, time signature, track name, end of track),
+// - the system exclusive messages (ignored).
+//
+// The format 0 has a single track with all the channels. The format 1
+// has several tracks played simultaneously, the first one usually
+// holding the tempo map.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Kinds of the channel messages (the high nibble of the status byte)
+const (
+	NoteOff         = 0x80
+	NoteOn          = 0x90
+	PolyPressure    = 0xA0
+	ControlChange   = 0xB0
+	ProgramChange   = 0xC0
+	ChannelPressure = 0xD0
+	PitchBend       = 0xE0
+)
+
+// Types of the meta events
+const (
+	MetaTrackName     = 0x03
+	MetaEndOfTrack    = 0x2F
+	MetaTempo         = 0x51
+	MetaTimeSignature = 0x58
+)
+
+// Event is an event of a track. For a channel message, Kind is the kind
+// of message (NoteOn, ProgramChange, etc.), Channel is the channel
+// (from 0 to 15), and Data holds the parameters (e.g. the key and the
+// velocity of a note). For a meta event, Kind is 0xFF, Meta is the
+// type of the meta event, and Data holds its content.
+type Event struct {
+	Tick    int64 // absolute time of the event in ticks
+	Kind    byte
+	Channel int
+	Meta    byte
+	Data    []byte
+}
+
+// IsMeta returns true if the event is a meta event
+func (e Event) IsMeta() bool {
+	return e.Kind == 0xFF
+}
+
+// Track is a track of a MIDI file
+type Track struct {
+	Name   string
+	Events []Event // sorted by time
+}
+
+// File is the content of a MIDI file
+type File struct {
+	Format   int
+	Division int // number of ticks per quarter note
+	Tracks   []Track
+}
+
+// Load reads the MIDI file at the specified path
+func Load(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Decode reads a MIDI stream
+func Decode(r io.Reader) (*File, error) {
+	br := bufio.NewReader(r)
+	id, content, err := readChunk(br, "MThd")
+	if err != nil {
+		return nil, fmt.Errorf("the MIDI header can not be read: %w", err)
+	}
+	if id != "MThd" || len(content) < 6 {
+		return nil, errors.New("the stream is not a MIDI file")
+	}
+	f := &File{Format: int(binary.BigEndian.Uint16(content[0:2]))}
+	ntracks := int(binary.BigEndian.Uint16(content[2:4]))
+	division := binary.BigEndian.Uint16(content[4:6])
+	if division&0x8000 != 0 {
+		return nil, errors.New("the SMPTE time division is not supported")
+	}
+	if division == 0 {
+		return nil, errors.New("the time division of the MIDI file is 0")
+	}
+	f.Division = int(division)
+	if f.Format > 2 {
+		return nil, fmt.Errorf("the MIDI format %d is not supported", f.Format)
+	}
+
+	for len(f.Tracks) < ntracks {
+		id, content, err := readChunk(br, "MTrk")
+		if err != nil {
+			return nil, fmt.Errorf("the MIDI track %d can not be read: %w", len(f.Tracks)+1, err)
+		}
+		if id != "MTrk" {
+			continue // the unknown chunks are ignored
+		}
+		track, err := decodeTrack(content)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %w", len(f.Tracks)+1, err)
+		}
+		f.Tracks = append(f.Tracks, track)
+	}
+	return f, nil
+}
+
+// readChunk reads a chunk and returns its identifier and its content.
+// The content of a chunk whose identifier is not the expected one is
+// skipped (nil). The size in the header is not trusted: the content is
+// read as it comes, so that a corrupted size can not allocate up to 4
+// GiB.
+func readChunk(r io.Reader, expected string) (string, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", nil, err
+	}
+	id := string(header[0:4])
+	size := int64(binary.BigEndian.Uint32(header[4:8]))
+	if id != expected {
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return "", nil, err
+		}
+		return id, nil, nil
+	}
+	content, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(content)) < size {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	return id, content, nil
+}
+
+// readVarLen reads a variable length quantity: 7 bits per byte, the
+// most significant byte first, the bit 7 being set on all the bytes
+// except the last one.
+func readVarLen(data []byte, pos int) (uint32, int, error) {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		if pos >= len(data) {
+			return 0, pos, io.ErrUnexpectedEOF
+		}
+		b := data[pos]
+		pos++
+		v = v<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return v, pos, nil
+		}
+	}
+	return 0, pos, errors.New("variable length quantity too long")
+}
+
+// dataLength returns the number of data bytes of a channel message
+func dataLength(kind byte) int {
+	if kind == ProgramChange || kind == ChannelPressure {
+		return 1
+	}
+	return 2
+}
+
+func decodeTrack(data []byte) (Track, error) {
+	var track Track
+	var tick int64
+	var status byte // running status
+	for pos := 0; pos < len(data); {
+		delta, next, err := readVarLen(data, pos)
+		if err != nil {
+			return track, err
+		}
+		pos = next
+		tick += int64(delta)
+		if pos >= len(data) {
+			return track, io.ErrUnexpectedEOF
+		}
+
+		b := data[pos]
+		switch {
+		case b == 0xFF: // meta event
+			if pos+1 >= len(data) {
+				return track, io.ErrUnexpectedEOF
+			}
+			meta := data[pos+1]
+			length, next, err := readVarLen(data, pos+2)
+			if err != nil {
+				return track, err
+			}
+			end := next + int(length)
+			if end > len(data) {
+				return track, io.ErrUnexpectedEOF
+			}
+			e := Event{Tick: tick, Kind: 0xFF, Meta: meta, Data: data[next:end]}
+			pos = end
+			if meta == MetaTrackName && track.Name == "" {
+				track.Name = string(e.Data)
+			}
+			if meta == MetaEndOfTrack {
+				return track, nil
+			}
+			track.Events = append(track.Events, e)
+		case b == 0xF0 || b == 0xF7: // system exclusive (ignored)
+			length, next, err := readVarLen(data, pos+1)
+			if err != nil {
+				return track, err
+			}
+			pos = next + int(length)
+			status = 0
+		default:
+			if b&0x80 != 0 {
+				status = b
+				pos++
+			} else if status == 0 {
+				return track, fmt.Errorf("data byte 0x%02x without status at tick %d", b, tick)
+			}
+			kind := status & 0xF0
+			n := dataLength(kind)
+			if pos+n > len(data) {
+				return track, io.ErrUnexpectedEOF
+			}
+			track.Events = append(track.Events, Event{
+				Tick:    tick,
+				Kind:    kind,
+				Channel: int(status & 0x0F),
+				Data:    data[pos : pos+n],
+			})
+			pos += n
+		}
+	}
+	// The end of track event is missing: the track is kept as it is
+	return track, nil
+}
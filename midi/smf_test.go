@@ -0,0 +1,132 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// chunk returns a chunk of a MIDI file
+func chunk(id string, content []byte) []byte {
+	b := []byte(id)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(content)))
+	return append(b, content...)
+}
+
+// smf returns the content of a MIDI file with the specified tracks
+// (the content of the MTrk chunks).
+func smf(format, division int, tracks ...[]byte) []byte {
+	header := []byte{0, byte(format), 0, byte(len(tracks)), byte(division >> 8), byte(division)}
+	b := chunk("MThd", header)
+	for _, t := range tracks {
+		b = append(b, chunk("MTrk", t)...)
+	}
+	return b
+}
+
+// testFile is a MIDI file of format 1, with a tempo track (120 BPM,
+// 3/4, then 60 BPM from the second bar) and a melody track.
+func testFile() []byte {
+	tempo := []byte{
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // 500000 µs/quarter
+		0x00, 0xFF, 0x58, 0x04, 0x03, 0x02, 0x18, 0x08, // 3/4
+		0x8B, 0x20, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40, // 1440 ticks later: 1000000 µs/quarter
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	melody := []byte{
+		0x00, 0xFF, 0x03, 0x04, 'L', 'e', 'a', 'd',
+		0x00, 0xC0, 25, // program 25 on channel 0
+		0x00, 0x90, 60, 100, // note on Do3
+		0x00, 64, 80, // running status: note on Mi3
+		0x00, 0xF0, 0x03, 0x7E, 0x09, 0xF7, // sysex (ignored)
+		0x83, 0x60, 0x90, 60, 0, // 480 ticks later: note on with velocity 0
+		0x83, 0x60, 0x80, 64, 0, // 480 ticks later: note off
+		0x83, 0x60, 0x91, 67, 127, // 480 ticks later: Sol3 on channel 1
+		0x83, 0x60, 0x81, 67, 0,
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	return smf(1, 480, tempo, melody)
+}
+
+func TestDecode(t *testing.T) {
+	f, err := Decode(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Format != 1 || f.Division != 480 || len(f.Tracks) != 2 {
+		t.Fatalf("header is format %d, division %d, %d tracks (should be 1, 480, 2)", f.Format, f.Division, len(f.Tracks))
+	}
+	if f.Tracks[1].Name != "Lead" {
+		t.Errorf("track name is %q (should be Lead)", f.Tracks[1].Name)
+	}
+	if n := len(f.Tracks[0].Events); n != 3 {
+		t.Errorf("number of events of the tempo track is %d (should be 3)", n)
+	}
+	if e := f.Tracks[0].Events[2]; e.Tick != 1440 || !e.IsMeta() || e.Meta != MetaTempo {
+		t.Errorf("tempo change is %+v (should be at tick 1440)", e)
+	}
+
+	events := f.Tracks[1].Events
+	if len(events) != 8 {
+		t.Fatalf("number of events of the melody is %d (should be 8)", len(events))
+	}
+	exp := []struct {
+		tick    int64
+		kind    byte
+		channel int
+		data    []byte
+	}{
+		{0, ProgramChange, 0, []byte{25}},
+		{0, NoteOn, 0, []byte{60, 100}},
+		{0, NoteOn, 0, []byte{64, 80}},
+		{480, NoteOn, 0, []byte{60, 0}},
+		{960, NoteOff, 0, []byte{64, 0}},
+		{1440, NoteOn, 1, []byte{67, 127}},
+		{1920, NoteOff, 1, []byte{67, 0}},
+	}
+	for i, e := range exp {
+		got := events[i+1]
+		if got.Tick != e.tick || got.Kind != e.kind || got.Channel != e.channel || !bytes.Equal(got.Data, e.data) {
+			t.Errorf("event %d is %+v (should be %+v)", i+1, got, e)
+		}
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":     {},
+		"notmidi":   chunk("RIFF", make([]byte, 6)),
+		"smpte":     chunk("MThd", []byte{0, 0, 0, 1, 0xE7, 0x28}),
+		"truncated": smf(0, 96, []byte{0x00, 0x90, 60}),
+		"status":    smf(0, 96, []byte{0x00, 60, 100}),
+		"varlen":    smf(0, 96, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x7F}),
+		"tracks":    smf(1, 96, []byte{0x00, 0xFF, 0x2F, 0x00})[:20],
+		// Sizes of 4 GiB announced in the headers of the chunks
+		"hugeheader": []byte("MThd\xff\xff\xff\xff\x00\x00\x00\x01\x00\x60"),
+		"hugechunk":  append(chunk("MThd", []byte{0, 0, 0, 1, 0, 96}), []byte("XFIH\xff\xff\xff\xff\x00")...),
+	}
+	for name, data := range tests {
+		if _, err := Decode(bytes.NewReader(data)); err == nil {
+			t.Errorf("decoding %s should fail", name)
+		}
+	}
+}
+
+func TestReadVarLen(t *testing.T) {
+	tests := []struct {
+		data []byte
+		exp  uint32
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x7F}, 127},
+		{[]byte{0x81, 0x00}, 128},
+		{[]byte{0xC0, 0x00}, 8192},
+		{[]byte{0xFF, 0xFF, 0xFF, 0x7F}, 0x0FFFFFFF},
+	}
+	for _, tt := range tests {
+		v, pos, err := readVarLen(tt.data, 0)
+		if err != nil || v != tt.exp || pos != len(tt.data) {
+			t.Errorf("value of % x is %d (should be %d)", tt.data, v, tt.exp)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package music
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A chord (accord) is a set of notes played together. It is defined by
+// a root note (la fondamentale) and a quality (majeur, mineur, septième,
+// etc.), that is the list of intervals from the root to each note of
+// the chord. For example the major chord is made of the root, the
+// major third (4 half-tones) and the fifth (7 half-tones): Do, Mi, Sol
+// for the chord of Do.
+//
+// A chord is identified by a symbol that concatenates the name of the
+// root and the symbol of the quality. The name of the root can be
+// given with the french naming convention (Do, Re, Mi, etc.), as in
+// the guitar package, or with the english naming convention (C, D, E,
+// etc.), possibly altered with # or b. For example "Lam7" and "Am7" are
+// the same chord (La minor seventh). A slash chord, like "Sol/Si" or
+// "G/B", specifies a bass note other than the root.
+
+// ChordQuality defines the intervals from the root of the chord to
+// each of its notes (the root itself is the interval 0).
+type ChordQuality struct {
+	Symbol    string
+	Intervals []Interval
+}
+
+// The qualities are ordered by preference when identifying a chord
+// from a set of notes (simple chords first).
+var chordQualities []ChordQuality = []ChordQuality{
+	// Triads and power chord
+	{"", []Interval{0, 4, 7}},
+	{"m", []Interval{0, 3, 7}},
+	{"5", []Interval{0, 7}},
+	{"dim", []Interval{0, 3, 6}},
+	{"aug", []Interval{0, 4, 8}},
+	{"sus4", []Interval{0, 5, 7}},
+	{"sus2", []Interval{0, 2, 7}},
+	// Sevenths and sixths
+	{"7", []Interval{0, 4, 7, 10}},
+	{"m7", []Interval{0, 3, 7, 10}},
+	{"maj7", []Interval{0, 4, 7, 11}},
+	{"6", []Interval{0, 4, 7, 9}},
+	{"m6", []Interval{0, 3, 7, 9}},
+	{"m7b5", []Interval{0, 3, 6, 10}},
+	{"dim7", []Interval{0, 3, 6, 9}},
+	{"mmaj7", []Interval{0, 3, 7, 11}},
+	{"7sus4", []Interval{0, 5, 7, 10}},
+	// Extensions
+	{"add9", []Interval{0, 4, 7, 14}},
+	{"madd9", []Interval{0, 3, 7, 14}},
+	{"9", []Interval{0, 4, 7, 10, 14}},
+	{"m9", []Interval{0, 3, 7, 10, 14}},
+	{"maj9", []Interval{0, 4, 7, 11, 14}},
+	{"11", []Interval{0, 4, 7, 10, 14, 17}},
+	{"m11", []Interval{0, 3, 7, 10, 14, 17}},
+	{"13", []Interval{0, 4, 7, 10, 14, 21}},
+	{"m13", []Interval{0, 3, 7, 10, 14, 21}},
+	{"maj13", []Interval{0, 4, 7, 11, 14, 21}},
+}
+
+// chordQualityAliases are the alternative symbols accepted when parsing
+// a chord symbol, associated to the symbol used in chordQualities.
+var chordQualityAliases map[string]string = map[string]string{
+	"M":       "",
+	"maj":     "",
+	"min":     "m",
+	"-":       "m",
+	"°":       "dim",
+	"+":       "aug",
+	"sus":     "sus4",
+	"M7":      "maj7",
+	"Δ7":      "maj7",
+	"Δ":       "maj7",
+	"min7":    "m7",
+	"-7":      "m7",
+	"ø":       "m7b5",
+	"ø7":      "m7b5",
+	"°7":      "dim7",
+	"m(maj7)": "mmaj7",
+	"mM7":     "mmaj7",
+	"M9":      "maj9",
+	"M13":     "maj13",
+}
+
+// chordRootLabels are the names of the notes used for naming a chord.
+// The french names are written without accents, as in the guitar
+// standard chords table.
+var chordRootLabels []string = []string{
+	"Do", "Do#", "Re", "Re#", "Mi", "Fa", "Fa#", "Sol", "Sol#", "La", "La#", "Si",
+}
+
+var englishRootLabels []string = []string{
+	"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B",
+}
+
+// Chord is a chord quality played from a root note. If Slash is true,
+// the chord is played with the Bass note as the lowest note (for
+// example "Sol/Si"), otherwise the bass is the root.
+type Chord struct {
+	Root    NoteIndex
+	Quality ChordQuality
+	Bass    NoteIndex
+	Slash   bool
+}
+
+// ParseChord returns the chord corresponding to the specified symbol
+// (for example "Lam7", "Am7", "Sol/Si" or "G/B").
+func ParseChord(symbol string) (Chord, error) {
+	chordpart, basspart, slash := strings.Cut(symbol, "/")
+
+	var bass NoteIndex
+	if slash {
+		index, ok := parseNoteName(basspart)
+		if !ok {
+			return Chord{}, fmt.Errorf("the bass %q of the chord %q is not a note name", basspart, symbol)
+		}
+		bass = index
+	}
+
+	// The root name may be ambiguous (for example "Do" is the french Do
+	// or the english D followed by "o"), then we try all the possible
+	// root names until the remaining part is a valid quality.
+	for _, candidate := range rootCandidates(chordpart) {
+		quality, ok := lookupChordQuality(candidate.rest)
+		if !ok {
+			continue
+		}
+		chord := Chord{Root: candidate.index, Quality: quality, Bass: candidate.index}
+		if slash && bass != candidate.index {
+			chord.Bass = bass
+			chord.Slash = true
+		}
+		return chord, nil
+	}
+	return Chord{}, fmt.Errorf("the symbol %q is not a valid chord symbol", symbol)
+}
+
+// IdentifyChord returns the chord made of the specified notes,
+// whatever their octaves and their order. The lowest note is
+// considered as the bass of the chord, and then a chord whose root is
+// the lowest note is preferred (for example La, Do, Mi, Sol is
+// identified as Lam7 and not Do6/La). If no chord quality corresponds
+// to the set of notes, it returns false.
+func IdentifyChord(notes []Note) (Chord, bool) {
+	if len(notes) == 0 {
+		return Chord{}, false
+	}
+
+	lowest := notes[0]
+	for _, note := range notes[1:] {
+		if lowest.IntervalTo(note) < 0 {
+			lowest = note
+		}
+	}
+	bass := pitchClass(lowest.Index)
+
+	classes := pitchClasses(notes)
+	roots := []NoteIndex{bass}
+	for _, c := range classes {
+		if c != bass {
+			roots = append(roots, c)
+		}
+	}
+
+	for _, root := range roots {
+		for _, quality := range chordQualities {
+			chord := Chord{Root: root, Quality: quality, Bass: root}
+			if !slices.Equal(chord.PitchClasses(), classes) {
+				continue
+			}
+			if root != bass {
+				chord.Bass = bass
+				chord.Slash = true
+			}
+			return chord, true
+		}
+	}
+
+	// The bass may also be a note that does not belong to the chord
+	// (for example Do/Si)
+	for _, root := range roots[1:] {
+		for _, quality := range chordQualities {
+			chord := Chord{Root: root, Quality: quality, Bass: bass, Slash: true}
+			if slices.Equal(chord.PitchClasses(), classes) {
+				return chord, true
+			}
+		}
+	}
+	return Chord{}, false
+}
+
+// Name returns the symbol of the chord with the french naming
+// convention (for example "Lam7" or "Sol/Si").
+func (c Chord) Name() string {
+	return c.name(chordRootLabels)
+}
+
+// EnglishName returns the symbol of the chord with the english naming
+// convention (for example "Am7" or "G/B").
+func (c Chord) EnglishName() string {
+	return c.name(englishRootLabels)
+}
+
+func (c Chord) name(labels []string) string {
+	name := labels[pitchClass(c.Root)] + c.Quality.Symbol
+	if c.Slash {
+		name += "/" + labels[pitchClass(c.Bass)]
+	}
+	return name
+}
+
+// Notes returns the notes of the chord in root position, starting from
+// the root in the specified octave. For a slash chord, the bass note
+// is added below the root.
+func (c Chord) Notes(octave int) []Note {
+	root := Note{Octave: octave, Index: c.Root}
+	notes := make([]Note, 0, len(c.Quality.Intervals)+1)
+	if c.Slash {
+		bass := Note{Octave: octave, Index: c.Bass}
+		if root.IntervalTo(bass) >= 0 {
+			bass.Add(-Octave)
+		}
+		notes = append(notes, bass)
+	}
+	for _, interval := range c.Quality.Intervals {
+		notes = append(notes, root.Derived(interval))
+	}
+	return notes
+}
+
+// Inversion returns the notes of the chord in the specified inversion,
+// starting from the root in the specified octave. The first inversion
+// moves the root one octave up (the third becomes the bass), the second
+// inversion moves also the third, and so on. The inversion 0 is the
+// root position. The negative inversions move the highest notes one
+// octave down (the inversion -1 of a triad is its second inversion one
+// octave lower).
+func (c Chord) Inversion(octave int, inversion int) []Note {
+	root := Chord{Root: c.Root, Quality: c.Quality, Bass: c.Root}
+	notes := root.Notes(octave)
+	if n := len(notes); n > 0 && inversion < 0 {
+		notes = root.Notes(octave + floorDiv(inversion, n))
+		inversion = floorMod(inversion, n)
+	}
+	for i := range inversion {
+		notes = append(notes, notes[i].Derived(Octave))
+	}
+	return notes[inversion:]
+}
+
+// PitchClasses returns the indices of the notes of the chord in an
+// octave, whatever their octave, sorted in ascending order and without
+// duplicates.
+func (c Chord) PitchClasses() []NoteIndex {
+	classes := make([]NoteIndex, 0, len(c.Quality.Intervals)+1)
+	for _, interval := range c.Quality.Intervals {
+		classes = append(classes, pitchClass(c.Root+NoteIndex(interval)))
+	}
+	if c.Slash {
+		classes = append(classes, pitchClass(c.Bass))
+	}
+	slices.Sort(classes)
+	return slices.Compact(classes)
+}
+
+// Contains returns true if the specified note is one of the notes of
+// the chord, whatever its octave.
+func (c Chord) Contains(note Note) bool {
+	return slices.Contains(c.PitchClasses(), pitchClass(note.Index))
+}
+
+func lookupChordQuality(symbol string) (ChordQuality, bool) {
+	if alias, ok := chordQualityAliases[symbol]; ok {
+		symbol = alias
+	}
+	for _, quality := range chordQualities {
+		if quality.Symbol == symbol {
+			return quality, true
+		}
+	}
+	return ChordQuality{}, false
+}
+
+type rootCandidate struct {
+	index NoteIndex
+	rest  string
+}
+
+var frenchRootNames []string = []string{"Sol", "Do", "Re", "Ré", "Mi", "Fa", "La", "Si"}
+var englishRootNames []string = []string{"C", "D", "E", "F", "G", "A", "B"}
+
+// rootCandidates returns all the possible interpretations of the
+// beginning of the symbol as a note name (french or english, possibly
+// altered with # or b), with the remaining part of the symbol.
+func rootCandidates(symbol string) []rootCandidate {
+	candidates := make([]rootCandidate, 0)
+	for _, names := range [][]string{frenchRootNames, englishRootNames} {
+		for _, name := range names {
+			if !strings.HasPrefix(symbol, name) {
+				continue
+			}
+			index := rootIndex(name)
+			rest := symbol[len(name):]
+			// The alteration is optional: the root "Sib" could also be
+			// the root Si with the quality "b" (that does not exist, but
+			// we let lookupChordQuality decide).
+			if strings.HasPrefix(rest, "#") {
+				candidates = append(candidates, rootCandidate{pitchClass(index + 1), rest[1:]})
+			} else if strings.HasPrefix(rest, "b") {
+				candidates = append(candidates, rootCandidate{pitchClass(index - 1), rest[1:]})
+			}
+			candidates = append(candidates, rootCandidate{index, rest})
+		}
+	}
+	return candidates
+}
+
+// parseNoteName returns the index of the specified note name (french
+// or english, possibly altered with # or b).
+func parseNoteName(name string) (NoteIndex, bool) {
+	for _, candidate := range rootCandidates(name) {
+		if candidate.rest == "" {
+			return candidate.index, true
+		}
+	}
+	return 0, false
+}
+
+func rootIndex(name string) NoteIndex {
+	if i := slices.Index(englishRootLabels, name); i >= 0 {
+		return NoteIndex(i)
+	}
+	return label2Index[name]
+}
+
+// pitchClass returns the index of the note in the octave, between 0 and
+// 11, whatever the octave shift of the index.
+func pitchClass(index NoteIndex) NoteIndex {
+	return NoteIndex(floorMod(int(index), int(Octave)))
+}
+
+// pitchClasses returns the sorted set of pitch classes of the notes
+func pitchClasses(notes []Note) []NoteIndex {
+	classes := make([]NoteIndex, len(notes))
+	for i, note := range notes {
+		classes[i] = pitchClass(note.Index)
+	}
+	slices.Sort(classes)
+	return slices.Compact(classes)
+}
@@ -0,0 +1,147 @@
+package music
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		symbol  string
+		name    string
+		english string
+	}{
+		{"Do", "Do", "C"},
+		{"C", "Do", "C"},
+		{"Lam", "Lam", "Am"},
+		{"Lam7", "Lam7", "Am7"},
+		{"Am7", "Lam7", "Am7"},
+		{"Dom7", "Dom7", "Cm7"},
+		{"Dm7", "Rem7", "Dm7"},
+		{"Ré7", "Re7", "D7"},
+		{"Sib", "La#", "A#"},
+		{"Bbmaj7", "La#maj7", "A#maj7"},
+		{"Fa#m7b5", "Fa#m7b5", "F#m7b5"},
+		{"Esus4", "Misus4", "Esus4"},
+		{"G/B", "Sol/Si", "G/B"},
+		{"Sol/Si", "Sol/Si", "G/B"},
+		{"G/G", "Sol", "G"},
+		{"CM7", "Domaj7", "Cmaj7"},
+		{"Mi5", "Mi5", "E5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			c, err := ParseChord(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Name() != tt.name {
+				t.Errorf("name is %s (should be %s)", c.Name(), tt.name)
+			}
+			if c.EnglishName() != tt.english {
+				t.Errorf("english name is %s (should be %s)", c.EnglishName(), tt.english)
+			}
+		})
+	}
+
+	for _, symbol := range []string{"", "H7", "Lax", "Do/X", "m7"} {
+		if _, err := ParseChord(symbol); err == nil {
+			t.Errorf("the symbol %q should not be a valid chord", symbol)
+		}
+	}
+}
+
+func TestChord_Notes(t *testing.T) {
+	c, err := ParseChord("Am7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := c.Notes(2)
+	exp := []Note{
+		{Octave: 2, Index: Label2Index("La")},
+		{Octave: 3, Index: Label2Index("Do")},
+		{Octave: 3, Index: Label2Index("Mi")},
+		{Octave: 3, Index: Label2Index("Sol")},
+	}
+	if !slices.Equal(notes, exp) {
+		t.Errorf("notes are %v (should be %v)", notes, exp)
+	}
+
+	// Slash chord: the bass is below the root
+	c, err = ParseChord("G/B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes = c.Notes(2)
+	bass := Note{Octave: 1, Index: Label2Index("Si")}
+	if notes[0] != bass {
+		t.Errorf("bass is %v (should be %v)", notes[0], bass)
+	}
+
+	// First inversion of Do: Mi, Sol, Do
+	c, err = ParseChord("Do")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes = c.Inversion(3, 1)
+	exp = []Note{
+		{Octave: 3, Index: Label2Index("Mi")},
+		{Octave: 3, Index: Label2Index("Sol")},
+		{Octave: 4, Index: Label2Index("Do")},
+	}
+	if !slices.Equal(notes, exp) {
+		t.Errorf("notes are %v (should be %v)", notes, exp)
+	}
+
+	// Negative inversion: the highest note goes one octave down
+	notes = c.Inversion(3, -1)
+	exp = []Note{
+		{Octave: 2, Index: MustLookupIndex("Sol")},
+		{Octave: 3, Index: MustLookupIndex("Do")},
+		{Octave: 3, Index: MustLookupIndex("Mi")},
+	}
+	if !slices.Equal(notes, exp) {
+		t.Errorf("notes are %v (should be %v)", notes, exp)
+	}
+	notes = c.Inversion(3, -3)
+	if exp := c.Inversion(2, 0); !slices.Equal(notes, exp) {
+		t.Errorf("notes are %v (should be %v)", notes, exp)
+	}
+}
+
+func TestIdentifyChord(t *testing.T) {
+	note := func(octave int, label string) Note {
+		return Note{Octave: octave, Index: Label2Index(label)}
+	}
+	tests := []struct {
+		want  string
+		notes []Note
+	}{
+		{"Do", []Note{note(3, "Do"), note(3, "Mi"), note(3, "Sol")}},
+		{"Do", []Note{note(3, "Sol"), note(2, "Do"), note(4, "Mi"), note(3, "Do")}},
+		{"Lam7", []Note{note(2, "La"), note(3, "Do"), note(3, "Mi"), note(3, "Sol")}},
+		{"Lam7", []Note{note(3, "Do"), note(2, "La"), note(3, "Mi"), note(3, "Sol")}},
+		{"Do6/Mi", []Note{note(2, "Mi"), note(3, "Do"), note(3, "La"), note(3, "Sol")}},
+		{"Sol/Si", []Note{note(2, "Si"), note(3, "Re"), note(3, "Sol")}},
+		{"Domaj7/Si", []Note{note(2, "Si"), note(3, "Do"), note(3, "Mi"), note(3, "Sol")}},
+		{"Do/Fa#", []Note{note(2, "Fa#"), note(3, "Do"), note(3, "Mi"), note(3, "Sol")}},
+		{"Mi5", []Note{note(1, "Mi"), note(1, "Si"), note(2, "Mi")}},
+		{"Sol7", []Note{note(2, "Sol"), note(2, "Si"), note(3, "Re"), note(3, "Fa")}},
+		{"Resus2", []Note{note(2, "Re"), note(2, "Mi"), note(2, "La")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			c, ok := IdentifyChord(tt.notes)
+			if !ok {
+				t.Fatalf("no chord identified (should be %s)", tt.want)
+			}
+			if c.Name() != tt.want {
+				t.Errorf("chord is %s (should be %s)", c.Name(), tt.want)
+			}
+		})
+	}
+
+	if _, ok := IdentifyChord([]Note{note(3, "Do"), note(3, "Do#"), note(3, "Re")}); ok {
+		t.Errorf("a cluster of half-tones should not be identified as a chord")
+	}
+}
@@ -1,8 +1,12 @@
 package music
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/gboulant/musicall"
 )
@@ -13,13 +17,13 @@ import (
 //
 // log(Frequency) = log(FrequencyLa3) + interval/12
 //
-// We use the La3 as reference frequency
+// We use the La3 as reference frequency. Other tuning systems can be
+// used instead of the tempered equal scale (see tuning.go).
 //
 
 const FrequencyLa3 float64 = 440.0 // Herz
-var logFrequencyLa3 float64 = math.Log2(FrequencyLa3)
 
-var La3 Note = Note{3, 9}
+var La3 Note = Note{Octave: 3, Index: 9}
 
 type Interval int
 
@@ -30,13 +34,43 @@ var Quinte Interval = 3*Tone + HalfTone
 
 type NoteIndex Interval
 
-// Label2Index can be used to get the note index in an octave from its
+// ErrUnknownNote is the error returned (wrapped) when a note label or a
+// note name is not defined.
+var ErrUnknownNote = errors.New("unknown note")
+
+// LookupIndex can be used to get the note index in an octave from its
 // symbolic name (Do, Ré, etc.). We designate the "Ré" as "Re" (without
-// accents) so that it can be used even with a qwerty keyboard ;-).
-func Label2Index(label string) NoteIndex {
+// accents) so that it can be used even with a qwerty keyboard ;-). The
+// error wraps ErrUnknownNote if the label does not exist.
+func LookupIndex(label string) (NoteIndex, error) {
 	index, ok := label2Index[label]
 	if !ok {
-		musicall.LogError("err: (Label2Index) the label %s does not exist\n", label)
+		return 0, fmt.Errorf("%w: the label %q does not exist", ErrUnknownNote, label)
+	}
+	return index, nil
+}
+
+// MustLookupIndex is like LookupIndex but panics if the label does not
+// exist. It simplifies the programs where the labels are constants.
+func MustLookupIndex(label string) NoteIndex {
+	index, err := LookupIndex(label)
+	if err != nil {
+		panic(err)
+	}
+	return index
+}
+
+// Label2Index returns the note index of the label. As before the
+// function LookupIndex, the error is reported with musicall.LogError
+// (that exits the process by default), and the index 0 is returned if
+// LogError returns.
+//
+// Deprecated: use LookupIndex, or MustLookupIndex for the constant
+// labels.
+func Label2Index(label string) NoteIndex {
+	index, err := LookupIndex(label)
+	if err != nil {
+		musicall.LogError("err: (Label2Index) %v\n", err)
 	}
 	return index
 }
@@ -62,43 +96,206 @@ var label2Index map[string]NoteIndex = map[string]NoteIndex{
 // L'ensemble de ces notes (de 0 à 11) compose la gamme dite chromatique.
 // La restriction aux notes non altérées compose la gamme dites diatonique
 
+// Note is a note of the tempered scale, characterized by its octave
+// and its index in the octave. By default, an octave is divided in 12
+// half-tones (the index is then between 0 and 11), but a note can also
+// be defined in a system with another number of equal divisions of the
+// octave (N-EDO, for example 19, 24 for the quarter-tones, or 31). In
+// any system, a note can be shifted by a deviation in cents (1/100 of
+// a tempered half-tone, i.e. 1/1200 of an octave) for microtonal
+// adjustments.
 type Note struct {
-	Octave int       // index of the octave where is considered the note
-	Index  NoteIndex // index of the note in the octave, counted in number of half-tones
+	Octave    int       // index of the octave where is considered the note
+	Index     NoteIndex // index of the note in the octave, counted in number of divisions (half-tones by default)
+	Cents     float64   // deviation from the note, in cents (0 by default)
+	Divisions int       // number of equal divisions of the octave (0 means 12, the half-tones)
+}
+
+// NewEDONote returns the note of the specified octave and index in the
+// system of N equal divisions of the octave (N-EDO). For example,
+// NewEDONote(24, 3, 19) is the La3 raised by a quarter tone.
+func NewEDONote(divisions int, octave int, index NoteIndex) Note {
+	return Note{Octave: octave, Index: index, Divisions: divisions}
 }
 
+// OctaveSize returns the number of divisions of the octave in the
+// system of the note (12 for the tempered half-tones).
+func (n Note) OctaveSize() Interval {
+	if n.Divisions <= 0 {
+		return Octave
+	}
+	return Interval(n.Divisions)
+}
+
+// StepCents returns the size in cents of one division of the octave in
+// the system of the note (100 cents for the tempered half-tones).
+func (n Note) StepCents() float64 {
+	return 1200. / float64(n.OctaveSize())
+}
+
+// steps returns the number of divisions from the reference Do0
+func (n Note) steps() int {
+	return n.Octave*int(n.OctaveSize()) + int(n.Index)
+}
+
+// cents returns the position of the note in cents from the reference
+// Do0, including its deviation.
+func (n Note) cents() float64 {
+	return float64(n.steps())*n.StepCents() + n.Cents
+}
+
+// Add shifts the note by the specified interval, counted in number of
+// divisions of the octave of the note system (half-tones by default).
+// The interval can be negative, and the note can go below the Do0 (at
+// negative octaves).
 func (n *Note) Add(interval Interval) {
-	i := Interval(n.Octave*int(Octave) + int(n.Index))
-	i += interval
-	n.Octave = int(i / Octave)
-	n.Index = NoteIndex(i % Octave)
+	size := int(n.OctaveSize())
+	i := n.steps() + int(interval)
+	n.Octave = floorDiv(i, size)
+	n.Index = NoteIndex(floorMod(i, size))
 }
 
+// IntervalTo returns the interval from this note to the other note,
+// counted in number of divisions of the octave of this note system
+// (half-tones by default). The deviations in cents are ignored, except
+// if the other note is defined in another system (the interval is then
+// rounded to the nearest division). Use CentsTo for an exact value.
 func (n Note) IntervalTo(other Note) Interval {
-	interval1 := n.Octave*int(Octave) + int(n.Index) // intervals from the reference Do0
-	interval2 := other.Octave*int(Octave) + int(other.Index)
-	return Interval(interval2 - interval1)
+	if other.OctaveSize() == n.OctaveSize() {
+		return Interval(other.steps() - n.steps())
+	}
+	return Interval(math.Round(n.CentsTo(other) / n.StepCents()))
+}
+
+// CentsTo returns the interval in cents from this note to the other
+// note, including the deviations of both notes.
+func (n Note) CentsTo(other Note) float64 {
+	return other.cents() - n.cents()
 }
 
 func (n Note) Derived(interval Interval) Note {
-	note := Note{n.Octave, n.Index}
+	note := n
 	note.Add(interval)
 	return note
 }
 
+// Frequency returns the frequency of the note in the current tuning
+// system (the tempered equal scale with La3 = 440 Hz by default, see
+// SetTuning).
 func (n Note) Frequency() float64 {
-	interval := La3.IntervalTo(n)
-	logF := logFrequencyLa3 + float64(interval)/float64(Octave)
-	return math.Pow(2, logF)
+	return currentTuning.Frequency(n)
 }
 
-func (n Note) Name() string {
-	var nlabel string
-	for label, index := range label2Index {
-		if index == n.Index {
-			nlabel = label
-			break
+// FrequencyIn returns the frequency of the note in the specified tuning
+// system.
+func (n Note) FrequencyIn(t Tuning) float64 {
+	return t.Frequency(n)
+}
+
+// MIDINumber returns the number of the note in the MIDI convention,
+// where the La3 (A4 in the english convention) is the key 69 and the
+// Do3 (middle C) is the key 60. A note that is not a tempered half-tone
+// gives the number of the nearest half-tone.
+func (n Note) MIDINumber() int {
+	return int(math.Round(La3.CentsTo(n)/100.)) + 69
+}
+
+// NoteFromMIDINumber returns the note of the specified MIDI key number
+func NoteFromMIDINumber(key int) Note {
+	return La3.Derived(Interval(key - 69))
+}
+
+// NearestNote returns the tempered half-tone whose frequency (in the
+// current tuning) is the nearest to the specified frequency, and the
+// deviation in cents of the frequency from this note. It is the
+// function of a tuner: a positive deviation means that the frequency
+// is too high.
+func NearestNote(frequency float64) (Note, float64) {
+	cents := RatioToCents(frequency / La3.Frequency())
+	note := La3.Derived(Interval(math.Round(cents / 100.)))
+	deviation := RatioToCents(frequency / note.Frequency())
+	// With a tuning system other than the tempered scale, the nearest
+	// note may be the neighbour of the tempered nearest note.
+	for _, neighbour := range []Note{note.Derived(-1), note.Derived(1)} {
+		d := RatioToCents(frequency / neighbour.Frequency())
+		if math.Abs(d) < math.Abs(deviation) {
+			note, deviation = neighbour, d
+		}
+	}
+	return note, deviation
+}
+
+// index2Label gives the name of each half-tone of the octave
+var index2Label []string = []string{
+	"Do", "Do#", "Ré", "Ré#", "Mi", "Fa", "Fa#", "Sol", "Sol#", "La", "La#", "Si",
+}
+
+var octavePattern = regexp.MustCompile(`-?[0-9]+$`)
+
+// ParseNote returns the note of the specified name: a note label
+// (french or english, possibly altered with # or b) followed by the
+// octave, e.g. "La3", "Re#2", "Sib1" or "Sol-1". The french names
+// number the octaves as Name does (the La3 is the 440 Hz), the english
+// names follow the scientific numbering: "A4" is the La3 and "C4" the
+// Do3. The alteration keeps the octave of the label: "Dob4" is the Si3.
+// The error wraps ErrUnknownNote if the name is not valid.
+func ParseNote(name string) (Note, error) {
+	loc := octavePattern.FindStringIndex(name)
+	if loc == nil || loc[0] == 0 {
+		return Note{}, fmt.Errorf("%w: the name %q should be a label followed by an octave (e.g. La3)", ErrUnknownNote, name)
+	}
+	octave, err := strconv.Atoi(name[loc[0]:])
+	if err != nil {
+		return Note{}, fmt.Errorf("%w: the octave of %q is not valid", ErrUnknownNote, name)
+	}
+	label := name[:loc[0]]
+	for i, names := range [][]string{frenchRootNames, englishRootNames} {
+		for _, root := range names {
+			alteration, ok := strings.CutPrefix(label, root)
+			if !ok {
+				continue
+			}
+			note := Note{Octave: octave - i, Index: rootIndex(root)}
+			switch alteration {
+			case "":
+				return note, nil
+			case "#":
+				return note.Derived(HalfTone), nil
+			case "b":
+				return note.Derived(-HalfTone), nil
+			}
 		}
 	}
-	return fmt.Sprintf("%s%d", nlabel, n.Octave)
+	return Note{}, fmt.Errorf("%w: the label %q does not exist", ErrUnknownNote, label)
+}
+
+// MustParseNote is like ParseNote but panics if the name is not valid.
+// It simplifies the programs where the names are constants.
+func MustParseNote(name string) Note {
+	n, err := ParseNote(name)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Name returns the name of the note, e.g. "La3". A note that is not a
+// tempered half-tone (because of its deviation in cents, or because it
+// is defined in another system than the 12 half-tones) is named from
+// the nearest half-tone, followed by the deviation in cents, e.g.
+// "La3+50c" for a La3 raised by a quarter-tone.
+func (n Note) Name() string {
+	// A note exactly between two half-tones (a quarter-tone) is named
+	// from the lower half-tone, e.g. La3+50c rather than La#3-50c
+	cents := n.cents()
+	halftones := int(math.Ceil(cents/100. - 0.5))
+	deviation := cents - float64(halftones)*100.
+
+	octave := floorDiv(halftones, int(Octave))
+	label := index2Label[floorMod(halftones, int(Octave))]
+	name := fmt.Sprintf("%s%d", label, octave)
+	if math.Abs(deviation) >= 0.5 {
+		name += fmt.Sprintf("%+.0fc", deviation)
+	}
+	return name
 }
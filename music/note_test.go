@@ -1,9 +1,11 @@
 package music
 
 import (
+	"errors"
 	"math"
 	"testing"
 
+	"github.com/gboulant/musicall"
 	"github.com/gboulant/musicall/sound"
 	"github.com/gboulant/musicall/wave"
 	"github.com/gopxl/beep"
@@ -12,12 +14,15 @@ import (
 const sampleRate = wave.DefaultSampleRate
 
 func init() {
+	// Les sons des tests sont rendus en mémoire, sans carte son (utiliser
+	// MUSICALL_AUDIO=speaker pour les écouter)
+	sound.SetBackend(sound.NewNullBackend())
 	sound.Init(sampleRate)
 }
 
 func TestNote_Interval(t *testing.T) {
-	n1 := Note{3, 9} // La3
-	n2 := Note{0, 0} // Do0
+	n1 := Note{Octave: 3, Index: 9} // La3
+	n2 := Note{Octave: 0, Index: 0} // Do0
 
 	res := n1.IntervalTo(n2)
 	exp := Interval(-45)
@@ -106,7 +111,7 @@ func TestNote_GammeChromatique(t *testing.T) {
 
 	newLabelledNote := func(octave int, label string) labelledNote {
 		return labelledNote{
-			note:  Note{octave, Label2Index(label)},
+			note:  Note{Octave: octave, Index: Label2Index(label)},
 			label: label,
 		}
 	}
@@ -153,11 +158,194 @@ func TestNote_GammeChromatique(t *testing.T) {
 }
 
 func TestNote_Add(t *testing.T) {
-	n := Note{0, 0} // Do0
+	n := Note{Octave: 0, Index: 0} // Do0
 	n.Add(45)
 
-	exp := Note{3, 9} // La3
+	exp := Note{Octave: 3, Index: 9} // La3
 	if n.Octave != exp.Octave || n.Index != exp.Index {
 		t.Errorf("result is %v (should be %v)", n, exp)
 	}
 }
+
+func TestNote_AddNegative(t *testing.T) {
+	tests := []struct {
+		name     string
+		note     Note
+		interval Interval
+		want     Note
+	}{
+		{"La3-45", Note{Octave: 3, Index: 9}, -45, Note{Octave: 0, Index: 0}},
+		{"La3-10", Note{Octave: 3, Index: 9}, -10, Note{Octave: 2, Index: 11}},
+		{"Do0-1", Note{Octave: 0, Index: 0}, -1, Note{Octave: -1, Index: 11}},
+		{"Do0-13", Note{Octave: 0, Index: 0}, -13, Note{Octave: -2, Index: 11}},
+		{"Re0-24", Note{Octave: 0, Index: 2}, -24, Note{Octave: -2, Index: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.note.Derived(tt.interval)
+			if got != tt.want {
+				t.Errorf("result is %v (should be %v)", got, tt.want)
+			}
+			if back := got.IntervalTo(tt.note); back != -tt.interval {
+				t.Errorf("interval back is %d (should be %d)", back, -tt.interval)
+			}
+		})
+	}
+}
+
+func TestNote_EDO(t *testing.T) {
+	// In 24-EDO (quarter-tones), the index 18 is the La
+	La3q := NewEDONote(24, 3, 18)
+	if got := La3q.Frequency(); !almostEqual(got, FrequencyLa3, 1e-9) {
+		t.Errorf("frequency of La3 in 24-EDO is %.3f (should be %.3f)", got, FrequencyLa3)
+	}
+	if got := La3q.Derived(1).Frequency(); !almostEqual(got, FrequencyLa3*CentsToRatio(50), 1e-9) {
+		t.Errorf("frequency of La3 + a quarter-tone is %.3f", got)
+	}
+	if got := La3q.Derived(1).Name(); got != "La3+50c" {
+		t.Errorf("name is %s (should be La3+50c)", got)
+	}
+
+	// In 19-EDO, an octave is 19 steps
+	Do3 := NewEDONote(19, 3, 0)
+	n := Do3.Derived(20)
+	if n.Octave != 4 || n.Index != 1 {
+		t.Errorf("Do3 + 20 steps in 19-EDO is %v (should be octave 4, index 1)", n)
+	}
+	if got := Do3.Derived(-1); got.Octave != 2 || got.Index != 18 {
+		t.Errorf("Do3 - 1 step in 19-EDO is %v (should be octave 2, index 18)", got)
+	}
+	if got := Do3.IntervalTo(n); got != 20 {
+		t.Errorf("interval is %d (should be 20)", got)
+	}
+	ratio := Do3.Derived(19).Frequency() / Do3.Frequency()
+	if !almostEqual(ratio, 2., 1e-9) {
+		t.Errorf("ratio of the 19-EDO octave is %.6f (should be 2)", ratio)
+	}
+
+	// 31-EDO: the interval with a 12-EDO note is rounded to the nearest step
+	Do3_31 := NewEDONote(31, 3, 0)
+	Sol3 := Note{Octave: 3, Index: Label2Index("Sol")}
+	if got := Do3_31.IntervalTo(Sol3); got != 18 {
+		t.Errorf("interval Do3-Sol3 in 31-EDO is %d (should be 18)", got)
+	}
+	if got := Do3_31.CentsTo(Sol3); !almostEqual(got, 700, 1e-9) {
+		t.Errorf("interval Do3-Sol3 is %.3f cents (should be 700)", got)
+	}
+}
+
+func TestNote_Cents(t *testing.T) {
+	n := Note{Octave: 3, Index: 9, Cents: -15}
+	if got := n.Frequency(); !almostEqual(got, FrequencyLa3*CentsToRatio(-15), 1e-9) {
+		t.Errorf("frequency is %.3f (should be %.3f)", got, FrequencyLa3*CentsToRatio(-15))
+	}
+	if got := n.Name(); got != "La3-15c" {
+		t.Errorf("name is %s (should be La3-15c)", got)
+	}
+	// The deviation is kept when adding an interval
+	if got := n.Derived(Octave); got.Cents != -15 || got.Octave != 4 {
+		t.Errorf("derived note is %v (should be La4-15c)", got)
+	}
+	if got := La3.CentsTo(n); !almostEqual(got, -15, 1e-9) {
+		t.Errorf("interval is %.3f cents (should be -15)", got)
+	}
+	if got := n.MIDINumber(); got != 69 {
+		t.Errorf("MIDI number is %d (should be 69)", got)
+	}
+
+	// The deviation flows through the ratio tunings
+	Do3 := Note{Octave: 3, Index: 0}
+	just := NewJustIntonation(Do3, 100.)
+	Sol3 := Note{Octave: 3, Index: 7, Cents: 10}
+	if got := just.Frequency(Sol3); !almostEqual(got, 150.*CentsToRatio(10), 1e-9) {
+		t.Errorf("frequency is %.3f (should be %.3f)", got, 150.*CentsToRatio(10))
+	}
+}
+
+func TestNearestNote(t *testing.T) {
+	tests := []struct {
+		frequency float64
+		want      Note
+		cents     float64
+	}{
+		{440., La3, 0},
+		{445., La3, 19.56},
+		{430., La3, -39.80},
+		{82.41, Note{Octave: 1, Index: Label2Index("Mi")}, 0},
+		{32.703, Note{Octave: 0, Index: 0}, 0},
+		{16.352, Note{Octave: -1, Index: 0}, 0},
+	}
+	for _, tt := range tests {
+		note, cents := NearestNote(tt.frequency)
+		if note != tt.want {
+			t.Errorf("nearest note of %.2f Hz is %s (should be %s)", tt.frequency, note.Name(), tt.want.Name())
+		}
+		if !almostEqual(cents, tt.cents, 0.5) {
+			t.Errorf("deviation of %.2f Hz is %.2f cents (should be %.2f)", tt.frequency, cents, tt.cents)
+		}
+	}
+}
+
+func TestLookupIndex(t *testing.T) {
+	if i, err := LookupIndex("Sol#"); err != nil || i != 8 {
+		t.Errorf("index of Sol# is %d, %v (should be 8)", i, err)
+	}
+	if _, err := LookupIndex("Ra"); !errors.Is(err, ErrUnknownNote) {
+		t.Errorf("error is %v (should be ErrUnknownNote)", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustLookupIndex should panic on an unknown label")
+		}
+	}()
+	MustLookupIndex("Ra")
+}
+
+func TestLabel2Index_LogError(t *testing.T) {
+	// The deprecated Label2Index reports the errors with LogError
+	defer func(f func(string, ...any)) { musicall.LogError = f }(musicall.LogError)
+	logged := 0
+	musicall.LogError = func(format string, v ...any) { logged++ }
+	if i := Label2Index("La"); i != 9 || logged != 0 {
+		t.Errorf("index of La is %d with %d errors (should be 9 without error)", i, logged)
+	}
+	if i := Label2Index("Ra"); i != 0 || logged != 1 {
+		t.Errorf("index of Ra is %d with %d errors (should be 0 with 1 error)", i, logged)
+	}
+}
+
+func TestParseNote(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{"La3", "La3"},
+		{"Ré2", "Ré2"},
+		{"Re#2", "Ré#2"},
+		{"Sib1", "La#1"},
+		{"Dob4", "Si3"},
+		{"Si#3", "Do4"},
+		{"Sol-1", "Sol-1"},
+		{"A4", "La3"},
+		{"C4", "Do3"},
+		{"Eb2", "Ré#1"},
+	}
+	for _, tt := range tests {
+		n, err := ParseNote(tt.name)
+		if err != nil {
+			t.Errorf("parsing %s: %v", tt.name, err)
+			continue
+		}
+		if n.Name() != tt.exp {
+			t.Errorf("note %s is %s (should be %s)", tt.name, n.Name(), tt.exp)
+		}
+	}
+	if n := MustParseNote("A4"); n.Frequency() != FrequencyLa3 {
+		t.Errorf("frequency of A4 is %v (should be %v)", n.Frequency(), FrequencyLa3)
+	}
+	for _, name := range []string{"", "La", "3", "H3", "Lax3", "Do+3"} {
+		if _, err := ParseNote(name); !errors.Is(err, ErrUnknownNote) {
+			t.Errorf("parsing %q should fail with ErrUnknownNote (got %v)", name, err)
+		}
+	}
+}
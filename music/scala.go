@@ -0,0 +1,294 @@
+package music
+
+// This file implements the reading of the Scala files, the format of
+// the tuning software Scala (https://www.huygens-fokker.org/scala/),
+// widely used to share tuning systems:
+//
+// - the scale files (.scl) define the degrees of a scale in cents or
+//   ratios from the tonic,
+// - the keyboard mapping files (.kbm) define how the keys (MIDI note
+//   numbers) are mapped to the degrees of the scale, and the reference
+//   frequency.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ScalaScale is the content of a Scala scale file (.scl). The pitches
+// are the deviations in cents from the tonic of the degrees 1 to N of
+// the scale (the tonic itself, degree 0, is implicit). The last pitch
+// is the period of the scale, usually the octave (1200 cents).
+type ScalaScale struct {
+	Description string
+	Pitches     []float64
+}
+
+// Period returns the interval in cents after which the scale repeats
+func (s ScalaScale) Period() float64 {
+	return s.Pitches[len(s.Pitches)-1]
+}
+
+// degreeCents returns the deviation in cents from the tonic of the
+// specified degree (that could be negative or beyond the period).
+func (s ScalaScale) degreeCents(degree int) float64 {
+	n := len(s.Pitches)
+	periods := floorDiv(degree, n)
+	index := floorMod(degree, n)
+	cents := float64(periods) * s.Period()
+	if index > 0 {
+		cents += s.Pitches[index-1]
+	}
+	return cents
+}
+
+// ParseScala reads a Scala scale file (.scl) from the specified reader
+func ParseScala(r io.Reader) (ScalaScale, error) {
+	lines, err := scalaLines(r)
+	if err != nil {
+		return ScalaScale{}, err
+	}
+	if len(lines) < 2 {
+		return ScalaScale{}, fmt.Errorf("the scala scale is incomplete (%d lines)", len(lines))
+	}
+
+	scale := ScalaScale{Description: lines[0]}
+	count, err := strconv.Atoi(firstField(lines[1]))
+	if err != nil {
+		return ScalaScale{}, fmt.Errorf("the number of notes %q is not valid: %w", lines[1], err)
+	}
+	if count < 1 || len(lines)-2 < count {
+		return ScalaScale{}, fmt.Errorf("the scala scale should have %d pitches (%d found)", count, len(lines)-2)
+	}
+
+	scale.Pitches = make([]float64, count)
+	for i := range count {
+		cents, err := parseScalaPitch(firstField(lines[i+2]))
+		if err != nil {
+			return ScalaScale{}, err
+		}
+		scale.Pitches[i] = cents
+	}
+	return scale, nil
+}
+
+// LoadScala reads the Scala scale file (.scl) at the specified path
+func LoadScala(path string) (ScalaScale, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ScalaScale{}, err
+	}
+	defer f.Close()
+	return ParseScala(f)
+}
+
+// parseScalaPitch converts a pitch of a scala file in cents. A pitch
+// containing a dot is a value in cents, otherwise it is a ratio (e.g.
+// 3/2) or an integer (e.g. 2, that means 2/1).
+func parseScalaPitch(value string) (float64, error) {
+	if strings.Contains(value, ".") {
+		cents, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("the pitch %q is not valid: %w", value, err)
+		}
+		return cents, nil
+	}
+
+	numerator, denominator, isratio := strings.Cut(value, "/")
+	num, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0, fmt.Errorf("the pitch %q is not valid: %w", value, err)
+	}
+	den := 1.
+	if isratio {
+		if den, err = strconv.ParseFloat(denominator, 64); err != nil {
+			return 0, fmt.Errorf("the pitch %q is not valid: %w", value, err)
+		}
+	}
+	if num <= 0 || den <= 0 {
+		return 0, fmt.Errorf("the pitch %q is not a positive ratio", value)
+	}
+	return RatioToCents(num / den), nil
+}
+
+// MaxMappingSize is the maximal size of the pattern of a keyboard
+// mapping (the MIDI keyboards have 128 keys)
+const MaxMappingSize = 1024
+
+// KeyboardMapping is the content of a Scala keyboard mapping file
+// (.kbm). The keys are the MIDI note numbers (see Note.MIDINumber).
+type KeyboardMapping struct {
+	Size               int     // size of the mapping pattern (0 for a linear mapping)
+	FirstKey           int     // first key to retune
+	LastKey            int     // last key to retune
+	MiddleKey          int     // key where the degree 0 of the scale is mapped
+	ReferenceKey       int     // key whose frequency is given
+	ReferenceFrequency float64 // frequency of the reference key
+	OctaveDegree       int     // degree of the scale to consider as the formal octave
+	Mapping            []int   // degree mapped to each key of the pattern (-1 if not mapped)
+}
+
+// DefaultKeyboardMapping is the linear mapping with the tonic of the
+// scale on the Do3 (MIDI 60) and the La3 (MIDI 69) at 440 Hz.
+func DefaultKeyboardMapping() KeyboardMapping {
+	return KeyboardMapping{
+		Size:               0,
+		FirstKey:           0,
+		LastKey:            127,
+		MiddleKey:          60,
+		ReferenceKey:       69,
+		ReferenceFrequency: FrequencyLa3,
+	}
+}
+
+// ParseKeyboardMapping reads a Scala keyboard mapping file (.kbm) from
+// the specified reader.
+func ParseKeyboardMapping(r io.Reader) (KeyboardMapping, error) {
+	lines, err := scalaLines(r)
+	if err != nil {
+		return KeyboardMapping{}, err
+	}
+	if len(lines) < 7 {
+		return KeyboardMapping{}, fmt.Errorf("the keyboard mapping is incomplete (%d lines)", len(lines))
+	}
+
+	values := make([]float64, 7)
+	for i := range values {
+		if values[i], err = strconv.ParseFloat(firstField(lines[i]), 64); err != nil {
+			return KeyboardMapping{}, fmt.Errorf("the value %q of the keyboard mapping is not valid: %w", lines[i], err)
+		}
+	}
+	// La taille est vérifiée avant d'allouer la table: un fichier
+	// corrompu ne doit pas provoquer de panique ni d'allocation énorme.
+	if !(values[0] >= 0 && values[0] <= MaxMappingSize) {
+		return KeyboardMapping{}, fmt.Errorf("the size %v of the keyboard mapping is not valid (0 to %d)", values[0], MaxMappingSize)
+	}
+	kbm := KeyboardMapping{
+		Size:               int(values[0]),
+		FirstKey:           int(values[1]),
+		LastKey:            int(values[2]),
+		MiddleKey:          int(values[3]),
+		ReferenceKey:       int(values[4]),
+		ReferenceFrequency: values[5],
+		OctaveDegree:       int(values[6]),
+	}
+
+	kbm.Mapping = make([]int, kbm.Size)
+	for i := range kbm.Size {
+		if i+7 >= len(lines) {
+			// The missing mapping entries at the end are not mapped
+			kbm.Mapping[i] = -1
+			continue
+		}
+		entry := firstField(lines[i+7])
+		if entry == "x" || entry == "X" {
+			kbm.Mapping[i] = -1
+			continue
+		}
+		if kbm.Mapping[i], err = strconv.Atoi(entry); err != nil {
+			return KeyboardMapping{}, fmt.Errorf("the mapping entry %q is not valid: %w", entry, err)
+		}
+	}
+	return kbm, nil
+}
+
+// LoadKeyboardMapping reads the Scala keyboard mapping file (.kbm) at
+// the specified path.
+func LoadKeyboardMapping(path string) (KeyboardMapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return KeyboardMapping{}, err
+	}
+	defer f.Close()
+	return ParseKeyboardMapping(f)
+}
+
+// degree returns the degree of the scale mapped to the specified key,
+// or false if the key is not mapped.
+func (m KeyboardMapping) degree(key int, scaleSize int) (int, bool) {
+	if key < m.FirstKey || key > m.LastKey {
+		return 0, false
+	}
+	if m.Size == 0 {
+		return key - m.MiddleKey, true
+	}
+	octaveDegree := m.OctaveDegree
+	if octaveDegree == 0 {
+		octaveDegree = scaleSize
+	}
+	repetition := floorDiv(key-m.MiddleKey, m.Size)
+	degree := m.Mapping[floorMod(key-m.MiddleKey, m.Size)]
+	if degree < 0 {
+		return 0, false
+	}
+	return repetition*octaveDegree + degree, true
+}
+
+// ScalaTuning is the tuning system defined by a Scala scale and a
+// keyboard mapping.
+type ScalaTuning struct {
+	Scale   ScalaScale
+	Mapping KeyboardMapping
+}
+
+// NewScalaTuning returns the tuning system defined by the specified
+// Scala scale, with the default keyboard mapping (the tonic on Do3,
+// and La3 = 440 Hz).
+func NewScalaTuning(scale ScalaScale) ScalaTuning {
+	return ScalaTuning{Scale: scale, Mapping: DefaultKeyboardMapping()}
+}
+
+// Frequency returns the frequency of the note, or NaN if the note is
+// not mapped to a degree of the scale by the keyboard mapping.
+func (t ScalaTuning) Frequency(n Note) float64 {
+	size := len(t.Scale.Pitches)
+	key := n.MIDINumber()
+	degree, ok := t.Mapping.degree(key, size)
+	if !ok {
+		return math.NaN()
+	}
+	// The reference key may not be mapped (in this case, we consider
+	// the degree it would have with a linear mapping)
+	refdegree, ok := t.Mapping.degree(t.Mapping.ReferenceKey, size)
+	if !ok {
+		refdegree = t.Mapping.ReferenceKey - t.Mapping.MiddleKey
+	}
+	cents := t.Scale.degreeCents(degree) - t.Scale.degreeCents(refdegree)
+	// The deviation of the note from the key (a microtonal deviation) is
+	// applied to the frequency of the key.
+	cents += NoteFromMIDINumber(key).CentsTo(n)
+	return t.Mapping.ReferenceFrequency * CentsToRatio(cents)
+}
+
+// scalaLines returns the lines of a Scala file, without the comments
+// (lines starting with !).
+func scalaLines(r io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// firstField returns the first field of a line (the text after the
+// value is ignored in the Scala files).
+func firstField(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,167 @@
+package music
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+const scalaPythagorean = `! pyth_12.scl
+!
+12-tone Pythagorean scale
+ 12
+!
+ 256/243
+ 9/8
+ 32/27
+ 81/64
+ 4/3
+ 729/512
+ 3/2
+ 128/81
+ 27/16
+ 16/9
+ 243/128
+ 2/1
+`
+
+const scalaEqual19 = `! 19-EDO
+19 equal divisions of the octave
+19
+`
+
+func TestParseScala(t *testing.T) {
+	scale, err := ParseScala(strings.NewReader(scalaPythagorean))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scale.Description != "12-tone Pythagorean scale" {
+		t.Errorf("description is %q", scale.Description)
+	}
+	if len(scale.Pitches) != 12 {
+		t.Fatalf("number of pitches is %d (should be 12)", len(scale.Pitches))
+	}
+	if !almostEqual(scale.Period(), 1200, 1e-9) {
+		t.Errorf("period is %.3f (should be 1200)", scale.Period())
+	}
+	if !almostEqual(scale.Pitches[6], RatioToCents(3./2.), 1e-9) {
+		t.Errorf("fifth is %.3f cents (should be %.3f)", scale.Pitches[6], RatioToCents(3./2.))
+	}
+
+	// A scale with cents values
+	var b strings.Builder
+	b.WriteString(scalaEqual19)
+	for i := 1; i <= 19; i++ {
+		fmt.Fprintf(&b, " %.5f\n", float64(i)*1200./19.)
+	}
+	scale, err = ParseScala(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scale.Pitches) != 19 || !almostEqual(scale.Period(), 1200, 1e-3) {
+		t.Errorf("19-EDO scale is not correctly read: %v", scale.Pitches)
+	}
+
+	if _, err := ParseScala(strings.NewReader("description\n3\n100.0\n")); err == nil {
+		t.Errorf("an incomplete scale should return an error")
+	}
+	if _, err := ParseScala(strings.NewReader("description\n1\nabc\n")); err == nil {
+		t.Errorf("a scale with an invalid pitch should return an error")
+	}
+}
+
+const kbmWhiteKeys = `! Map a 7 notes scale on the white keys
+12
+0
+127
+60
+69
+440.0
+7
+! mapping
+0
+x
+1
+x
+2
+3
+x
+4
+x
+5
+x
+6
+`
+
+func TestScalaTuning(t *testing.T) {
+	scale, err := ParseScala(strings.NewReader(scalaPythagorean))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the default mapping, the tonic is on Do3 and La3 = 440 Hz
+	tuning := NewScalaTuning(scale)
+	if got := tuning.Frequency(La3); !almostEqual(got, 440, 1e-9) {
+		t.Errorf("frequency of La3 is %.3f (should be 440)", got)
+	}
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+	Sol3 := Do3.Derived(Quinte)
+	ratio := tuning.Frequency(Sol3) / tuning.Frequency(Do3)
+	if !almostEqual(ratio, 3./2., 1e-9) {
+		t.Errorf("ratio of the fifth is %.6f (should be 1.5)", ratio)
+	}
+	ratio = tuning.Frequency(Do3.Derived(Octave)) / tuning.Frequency(Do3)
+	if !almostEqual(ratio, 2., 1e-9) {
+		t.Errorf("ratio of the octave is %.6f (should be 2)", ratio)
+	}
+
+	// The same pythagorean tuning with the RatioTuning
+	pythagorean := NewPythagorean(Do3, tuning.Frequency(Do3))
+	for i := range Octave {
+		n := Do3.Derived(i)
+		if !almostEqual(tuning.Frequency(n), pythagorean.Frequency(n), 1e-9) {
+			t.Errorf("frequency of %s is %.3f (should be %.3f)", n.Name(), tuning.Frequency(n), pythagorean.Frequency(n))
+		}
+	}
+}
+
+func TestParseKeyboardMapping(t *testing.T) {
+	kbm, err := ParseKeyboardMapping(strings.NewReader(kbmWhiteKeys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kbm.Size != 12 || kbm.OctaveDegree != 7 || kbm.ReferenceFrequency != 440 {
+		t.Errorf("keyboard mapping is not correctly read: %+v", kbm)
+	}
+
+	// A just intonation scale on the white keys
+	scl := "just major\n7\n9/8\n5/4\n4/3\n3/2\n5/3\n15/8\n2/1\n"
+	scale, err := ParseScala(strings.NewReader(scl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuning := ScalaTuning{Scale: scale, Mapping: kbm}
+
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+	if got := tuning.Frequency(Do3.Derived(1)); !math.IsNaN(got) {
+		t.Errorf("frequency of Do#3 is %.3f (should be NaN, not mapped)", got)
+	}
+	if got := tuning.Frequency(La3); !almostEqual(got, 440, 1e-9) {
+		t.Errorf("frequency of La3 is %.3f (should be 440)", got)
+	}
+	ratio := tuning.Frequency(Do3.Derived(Octave+4)) / tuning.Frequency(Do3)
+	if !almostEqual(ratio, 5./2., 1e-9) {
+		t.Errorf("ratio of Mi4/Do3 is %.6f (should be 2.5)", ratio)
+	}
+}
+
+func TestParseKeyboardMapping_Size(t *testing.T) {
+	// A malformed size is an error, not a panic
+	for _, size := range []string{"-1", "1e12", "NaN"} {
+		kbm := size + "\n0\n127\n60\n69\n440.0\n12\n"
+		if _, err := ParseKeyboardMapping(strings.NewReader(kbm)); err == nil {
+			t.Errorf("the keyboard mapping of size %s should be rejected", size)
+		}
+	}
+}
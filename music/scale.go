@@ -0,0 +1,183 @@
+package music
+
+import "github.com/gboulant/musicall/wave"
+
+// A scale (gamme) is defined by a tonic note and by a pattern of
+// intervals between its successive degrees. For example the major
+// scale is the pattern T-T-½T-T-T-T-½T, i.e. {2, 2, 1, 2, 2, 2, 1} in
+// number of half-tones. Played from Do, it gives the notes Do, Ré, Mi,
+// Fa, Sol, La, Si, and then Do at the next octave.
+
+// ScalePattern is the sequence of intervals between two successive
+// degrees of a scale. The sum of the intervals is the span of the
+// scale, usually one Octave.
+type ScalePattern []Interval
+
+// Standard scale patterns. The modes of the major scale (Ionian,
+// Dorian, etc.) are the rotations of the major pattern, starting from
+// each of its degrees.
+var (
+	ChromaticPattern ScalePattern = ScalePattern{
+		HalfTone, HalfTone, HalfTone, HalfTone, HalfTone, HalfTone,
+		HalfTone, HalfTone, HalfTone, HalfTone, HalfTone, HalfTone}
+
+	MajorPattern ScalePattern = ScalePattern{Tone, Tone, HalfTone, Tone, Tone, Tone, HalfTone}
+
+	IonianPattern     ScalePattern = MajorPattern.Mode(1)
+	DorianPattern     ScalePattern = MajorPattern.Mode(2)
+	PhrygianPattern   ScalePattern = MajorPattern.Mode(3)
+	LydianPattern     ScalePattern = MajorPattern.Mode(4)
+	MixolydianPattern ScalePattern = MajorPattern.Mode(5)
+	AeolianPattern    ScalePattern = MajorPattern.Mode(6)
+	LocrianPattern    ScalePattern = MajorPattern.Mode(7)
+
+	NaturalMinorPattern  ScalePattern = AeolianPattern
+	HarmonicMinorPattern ScalePattern = ScalePattern{Tone, HalfTone, Tone, Tone, HalfTone, Tone + HalfTone, HalfTone}
+	MelodicMinorPattern  ScalePattern = ScalePattern{Tone, HalfTone, Tone, Tone, Tone, Tone, HalfTone}
+
+	MajorPentatonicPattern ScalePattern = ScalePattern{Tone, Tone, Tone + HalfTone, Tone, Tone + HalfTone}
+	MinorPentatonicPattern ScalePattern = ScalePattern{Tone + HalfTone, Tone, Tone, Tone + HalfTone, Tone}
+	BluesPattern           ScalePattern = ScalePattern{Tone + HalfTone, Tone, HalfTone, HalfTone, Tone + HalfTone, Tone}
+	WholeTonePattern       ScalePattern = ScalePattern{Tone, Tone, Tone, Tone, Tone, Tone}
+)
+
+// Span returns the total interval covered by the pattern, i.e. the
+// interval between the tonic and the tonic of the next repetition of
+// the scale (one Octave for the standard scales).
+func (p ScalePattern) Span() Interval {
+	var span Interval
+	for _, i := range p {
+		span += i
+	}
+	return span
+}
+
+// Mode returns the pattern obtained when starting the scale from the
+// specified degree (1 is the pattern itself). For example the mode 6
+// of the major pattern is the natural minor pattern (Aeolian mode).
+func (p ScalePattern) Mode(degree int) ScalePattern {
+	n := len(p)
+	mode := make(ScalePattern, n)
+	for i := range n {
+		mode[i] = p[floorMod(degree-1+i, n)]
+	}
+	return mode
+}
+
+// offsets returns the intervals from the tonic to each degree of one
+// repetition of the scale (the first one is 0, the tonic itself).
+func (p ScalePattern) offsets() []Interval {
+	offsets := make([]Interval, len(p))
+	var offset Interval
+	for i, step := range p {
+		offsets[i] = offset
+		offset += step
+	}
+	return offsets
+}
+
+// Scale is a scale pattern played from a given tonic note.
+type Scale struct {
+	Tonic   Note
+	Pattern ScalePattern
+}
+
+func NewScale(tonic Note, pattern ScalePattern) Scale {
+	return Scale{Tonic: tonic, Pattern: pattern}
+}
+
+// Len returns the number of degrees in one repetition of the scale
+// (7 for the major scale, 5 for a pentatonic scale, etc.).
+func (s Scale) Len() int {
+	return len(s.Pattern)
+}
+
+// Degree returns the note of the specified degree. The degrees are
+// counted from 1 (the tonic) like musicians do, and continue on the
+// next octaves (degree 8 of a major scale is the tonic one octave
+// higher). The degree 0 and the negative degrees are the notes below
+// the tonic. A scale without pattern has only its tonic.
+func (s Scale) Degree(degree int) Note {
+	n := s.Len()
+	if n == 0 {
+		return s.Tonic
+	}
+	repetition := floorDiv(degree-1, n)
+	position := floorMod(degree-1, n)
+	interval := Interval(repetition)*s.Pattern.Span() + s.Pattern.offsets()[position]
+	return s.Tonic.Derived(interval)
+}
+
+// DegreeOf returns the degree (between 1 and Len) of the specified
+// note in the scale, whatever its octave, or false if the note does
+// not belong to the scale.
+func (s Scale) DegreeOf(note Note) (int, bool) {
+	span := s.Pattern.Span()
+	if span == 0 {
+		return 0, false
+	}
+	interval := floorMod(int(s.Tonic.IntervalTo(note)), int(span))
+	for i, offset := range s.Pattern.offsets() {
+		if Interval(interval) == offset {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// Contains returns true if the specified note belongs to the scale,
+// whatever its octave.
+func (s Scale) Contains(note Note) bool {
+	_, ok := s.DegreeOf(note)
+	return ok
+}
+
+// Transpose returns the same scale starting from a tonic shifted by
+// the specified interval.
+func (s Scale) Transpose(interval Interval) Scale {
+	return Scale{Tonic: s.Tonic.Derived(interval), Pattern: s.Pattern}
+}
+
+// Notes returns the ascending notes of the scale over the specified
+// number of octaves (repetitions of the pattern), including the final
+// tonic. For example, the major scale of Do3 over one octave gives Do3,
+// Ré3, Mi3, Fa3, Sol3, La3, Si3, Do4. A scale without pattern, or a
+// negative number of octaves, gives no notes.
+func (s Scale) Notes(octaves int) []Note {
+	if octaves < 0 || s.Len() == 0 {
+		return nil
+	}
+	notes := make([]Note, octaves*s.Len()+1)
+	for i := range notes {
+		notes[i] = s.Degree(i + 1)
+	}
+	return notes
+}
+
+// Render synthesizes each note of the scale (see Notes) with the
+// specified synthesizer, for the specified duration (in seconds). It
+// returns one signal per note, in the order of the notes.
+func (s Scale) Render(synthesizer wave.HarmonicSynthesizer, duration float64, octaves int) [][]float64 {
+	notes := s.Notes(octaves)
+	signals := make([][]float64, len(notes))
+	for i, note := range notes {
+		synthesizer.SetFrequency(note.Frequency())
+		signals[i] = synthesizer.Synthesize(duration)
+	}
+	return signals
+}
+
+// floorDiv and floorMod are the integer division and modulo rounded
+// toward minus infinity (while the go operators / and % truncate toward
+// zero), so that the result is consistent for negative values.
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int) int {
+	return a - b*floorDiv(a, b)
+}
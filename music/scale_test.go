@@ -0,0 +1,163 @@
+package music
+
+import (
+	"testing"
+
+	"github.com/gboulant/musicall/wave"
+)
+
+func TestScalePattern_Mode(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern ScalePattern
+		want    ScalePattern
+	}{
+		{"Aeolian", AeolianPattern, ScalePattern{2, 1, 2, 2, 1, 2, 2}},
+		{"Dorian", DorianPattern, ScalePattern{2, 1, 2, 2, 2, 1, 2}},
+		{"Locrian", LocrianPattern, ScalePattern{1, 2, 2, 1, 2, 2, 2}},
+		{"MinorPentatonic", MajorPentatonicPattern.Mode(5), MinorPentatonicPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.pattern) != len(tt.want) {
+				t.Fatalf("pattern is %v (should be %v)", tt.pattern, tt.want)
+			}
+			for i := range tt.want {
+				if tt.pattern[i] != tt.want[i] {
+					t.Errorf("pattern is %v (should be %v)", tt.pattern, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	patterns := []ScalePattern{
+		MajorPattern, HarmonicMinorPattern, MelodicMinorPattern,
+		MajorPentatonicPattern, MinorPentatonicPattern,
+		BluesPattern, WholeTonePattern, ChromaticPattern,
+	}
+	for _, p := range patterns {
+		if p.Span() != Octave {
+			t.Errorf("span of %v is %d (should be %d)", p, p.Span(), Octave)
+		}
+	}
+}
+
+func TestScale_Notes(t *testing.T) {
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+	s := NewScale(Do3, MajorPattern)
+
+	notes := s.Notes(1)
+	labels := []string{"Do", "Re", "Mi", "Fa", "Sol", "La", "Si", "Do"}
+	if len(notes) != len(labels) {
+		t.Fatalf("number of notes is %d (should be %d)", len(notes), len(labels))
+	}
+	for i, note := range notes {
+		exp := Note{Octave: 3, Index: Label2Index(labels[i])}
+		if i == len(labels)-1 {
+			exp.Octave = 4
+		}
+		if note != exp {
+			t.Errorf("note %d is %v (should be %v)", i, note, exp)
+		}
+	}
+
+	notes = s.Notes(2)
+	if len(notes) != 15 {
+		t.Errorf("number of notes is %d (should be %d)", len(notes), 15)
+	}
+
+	// No notes for a negative number of octaves or an empty pattern
+	if notes := s.Notes(-1); len(notes) != 0 {
+		t.Errorf("number of notes is %d (should be 0)", len(notes))
+	}
+	empty := NewScale(Do3, ScalePattern{})
+	if notes := empty.Notes(1); len(notes) != 0 {
+		t.Errorf("number of notes is %d (should be 0)", len(notes))
+	}
+	if note := empty.Degree(3); note != Do3 {
+		t.Errorf("degree 3 is %v (should be %v)", note, Do3)
+	}
+	if empty.Contains(Do3) {
+		t.Errorf("the empty scale should not contain %v", Do3)
+	}
+}
+
+func TestScale_Degree(t *testing.T) {
+	La2 := Note{Octave: 2, Index: Label2Index("La")}
+	s := NewScale(La2, MinorPentatonicPattern)
+
+	tests := []struct {
+		degree int
+		want   Note
+	}{
+		{1, La2},
+		{2, Note{Octave: 3, Index: Label2Index("Do")}},
+		{5, Note{Octave: 3, Index: Label2Index("Sol")}},
+		{6, Note{Octave: 3, Index: Label2Index("La")}},
+		{0, Note{Octave: 2, Index: Label2Index("Sol")}},
+		{-4, Note{Octave: 1, Index: Label2Index("La")}},
+	}
+	for _, tt := range tests {
+		if got := s.Degree(tt.degree); got != tt.want {
+			t.Errorf("degree %d is %v (should be %v)", tt.degree, got, tt.want)
+		}
+	}
+}
+
+func TestScale_Contains(t *testing.T) {
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+	s := NewScale(Do3, MajorPattern)
+
+	if !s.Contains(Note{Octave: 5, Index: Label2Index("Fa")}) {
+		t.Errorf("Fa5 should belong to the major scale of Do")
+	}
+	if s.Contains(Note{Octave: 3, Index: Label2Index("Fa#")}) {
+		t.Errorf("Fa#3 should not belong to the major scale of Do")
+	}
+	if d, ok := s.DegreeOf(Note{Octave: 1, Index: Label2Index("Sol")}); !ok || d != 5 {
+		t.Errorf("degree of Sol1 is %d (should be %d)", d, 5)
+	}
+
+	// The major scale of Sol has a Fa# instead of a Fa
+	s = s.Transpose(Quinte)
+	if s.Contains(Note{Octave: 3, Index: Label2Index("Fa")}) {
+		t.Errorf("Fa3 should not belong to the major scale of Sol")
+	}
+	if !s.Contains(Note{Octave: 3, Index: Label2Index("Fa#")}) {
+		t.Errorf("Fa#3 should belong to the major scale of Sol")
+	}
+}
+
+func TestScale_Render(t *testing.T) {
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+	s := NewScale(Do3, BluesPattern)
+
+	d := 0.1
+	synthesizer := wave.NewSineWaveSynthesizer(0., 1., sampleRate)
+	signals := s.Render(synthesizer, d, 1)
+	if len(signals) != s.Len()+1 {
+		t.Fatalf("number of signals is %d (should be %d)", len(signals), s.Len()+1)
+	}
+	explen := int(d * float64(sampleRate))
+	for i, signal := range signals {
+		if len(signal) != explen {
+			t.Errorf("len of signal %d is %d (should be %d)", i, len(signal), explen)
+		}
+	}
+}
+
+func TestScale_EDO(t *testing.T) {
+	// The major scale in 19-EDO (the tone is 3 steps, the half-tone 2)
+	Do3 := NewEDONote(19, 3, 0)
+	s := NewScale(Do3, ScalePattern{3, 3, 2, 3, 3, 3, 2})
+	if got := s.Degree(8); got != NewEDONote(19, 4, 0) {
+		t.Errorf("degree 8 is %v (should be Do4)", got)
+	}
+	if got := s.Degree(5); got != NewEDONote(19, 3, 11) {
+		t.Errorf("degree 5 is %v (should be the index 11)", got)
+	}
+	if !s.Contains(NewEDONote(19, 1, 8)) {
+		t.Errorf("the index 8 (Fa) should belong to the scale")
+	}
+}
@@ -0,0 +1,347 @@
+package music
+
+// This file defines a compact text notation for writing melodies, and
+// its parser (see ParseScore). A score is a sequence of tokens
+// separated by spaces (or new lines):
+//
+//	tempo=90 meter=3/4
+//	mf Do4 q Re4 e Mi4 e | Sol4 h ~ Sol4 q | r q
+//	|: p La3 e Si3 Do4 Re4 :| ff Do5 w
+//
+// The tokens are:
+//
+//   - a note: a note name as read by ParseNote, i.e. a label (french or
+//     english, possibly altered with # or b) followed by its octave,
+//     e.g. Do4, Re#3, Sib2 or A4 (the La3),
+//   - a rest: r,
+//   - a duration, after a note or a rest: w (whole), h (half), q
+//     (quarter), e (eighth), s (sixteenth) or t (thirty-second),
+//     possibly dotted (q. or q..) and/or in a triplet (e3). A note
+//     without duration has the duration of the previous one (a quarter
+//     at the beginning),
+//   - a tie: ~, between two notes of the same pitch, that make a single
+//     note,
+//   - a dynamic: ppp, pp, p, mp, mf, f, ff, fff, that defines the
+//     velocity of the following notes (mf at the beginning),
+//   - a bar line: |, for the readability (the durations of the bars are
+//     not checked),
+//   - a repeat: the part between |: and :| is played twice (from the
+//     beginning, or from the previous :|, if there is no |:),
+//   - a directive: tempo=<bpm> or meter=<beats>/<unit>, that changes the
+//     tempo or the time signature (at the beginning of a bar) of the
+//     tempo map of the score,
+//   - a comment: from % to the end of the line.
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ScoreNote is a note of a score, placed at its position in the score
+type ScoreNote struct {
+	Note     Note
+	Start    Ticks
+	Duration Ticks
+	Velocity float64 // from 0 to 1
+}
+
+// Score is the result of the parsing of a text score: the notes sorted
+// by start time (the rests are the intervals without notes), the
+// duration of the score (including the final rests), and the tempo map
+// defined by its directives (120 BPM in 4/4 by default).
+type Score struct {
+	Notes    []ScoreNote
+	Duration Ticks
+	Tempo    *TempoMap
+}
+
+// Dynamics are the velocities of the dynamic marks, as the MIDI
+// velocities usually associated with them (divided by 127).
+var Dynamics = map[string]float64{
+	"ppp": 16. / 127,
+	"pp":  33. / 127,
+	"p":   49. / 127,
+	"mp":  64. / 127,
+	"mf":  80. / 127,
+	"f":   96. / 127,
+	"ff":  112. / 127,
+	"fff": 1,
+}
+
+// ScoreError is an error of the parsing of a score, located at the
+// token where it occurs (the line and the column start at 1).
+type ScoreError struct {
+	Line   int
+	Column int
+	Token  string
+	Msg    string
+}
+
+func (e *ScoreError) Error() string {
+	return fmt.Sprintf("score line %d, column %d (%q): %s", e.Line, e.Column, e.Token, e.Msg)
+}
+
+type scoreToken struct {
+	text         string
+	line, column int
+}
+
+func (t scoreToken) errorf(format string, args ...any) error {
+	return &ScoreError{Line: t.line, Column: t.column, Token: t.text, Msg: fmt.Sprintf(format, args...)}
+}
+
+// tokenizeScore splits the text in tokens, without the comments
+func tokenizeScore(text string) []scoreToken {
+	var tokens []scoreToken
+	for l, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "%"); i >= 0 {
+			line = line[:i]
+		}
+		runes := []rune(line)
+		for i := 0; i < len(runes); {
+			if unicode.IsSpace(runes[i]) {
+				i++
+				continue
+			}
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, scoreToken{string(runes[start:i]), l + 1, start + 1})
+		}
+	}
+	return tokens
+}
+
+// expandRepeats returns the tokens with the repeated parts written
+// twice. The repeat signs are replaced by bar lines.
+func expandRepeats(tokens []scoreToken) ([]scoreToken, error) {
+	var expanded []scoreToken
+	start := 0 // index in expanded of the beginning of the repeated part
+	open := false
+	for _, t := range tokens {
+		switch t.text {
+		case "|:":
+			if open {
+				return nil, t.errorf("a repeat is already open (the repeats can not be nested)")
+			}
+			open = true
+			expanded = append(expanded, scoreToken{"|", t.line, t.column})
+			start = len(expanded)
+		case ":|", ":|:":
+			part := append([]scoreToken{}, expanded[start:]...)
+			expanded = append(expanded, scoreToken{"|", t.line, t.column})
+			expanded = append(expanded, part...)
+			expanded = append(expanded, scoreToken{"|", t.line, t.column})
+			start = len(expanded)
+			open = t.text == ":|:"
+		default:
+			expanded = append(expanded, t)
+		}
+	}
+	if open {
+		return nil, tokens[len(tokens)-1].errorf("the repeat is not closed with :|")
+	}
+	return expanded, nil
+}
+
+var durationPattern = regexp.MustCompile(`^([whqest])(\.{0,2})(3?)$`)
+
+var durationValues = map[string]Ticks{
+	"w": Whole, "h": Half, "q": Quarter, "e": Eighth, "s": Sixteenth, "t": ThirtySecond,
+}
+
+// parseDuration returns the duration of a duration token
+func parseDuration(text string) (Ticks, bool) {
+	m := durationPattern.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	value := durationValues[m[1]]
+	switch m[2] {
+	case ".":
+		value = value.Dotted()
+	case "..":
+		value = value.DoubleDotted()
+	}
+	if m[3] != "" {
+		value = value.Triplet()
+	}
+	return value, true
+}
+
+// scoreParser holds the state of the parsing. The duration of a note
+// (or a rest) can follow it, then the note is added (flushed) when the
+// next element starts.
+type scoreParser struct {
+	score    Score
+	at       Ticks
+	value    Ticks
+	velocity float64
+
+	pending  *scoreElement // note or rest being read
+	tied     bool          // the last note is tied to the next one
+	tiedFrom scoreToken
+}
+
+type scoreElement struct {
+	token   scoreToken
+	rest    bool
+	note    Note
+	value   Ticks
+	hasTie  bool
+	hasTime bool
+	tie     scoreToken
+}
+
+func (p *scoreParser) flush() error {
+	e := p.pending
+	if e == nil {
+		return nil
+	}
+	p.pending = nil
+	value := p.value
+	if e.hasTime {
+		value = e.value
+	}
+	p.value = value
+
+	switch {
+	case p.tied && e.rest:
+		return e.token.errorf("a rest can not be tied to a note")
+	case p.tied:
+		last := &p.score.Notes[len(p.score.Notes)-1]
+		if last.Note.CentsTo(e.note) != 0 {
+			return e.token.errorf("the tied notes %s and %s should have the same pitch", last.Note.Name(), e.note.Name())
+		}
+		last.Duration += value
+	case !e.rest:
+		p.score.Notes = append(p.score.Notes, ScoreNote{Note: e.note, Start: p.at, Duration: value, Velocity: p.velocity})
+	}
+	p.tied, p.tiedFrom = e.hasTie, e.tie
+	p.at += value
+	return nil
+}
+
+func (p *scoreParser) directive(t scoreToken, name, value string) error {
+	switch name {
+	case "tempo":
+		bpm, err := strconv.ParseFloat(value, 64)
+		if err != nil || bpm <= 0 {
+			return t.errorf("the tempo %q is not a positive number", value)
+		}
+		p.score.Tempo.SetTempo(p.at, bpm)
+	case "meter":
+		beats, unit, ok := strings.Cut(value, "/")
+		b, err1 := strconv.Atoi(beats)
+		u, err2 := strconv.Atoi(unit)
+		if !ok || err1 != nil || err2 != nil {
+			return t.errorf("the meter %q is not a time signature (e.g. 3/4)", value)
+		}
+		pos := p.score.Tempo.Position(p.at)
+		if pos.Beat != 1 || pos.Tick != 0 {
+			return t.errorf("the meter can only change at the beginning of a bar (not at %v)", pos)
+		}
+		// La signature est validée par la carte des tempos (unité
+		// puissance de 2, au plus MaxTimeSignatureUnit)
+		if err := p.score.Tempo.SetTimeSignature(pos.Bar, TimeSignature{Beats: b, Unit: u}); err != nil {
+			return t.errorf("the meter %q is not valid: %v", value, err)
+		}
+	default:
+		return t.errorf("unknown directive %q (should be tempo or meter)", name)
+	}
+	return nil
+}
+
+func (p *scoreParser) parse(t scoreToken) error {
+	if value, ok := parseDuration(t.text); ok {
+		if p.pending == nil {
+			return t.errorf("a duration must follow a note or a rest")
+		}
+		if p.pending.hasTie {
+			return t.errorf("the duration must be written before the tie")
+		}
+		if p.pending.hasTime {
+			return t.errorf("the %s has already a duration", p.pending.token.text)
+		}
+		p.pending.value, p.pending.hasTime = value, true
+		return nil
+	}
+	if t.text == "~" {
+		if p.pending == nil || p.pending.rest || p.pending.hasTie {
+			return t.errorf("a tie must follow a note")
+		}
+		p.pending.hasTie, p.pending.tie = true, t
+		return nil
+	}
+
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if velocity, ok := Dynamics[t.text]; ok {
+		p.velocity = velocity
+		return nil
+	}
+	if p.tied && t.text != "|" {
+		if _, err := ParseNote(t.text); err != nil {
+			return t.errorf("the tie must be followed by a note")
+		}
+	}
+	switch {
+	case t.text == "|":
+		return nil
+	case t.text == "r":
+		p.pending = &scoreElement{token: t, rest: true}
+		return nil
+	case strings.Contains(t.text, "="):
+		name, value, _ := strings.Cut(t.text, "=")
+		return p.directive(t, name, value)
+	}
+	note, err := ParseNote(t.text)
+	if err != nil {
+		return t.errorf("unknown token (should be a note like Do4, a rest r, a duration like q, or a dynamic like mf)")
+	}
+	p.pending = &scoreElement{token: t, note: note}
+	return nil
+}
+
+// ParseScore parses a score written in the text notation of this
+// package (see the beginning of this file). The errors are of type
+// *ScoreError, with the location of the faulty token.
+func ParseScore(text string) (Score, error) {
+	tokens, err := expandRepeats(tokenizeScore(text))
+	if err != nil {
+		return Score{}, err
+	}
+	p := &scoreParser{
+		score:    Score{Tempo: MustNewTempoMap(120, CommonTime)},
+		value:    Quarter,
+		velocity: Dynamics["mf"],
+	}
+	for _, t := range tokens {
+		if err := p.parse(t); err != nil {
+			return Score{}, err
+		}
+	}
+	if err := p.flush(); err != nil {
+		return Score{}, err
+	}
+	if p.tied {
+		return Score{}, p.tiedFrom.errorf("the tie must be followed by a note")
+	}
+	p.score.Duration = p.at
+	return p.score, nil
+}
+
+// MustParseScore is like ParseScore but panics if the score can not be
+// parsed. It simplifies the scores written in the programs.
+func MustParseScore(text string) Score {
+	s, err := ParseScore(text)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
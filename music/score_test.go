@@ -0,0 +1,154 @@
+package music
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseScore(t *testing.T) {
+	s, err := ParseScore("Do4 q Re4 e Mi4 e | Sol4 h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []struct {
+		name            string
+		start, duration Ticks
+	}{
+		{"Do4", 0, Quarter},
+		{"Ré4", Quarter, Eighth},
+		{"Mi4", Quarter + Eighth, Eighth},
+		{"Sol4", Half, Half},
+	}
+	if len(s.Notes) != len(exp) {
+		t.Fatalf("number of notes is %d (should be %d)", len(s.Notes), len(exp))
+	}
+	for i, e := range exp {
+		n := s.Notes[i]
+		if n.Note.Name() != e.name || n.Start != e.start || n.Duration != e.duration {
+			t.Errorf("note %d is %s at %d for %d (should be %s at %d for %d)",
+				i, n.Note.Name(), n.Start, n.Duration, e.name, e.start, e.duration)
+		}
+		if n.Velocity != Dynamics["mf"] {
+			t.Errorf("velocity is %v (should be mf)", n.Velocity)
+		}
+	}
+	if s.Duration != Whole {
+		t.Errorf("duration is %d (should be %d)", s.Duration, Whole)
+	}
+}
+
+func TestParseScore_Elements(t *testing.T) {
+	text := `
+	% Durées implicites, silences, liaisons et nuances
+	tempo=90 meter=3/4
+	p La3 e Si3 Do4 Re4 r q | Sol4 h ~ Sol4 q | ff Sib2 q. A4 e3 Dob4 e3 r e3 r e
+	`
+	s, err := ParseScore(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []struct {
+		name            string
+		start, duration Ticks
+	}{
+		{"La3", 0, Eighth},
+		{"Si3", Eighth, Eighth},
+		{"Do4", Quarter, Eighth},
+		{"Ré4", Quarter + Eighth, Eighth},
+		{"Sol4", Half.Dotted(), Half.Dotted()},
+		{"La#2", Half.Dotted().Times(2), Quarter.Dotted()},
+		{"La3", Half.Dotted().Times(2) + Quarter.Dotted(), Eighth.Triplet()},
+		{"Si3", Half.Dotted().Times(2) + Quarter.Dotted() + Eighth.Triplet(), Eighth.Triplet()},
+	}
+	if len(s.Notes) != len(exp) {
+		t.Fatalf("notes are %v (should be %d notes)", s.Notes, len(exp))
+	}
+	for i, e := range exp {
+		n := s.Notes[i]
+		if n.Note.Name() != e.name || n.Start != e.start || n.Duration != e.duration {
+			t.Errorf("note %d is %s at %d for %d (should be %s at %d for %d)",
+				i, n.Note.Name(), n.Start, n.Duration, e.name, e.start, e.duration)
+		}
+	}
+	if v := s.Notes[0].Velocity; v != Dynamics["p"] {
+		t.Errorf("velocity is %v (should be p)", v)
+	}
+	if v := s.Notes[5].Velocity; v != Dynamics["ff"] {
+		t.Errorf("velocity is %v (should be ff)", v)
+	}
+	if s.Duration != Half.Dotted().Times(3) {
+		t.Errorf("duration is %d (should be 3 bars of 3/4)", s.Duration)
+	}
+	if bpm := s.Tempo.TempoAt(0); bpm != 90 {
+		t.Errorf("tempo is %v (should be 90)", bpm)
+	}
+	if sig := s.Tempo.TimeSignatureAt(0); sig != WaltzTime {
+		t.Errorf("time signature is %v (should be 3/4)", sig)
+	}
+	if sec := s.Tempo.Seconds(s.Duration); math.Abs(sec-6) > 1e-9 {
+		t.Errorf("duration is %v s (should be 6)", sec)
+	}
+}
+
+func TestParseScore_Repeats(t *testing.T) {
+	s, err := ParseScore("Do4 Re4 :| Mi4 |: Fa4 Sol4 :|: La4 :| Si4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"Do4", "Ré4", "Do4", "Ré4", "Mi4", "Fa4", "Sol4", "Fa4", "Sol4", "La4", "La4", "Si4"}
+	if len(s.Notes) != len(exp) {
+		t.Fatalf("number of notes is %d (should be %d)", len(s.Notes), len(exp))
+	}
+	for i, name := range exp {
+		n := s.Notes[i]
+		if n.Note.Name() != name || n.Start != Quarter.Times(i) {
+			t.Errorf("note %d is %s at %d (should be %s at %d)", i, n.Note.Name(), n.Start, name, Quarter.Times(i))
+		}
+	}
+}
+
+func TestParseScore_Errors(t *testing.T) {
+	tests := []struct {
+		text         string
+		line, column int
+	}{
+		{"Do4 q Ré x", 1, 7},
+		{"q Do4", 1, 1},
+		{"Do4 q h", 1, 7},
+		{"Do4 ~ Re4", 1, 7},
+		{"Do4 ~ r", 1, 7},
+		{"Do4 ~", 1, 5},
+		{"r ~", 1, 3},
+		{"Do4 ~ q Do4", 1, 7},
+		{"Do4\n  Re4 |: Mi4 |: Fa4 :|", 2, 14},
+		{"|: Do4", 1, 4},
+		{"tempo=fast Do4", 1, 1},
+		{"meter=3/5", 1, 1},
+		{"meter=4/8192 Do4 q meter=3/4 Re4", 1, 1},
+		{"meter=4/128", 1, 1},
+		{"Do4 meter=3/4", 1, 5},
+		{"key=Do", 1, 1},
+		{"Do4 % Re4 is ignored\nH4", 2, 1},
+	}
+	for _, tt := range tests {
+		_, err := ParseScore(tt.text)
+		var serr *ScoreError
+		if !errors.As(err, &serr) {
+			t.Errorf("parsing %q should fail with a ScoreError (got %v)", tt.text, err)
+			continue
+		}
+		if serr.Line != tt.line || serr.Column != tt.column {
+			t.Errorf("error of %q is at %d:%d (should be at %d:%d): %v", tt.text, serr.Line, serr.Column, tt.line, tt.column, err)
+		}
+	}
+}
+
+func TestMustParseScore(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustParseScore should panic on an invalid score")
+		}
+	}()
+	MustParseScore("Do4 ~ Re4")
+}
@@ -0,0 +1,377 @@
+package music
+
+// This file defines the musical time: the durations are expressed as
+// note values (whole, half, quarter, etc.) instead of seconds, and the
+// positions as bars and beats. The conversion to seconds (or samples)
+// depends on the tempo and on the time signature, that can change
+// along the piece (see TempoMap). Changing the tempo of a song then
+// only needs to change its tempo map.
+//
+// Les durées sont comptées en ticks, une subdivision de la noire
+// (TicksPerQuarter, comme la résolution des fichiers MIDI), de sorte
+// que les durées des triolets et des notes pointées restent exactes.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/gboulant/musicall/wave"
+)
+
+// TicksPerQuarter is the number of ticks of a quarter note. It is
+// divisible by 3 and 5, so that the triplets and the quintuplets of the
+// usual note values are exact.
+const TicksPerQuarter = 960
+
+// Ticks is a musical duration (or a position from the beginning of the
+// piece), in ticks.
+type Ticks int64
+
+// Note values
+const (
+	Whole        Ticks = 4 * TicksPerQuarter // ronde
+	Half         Ticks = 2 * TicksPerQuarter // blanche
+	Quarter      Ticks = TicksPerQuarter     // noire
+	Eighth       Ticks = TicksPerQuarter / 2 // croche
+	Sixteenth    Ticks = TicksPerQuarter / 4 // double croche
+	ThirtySecond Ticks = TicksPerQuarter / 8 // triple croche
+)
+
+// Dotted returns the duration of the dotted note value (one and a half
+// times the value).
+func (t Ticks) Dotted() Ticks {
+	return t * 3 / 2
+}
+
+// DoubleDotted returns the duration of the double dotted note value
+// (1 + 1/2 + 1/4 of the value).
+func (t Ticks) DoubleDotted() Ticks {
+	return t * 7 / 4
+}
+
+// Triplet returns the duration of a note of a triplet: 3 notes in the
+// time of 2.
+func (t Ticks) Triplet() Ticks {
+	return t.Tuplet(3, 2)
+}
+
+// Tuplet returns the duration of a note of a tuplet: n notes in the
+// time of m notes of this value (e.g. Tuplet(5, 4) for a quintuplet).
+func (t Ticks) Tuplet(n, m int) Ticks {
+	return t * Ticks(m) / Ticks(n)
+}
+
+// Times returns the duration of n notes of this value
+func (t Ticks) Times(n int) Ticks {
+	return t * Ticks(n)
+}
+
+// Beats returns the duration in quarter notes
+func (t Ticks) Beats() float64 {
+	return float64(t) / TicksPerQuarter
+}
+
+// BeatsToTicks converts a duration in quarter notes to ticks
+func BeatsToTicks(beats float64) Ticks {
+	return Ticks(math.Round(beats * TicksPerQuarter))
+}
+
+// -------------------------------------------------------------
+// TimeSignature is the time signature (la mesure) of a bar: the number
+// of beats of the bar, and the note value of a beat, given as the
+// denominator (4 for a quarter note, 8 for an eighth note). For
+// example, 3/4 is three quarter notes per bar, and 6/8 is six eighth
+// notes per bar.
+type TimeSignature struct {
+	Beats int
+	Unit  int
+}
+
+// Usual time signatures
+var (
+	CommonTime = TimeSignature{4, 4}
+	WaltzTime  = TimeSignature{3, 4}
+	CutTime    = TimeSignature{2, 2}
+)
+
+// MaxTimeSignatureUnit is the largest note value of a beat (a 64th
+// note), the shortest beat with an exact number of ticks.
+const MaxTimeSignatureUnit = 64
+
+// ErrTimeSignature is the error returned (wrapped) when a time
+// signature is not valid.
+var ErrTimeSignature = errors.New("invalid time signature")
+
+// Check returns an error if the time signature is not valid: the number
+// of beats must be positive, and the unit a power of 2 lower than or
+// equal to MaxTimeSignatureUnit. The signatures read from an input (a
+// file, a score) must be checked before being used.
+func (s TimeSignature) Check() error {
+	if s.Beats <= 0 || s.Unit <= 0 || s.Unit > MaxTimeSignatureUnit || s.Unit&(s.Unit-1) != 0 {
+		return fmt.Errorf("%w: %d/%d", ErrTimeSignature, s.Beats, s.Unit)
+	}
+	return nil
+}
+
+// BeatTicks returns the duration of a beat, or 0 if the signature is
+// not valid (see Check).
+func (s TimeSignature) BeatTicks() Ticks {
+	if s.Check() != nil {
+		return 0
+	}
+	return Whole / Ticks(s.Unit)
+}
+
+// BarTicks returns the duration of a bar
+func (s TimeSignature) BarTicks() Ticks {
+	return s.BeatTicks() * Ticks(s.Beats)
+}
+
+func (s TimeSignature) String() string {
+	return fmt.Sprintf("%d/%d", s.Beats, s.Unit)
+}
+
+// Position is a position in a piece, as displayed by the sequencers:
+// the number of the bar and the number of the beat in this bar (both
+// starting at 1), and the ticks after this beat.
+type Position struct {
+	Bar  int
+	Beat int
+	Tick Ticks
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d.%d.%03d", p.Bar, p.Beat, p.Tick)
+}
+
+// -------------------------------------------------------------
+// TempoMap defines the tempo and the time signature along a piece. The
+// tempo is expressed in beats per minute (BPM), a beat being a quarter
+// note. The tempo can change suddenly (SetTempo), or progressively with
+// a linear ramp (RampTempo, for an accelerando or a ritardando).
+type TempoMap struct {
+	tempos     []TempoChange // sorted by tick, the first one at 0
+	signatures []SignatureChange
+}
+
+// TempoChange is a change of tempo of a tempo map
+type TempoChange struct {
+	At   Ticks
+	BPM  float64
+	Ramp bool // the tempo ramps from the previous change to this one
+}
+
+// SignatureChange is a change of time signature of a tempo map
+type SignatureChange struct {
+	Bar       int   // first bar with this signature
+	At        Ticks // start of this bar
+	Signature TimeSignature
+}
+
+// NewTempoMap returns a tempo map with a constant tempo and time
+// signature. The error wraps ErrTimeSignature if the signature is not
+// valid.
+func NewTempoMap(bpm float64, signature TimeSignature) (*TempoMap, error) {
+	if err := signature.Check(); err != nil {
+		return nil, err
+	}
+	m := &TempoMap{
+		tempos:     []TempoChange{{0, bpm, false}},
+		signatures: []SignatureChange{{1, 0, signature}},
+	}
+	return m, nil
+}
+
+// MustNewTempoMap is like NewTempoMap but panics if the signature is
+// not valid. It simplifies the programs where the signatures are
+// constants.
+func MustNewTempoMap(bpm float64, signature TimeSignature) *TempoMap {
+	m, err := NewTempoMap(bpm, signature)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m *TempoMap) setTempo(change TempoChange) {
+	i := sort.Search(len(m.tempos), func(i int) bool { return m.tempos[i].At >= change.At })
+	if i < len(m.tempos) && m.tempos[i].At == change.At {
+		m.tempos[i] = change
+		return
+	}
+	m.tempos = append(m.tempos, TempoChange{})
+	copy(m.tempos[i+1:], m.tempos[i:])
+	m.tempos[i] = change
+}
+
+// SetTempo changes the tempo at the specified position
+func (m *TempoMap) SetTempo(at Ticks, bpm float64) {
+	m.setTempo(TempoChange{at, bpm, false})
+}
+
+// RampTempo changes the tempo progressively, from the tempo of the
+// previous change, so that the specified tempo is reached at the
+// specified position.
+func (m *TempoMap) RampTempo(at Ticks, bpm float64) {
+	m.setTempo(TempoChange{at, bpm, true})
+}
+
+// SetTimeSignature changes the time signature from the specified bar
+// (starting at 1). The bars after this one keep their signature, but
+// are shifted. The error wraps ErrTimeSignature if the signature is
+// not valid (the tempo map is then unchanged).
+func (m *TempoMap) SetTimeSignature(bar int, signature TimeSignature) error {
+	if err := signature.Check(); err != nil {
+		return err
+	}
+	bar = max(bar, 1)
+	i := sort.Search(len(m.signatures), func(i int) bool { return m.signatures[i].Bar >= bar })
+	if i < len(m.signatures) && m.signatures[i].Bar == bar {
+		m.signatures[i].Signature = signature
+	} else {
+		m.signatures = append(m.signatures, SignatureChange{})
+		copy(m.signatures[i+1:], m.signatures[i:])
+		m.signatures[i] = SignatureChange{Bar: bar, Signature: signature}
+	}
+	// Les positions (en ticks) des changements suivants sont recalculées
+	for j := 1; j < len(m.signatures); j++ {
+		prev := m.signatures[j-1]
+		m.signatures[j].At = prev.At + Ticks(m.signatures[j].Bar-prev.Bar)*prev.Signature.BarTicks()
+	}
+	return nil
+}
+
+// TempoAt returns the tempo (BPM) at the specified position
+func (m *TempoMap) TempoAt(at Ticks) float64 {
+	i := m.segment(at)
+	c := m.tempos[i]
+	if i+1 < len(m.tempos) && m.tempos[i+1].Ramp {
+		next := m.tempos[i+1]
+		x := float64(at-c.At) / float64(next.At-c.At)
+		return c.BPM + x*(next.BPM-c.BPM)
+	}
+	return c.BPM
+}
+
+// segment returns the index of the last tempo change before at
+func (m *TempoMap) segment(at Ticks) int {
+	i := sort.Search(len(m.tempos), func(i int) bool { return m.tempos[i].At > at })
+	return max(i-1, 0)
+}
+
+// segmentSeconds returns the time in seconds between the change i and
+// the position at (in the segment of the change i).
+//
+// Pendant une rampe linéaire du tempo b(x) = b0 + (b1-b0)·x/L, la durée
+// d'un tick est 60/(TicksPerQuarter·b(x)), et son intégrale de 0 à x
+// vaut 60·L/(TicksPerQuarter·(b1-b0))·ln(b(x)/b0).
+func (m *TempoMap) segmentSeconds(i int, at Ticks) float64 {
+	c := m.tempos[i]
+	x := float64(at - c.At)
+	if i+1 < len(m.tempos) && m.tempos[i+1].Ramp && m.tempos[i+1].BPM != c.BPM {
+		next := m.tempos[i+1]
+		length := float64(next.At - c.At)
+		slope := (next.BPM - c.BPM) / length
+		return 60 / (TicksPerQuarter * slope) * math.Log((c.BPM+slope*x)/c.BPM)
+	}
+	return 60 * x / (TicksPerQuarter * c.BPM)
+}
+
+// Seconds returns the time in seconds of the specified position
+func (m *TempoMap) Seconds(at Ticks) float64 {
+	seconds := 0.
+	i := m.segment(at)
+	for j := range i {
+		seconds += m.segmentSeconds(j, m.tempos[j+1].At)
+	}
+	return seconds + m.segmentSeconds(i, at)
+}
+
+// Duration returns the duration in seconds of a note value played at
+// the specified position. This is the duration expected by the
+// synthesizers (e.g. guitar.Guitar.Pluck).
+func (m *TempoMap) Duration(at Ticks, value Ticks) float64 {
+	return m.Seconds(at+value) - m.Seconds(at)
+}
+
+// Samples returns the number of samples at the specified sample rate
+// from the beginning of the piece to the specified position.
+func (m *TempoMap) Samples(at Ticks, sampleRate int) int {
+	return int(math.Round(m.Seconds(at) * float64(wave.SampleRate(sampleRate))))
+}
+
+// TickAt returns the position at the specified time in seconds (the
+// inverse of Seconds), rounded to the nearest tick.
+func (m *TempoMap) TickAt(seconds float64) Ticks {
+	start := 0.
+	for i, c := range m.tempos {
+		end := math.Inf(1)
+		if i+1 < len(m.tempos) {
+			end = start + m.segmentSeconds(i, m.tempos[i+1].At)
+		}
+		if seconds < end {
+			dt := seconds - start
+			if i+1 < len(m.tempos) && m.tempos[i+1].Ramp && m.tempos[i+1].BPM != c.BPM {
+				next := m.tempos[i+1]
+				slope := (next.BPM - c.BPM) / float64(next.At-c.At)
+				x := c.BPM * (math.Exp(dt*TicksPerQuarter*slope/60) - 1) / slope
+				return c.At + Ticks(math.Round(x))
+			}
+			return c.At + Ticks(math.Round(dt*TicksPerQuarter*c.BPM/60))
+		}
+		start = end
+	}
+	return 0
+}
+
+// Tempos returns the changes of tempo, the first one being at 0
+func (m *TempoMap) Tempos() []TempoChange {
+	return slices.Clone(m.tempos)
+}
+
+// TimeSignatures returns the changes of time signature, the first one
+// being at the bar 1.
+func (m *TempoMap) TimeSignatures() []SignatureChange {
+	return slices.Clone(m.signatures)
+}
+
+// TimeSignatureAt returns the time signature at the specified position
+func (m *TempoMap) TimeSignatureAt(at Ticks) TimeSignature {
+	return m.signatures[m.signatureSegment(at)].Signature
+}
+
+func (m *TempoMap) signatureSegment(at Ticks) int {
+	i := sort.Search(len(m.signatures), func(i int) bool { return m.signatures[i].At > at })
+	return max(i-1, 0)
+}
+
+// Position returns the bar, the beat and the ticks of the specified
+// position.
+func (m *TempoMap) Position(at Ticks) Position {
+	s := m.signatures[m.signatureSegment(at)]
+	offset := at - s.At
+	bars := offset / s.Signature.BarTicks()
+	offset -= bars * s.Signature.BarTicks()
+	beats := offset / s.Signature.BeatTicks()
+	return Position{
+		Bar:  s.Bar + int(bars),
+		Beat: int(beats) + 1,
+		Tick: offset - beats*s.Signature.BeatTicks(),
+	}
+}
+
+// At returns the position in ticks of the specified bar and beat (both
+// starting at 1), plus the specified ticks.
+func (m *TempoMap) At(bar int, beat int, tick Ticks) Ticks {
+	i := sort.Search(len(m.signatures), func(i int) bool { return m.signatures[i].Bar > bar })
+	s := m.signatures[max(i-1, 0)]
+	return s.At + Ticks(bar-s.Bar)*s.Signature.BarTicks() + Ticks(beat-1)*s.Signature.BeatTicks() + tick
+}
+
+// Bar returns the position in ticks of the beginning of the bar
+func (m *TempoMap) Bar(bar int) Ticks {
+	return m.At(bar, 1, 0)
+}
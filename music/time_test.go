@@ -0,0 +1,191 @@
+package music
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestTicks_NoteValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Ticks
+		exp   Ticks
+	}{
+		{"whole", Whole, 3840},
+		{"dotted quarter", Quarter.Dotted(), 1440},
+		{"double dotted half", Half.DoubleDotted(), 3360},
+		{"eighth triplet", Eighth.Triplet(), 320},
+		{"quarter quintuplet", Quarter.Tuplet(5, 4), 768},
+		{"sixteenth sextuplet", Sixteenth.Tuplet(6, 4), 160},
+		{"3 eighth triplets", Eighth.Triplet().Times(3), Quarter},
+	}
+	for _, tt := range tests {
+		if tt.value != tt.exp {
+			t.Errorf("%s is %d ticks (should be %d)", tt.name, tt.value, tt.exp)
+		}
+	}
+	if b := Half.Dotted().Beats(); b != 3 {
+		t.Errorf("dotted half is %v beats (should be 3)", b)
+	}
+	if v := BeatsToTicks(0.5); v != Eighth {
+		t.Errorf("0.5 beat is %d ticks (should be %d)", v, Eighth)
+	}
+}
+
+func TestTimeSignature(t *testing.T) {
+	tests := []struct {
+		signature TimeSignature
+		beat, bar Ticks
+		name      string
+	}{
+		{CommonTime, Quarter, Whole, "4/4"},
+		{WaltzTime, Quarter, Half.Dotted(), "3/4"},
+		{TimeSignature{6, 8}, Eighth, Half.Dotted(), "6/8"},
+		{CutTime, Half, Whole, "2/2"},
+	}
+	for _, tt := range tests {
+		if tt.signature.BeatTicks() != tt.beat || tt.signature.BarTicks() != tt.bar {
+			t.Errorf("%v: beat %d, bar %d (should be %d, %d)", tt.signature, tt.signature.BeatTicks(), tt.signature.BarTicks(), tt.beat, tt.bar)
+		}
+		if tt.signature.String() != tt.name {
+			t.Errorf("name is %s (should be %s)", tt.signature, tt.name)
+		}
+	}
+}
+
+func TestTimeSignature_Check(t *testing.T) {
+	for _, s := range []TimeSignature{{0, 4}, {-3, 4}, {3, 0}, {3, 6}, {4, 128}, {4, 8192}, {4, -4}} {
+		if err := s.Check(); !errors.Is(err, ErrTimeSignature) {
+			t.Errorf("error of %v is %v (should be ErrTimeSignature)", s, err)
+		}
+		if s.BeatTicks() != 0 {
+			t.Errorf("beat of %v is %d ticks (should be 0)", s, s.BeatTicks())
+		}
+	}
+	if err := (TimeSignature{7, 64}).Check(); err != nil {
+		t.Errorf("the signature 7/64 should be valid: %v", err)
+	}
+
+	// An invalid signature is rejected by the tempo map
+	if _, err := NewTempoMap(120, TimeSignature{4, 8192}); !errors.Is(err, ErrTimeSignature) {
+		t.Errorf("error is %v (should be ErrTimeSignature)", err)
+	}
+	m := MustNewTempoMap(120, CommonTime)
+	if err := m.SetTimeSignature(2, TimeSignature{3, 0}); !errors.Is(err, ErrTimeSignature) {
+		t.Errorf("error is %v (should be ErrTimeSignature)", err)
+	}
+	if n := len(m.TimeSignatures()); n != 1 {
+		t.Errorf("number of signatures is %d (should be 1)", n)
+	}
+	if p := m.Position(3 * Whole); p != (Position{4, 1, 0}) {
+		t.Errorf("position is %v (should be 4.1.000)", p)
+	}
+}
+
+func TestTempoMap_Constant(t *testing.T) {
+	m := MustNewTempoMap(120, CommonTime)
+	if s := m.Seconds(Whole); s != 2 {
+		t.Errorf("a whole note at 120 BPM is %v s (should be 2)", s)
+	}
+	if d := m.Duration(Whole, Eighth.Triplet()); !almostEqual(d, 1./6, 1e-12) {
+		t.Errorf("an eighth triplet is %v s (should be %v)", d, 1./6)
+	}
+	if n := m.Samples(Quarter, 44100); n != 22050 {
+		t.Errorf("a quarter is %d samples (should be 22050)", n)
+	}
+	if tick := m.TickAt(1.5); tick != Quarter.Times(3) {
+		t.Errorf("1.5 s is %d ticks (should be %d)", tick, Quarter.Times(3))
+	}
+}
+
+func TestTempoMap_Changes(t *testing.T) {
+	m := MustNewTempoMap(120, CommonTime)
+	m.SetTempo(Whole, 60)
+	if bpm := m.TempoAt(Whole - 1); bpm != 120 {
+		t.Errorf("tempo before the change is %v (should be 120)", bpm)
+	}
+	if bpm := m.TempoAt(Whole); bpm != 60 {
+		t.Errorf("tempo after the change is %v (should be 60)", bpm)
+	}
+	// 2 s for the first bar, then 4 s for the second one
+	if s := m.Seconds(2 * Whole); !almostEqual(s, 6, 1e-12) {
+		t.Errorf("time of bar 3 is %v s (should be 6)", s)
+	}
+	if tick := m.TickAt(4); tick != Whole+Half {
+		t.Errorf("4 s is %d ticks (should be %d)", tick, Whole+Half)
+	}
+}
+
+func TestTempoMap_Ramp(t *testing.T) {
+	m := MustNewTempoMap(60, CommonTime)
+	m.RampTempo(Whole, 120)
+	if bpm := m.TempoAt(Half); !almostEqual(bpm, 90, 1e-12) {
+		t.Errorf("tempo in the middle of the ramp is %v (should be 90)", bpm)
+	}
+	if bpm := m.TempoAt(2 * Whole); bpm != 120 {
+		t.Errorf("tempo after the ramp is %v (should be 120)", bpm)
+	}
+	// The duration of the ramp is the integral of 60/bpm over 4 beats:
+	// 4·60/(120-60)·ln(120/60) = 4·ln(2)
+	exp := 4 * math.Ln2
+	if s := m.Seconds(Whole); !almostEqual(s, exp, 1e-12) {
+		t.Errorf("duration of the ramp is %v s (should be %v)", s, exp)
+	}
+	// Numerical check by summing the duration of each tick
+	sum := 0.
+	for tick := Ticks(0); tick < Whole; tick++ {
+		sum += 60 / (TicksPerQuarter * m.TempoAt(tick))
+	}
+	if !almostEqual(sum, exp, 1e-3) {
+		t.Errorf("numerical duration of the ramp is %v s (should be %v)", sum, exp)
+	}
+	if s := m.Seconds(Whole + Quarter); !almostEqual(s, exp+0.5, 1e-12) {
+		t.Errorf("time after the ramp is %v s (should be %v)", s, exp+0.5)
+	}
+	// TickAt is the inverse of Seconds
+	for _, tick := range []Ticks{0, 100, Half, Whole - 1, Whole, 3 * Whole} {
+		if got := m.TickAt(m.Seconds(tick)); got != tick {
+			t.Errorf("TickAt(Seconds(%d)) is %d", tick, got)
+		}
+	}
+}
+
+func TestTempoMap_Position(t *testing.T) {
+	m := MustNewTempoMap(100, CommonTime)
+	m.SetTimeSignature(3, WaltzTime)
+	m.SetTimeSignature(5, TimeSignature{6, 8})
+
+	tests := []struct {
+		tick Ticks
+		pos  Position
+	}{
+		{0, Position{1, 1, 0}},
+		{Quarter + 10, Position{1, 2, 10}},
+		{2 * Whole, Position{3, 1, 0}},
+		{2*Whole + Half.Dotted() + Quarter, Position{4, 2, 0}},
+		{2*Whole + 2*Half.Dotted(), Position{5, 1, 0}},
+		{2*Whole + 2*Half.Dotted() + 5*Eighth, Position{5, 6, 0}},
+		{2*Whole + 3*Half.Dotted(), Position{6, 1, 0}},
+	}
+	for _, tt := range tests {
+		if p := m.Position(tt.tick); p != tt.pos {
+			t.Errorf("position of %d is %v (should be %v)", tt.tick, p, tt.pos)
+		}
+		if tick := m.At(tt.pos.Bar, tt.pos.Beat, tt.pos.Tick); tick != tt.tick {
+			t.Errorf("ticks of %v is %d (should be %d)", tt.pos, tick, tt.tick)
+		}
+	}
+	if s := m.TimeSignatureAt(m.Bar(4)); s != WaltzTime {
+		t.Errorf("signature of bar 4 is %v (should be 3/4)", s)
+	}
+	if p := (Position{12, 3, 5}).String(); p != "12.3.005" {
+		t.Errorf("position is %s (should be 12.3.005)", p)
+	}
+
+	// Changing a signature shifts the following bars
+	m.SetTimeSignature(3, CommonTime)
+	if tick := m.Bar(5); tick != 4*Whole {
+		t.Errorf("bar 5 is at %d ticks (should be %d)", tick, 4*Whole)
+	}
+}
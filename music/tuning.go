@@ -0,0 +1,179 @@
+package music
+
+import (
+	"math"
+)
+
+// A tuning system (tempérament) defines how the frequency of each note
+// is calculated. The default tuning system is the equal temperament
+// (tempérament égal), where the ratio of frequencies between two
+// successive notes is constant (2^1/12), anchored on the reference
+// frequency La3 = 440 Hz. The other tuning systems define the notes of
+// an octave with ratios from a tonic note, for example the just
+// intonation (gamme naturelle, based on the ratios of small integers)
+// or the pythagorean tuning (gamme de Pythagore, based on a chain of
+// perfect fifths 3/2).
+
+// Tuning is the interface of the tuning systems, i.e. the functions
+// that compute the frequency of a note.
+type Tuning interface {
+	Frequency(n Note) float64
+}
+
+// currentTuning is the tuning used by the function Note.Frequency
+var currentTuning Tuning = NewEqualTemperament(FrequencyLa3)
+
+// SetTuning changes the tuning system used by Note.Frequency (and then
+// by all the functions that compute the frequency of a note, for
+// example guitar.Note.Frequency).
+func SetTuning(t Tuning) {
+	currentTuning = t
+}
+
+// CurrentTuning returns the tuning system used by Note.Frequency
+func CurrentTuning() Tuning {
+	return currentTuning
+}
+
+// -------------------------------------------------------------
+// Equal temperament
+
+// EqualTemperament is the tempered equal scale, anchored on the
+// frequency of a reference note (La3 = 440 Hz by default, but some
+// musicians prefer 432 Hz or 442 Hz).
+type EqualTemperament struct {
+	Reference          Note
+	ReferenceFrequency float64
+}
+
+// NewEqualTemperament returns the equal temperament with the specified
+// reference pitch, i.e. the frequency of the La3.
+func NewEqualTemperament(frequencyLa3 float64) EqualTemperament {
+	return EqualTemperament{Reference: La3, ReferenceFrequency: frequencyLa3}
+}
+
+// Frequency computes the frequency of the note from its interval with
+// the reference note:
+//
+//	log2(Frequency) = log2(ReferenceFrequency) + interval/12
+//
+// i.e. Frequency = ReferenceFrequency * 2^(interval/12). The interval is
+// computed in cents (1200 by octave), so that the notes of any N-EDO
+// system and the deviations in cents are taken into account.
+func (t EqualTemperament) Frequency(n Note) float64 {
+	return t.ReferenceFrequency * CentsToRatio(t.Reference.CentsTo(n))
+}
+
+// -------------------------------------------------------------
+// Tunings defined by ratios from a tonic note
+
+// RatioTuning is a tuning system where the frequency of each note of
+// an octave is defined by its ratio with the frequency of the tonic
+// note. The ratios are given for each index of the octave counted from
+// the tonic (Ratios[0] is 1, the tonic itself). The ratio of the notes
+// of the other octaves are multiplied by a power of 2. The number of
+// ratios is the number of divisions of the octave in the system of the
+// tonic (12 by default).
+type RatioTuning struct {
+	Tonic          Note
+	TonicFrequency float64
+	Ratios         []float64
+}
+
+func (t RatioTuning) Frequency(n Note) float64 {
+	interval := int(t.Tonic.IntervalTo(n))
+	octaves := floorDiv(interval, len(t.Ratios))
+	index := floorMod(interval, len(t.Ratios))
+	// The deviation in cents that is not counted in the interval (a
+	// microtonal deviation) is applied to the frequency of the note.
+	deviation := t.Tonic.CentsTo(n) - float64(interval)*t.Tonic.StepCents()
+	return t.TonicFrequency * t.Ratios[index] * math.Pow(2, float64(octaves)) * CentsToRatio(deviation)
+}
+
+// NewJustIntonation returns the just intonation (gamme naturelle, or
+// gamme de Zarlino) played from the specified tonic. The ratios are
+// the ratios of small integers (5-limit): the major third is 5/4, the
+// fifth is 3/2, etc.
+func NewJustIntonation(tonic Note, tonicFrequency float64) RatioTuning {
+	return RatioTuning{
+		Tonic:          tonic,
+		TonicFrequency: tonicFrequency,
+		Ratios: []float64{
+			1., 16. / 15., 9. / 8., 6. / 5., 5. / 4., 4. / 3.,
+			45. / 32., 3. / 2., 8. / 5., 5. / 3., 9. / 5., 15. / 8.,
+		},
+	}
+}
+
+// NewPythagorean returns the pythagorean tuning (gamme de Pythagore)
+// played from the specified tonic. All the notes are obtained with a
+// chain of perfect fifths (ratio 3/2), from 5 fifths below the tonic
+// to 6 fifths above the tonic, and brought back in the octave of the
+// tonic.
+func NewPythagorean(tonic Note, tonicFrequency float64) RatioTuning {
+	return RatioTuning{
+		Tonic:          tonic,
+		TonicFrequency: tonicFrequency,
+		Ratios:         chainOfFifths(3./2., -5),
+	}
+}
+
+// NewMeantone returns the quarter-comma meantone tuning (tempérament
+// mésotonique) played from the specified tonic. The fifths are
+// narrowed so that four fifths make a pure major third (5/4), i.e. a
+// fifth ratio of 5^(1/4). The chain of fifths goes from 3 fifths below
+// the tonic to 8 fifths above the tonic.
+func NewMeantone(tonic Note, tonicFrequency float64) RatioTuning {
+	return RatioTuning{
+		Tonic:          tonic,
+		TonicFrequency: tonicFrequency,
+		Ratios:         chainOfFifths(math.Pow(5, 0.25), -3),
+	}
+}
+
+// NewCentsTuning returns a tuning defined by a table of deviations in
+// cents from the tonic (100 cents is a tempered half-tone, 1200 cents
+// is an octave). The table must give the deviation of each index of
+// the octave counted from the tonic (the first value is usually 0).
+func NewCentsTuning(tonic Note, tonicFrequency float64, cents []float64) RatioTuning {
+	ratios := make([]float64, len(cents))
+	for i, c := range cents {
+		ratios[i] = CentsToRatio(c)
+	}
+	return RatioTuning{Tonic: tonic, TonicFrequency: tonicFrequency, Ratios: ratios}
+}
+
+// chainOfFifths computes the ratios of the notes of an octave obtained
+// by stacking 12 fifths of the specified ratio, starting from lowest
+// fifths (negative value means below the tonic).
+func chainOfFifths(fifth float64, lowest int) []float64 {
+	ratios := make([]float64, Octave)
+	for k := lowest; k < lowest+int(Octave); k++ {
+		ratio := math.Pow(fifth, float64(k))
+		// We normalize the ratio (by multiplying or dividing by 2) to
+		// stay in the octave [1, 2[
+		for ratio >= 2. {
+			ratio /= 2.
+		}
+		for ratio < 1. {
+			ratio *= 2.
+		}
+		index := floorMod(k*int(Quinte), int(Octave))
+		ratios[index] = ratio
+	}
+	return ratios
+}
+
+// -------------------------------------------------------------
+// Cents
+
+// RatioToCents converts a ratio of frequencies to a number of cents
+// (1200 cents by octave).
+func RatioToCents(ratio float64) float64 {
+	return 1200. * math.Log2(ratio)
+}
+
+// CentsToRatio converts a number of cents to a ratio of frequencies
+func CentsToRatio(cents float64) float64 {
+	return math.Pow(2, cents/1200.)
+}
@@ -0,0 +1,90 @@
+package music
+
+import (
+	"testing"
+)
+
+func TestEqualTemperament(t *testing.T) {
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+
+	tuning := NewEqualTemperament(FrequencyLa3)
+	if got := tuning.Frequency(Do3); !almostEqual(got, 261.626, 1e-3) {
+		t.Errorf("frequency of Do3 is %.3f (should be %.3f)", got, 261.626)
+	}
+
+	tuning = NewEqualTemperament(432)
+	if got := tuning.Frequency(La3); got != 432 {
+		t.Errorf("frequency of La3 is %.3f (should be %.3f)", got, 432.)
+	}
+	if got := tuning.Frequency(La3.Derived(Octave)); got != 864 {
+		t.Errorf("frequency of La4 is %.3f (should be %.3f)", got, 864.)
+	}
+}
+
+func TestSetTuning(t *testing.T) {
+	defer SetTuning(CurrentTuning())
+
+	SetTuning(NewEqualTemperament(442))
+	if got := La3.Frequency(); got != 442 {
+		t.Errorf("frequency of La3 is %.3f (should be %.3f)", got, 442.)
+	}
+	if got := La3.FrequencyIn(NewEqualTemperament(FrequencyLa3)); got != FrequencyLa3 {
+		t.Errorf("frequency of La3 is %.3f (should be %.3f)", got, FrequencyLa3)
+	}
+}
+
+func TestRatioTuning(t *testing.T) {
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+	f := Do3.Frequency()
+
+	tests := []struct {
+		name   string
+		tuning Tuning
+		note   Note
+		ratio  float64
+	}{
+		{"just third", NewJustIntonation(Do3, f), Do3.Derived(4), 5. / 4.},
+		{"just fifth", NewJustIntonation(Do3, f), Do3.Derived(Quinte), 3. / 2.},
+		{"just octave", NewJustIntonation(Do3, f), Do3.Derived(Octave + 4), 5. / 2.},
+		{"just below", NewJustIntonation(Do3, f), Do3.Derived(-Octave + Quinte), 3. / 4.},
+		{"pythagorean fifth", NewPythagorean(Do3, f), Do3.Derived(Quinte), 3. / 2.},
+		{"pythagorean third", NewPythagorean(Do3, f), Do3.Derived(4), 81. / 64.},
+		{"pythagorean fourth", NewPythagorean(Do3, f), Do3.Derived(5), 4. / 3.},
+		{"pythagorean half-tone", NewPythagorean(Do3, f), Do3.Derived(1), 256. / 243.},
+		{"meantone third", NewMeantone(Do3, f), Do3.Derived(4), 5. / 4.},
+		{"cents", NewCentsTuning(Do3, f, []float64{0, 100, 200, 300, 400, 500, 600, 700, 800, 900, 1000, 1100}), Do3.Derived(Quinte), CentsToRatio(700)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tuning.Frequency(tt.note) / f
+			if !almostEqual(got, tt.ratio, 1e-9) {
+				t.Errorf("ratio is %.6f (should be %.6f)", got, tt.ratio)
+			}
+		})
+	}
+}
+
+func TestCents(t *testing.T) {
+	if got := RatioToCents(2.); !almostEqual(got, 1200., 1e-9) {
+		t.Errorf("cents of the octave is %.3f (should be 1200)", got)
+	}
+	if got := RatioToCents(3. / 2.); !almostEqual(got, 701.955, 1e-3) {
+		t.Errorf("cents of the fifth is %.3f (should be 701.955)", got)
+	}
+	if got := CentsToRatio(RatioToCents(5. / 4.)); !almostEqual(got, 1.25, 1e-9) {
+		t.Errorf("ratio is %.6f (should be 1.25)", got)
+	}
+}
+
+func TestNote_MIDINumber(t *testing.T) {
+	Do3 := Note{Octave: 3, Index: Label2Index("Do")}
+	if got := Do3.MIDINumber(); got != 60 {
+		t.Errorf("MIDI number of Do3 is %d (should be 60)", got)
+	}
+	if got := La3.MIDINumber(); got != 69 {
+		t.Errorf("MIDI number of La3 is %d (should be 69)", got)
+	}
+	if got := NoteFromMIDINumber(64); got != (Note{Octave: 3, Index: Label2Index("Mi")}) {
+		t.Errorf("note of MIDI number 64 is %v (should be Mi3)", got)
+	}
+}
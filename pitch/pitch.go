@@ -0,0 +1,70 @@
+package pitch
+
+// This package provides functions to estimate the fundamental frequency
+// (the pitch) of a signal, and then the music note that is played. It
+// can be used to verify the synthesizers or to build a tuner.
+//
+// Two methods are implemented:
+//
+// - the YIN algorithm (de Cheveigné and Kawahara, 2002), based on the
+//   autocorrelation of the signal in the time domain, that is robust
+//   for the harmonic sounds (guitar, voice) whose fundamental is not
+//   always the frequency of maximal amplitude,
+// - the search of the peak of maximal amplitude in the spectrum of the
+//   signal, refined with a parabolic interpolation between the
+//   frequency bins.
+
+import (
+	"github.com/gboulant/musicall/music"
+)
+
+// Result is the result of a pitch detection
+type Result struct {
+	Frequency  float64    // estimated fundamental frequency in Hz (0 if no pitch detected)
+	Confidence float64    // confidence of the estimation, between 0 (no pitch) and 1
+	Note       music.Note // nearest music note of the frequency
+	Cents      float64    // deviation of the frequency from the note in cents
+}
+
+// Detector defines the parameters of the pitch detection
+type Detector struct {
+	SampleRate   int
+	MinFrequency float64 // minimal frequency to search (Hz)
+	MaxFrequency float64 // maximal frequency to search (Hz)
+	Threshold    float64 // threshold of the YIN algorithm (typically between 0.1 and 0.2)
+}
+
+// NewDetector creates a detector with default parameters, suitable for
+// the range of a guitar (from 40 Hz to 2 kHz).
+func NewDetector(sampleRate int) *Detector {
+	return &Detector{
+		SampleRate:   sampleRate,
+		MinFrequency: 40.,
+		MaxFrequency: 2000.,
+		Threshold:    0.15,
+	}
+}
+
+// Detect estimates the fundamental frequency of the signal with the
+// YIN algorithm, and returns it with the nearest music note. The
+// signal should contain at least two periods of the minimal frequency
+// of the detector.
+func (d Detector) Detect(samples []float64) Result {
+	frequency, confidence := d.YIN(samples)
+	if frequency <= 0 {
+		return Result{}
+	}
+	note, cents := music.NearestNote(frequency)
+	return Result{
+		Frequency:  frequency,
+		Confidence: confidence,
+		Note:       note,
+		Cents:      cents,
+	}
+}
+
+// Detect estimates the fundamental frequency of the signal sampled at
+// the specified sample rate, with the default parameters of NewDetector.
+func Detect(samples []float64, sampleRate int) Result {
+	return NewDetector(sampleRate).Detect(samples)
+}
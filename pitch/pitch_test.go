@@ -0,0 +1,136 @@
+package pitch
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/wave"
+)
+
+func almostEqual(a, b float64, accuracy float64) bool {
+	return math.Abs(a-b) < accuracy
+}
+
+// harmonicSignal creates a signal with a fundamental and some
+// harmonics, where the fundamental is not the harmonic of maximal
+// amplitude (as for a plucked string).
+func harmonicSignal(f float64, d float64, r int) []float64 {
+	s := wave.SineWaveSignal(f, 0.4, d, r)
+	s2 := wave.SineWaveSignal(2*f, 1., d, r)
+	s3 := wave.SineWaveSignal(3*f, 0.6, d, r)
+	for i := range s {
+		s[i] += s2[i] + s3[i]
+	}
+	return s
+}
+
+func TestDetector_YIN(t *testing.T) {
+	r := wave.DefaultSampleRate
+	d := NewDetector(r)
+	for _, f := range []float64{82.41, 110., 196., 440., 1318.5} {
+		s := wave.SineWaveSignal(f, 1., 0.1, r)
+		res, confidence := d.YIN(s)
+		if !almostEqual(res, f, f*1e-3) {
+			t.Errorf("frequency is %.3f (should be %.3f)", res, f)
+		}
+		if confidence < 0.9 {
+			t.Errorf("confidence is %.3f (should be > 0.9)", confidence)
+		}
+
+		s = harmonicSignal(f, 0.1, r)
+		res, _ = d.YIN(s)
+		if !almostEqual(res, f, f*1e-3) {
+			t.Errorf("frequency of the harmonic signal is %.3f (should be %.3f)", res, f)
+		}
+	}
+}
+
+func TestDetector_YINSilence(t *testing.T) {
+	r := wave.DefaultSampleRate
+	d := NewDetector(r)
+	s := make([]float64, r/10)
+	res, confidence := d.YIN(s)
+	if res != 0 || confidence != 0 {
+		t.Errorf("result on silence is (%.3f, %.3f) (should be (0, 0))", res, confidence)
+	}
+
+	// The signal is too short for the minimal frequency
+	res, _ = d.YIN(s[:10])
+	if res != 0 {
+		t.Errorf("frequency is %.3f (should be 0)", res)
+	}
+}
+
+func TestDetector_SpectrumPeak(t *testing.T) {
+	r := wave.DefaultSampleRate
+	d := NewDetector(r)
+	for _, f := range []float64{110., 440., 443.7} {
+		s := wave.SineWaveSignal(f, 0.5, 0.2, r)
+		res, amplitude := d.SpectrumPeak(s)
+		// The resolution without interpolation would be 5 Hz
+		if !almostEqual(res, f, 0.1) {
+			t.Errorf("frequency is %.3f (should be %.3f)", res, f)
+		}
+		if !almostEqual(amplitude, 0.5, 0.02) {
+			t.Errorf("amplitude is %.3f (should be %.3f)", amplitude, 0.5)
+		}
+	}
+
+	// The peak of the harmonic signal is the second harmonic
+	f := 200.
+	s := harmonicSignal(f, 0.2, r)
+	res, _ := d.SpectrumPeak(s)
+	if !almostEqual(res, 2*f, 0.1) {
+		t.Errorf("frequency is %.3f (should be %.3f)", res, 2*f)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	r := wave.DefaultSampleRate
+	tests := []struct {
+		frequency float64
+		note      music.Note
+		cents     float64
+	}{
+		{440., music.La3, 0.},
+		{music.La3.Derived(-29).Frequency(), music.Note{Octave: 1, Index: 4}, 0.}, // Mi1
+		{445., music.La3, 19.56},
+		{430., music.La3, -39.80},
+	}
+	for _, tt := range tests {
+		s := harmonicSignal(tt.frequency, 0.1, r)
+		res := Detect(s, r)
+		if !almostEqual(res.Frequency, tt.frequency, tt.frequency*1e-3) {
+			t.Errorf("frequency is %.3f (should be %.3f)", res.Frequency, tt.frequency)
+		}
+		if res.Note != tt.note {
+			t.Errorf("note is %s (should be %s)", res.Note.Name(), tt.note.Name())
+		}
+		if !almostEqual(res.Cents, tt.cents, 2.) {
+			t.Errorf("cents is %.2f (should be %.2f)", res.Cents, tt.cents)
+		}
+	}
+
+	res := Detect(make([]float64, r/10), r)
+	if res.Frequency != 0 || res.Confidence != 0 {
+		t.Errorf("result on silence is %v (should be empty)", res)
+	}
+}
+
+func TestDetector_YINPluck(t *testing.T) {
+	// During the attack of a plucked string, the YIN algorithm could
+	// find twice the period (an error of one octave)
+	r := wave.DefaultSampleRate
+	d := NewDetector(r)
+	for _, f := range []float64{82.41, 110.} {
+		s := wave.NewKarplusStrongSynthesizer(f, 1., r).Synthesize(0.2)
+		res, _ := d.YIN(s[:r/10])
+		// The period of the Karplus-Strong synthesizer is an integer
+		// number of samples, plus a half sample for the average filter
+		exp := float64(r) / (math.Floor(float64(r)/f) + 0.5)
+		if !almostEqual(res, exp, 0.1) {
+			t.Errorf("frequency of the pluck is %.3f (should be %.3f)", res, exp)
+		}
+	}
+}
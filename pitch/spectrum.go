@@ -0,0 +1,53 @@
+package pitch
+
+import (
+	"math"
+
+	"github.com/gboulant/musicall/fft"
+)
+
+// SpectrumPeak returns the frequency of maximal amplitude in the
+// spectrum of the signal, between the minimal and maximal frequencies
+// of the detector, with its amplitude.
+//
+// The frequency resolution of the spectrum is r/N (where r is the
+// sample rate and N the number of samples). To get a better precision,
+// the signal is weighted by a Hann window (to reduce the spectral
+// leakage), zero-padded up to a power of 2 (at least 4 times the
+// number of samples), and the position of the peak is refined with a
+// parabolic interpolation of the logarithm of the amplitudes around
+// the frequency bin of maximal amplitude.
+func (d Detector) SpectrumPeak(samples []float64) (frequency, amplitude float64) {
+	n := len(samples)
+	if n < 4 {
+		return 0, 0
+	}
+
+	// Le spectre est corrigé du gain de la fenêtre et du bourrage de
+	// zéros: l'amplitude est celle du signal d'origine.
+	frequencies, amplitudes := fft.Spectrum(samples, d.SampleRate, fft.SpectrumOptions{
+		Window: fft.Hann,
+		Size:   fft.NextPowerOfTwo(4 * n),
+	})
+	imax := -1
+	for i, f := range frequencies {
+		if f < d.MinFrequency || f > d.MaxFrequency {
+			continue
+		}
+		if imax < 0 || amplitudes[i] > amplitudes[imax] {
+			imax = i
+		}
+	}
+	if imax <= 0 || imax >= len(amplitudes)-1 || amplitudes[imax] == 0 {
+		return 0, 0
+	}
+
+	a := math.Log(amplitudes[imax-1] + 1e-300)
+	b := math.Log(amplitudes[imax])
+	c := math.Log(amplitudes[imax+1] + 1e-300)
+	offset := parabolicOffset(a, b, c)
+	step := frequencies[1] - frequencies[0]
+	frequency = frequencies[imax] + offset*step
+	amplitude = math.Exp(b - 0.25*(a-c)*offset)
+	return frequency, amplitude
+}
@@ -0,0 +1,128 @@
+package pitch
+
+import "math"
+
+// YIN estimates the fundamental frequency of the signal using the YIN
+// algorithm. It returns the frequency (0 if no periodicity is found)
+// and a confidence value between 0 and 1.
+//
+// The principle is to search the smallest lag tau (the period) for
+// which the signal is similar to itself shifted by tau. The similarity
+// is measured by the difference function:
+//
+//	d(tau) = sum_j (x[j] - x[j+tau])^2
+//
+// normalized by its cumulative mean (so that the lag 0 is not the
+// minimum):
+//
+//	d'(tau) = d(tau) * tau / sum_{k=1..tau} d(k)
+//
+// The period is the first lag where d' goes below the threshold (or the
+// global minimum if there is none), refined by a parabolic
+// interpolation. The confidence is 1 - d'(period).
+func (d Detector) YIN(samples []float64) (frequency, confidence float64) {
+	window := len(samples) / 2
+	tauMin := int(float64(d.SampleRate) / d.MaxFrequency)
+	tauMax := int(float64(d.SampleRate) / d.MinFrequency)
+	if tauMin < 2 {
+		tauMin = 2
+	}
+	if tauMax > window {
+		tauMax = window
+	}
+	if tauMax <= tauMin {
+		return 0, 0
+	}
+
+	// Difference function
+	diff := make([]float64, tauMax+1)
+	for tau := 1; tau <= tauMax; tau++ {
+		var sum float64
+		for j := range window {
+			delta := samples[j] - samples[j+tau]
+			sum += delta * delta
+		}
+		diff[tau] = sum
+	}
+
+	// Cumulative mean normalized difference function
+	cmnd := make([]float64, tauMax+1)
+	cmnd[0] = 1
+	var cumulative float64
+	for tau := 1; tau <= tauMax; tau++ {
+		cumulative += diff[tau]
+		if cumulative == 0 {
+			// The signal is constant (silence)
+			cmnd[tau] = 1
+		} else {
+			cmnd[tau] = diff[tau] * float64(tau) / cumulative
+		}
+	}
+
+	// Absolute threshold: first dip below the threshold, followed down to
+	// its local minimum. If there is none, we take the global minimum.
+	period := -1
+	for tau := tauMin; tau < tauMax; tau++ {
+		if cmnd[tau] < d.Threshold {
+			for tau+1 < tauMax && cmnd[tau+1] < cmnd[tau] {
+				tau++
+			}
+			period = tau
+			break
+		}
+	}
+	if period < 0 {
+		period = tauMin
+		for tau := tauMin; tau < tauMax; tau++ {
+			if cmnd[tau] < cmnd[period] {
+				period = tau
+			}
+		}
+	}
+	if cmnd[period] >= 1 {
+		return 0, 0
+	}
+	period = d.correctOctave(cmnd, period, tauMin)
+
+	refined := float64(period) + parabolicOffset(cmnd[period-1], cmnd[period], cmnd[period+1])
+	confidence = math.Max(0, math.Min(1, 1-cmnd[period]))
+	return float64(d.SampleRate) / refined, confidence
+}
+
+// correctOctave checks if a sub-multiple of the period (half, third or
+// quarter) is nearly as good as the period. This occurs during the
+// attack of a plucked string, where the high harmonics decay quickly so
+// that the signal is not yet periodic enough to go below the threshold
+// at the true period, but goes below it at twice the period (an error
+// of one octave). The shortest good candidate is preferred.
+func (d Detector) correctOctave(cmnd []float64, period int, tauMin int) int {
+	for k := 4; k >= 2; k-- {
+		candidate := int(math.Round(float64(period) / float64(k)))
+		if candidate-1 < tauMin {
+			continue
+		}
+		// Search the local minimum around the candidate
+		best := candidate
+		for tau := candidate - 1; tau <= candidate+1; tau++ {
+			if cmnd[tau] < cmnd[best] {
+				best = tau
+			}
+		}
+		if cmnd[best] < cmnd[period]+d.Threshold {
+			return best
+		}
+	}
+	return period
+}
+
+// parabolicOffset returns the position (relative to the central point,
+// between -0.5 and 0.5) of the extremum of the parabola passing through
+// the three points (-1, a), (0, b) and (1, c).
+func parabolicOffset(a, b, c float64) float64 {
+	denominator := a - 2*b + c
+	if denominator == 0 {
+		return 0
+	}
+	offset := 0.5 * (a - c) / denominator
+	return math.Max(-0.5, math.Min(0.5, offset))
+}
@@ -0,0 +1,66 @@
+package sequencer
+
+import (
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/sound"
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/effects"
+)
+
+// Pitch is the pitch of a note: a music.Note, a guitar.Note, or a
+// frequency in Hz (see Hz).
+type Pitch interface {
+	Frequency() float64
+}
+
+// Hz is a pitch defined directly by its frequency in Hz
+type Hz float64
+
+func (h Hz) Frequency() float64 {
+	return float64(h)
+}
+
+// Instrument plays the notes of the events. The velocity is the
+// intensity of the note, between 0 and 1. The streamer returned by
+// Play should last the duration of the note (in seconds).
+type Instrument interface {
+	Play(pitch Pitch, velocity float64, duration float64) beep.Streamer
+}
+
+// InstrumentFunc is a function that implements the Instrument interface
+type InstrumentFunc func(pitch Pitch, velocity float64, duration float64) beep.Streamer
+
+func (f InstrumentFunc) Play(pitch Pitch, velocity float64, duration float64) beep.Streamer {
+	return f(pitch, velocity, duration)
+}
+
+// Synth returns an instrument that plays the notes with the harmonic
+// synthesizer. The amplitude of the signal is multiplied by the
+// velocity.
+func Synth(s wave.HarmonicSynthesizer) Instrument {
+	return InstrumentFunc(func(pitch Pitch, velocity float64, duration float64) beep.Streamer {
+		s.SetFrequency(pitch.Frequency())
+		samples := s.Synthesize(duration)
+		for i := range samples {
+			samples[i] *= velocity
+		}
+		return sound.NewSound(samples)
+	})
+}
+
+// Guitar returns an instrument that plays the notes with the guitar. A
+// guitar.Note is plucked on its string (with the tuning and the stereo
+// spread of the guitar), the other pitches are plucked at their
+// frequency.
+func Guitar(g *guitar.Guitar) Instrument {
+	return InstrumentFunc(func(pitch Pitch, velocity float64, duration float64) beep.Streamer {
+		var s beep.Streamer
+		if note, ok := pitch.(guitar.Note); ok {
+			s = g.Pluck(note, duration)
+		} else {
+			s = g.PluckFrequency(pitch.Frequency(), duration)
+		}
+		return &effects.Gain{Streamer: s, Gain: velocity - 1}
+	})
+}
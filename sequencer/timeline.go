@@ -0,0 +1,278 @@
+package sequencer
+
+// This package implements a sequencer: the notes are events placed at
+// absolute times on a timeline, instead of being chained with beep.Seq
+// and silences. The notes can overlap, and an event can be moved or
+// removed without changing the others. The timeline is rendered into a
+// single streamer, each note being placed at the exact sample of its
+// start time.
+//
+// The times and durations are expressed in seconds, or in beats (quarter
+// notes) converted to seconds with the tempo map of the timeline, that
+// can define tempo changes and ramps (see music.TempoMap).
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/sound"
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+)
+
+// Unit is the unit of the times and durations of a timeline
+type Unit int
+
+const (
+	Seconds Unit = iota
+	Beats
+)
+
+// DefaultTempo is the tempo of a new timeline, in beats per minute
+const DefaultTempo = 120.
+
+// Event is a note placed on the timeline: the instrument plays the
+// pitch at the time Time, during the duration Duration (both in the
+// unit of the timeline), with the velocity Velocity (between 0 and 1).
+type Event struct {
+	Time       float64
+	Duration   float64
+	Instrument string
+	Pitch      Pitch
+	Velocity   float64
+}
+
+// noteKey identifies a note started by NoteOn and not yet stopped
+type noteKey struct {
+	instrument string
+	frequency  float64
+}
+
+// Timeline is a sequence of events at absolute times
+type Timeline struct {
+	SampleRate int
+	Unit       Unit
+	Tempo      *music.TempoMap // for the unit Beats
+
+	events      []Event
+	instruments map[string]Instrument
+	open        map[noteKey]Event
+}
+
+// NewTimeline returns an empty timeline whose times are expressed in
+// the specified unit.
+func NewTimeline(sampleRate int, unit Unit) *Timeline {
+	return &Timeline{
+		SampleRate:  wave.SampleRate(sampleRate),
+		Unit:        unit,
+		Tempo:       music.MustNewTempoMap(DefaultTempo, music.CommonTime),
+		instruments: make(map[string]Instrument),
+		open:        make(map[noteKey]Event),
+	}
+}
+
+// SetInstrument defines the instrument used for the events with the
+// specified instrument name.
+func (t *Timeline) SetInstrument(name string, i Instrument) {
+	t.instruments[name] = i
+}
+
+// Add adds an event to the timeline
+func (t *Timeline) Add(e Event) {
+	t.events = append(t.events, e)
+}
+
+// Note adds the note played by the instrument at the specified time and
+// during the specified duration.
+func (t *Timeline) Note(time float64, duration float64, instrument string, pitch Pitch, velocity float64) {
+	t.Add(Event{Time: time, Duration: duration, Instrument: instrument, Pitch: pitch, Velocity: velocity})
+}
+
+// Score adds the notes of a score (see music.ParseScore) played by the
+// instrument, from the specified time. With the unit Beats, the notes
+// follow the tempo map of the timeline; with the unit Seconds, their
+// times are computed with the tempo map of the score.
+func (t *Timeline) Score(time float64, instrument string, s music.Score) {
+	for _, n := range s.Notes {
+		if t.Unit == Beats {
+			t.Note(time+n.Start.Beats(), n.Duration.Beats(), instrument, n.Note, n.Velocity)
+			continue
+		}
+		t.Note(time+s.Tempo.Seconds(n.Start), s.Tempo.Duration(n.Start, n.Duration), instrument, n.Note, n.Velocity)
+	}
+}
+
+// NoteOn starts a note played by the instrument at the specified time.
+// The note is added to the timeline when it is stopped by NoteOff.
+func (t *Timeline) NoteOn(time float64, instrument string, pitch Pitch, velocity float64) {
+	key := noteKey{instrument, pitch.Frequency()}
+	t.open[key] = Event{Time: time, Instrument: instrument, Pitch: pitch, Velocity: velocity}
+}
+
+// NoteOff stops the note started by NoteOn with the same instrument and
+// pitch, and adds it to the timeline. It returns an error if this note
+// has not been started.
+func (t *Timeline) NoteOff(time float64, instrument string, pitch Pitch) error {
+	key := noteKey{instrument, pitch.Frequency()}
+	e, ok := t.open[key]
+	if !ok {
+		return fmt.Errorf("the note %.2f Hz of %q is not started", key.frequency, instrument)
+	}
+	if time < e.Time {
+		return fmt.Errorf("the note %.2f Hz of %q stops before its start", key.frequency, instrument)
+	}
+	delete(t.open, key)
+	e.Duration = time - e.Time
+	t.Add(e)
+	return nil
+}
+
+// Events returns the events of the timeline sorted by time
+func (t *Timeline) Events() []Event {
+	events := slices.Clone(t.events)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time < events[j].Time
+	})
+	return events
+}
+
+// Seconds converts a time of the timeline in seconds
+func (t *Timeline) Seconds(time float64) float64 {
+	if t.Unit == Beats {
+		return t.Tempo.Seconds(music.BeatsToTicks(time))
+	}
+	return time
+}
+
+// Sample converts a time of the timeline in a number of samples
+func (t *Timeline) Sample(time float64) int {
+	return int(math.Round(t.Seconds(time) * float64(t.SampleRate)))
+}
+
+// Duration returns the end of the last event, in seconds
+func (t *Timeline) Duration() float64 {
+	end := 0.
+	for _, e := range t.events {
+		end = math.Max(end, t.Seconds(e.Time+e.Duration))
+	}
+	return end
+}
+
+// Streamer returns a streamer that plays the events of the timeline.
+// The notes are synthesized by their instrument when the streamer
+// reaches their start time. It returns an error if an instrument is
+// not defined, or if some notes are started and not stopped.
+func (t *Timeline) Streamer() (beep.Streamer, error) {
+	if len(t.open) > 0 {
+		return nil, fmt.Errorf("%d notes are started and not stopped", len(t.open))
+	}
+	seq := &sequence{}
+	for _, e := range t.Events() {
+		i, ok := t.instruments[e.Instrument]
+		if !ok {
+			return nil, fmt.Errorf("the instrument %q is not defined", e.Instrument)
+		}
+		if e.Time < 0 || e.Duration < 0 {
+			return nil, fmt.Errorf("the event at %v has a negative time or duration", e.Time)
+		}
+		start := t.Sample(e.Time)
+		frames := t.Sample(e.Time+e.Duration) - start
+		seq.events = append(seq.events, scheduled{e, i, start, frames, t.Seconds(e.Time+e.Duration) - t.Seconds(e.Time)})
+		seq.end = max(seq.end, start+frames)
+	}
+	return seq, nil
+}
+
+// Render renders the timeline into a recording
+func (t *Timeline) Render() (sound.Recording, error) {
+	s, err := t.Streamer()
+	if err != nil {
+		return sound.Recording{}, err
+	}
+	return sound.Render(s, t.SampleRate)
+}
+
+// -------------------------------------------------------------
+// sequence is the streamer that plays the events of a timeline
+
+type scheduled struct {
+	event      Event
+	instrument Instrument
+	start      int     // first sample
+	frames     int     // number of samples
+	duration   float64 // duration in seconds
+}
+
+type voice struct {
+	streamer beep.Streamer
+	start    int
+}
+
+type sequence struct {
+	events   []scheduled // sorted by start
+	next     int         // index of the next event to start
+	voices   []voice
+	position int // number of samples already streamed
+	end      int
+	buffer   [][2]float64
+	err      error
+}
+
+func (s *sequence) Stream(samples [][2]float64) (int, bool) {
+	if s.position >= s.end {
+		return 0, false
+	}
+	n := min(len(samples), s.end-s.position)
+	samples = samples[:n]
+	for i := range samples {
+		samples[i] = [2]float64{}
+	}
+
+	// Les notes qui commencent dans ce bloc sont synthétisées, et
+	// tronquées à leur durée (au nombre d'échantillons près).
+	for s.next < len(s.events) && s.events[s.next].start < s.position+n {
+		e := s.events[s.next]
+		st := e.instrument.Play(e.event.Pitch, e.event.Velocity, e.duration)
+		s.voices = append(s.voices, voice{beep.Take(e.frames, st), e.start})
+		s.next++
+	}
+
+	if cap(s.buffer) < n {
+		s.buffer = make([][2]float64, n)
+	}
+	active := s.voices[:0]
+	for _, v := range s.voices {
+		offset := max(0, v.start-s.position)
+		done := false
+		for filled := 0; offset+filled < n; {
+			buffer := s.buffer[:n-offset-filled]
+			vn, ok := v.streamer.Stream(buffer)
+			for i := range buffer[:vn] {
+				samples[offset+filled+i][0] += buffer[i][0]
+				samples[offset+filled+i][1] += buffer[i][1]
+			}
+			filled += vn
+			if !ok || vn == 0 {
+				done = true
+				break
+			}
+		}
+		if done {
+			if err := v.streamer.Err(); err != nil && s.err == nil {
+				s.err = err
+			}
+			continue
+		}
+		active = append(active, v)
+	}
+	s.voices = active
+	s.position += n
+	return n, true
+}
+
+func (s *sequence) Err() error {
+	return s.err
+}
@@ -0,0 +1,208 @@
+package sequencer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/guitar"
+	"github.com/gboulant/musicall/music"
+	"github.com/gboulant/musicall/sound"
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+)
+
+const sampleRate = 8000
+
+// constantInstrument plays a constant signal whose value is the
+// frequency divided by 1000, multiplied by the velocity.
+var constantInstrument = InstrumentFunc(func(pitch Pitch, velocity float64, duration float64) beep.Streamer {
+	samples := make([]float64, int(duration*sampleRate)+10) // longer than the note
+	for i := range samples {
+		samples[i] = velocity * pitch.Frequency() / 1000
+	}
+	return sound.NewSound(samples)
+})
+
+func almostEqual(a, b float64, accuracy float64) bool {
+	return math.Abs(a-b) < accuracy
+}
+
+func TestTimeline_Seconds(t *testing.T) {
+	tl := NewTimeline(sampleRate, Beats)
+	tl.Tempo = music.MustNewTempoMap(90, music.CommonTime)
+	if s := tl.Seconds(3); !almostEqual(s, 2, 1e-12) {
+		t.Errorf("3 beats at 90 bpm is %v s (should be 2)", s)
+	}
+	if n := tl.Sample(1.5); n != sampleRate {
+		t.Errorf("1.5 beats at 90 bpm is %d samples (should be %d)", n, sampleRate)
+	}
+	// The tempo doubles after the first bar
+	tl.Tempo.SetTempo(music.Whole, 180)
+	if s := tl.Seconds(6); !almostEqual(s, 4*60./90+2*60./180, 1e-12) {
+		t.Errorf("6 beats is %v s (should be %v)", s, 4*60./90+2*60./180)
+	}
+
+	tl.Unit = Seconds
+	if n := tl.Sample(0.25); n != sampleRate/4 {
+		t.Errorf("0.25 s is %d samples (should be %d)", n, sampleRate/4)
+	}
+}
+
+func TestTimeline_Render(t *testing.T) {
+	tl := NewTimeline(sampleRate, Seconds)
+	tl.SetInstrument("const", constantInstrument)
+	// Two overlapping notes, added in the reverse order
+	tl.Note(0.5, 0.5, "const", Hz(200), 1)
+	tl.Note(0.25, 0.5, "const", Hz(100), 0.5)
+
+	if d := tl.Duration(); d != 1 {
+		t.Errorf("duration is %v (should be 1)", d)
+	}
+	rec, err := tl.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != sampleRate {
+		t.Fatalf("number of frames is %d (should be %d)", rec.Frames(), sampleRate)
+	}
+	// The notes start and stop at the exact samples
+	for i, v := range rec.Channels[0] {
+		exp := 0.
+		if i >= 2000 && i < 6000 {
+			exp += 0.05
+		}
+		if i >= 4000 {
+			exp += 0.2
+		}
+		if !almostEqual(v, exp, 1e-12) {
+			t.Fatalf("sample %d is %v (should be %v)", i, v, exp)
+		}
+	}
+
+	events := tl.Events()
+	if len(events) != 2 || events[0].Time != 0.25 {
+		t.Errorf("events should be sorted by time: %v", events)
+	}
+}
+
+func TestTimeline_Beats(t *testing.T) {
+	tl := NewTimeline(sampleRate, Beats)
+	tl.SetInstrument("const", constantInstrument)
+	tl.Note(1, 1, "const", Hz(500), 1) // from 0.5 s to 1 s at 120 bpm
+	rec, err := tl.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != sampleRate {
+		t.Fatalf("number of frames is %d (should be %d)", rec.Frames(), sampleRate)
+	}
+	if rec.Channels[0][3999] != 0 || rec.Channels[0][4000] != 0.5 {
+		t.Errorf("note should start at the sample 4000")
+	}
+}
+
+func TestTimeline_Score(t *testing.T) {
+	score := music.MustParseScore("tempo=60 f La3 h r q | pp Do4 e")
+	for _, unit := range []Unit{Seconds, Beats} {
+		tl := NewTimeline(sampleRate, unit)
+		tl.Score(1, "const", score)
+		events := tl.Events()
+		if len(events) != 2 {
+			t.Fatalf("number of events is %d (should be 2)", len(events))
+		}
+		// The unit Seconds uses the tempo of the score (60 BPM), the
+		// unit Beats counts in beats
+		exp := []struct{ time, duration float64 }{{1, 2}, {4, 0.5}}
+		for i, e := range events {
+			if !almostEqual(e.Time, exp[i].time, 1e-9) || !almostEqual(e.Duration, exp[i].duration, 1e-9) {
+				t.Errorf("unit %d: event %d is at %v for %v (should be at %v for %v)",
+					unit, i, e.Time, e.Duration, exp[i].time, exp[i].duration)
+			}
+		}
+		if events[0].Pitch.Frequency() != 440 || events[1].Velocity != music.Dynamics["pp"] {
+			t.Errorf("unit %d: events are %+v", unit, events)
+		}
+	}
+}
+
+func TestTimeline_NoteOnOff(t *testing.T) {
+	tl := NewTimeline(sampleRate, Seconds)
+	tl.SetInstrument("const", constantInstrument)
+	la := music.Note{Octave: 3, Index: music.Label2Index("La")}
+	tl.NoteOn(0.1, "const", la, 1)
+	if _, err := tl.Streamer(); err == nil {
+		t.Errorf("a timeline with a started note should not be played")
+	}
+	if err := tl.NoteOff(0.05, "const", la); err == nil {
+		t.Errorf("a note should not stop before its start")
+	}
+	if err := tl.NoteOff(0.3, "const", la); err != nil {
+		t.Fatal(err)
+	}
+	if err := tl.NoteOff(0.4, "const", la); err == nil {
+		t.Errorf("a note that is not started should not be stopped")
+	}
+	events := tl.Events()
+	if len(events) != 1 || !almostEqual(events[0].Duration, 0.2, 1e-12) {
+		t.Fatalf("events are %v (should be a note of 0.2 s)", events)
+	}
+	rec, err := tl.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := rec.Channels[0][1000]; !almostEqual(v, 0.44, 1e-12) {
+		t.Errorf("sample is %v (should be 0.44)", v)
+	}
+}
+
+func TestTimeline_Errors(t *testing.T) {
+	tl := NewTimeline(sampleRate, Seconds)
+	tl.Note(0, 1, "piano", Hz(440), 1)
+	if _, err := tl.Streamer(); err == nil {
+		t.Errorf("an undefined instrument should be an error")
+	}
+
+	tl = NewTimeline(sampleRate, Seconds)
+	tl.SetInstrument("const", constantInstrument)
+	tl.Note(-1, 1, "const", Hz(440), 1)
+	if _, err := tl.Streamer(); err == nil {
+		t.Errorf("a negative time should be an error")
+	}
+
+	// An empty timeline is an empty streamer
+	rec, err := NewTimeline(sampleRate, Seconds).Render()
+	if err != nil || rec.Frames() != 0 {
+		t.Errorf("empty timeline gives %d frames (should be 0)", rec.Frames())
+	}
+}
+
+func TestInstruments(t *testing.T) {
+	tl := NewTimeline(sampleRate, Seconds)
+	tl.SetInstrument("sine", Synth(wave.NewSineWaveSynthesizer(0, 1, sampleRate)))
+	tl.SetInstrument("guitar", Guitar(guitar.NewGuitar(sampleRate)))
+	tl.Note(0, 0.5, "sine", Hz(100), 0.5)
+	tl.Note(0.25, 0.5, "guitar", guitar.Note{StringNum: guitar.La1, FretNum: 0}, 0.8)
+	tl.Note(0.5, 0.5, "guitar", Hz(440), 0.8)
+	rec, err := tl.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != sampleRate {
+		t.Fatalf("number of frames is %d (should be %d)", rec.Frames(), sampleRate)
+	}
+	// Before the guitar, the signal is the sine at half amplitude
+	for i := range sampleRate / 4 {
+		exp := 0.5 * math.Sin(2*math.Pi*100*float64(i)/sampleRate)
+		if !almostEqual(rec.Channels[0][i], exp, 1e-9) {
+			t.Fatalf("sample %d is %v (should be %v)", i, rec.Channels[0][i], exp)
+		}
+	}
+	// The guitar is heard after 0.25 s
+	var power float64
+	for _, v := range rec.Channels[0][sampleRate/2:] {
+		power += v * v
+	}
+	if power == 0 {
+		t.Errorf("the guitar should be heard")
+	}
+}
@@ -0,0 +1,78 @@
+package sound
+
+// This file implements the writing of the AIFF files (Audio Interchange
+// File Format), the format of the Apple systems, still required by
+// some DAWs. An AIFF file is an IFF container (as RIFF, but big endian)
+// made of the chunk COMM (the format) and the chunk SSND (the samples).
+// Only the PCM integer samples are supported (the floating point values
+// need the variant AIFF-C).
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// EncodeAIFF writes the samples of each channel as an AIFF stream
+func EncodeAIFF(w io.Writer, channels [][]float64, options EncodeOptions) error {
+	format, frames, err := validateChannels(channels, options)
+	if err != nil {
+		return err
+	}
+	if format.Float {
+		return errors.New("the AIFF format does not support floating point samples")
+	}
+	dataSize := uint64(frames) * uint64(format.frameSize())
+	if dataSize > math.MaxUint32-64 {
+		return fmt.Errorf("the AIFF data is too large (%d bytes)", dataSize)
+	}
+	padding := dataSize % 2
+
+	header := make([]byte, 54)
+	copy(header[0:4], "FORM")
+	binary.BigEndian.PutUint32(header[4:8], uint32(46+dataSize+padding))
+	copy(header[8:16], "AIFFCOMM")
+	binary.BigEndian.PutUint32(header[16:20], 18)
+	binary.BigEndian.PutUint16(header[20:22], uint16(format.Channels))
+	binary.BigEndian.PutUint32(header[22:26], uint32(frames))
+	binary.BigEndian.PutUint16(header[26:28], uint16(format.BitDepth))
+	rate := extendedFloat(float64(format.SampleRate))
+	copy(header[28:38], rate[:])
+	copy(header[38:42], "SSND")
+	binary.BigEndian.PutUint32(header[42:46], uint32(8+dataSize))
+	// The offset and block size (header[46:54]) are 0
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// The 8 bits samples are signed in the AIFF files
+	if frames > 0 {
+		if err := writeFrames(w, channels, format, options.Dither, binary.BigEndian, false); err != nil {
+			return err
+		}
+	}
+	if padding == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extendedFloat encodes a positive value as an IEEE 754 extended
+// precision number (80 bits: a sign bit and an exponent of 15 bits,
+// followed by a mantissa of 64 bits with an explicit integer bit), as
+// required for the sample rate of the AIFF files.
+func extendedFloat(value float64) [10]byte {
+	var b [10]byte
+	if value <= 0 {
+		return b
+	}
+	// value = fraction * 2^exponent, with fraction in [0.5, 1[
+	fraction, exponent := math.Frexp(value)
+	binary.BigEndian.PutUint16(b[0:2], uint16(exponent-1+16383))
+	binary.BigEndian.PutUint64(b[2:10], uint64(math.Ldexp(fraction, 64)))
+	return b
+}
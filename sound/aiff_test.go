@@ -0,0 +1,85 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestExtendedFloat(t *testing.T) {
+	tests := []struct {
+		v   float64
+		exp []byte
+	}{
+		{44100, []byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}},
+		{48000, []byte{0x40, 0x0E, 0xBB, 0x80, 0, 0, 0, 0, 0, 0}},
+		{8000, []byte{0x40, 0x0B, 0xFA, 0x00, 0, 0, 0, 0, 0, 0}},
+		{1, []byte{0x3F, 0xFF, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{0, make([]byte, 10)},
+	}
+	for _, tt := range tests {
+		if got := extendedFloat(tt.v); !bytes.Equal(got[:], tt.exp) {
+			t.Errorf("extended float of %v is % x (should be % x)", tt.v, got, tt.exp)
+		}
+	}
+}
+
+func TestEncodeAIFF(t *testing.T) {
+	var buffer bytes.Buffer
+	channels := [][]float64{{0.5, -0.5, 0}, {-1, 0.25, 0.5}}
+	options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 44100, BitDepth: 16}}
+	if err := EncodeAIFF(&buffer, channels, options); err != nil {
+		t.Fatal(err)
+	}
+	b := buffer.Bytes()
+	if len(b) != 54+12 {
+		t.Fatalf("size is %d (should be %d)", len(b), 54+12)
+	}
+	if string(b[0:4]) != "FORM" || string(b[8:16]) != "AIFFCOMM" || string(b[38:42]) != "SSND" {
+		t.Errorf("chunk identifiers are not valid")
+	}
+	if size := binary.BigEndian.Uint32(b[4:8]); int(size) != len(b)-8 {
+		t.Errorf("FORM size is %d (should be %d)", size, len(b)-8)
+	}
+	if n := binary.BigEndian.Uint16(b[20:22]); n != 2 {
+		t.Errorf("number of channels is %d (should be 2)", n)
+	}
+	if n := binary.BigEndian.Uint32(b[22:26]); n != 3 {
+		t.Errorf("number of frames is %d (should be 3)", n)
+	}
+	if n := binary.BigEndian.Uint16(b[26:28]); n != 16 {
+		t.Errorf("bit depth is %d (should be 16)", n)
+	}
+	if rate := b[28:32]; !bytes.Equal(rate, []byte{0x40, 0x0E, 0xAC, 0x44}) {
+		t.Errorf("sample rate is % x (should be 40 0e ac 44)", rate)
+	}
+	// Interleaved samples, signed and big endian
+	exp := []byte{0x40, 0x00, 0x80, 0x00, 0xC0, 0x00, 0x20, 0x00, 0x00, 0x00, 0x40, 0x00}
+	if !bytes.Equal(b[54:], exp) {
+		t.Errorf("samples are % x (should be % x)", b[54:], exp)
+	}
+}
+
+func TestEncodeAIFF_8Bits(t *testing.T) {
+	// The 8 bits samples are signed, and the odd data size is padded
+	var buffer bytes.Buffer
+	options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 8}}
+	if err := EncodeAIFF(&buffer, [][]float64{{0.5, -0.5, 0}}, options); err != nil {
+		t.Fatal(err)
+	}
+	b := buffer.Bytes()
+	if exp := []byte{0x40, 0xC0, 0x00, 0x00}; !bytes.Equal(b[54:], exp) {
+		t.Errorf("samples are % x (should be % x)", b[54:], exp)
+	}
+	if size := binary.BigEndian.Uint32(b[42:46]); size != 8+3 {
+		t.Errorf("SSND size is %d (should be %d)", size, 8+3)
+	}
+}
+
+func TestEncodeAIFF_Float(t *testing.T) {
+	var buffer bytes.Buffer
+	options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 32, Float: true}}
+	if err := EncodeAIFF(&buffer, [][]float64{{0}}, options); err == nil {
+		t.Errorf("encoding floating point samples should fail")
+	}
+}
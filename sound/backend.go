@@ -0,0 +1,243 @@
+package sound
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/speaker"
+)
+
+// Backend is the audio output where the sounds are played by the
+// functions Play and PlayAsync. The default backend is the speaker
+// (the sound card of the computer, through the package beep/speaker).
+// The null backend does not touch any audio device, and can be used
+// for the tests or on the machines without sound card.
+type Backend interface {
+	// Init prepares the backend for playing sounds at the specified
+	// sample rate.
+	Init(sampleRate int) error
+	// Play starts playing the streamer and returns immediately. The
+	// streamer is played to its end.
+	Play(s beep.Streamer)
+	// Lock and Unlock protect the streamers being played, to change
+	// their parameters (e.g. a volume) while they are played.
+	Lock()
+	Unlock()
+}
+
+// AudioEnv is the name of the environment variable that selects the
+// backend used by Init ("speaker" or "null"). If it is defined, it
+// overrides the backend specified by SetBackend. For example, for
+// running the demos on a machine without sound card:
+//
+//	MUSICALL_AUDIO=null ./d04.wavesound -n D02
+const AudioEnv = "MUSICALL_AUDIO"
+
+var backend Backend = SpeakerBackend{}
+
+// SetBackend changes the backend used to play the sounds. It must be
+// called before Init.
+func SetBackend(b Backend) {
+	backend = b
+}
+
+// CurrentBackend returns the backend used to play the sounds
+func CurrentBackend() Backend {
+	return backend
+}
+
+// LookupBackend returns a new backend of the specified name: "speaker"
+// or "null". The null backend plays the streamers in real time, as the
+// speaker, so that the programs behave the same without sound card.
+func LookupBackend(name string) (Backend, error) {
+	switch name {
+	case "speaker":
+		return SpeakerBackend{}, nil
+	case "null":
+		b := NewNullBackend()
+		b.RealTime = true
+		return b, nil
+	default:
+		return nil, fmt.Errorf("the audio backend %q is not defined (speaker or null)", name)
+	}
+}
+
+// backendFromEnv changes the backend if the environment variable
+// AudioEnv is defined.
+func backendFromEnv() error {
+	name, ok := os.LookupEnv(AudioEnv)
+	if !ok || name == "" {
+		return nil
+	}
+	b, err := LookupBackend(name)
+	if err != nil {
+		return err
+	}
+	backend = b
+	return nil
+}
+
+// -------------------------------------------------------------
+// Speaker backend
+
+// SpeakerBackend plays the sounds on the sound card (beep/speaker)
+type SpeakerBackend struct{}
+
+func (SpeakerBackend) Init(sampleRate int) error {
+	beepSampleRate := beep.SampleRate(sampleRate)
+	return speaker.Init(beepSampleRate, beepSampleRate.N(time.Second/10))
+}
+
+func (SpeakerBackend) Play(s beep.Streamer) {
+	speaker.Play(s)
+}
+
+func (SpeakerBackend) Lock() {
+	speaker.Lock()
+}
+
+func (SpeakerBackend) Unlock() {
+	speaker.Unlock()
+}
+
+// -------------------------------------------------------------
+// Null backend
+
+// NullBackend is a backend that never touches the audio device. As
+// for the speaker, each streamer played is pulled in a goroutine, by
+// chunks, and the lock is only held while a chunk is pulled. By
+// default, the streamers are pulled as fast as possible (much faster
+// than real time) and the samples are dropped. With Record, the
+// rendered sounds are kept, so that the tests can check the samples
+// that would have been heard (the memory grows with the sounds played,
+// it is not suited to endless streamers). With RealTime, the streamers
+// are pulled at the pace of a sound card, so that an endless streamer
+// (a live source) can be played and changed while it is played.
+type NullBackend struct {
+	RealTime bool
+	Record   bool
+
+	mutex      sync.Mutex
+	sampleRate int
+	played     []*Recording
+	playing    sync.WaitGroup
+}
+
+// nullChunk is the duration of the chunks pulled by the null backend,
+// in seconds
+const nullChunk = 0.01
+
+func NewNullBackend() *NullBackend {
+	return &NullBackend{sampleRate: samplerate}
+}
+
+func (b *NullBackend) Init(sampleRate int) error {
+	b.sampleRate = sampleRate
+	return nil
+}
+
+// Play starts pulling the streamer in a goroutine and returns
+// immediately. With Record, the sound is added to the sounds played at
+// once, and its samples are appended chunk by chunk (see Wait).
+func (b *NullBackend) Play(s beep.Streamer) {
+	b.mutex.Lock()
+	var rec *Recording
+	if b.Record {
+		rec = &Recording{
+			Format:   AudioFormat{SampleRate: b.sampleRate, Channels: 2, BitDepth: 64, Float: true},
+			Channels: [][]float64{{}, {}},
+		}
+		b.played = append(b.played, rec)
+	}
+	size := max(int(nullChunk*float64(b.sampleRate)), 1)
+	b.mutex.Unlock()
+
+	b.playing.Add(1)
+	go func() {
+		defer b.playing.Done()
+		buffer := make([][2]float64, size)
+		start := time.Now()
+		frames := 0
+		for {
+			// Les échantillons sont ajoutés avant de libérer le verrou,
+			// pour qu'un appelant réveillé par un beep.Callback du
+			// streamer (voir la fonction Play) les trouve tous.
+			b.mutex.Lock()
+			// The error of the streamer is ignored, as for the speaker
+			n, ok := s.Stream(buffer)
+			if rec != nil {
+				for _, frame := range buffer[:n] {
+					rec.Channels[0] = append(rec.Channels[0], frame[0])
+					rec.Channels[1] = append(rec.Channels[1], frame[1])
+				}
+			}
+			realTime := b.RealTime
+			b.mutex.Unlock()
+			if !ok {
+				return
+			}
+			frames += n
+			if realTime {
+				elapsed := time.Duration(float64(frames) / float64(b.sampleRate) * float64(time.Second))
+				time.Sleep(time.Until(start.Add(elapsed)))
+			}
+		}
+	}()
+}
+
+// Wait returns when all the streamers played are over. It never
+// returns if an endless streamer is played.
+func (b *NullBackend) Wait() {
+	b.playing.Wait()
+}
+
+func (b *NullBackend) Lock() {
+	b.mutex.Lock()
+}
+
+func (b *NullBackend) Unlock() {
+	b.mutex.Unlock()
+}
+
+// Played returns the sounds played since the creation of the backend
+// (or the last call to Reset), in the order they were played, if the
+// backend records them (see Record). The sounds still played are
+// returned as rendered so far.
+func (b *NullBackend) Played() []Recording {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	played := make([]Recording, len(b.played))
+	for i, rec := range b.played {
+		played[i] = copyRecording(rec)
+	}
+	return played
+}
+
+// Last returns the last sound played, or false if no sound was played
+func (b *NullBackend) Last() (Recording, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.played) == 0 {
+		return Recording{}, false
+	}
+	return copyRecording(b.played[len(b.played)-1]), true
+}
+
+// copyRecording returns a copy of a recording that is still rendered
+func copyRecording(rec *Recording) Recording {
+	channels := make([][]float64, len(rec.Channels))
+	for c, samples := range rec.Channels {
+		channels[c] = append([]float64(nil), samples...)
+	}
+	return Recording{Format: rec.Format, Channels: channels}
+}
+
+// Reset forgets the sounds played
+func (b *NullBackend) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.played = nil
+}
@@ -0,0 +1,106 @@
+package sound
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/generators"
+)
+
+func TestNullBackend(t *testing.T) {
+	b := recordingBackend()
+	if err := b.Init(8000); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Last(); ok {
+		t.Errorf("no sound should have been played")
+	}
+
+	b.Play(NewSound([]float64{0.5, -0.5}))
+	b.Play(beep.Seq(NewSound([]float64{0.25}), Silence(0.5, 8000)))
+	b.Wait()
+	played := b.Played()
+	if len(played) != 2 {
+		t.Fatalf("number of sounds played is %d (should be 2)", len(played))
+	}
+	checkChannels(t, played[0], [][]float64{{0.5, -0.5}, {0.5, -0.5}}, 1e-12)
+	last, _ := b.Last()
+	if last.Frames() != 4001 || last.Format.SampleRate != 8000 {
+		t.Errorf("last sound has %d samples at %d Hz (should be 4001 at 8000 Hz)", last.Frames(), last.Format.SampleRate)
+	}
+
+	b.Reset()
+	if len(b.Played()) != 0 {
+		t.Errorf("the sounds played should have been forgotten")
+	}
+
+	// By default, the sounds are not recorded
+	b = NewNullBackend()
+	b.Play(NewSound([]float64{0.5, -0.5}))
+	b.Wait()
+	if n := len(b.Played()); n != 0 {
+		t.Errorf("number of sounds recorded is %d (should be 0)", n)
+	}
+}
+
+func TestNullBackend_RealTime(t *testing.T) {
+	// An endless streamer is played in real time, and can be changed
+	// while it is played
+	b := recordingBackend()
+	b.RealTime = true
+	if err := b.Init(8000); err != nil {
+		t.Fatal(err)
+	}
+	volume := VolumeStreamer(generators.Silence(-1))
+	b.Play(volume)
+	time.Sleep(200 * time.Millisecond)
+	b.Lock()
+	volume.Silent = true
+	b.Unlock()
+
+	last, _ := b.Last()
+	if d := last.Duration(); d < 0.1 || d > 0.4 {
+		t.Errorf("duration played is %v s (should be about 0.2 s)", d)
+	}
+}
+
+func TestLookupBackend(t *testing.T) {
+	if b, err := LookupBackend("null"); err != nil {
+		t.Error(err)
+	} else if b, ok := b.(*NullBackend); !ok {
+		t.Errorf("backend null is %T (should be *NullBackend)", b)
+	} else if !b.RealTime || b.Record {
+		t.Errorf("backend null should play in real time, without recording")
+	}
+	if b, err := LookupBackend("speaker"); err != nil {
+		t.Error(err)
+	} else if _, ok := b.(SpeakerBackend); !ok {
+		t.Errorf("backend speaker is %T (should be SpeakerBackend)", b)
+	}
+	if _, err := LookupBackend("alsa"); err == nil {
+		t.Errorf("the backend alsa should not be defined")
+	}
+}
+
+func TestInit_Env(t *testing.T) {
+	defer SetBackend(CurrentBackend())
+	defer func(r int) { samplerate = r }(samplerate)
+
+	t.Setenv(AudioEnv, "null")
+	SetBackend(SpeakerBackend{})
+	if err := Init(22050); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := CurrentBackend().(*NullBackend); !ok {
+		t.Errorf("backend is %T (should be *NullBackend)", CurrentBackend())
+	}
+	if samplerate != 22050 {
+		t.Errorf("sample rate is %d (should be 22050)", samplerate)
+	}
+
+	t.Setenv(AudioEnv, "unknown")
+	if err := Init(22050); err == nil {
+		t.Errorf("the backend unknown should not be accepted")
+	}
+}
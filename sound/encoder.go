@@ -0,0 +1,326 @@
+package sound
+
+// This file defines the audio formats and the encoders that write the
+// samples ([]float64 by channel) into the audio files. The encoder is
+// selected by the extension of the file:
+//
+// - .wav (WAV, PCM integers or floating point values)
+// - .flac (FLAC, lossless compression of PCM integers)
+// - .aif, .aiff (AIFF, PCM integers, used by some DAWs)
+// - .raw, .pcm (raw interleaved samples without header, in the format
+//   of the options, signed and little endian)
+// - .f32 (raw interleaved float32 values without header, little
+//   endian, that can be loaded with numpy.fromfile(path, "<f4"))
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AudioFormat is the format of the samples of an audio file
+type AudioFormat struct {
+	SampleRate int
+	Channels   int
+	BitDepth   int  // number of bits per sample (8, 16, 24, 32 or 64)
+	Float      bool // samples are floating point values (32 or 64 bits)
+}
+
+// frameSize returns the number of bytes of a frame
+func (f AudioFormat) frameSize() int {
+	return f.Channels * f.BitDepth / 8
+}
+
+// Recording is the content of an audio file: the format and the
+// samples of each channel, as values between -1 and 1.
+type Recording struct {
+	Format   AudioFormat
+	Channels [][]float64
+}
+
+// Frames returns the number of samples of each channel
+func (r Recording) Frames() int {
+	if len(r.Channels) == 0 {
+		return 0
+	}
+	return len(r.Channels[0])
+}
+
+// Duration returns the duration of the recording in seconds
+func (r Recording) Duration() float64 {
+	return float64(r.Frames()) / float64(r.Format.SampleRate)
+}
+
+// Mono returns the average of the channels
+func (r Recording) Mono() []float64 {
+	samples := make([]float64, r.Frames())
+	for _, channel := range r.Channels {
+		for i, v := range channel {
+			samples[i] += v / float64(len(r.Channels))
+		}
+	}
+	return samples
+}
+
+// Save writes the recording in an audio file at the specified path, in
+// the format of the recording. The encoder is selected by the extension
+// of the file.
+func (r Recording) Save(path string) error {
+	return SaveChannels(path, r.Channels, EncodeOptions{AudioFormat: r.Format})
+}
+
+// EncodeOptions defines the format of the file to write. If the number
+// of channels is 0, it is the number of channels of the samples to
+// write. The dither applies to the integer formats: a triangular noise
+// of 1 LSB (the quantization step) is added before rounding the
+// samples, so that the quantization error is not correlated to the
+// signal (it is a light noise instead of a distortion).
+type EncodeOptions struct {
+	AudioFormat
+	Dither bool
+}
+
+// DefaultEncodeOptions returns the options for writing a mono file
+// with PCM samples of 16 bits (the CD quality), at the specified
+// sample rate.
+func DefaultEncodeOptions(sampleRate int) EncodeOptions {
+	return EncodeOptions{
+		AudioFormat: AudioFormat{SampleRate: sampleRate, Channels: 1, BitDepth: 16},
+	}
+}
+
+// -------------------------------------------------------------
+// Encoders
+
+// Encoder writes the samples of each channel in an audio format. The
+// samples are values between -1 and 1 (the values out of this range
+// are clipped for the integer formats). All the channels must have the
+// same number of samples.
+type Encoder interface {
+	Encode(w io.Writer, channels [][]float64, options EncodeOptions) error
+}
+
+// EncoderFunc is a function that implements the Encoder interface
+type EncoderFunc func(w io.Writer, channels [][]float64, options EncodeOptions) error
+
+func (f EncoderFunc) Encode(w io.Writer, channels [][]float64, options EncodeOptions) error {
+	return f(w, channels, options)
+}
+
+// encoders is the table of the encoders by file extension
+var encoders map[string]Encoder = map[string]Encoder{
+	".wav":  EncoderFunc(EncodeWAV),
+	".wave": EncoderFunc(EncodeWAV),
+	".flac": EncoderFunc(EncodeFLAC),
+	".aif":  EncoderFunc(EncodeAIFF),
+	".aiff": EncoderFunc(EncodeAIFF),
+	".raw":  EncoderFunc(EncodeRaw),
+	".pcm":  EncoderFunc(EncodeRaw),
+	".f32":  EncoderFunc(EncodeFloat32),
+}
+
+// RegisterEncoder adds (or replaces) the encoder used for the files
+// with the specified extension (e.g. ".ogg").
+func RegisterEncoder(extension string, e Encoder) {
+	encoders[strings.ToLower(extension)] = e
+}
+
+// EncoderFor returns the encoder selected by the extension of the file
+func EncoderFor(path string) (Encoder, error) {
+	extension := strings.ToLower(filepath.Ext(path))
+	e, ok := encoders[extension]
+	if !ok {
+		return nil, fmt.Errorf("no encoder is defined for the extension %q", extension)
+	}
+	return e, nil
+}
+
+// SaveSamples writes the signal in an audio file at the specified path.
+// If the options define several channels, the signal is written on each
+// channel. The encoder is selected by the extension of the file.
+func SaveSamples(path string, samples []float64, options EncodeOptions) error {
+	channels, options := monoChannels(samples, options)
+	return SaveChannels(path, channels, options)
+}
+
+// SaveChannels writes the samples of each channel in an audio file at
+// the specified path. The encoder is selected by the extension of the
+// file.
+func SaveChannels(path string, channels [][]float64, options EncodeOptions) error {
+	e, err := EncoderFor(path)
+	if err != nil {
+		return err
+	}
+	return saveWith(e, path, channels, options)
+}
+
+// SaveWAV writes the signal in a WAV file at the specified path,
+// whatever the extension of the file. If the options define several
+// channels, the signal is written on each channel.
+func SaveWAV(path string, samples []float64, options EncodeOptions) error {
+	channels, options := monoChannels(samples, options)
+	return SaveWAVChannels(path, channels, options)
+}
+
+// SaveWAVChannels writes the samples of each channel in a WAV file at
+// the specified path, whatever the extension of the file.
+func SaveWAVChannels(path string, channels [][]float64, options EncodeOptions) error {
+	return saveWith(EncoderFunc(EncodeWAV), path, channels, options)
+}
+
+// monoChannels returns the signal repeated on the number of channels
+// of the options (at least 1).
+func monoChannels(samples []float64, options EncodeOptions) ([][]float64, EncodeOptions) {
+	options.Channels = max(options.Channels, 1)
+	channels := make([][]float64, options.Channels)
+	for c := range channels {
+		channels[c] = samples
+	}
+	return channels, options
+}
+
+func saveWith(e Encoder, path string, channels [][]float64, options EncodeOptions) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	w := bufio.NewWriter(f)
+	if err = e.Encode(w, channels, options); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// validateChannels checks that the channels can be written with the
+// options, and returns the format completed with the number of
+// channels, and the number of frames.
+func validateChannels(channels [][]float64, options EncodeOptions) (AudioFormat, int, error) {
+	format := options.AudioFormat
+	if format.Channels == 0 {
+		format.Channels = len(channels)
+	}
+	if len(channels) == 0 || format.Channels != len(channels) {
+		return format, 0, fmt.Errorf("the number of channels %d does not match the format (%d channels)", len(channels), format.Channels)
+	}
+	frames := len(channels[0])
+	for _, channel := range channels {
+		if len(channel) != frames {
+			return format, 0, errors.New("the channels have not the same number of samples")
+		}
+	}
+	if format.SampleRate < 1 {
+		return format, 0, fmt.Errorf("the sample rate %d is not valid", format.SampleRate)
+	}
+	if format.Channels > math.MaxUint16 {
+		return format, 0, fmt.Errorf("the number of channels %d is not valid", format.Channels)
+	}
+	if format.Float {
+		if format.BitDepth != 32 && format.BitDepth != 64 {
+			return format, 0, fmt.Errorf("the float bit depth %d is not supported", format.BitDepth)
+		}
+		return format, frames, nil
+	}
+	if format.BitDepth != 8 && format.BitDepth != 16 && format.BitDepth != 24 && format.BitDepth != 32 {
+		return format, 0, fmt.Errorf("the PCM bit depth %d is not supported", format.BitDepth)
+	}
+	return format, frames, nil
+}
+
+// quantize converts the value to an integer of the specified number of
+// bits, in the range [-2^(n-1), 2^(n-1)-1].
+func quantize(value float64, bits int, dither bool) int64 {
+	scale := math.Ldexp(1, bits-1)
+	v := value * scale
+	if dither {
+		v += rand.Float64() - rand.Float64()
+	}
+	v = math.Max(-scale, math.Min(scale-1, math.Round(v)))
+	return int64(v)
+}
+
+// appendSample appends the encoding of the value to the buffer, in the
+// specified byte order. The 8 bits integers are unsigned if unsigned8
+// is true (WAV), and signed otherwise (AIFF).
+func appendSample(b []byte, value float64, format AudioFormat, dither bool, order binary.AppendByteOrder, unsigned8 bool) []byte {
+	if format.Float {
+		if format.BitDepth == 32 {
+			return order.AppendUint32(b, math.Float32bits(float32(value)))
+		}
+		return order.AppendUint64(b, math.Float64bits(value))
+	}
+
+	q := quantize(value, format.BitDepth, dither)
+	switch format.BitDepth {
+	case 8:
+		if unsigned8 {
+			return append(b, byte(q+128))
+		}
+		return append(b, byte(q))
+	case 16:
+		return order.AppendUint16(b, uint16(q))
+	case 24:
+		if order == binary.BigEndian {
+			return append(b, byte(q>>16), byte(q>>8), byte(q))
+		}
+		return append(b, byte(q), byte(q>>8), byte(q>>16))
+	default:
+		return order.AppendUint32(b, uint32(q))
+	}
+}
+
+// writeFrames writes the interleaved samples of the channels by blocks
+func writeFrames(w io.Writer, channels [][]float64, format AudioFormat, dither bool, order binary.AppendByteOrder, unsigned8 bool) error {
+	frames := len(channels[0])
+	block := make([]byte, 0, 4096*format.frameSize())
+	for i := range frames {
+		for _, channel := range channels {
+			block = appendSample(block, channel[i], format, dither, order, unsigned8)
+		}
+		if len(block) == cap(block) || i == frames-1 {
+			if _, err := w.Write(block); err != nil {
+				return err
+			}
+			block = block[:0]
+		}
+	}
+	return nil
+}
+
+// -------------------------------------------------------------
+// Raw encoders
+
+// EncodeRaw writes the interleaved samples without header, in the
+// format of the options (PCM signed integers or floating point values,
+// little endian).
+func EncodeRaw(w io.Writer, channels [][]float64, options EncodeOptions) error {
+	format, frames, err := validateChannels(channels, options)
+	if err != nil {
+		return err
+	}
+	if frames == 0 {
+		return nil
+	}
+	return writeFrames(w, channels, format, options.Dither, binary.LittleEndian, false)
+}
+
+// EncodeFloat32 writes the interleaved samples as float32 values
+// without header (little endian), whatever the bit depth of the
+// options. This is the simplest format to load the samples in another
+// program, e.g. with numpy: numpy.fromfile(path, "<f4").
+func EncodeFloat32(w io.Writer, channels [][]float64, options EncodeOptions) error {
+	options.Float = true
+	options.BitDepth = 32
+	return EncodeRaw(w, channels, options)
+}
@@ -0,0 +1,159 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestEncoderFor(t *testing.T) {
+	tests := []struct {
+		path string
+		exp  string // first bytes of the encoded stream
+	}{
+		{"output.sound.wav", "RIFF"},
+		{"output.sound.WAV", "RIFF"},
+		{"output.sound.flac", "fLaC"},
+		{"output.sound.aiff", "FORM"},
+		{"output.sound.aif", "FORM"},
+	}
+	for _, tt := range tests {
+		e, err := EncoderFor(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buffer bytes.Buffer
+		if err := e.Encode(&buffer, [][]float64{{0, 0.5}}, DefaultEncodeOptions(8000)); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buffer.Bytes()[:4]); got != tt.exp {
+			t.Errorf("stream of %s starts with %q (should be %q)", tt.path, got, tt.exp)
+		}
+	}
+	for _, path := range []string{"output.sound.mp3", "output.sound"} {
+		if _, err := EncoderFor(path); err == nil {
+			t.Errorf("the encoder for %s should not exist", path)
+		}
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	var called bool
+	RegisterEncoder(".TEST", EncoderFunc(func(w io.Writer, channels [][]float64, options EncodeOptions) error {
+		called = true
+		return nil
+	}))
+	defer delete(encoders, ".test")
+	if err := SaveSamples("output.TestRegisterEncoder.test", []float64{0}, DefaultEncodeOptions(8000)); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Errorf("the registered encoder should be called")
+	}
+}
+
+func TestEncodeRaw(t *testing.T) {
+	var buffer bytes.Buffer
+	options := DefaultEncodeOptions(8000)
+	if err := EncodeRaw(&buffer, [][]float64{{0.5, -1}, {-0.5, 0}}, EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 16}}); err != nil {
+		t.Fatal(err)
+	}
+	exp := []byte{0x00, 0x40, 0x00, 0xC0, 0x00, 0x80, 0x00, 0x00}
+	if !bytes.Equal(buffer.Bytes(), exp) {
+		t.Errorf("raw data is % x (should be % x)", buffer.Bytes(), exp)
+	}
+
+	// The 8 bits samples are signed
+	buffer.Reset()
+	options.BitDepth = 8
+	if err := EncodeRaw(&buffer, [][]float64{{0.5, -0.5}}, options); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []byte{0x40, 0xC0}; !bytes.Equal(buffer.Bytes(), exp) {
+		t.Errorf("raw data is % x (should be % x)", buffer.Bytes(), exp)
+	}
+}
+
+func TestEncodeFloat32(t *testing.T) {
+	var buffer bytes.Buffer
+	left := []float64{0.1, -0.7, 1.5}
+	right := []float64{0.2, 0.3, -0.4}
+	// The bit depth of the options is ignored
+	options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 16}}
+	if err := EncodeFloat32(&buffer, [][]float64{left, right}, options); err != nil {
+		t.Fatal(err)
+	}
+	values := make([]float32, 6)
+	if err := binary.Read(&buffer, binary.LittleEndian, values); err != nil {
+		t.Fatal(err)
+	}
+	for i := range left {
+		// The values out of [-1, 1] are not clipped
+		if values[2*i] != float32(left[i]) || values[2*i+1] != float32(right[i]) {
+			t.Errorf("frame %d is %v (should be [%v %v])", i, values[2*i:2*i+2], left[i], right[i])
+		}
+	}
+}
+
+func TestSaveChannels(t *testing.T) {
+	left := []float64{0, 0.5, -0.5, 0.25}
+	right := []float64{0.125, -0.25, 0.75, -1}
+	options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 16}}
+	for _, path := range []string{"output.TestSaveChannels.flac", "output.TestSaveChannels.aiff", "output.TestSaveChannels.raw"} {
+		if err := SaveChannels(path, [][]float64{left, right}, options); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() < int64(2*2*len(left)) {
+			t.Errorf("size of %s is %d (should be at least %d)", path, info.Size(), 2*2*len(left))
+		}
+	}
+
+	// The FLAC file is decoded to the same samples
+	data, err := os.ReadFile("output.TestSaveChannels.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, samples, err := decodeFLAC(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c, channel := range [][]float64{left, right} {
+		for i, v := range channel {
+			if got := float64(samples[c][i]) / 32768; !almostEqual(got, v, 1e-4) {
+				t.Errorf("sample %d of channel %d is %v (should be %v)", i, c, got, v)
+			}
+		}
+	}
+
+	if err := SaveChannels("output.TestSaveChannels.mp3", [][]float64{left, right}, options); err == nil {
+		t.Errorf("saving with an unknown extension should fail")
+	}
+}
+
+func TestRecording_Save(t *testing.T) {
+	rec := Recording{
+		Format:   AudioFormat{SampleRate: 8000, Channels: 1, BitDepth: 32, Float: true},
+		Channels: [][]float64{{0.1, math.Pi / 4, -0.3}},
+	}
+	path := "output.TestRecording_Save.f32"
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range rec.Channels[0] {
+		got := math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+		if got != float32(v) {
+			t.Errorf("sample %d is %v (should be %v)", i, got, float32(v))
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package sound
+
+// This file implements a FLAC encoder (Free Lossless Audio Codec) in
+// pure Go. The compression is lossless: the decoded samples are exactly
+// the quantized samples. The encoder uses the simple tools of the
+// format, which give a good compression of the musical signals:
+//
+// - the samples are cut in blocks of fixed size (4096 samples), each
+//   block being encoded in a frame,
+// - for a stereo signal, the correlation between the channels is
+//   removed by encoding the difference (side) of the channels with the
+//   left, the right or the mean (mid) channel,
+// - each channel of the block is predicted by a fixed polynomial
+//   predictor of order 0 to 4 (the sample is predicted from the
+//   previous ones), and the residual (the prediction error) is encoded
+//   with Rice codes, whose parameter is adapted to partitions of the
+//   block.
+//
+// The specification of the format is the RFC 9639.
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+const (
+	flacBlockSize         = 4096
+	flacMaxPartitionOrder = 8
+	flacMaxFixedOrder     = 4
+)
+
+// Channel assignments of the stereo frames
+const (
+	flacIndependent = iota
+	flacLeftSide
+	flacRightSide
+	flacMidSide
+)
+
+// EncodeFLAC writes the samples of each channel as a FLAC stream. The
+// samples are quantized as PCM integers of 8, 16 or 24 bits (the
+// floating point samples are not supported), on 1 to 8 channels.
+func EncodeFLAC(w io.Writer, channels [][]float64, options EncodeOptions) error {
+	format, frames, err := validateChannels(channels, options)
+	if err != nil {
+		return err
+	}
+	if format.Float {
+		return errors.New("the FLAC format does not support floating point samples")
+	}
+	if format.BitDepth == 32 {
+		return errors.New("the FLAC encoder does not support the bit depth 32")
+	}
+	if format.Channels > 8 {
+		return fmt.Errorf("the FLAC format supports up to 8 channels (%d channels)", format.Channels)
+	}
+	if format.SampleRate >= 1<<20 {
+		return fmt.Errorf("the sample rate %d is too large for the FLAC format", format.SampleRate)
+	}
+
+	// Quantization of the samples, and signature MD5 of the samples
+	// (interleaved, little endian)
+	samples := make([][]int64, format.Channels)
+	for c := range samples {
+		samples[c] = make([]int64, frames)
+	}
+	width := format.BitDepth / 8
+	signature := md5.New()
+	buffer := make([]byte, 0, width*format.Channels)
+	for i := range frames {
+		buffer = buffer[:0]
+		for c, channel := range channels {
+			q := quantize(channel[i], format.BitDepth, options.Dither)
+			samples[c][i] = q
+			for k := range width {
+				buffer = append(buffer, byte(q>>(8*k)))
+			}
+		}
+		signature.Write(buffer)
+	}
+
+	// The frames are encoded first, to know their sizes
+	encoded := make([][]byte, 0, frames/flacBlockSize+1)
+	minFrameSize, maxFrameSize := 0, 0
+	for number, start := 0, 0; start < frames; number, start = number+1, start+flacBlockSize {
+		end := min(start+flacBlockSize, frames)
+		block := make([][]int64, format.Channels)
+		for c := range block {
+			block[c] = samples[c][start:end]
+		}
+		frame := encodeFLACFrame(block, format.BitDepth, number)
+		encoded = append(encoded, frame)
+		if minFrameSize == 0 || len(frame) < minFrameSize {
+			minFrameSize = len(frame)
+		}
+		maxFrameSize = max(maxFrameSize, len(frame))
+	}
+
+	// Stream marker and metadata block STREAMINFO (the last metadata block)
+	var bw bitWriter
+	bw.write(0x664C6143, 32) // "fLaC"
+	bw.write(1, 1)           // last metadata block
+	bw.write(0, 7)           // type STREAMINFO
+	bw.write(34, 24)         // length
+	bw.write(flacBlockSize, 16)
+	bw.write(flacBlockSize, 16)
+	bw.write(uint64(minFrameSize), 24)
+	bw.write(uint64(maxFrameSize), 24)
+	bw.write(uint64(format.SampleRate), 20)
+	bw.write(uint64(format.Channels-1), 3)
+	bw.write(uint64(format.BitDepth-1), 5)
+	bw.write(uint64(frames)>>32, 4)
+	bw.write(uint64(frames)&0xFFFFFFFF, 32)
+	for _, b := range signature.Sum(nil) {
+		bw.write(uint64(b), 8)
+	}
+	if _, err := w.Write(bw.bytes()); err != nil {
+		return err
+	}
+	for _, frame := range encoded {
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeFLACFrame encodes a block of samples of each channel
+func encodeFLACFrame(block [][]int64, bitDepth int, number int) []byte {
+	size := len(block[0])
+
+	// Choice of the channel assignment
+	assignment := flacIndependent
+	subframes := make([]*bitWriter, len(block))
+	if len(block) == 2 {
+		left, right := block[0], block[1]
+		mid := make([]int64, size)
+		side := make([]int64, size)
+		for i := range size {
+			mid[i] = (left[i] + right[i]) >> 1
+			side[i] = left[i] - right[i]
+		}
+		l := encodeFLACSubframe(left, bitDepth)
+		r := encodeFLACSubframe(right, bitDepth)
+		m := encodeFLACSubframe(mid, bitDepth)
+		s := encodeFLACSubframe(side, bitDepth+1)
+		candidates := [][]*bitWriter{
+			flacIndependent: {l, r},
+			flacLeftSide:    {l, s},
+			flacRightSide:   {s, r},
+			flacMidSide:     {m, s},
+		}
+		best := -1
+		for k, candidate := range candidates {
+			length := candidate[0].length() + candidate[1].length()
+			if best < 0 || length < best {
+				assignment, subframes, best = k, candidate, length
+			}
+		}
+	} else {
+		for c, channel := range block {
+			subframes[c] = encodeFLACSubframe(channel, bitDepth)
+		}
+	}
+
+	// Frame header
+	var bw bitWriter
+	bw.write(0xFFF8, 16) // sync code and fixed block size
+	bw.write(0x7, 4)     // block size - 1 on 16 bits at the end of the header
+	bw.write(0x0, 4)     // sample rate of the STREAMINFO
+	if assignment == flacIndependent {
+		bw.write(uint64(len(block)-1), 4)
+	} else {
+		bw.write(uint64(0x8+assignment-flacLeftSide), 4)
+	}
+	bw.write(uint64(flacSampleSizeCode(bitDepth)), 3)
+	bw.write(0, 1)
+	for _, b := range utf8Number(uint64(number)) {
+		bw.write(uint64(b), 8)
+	}
+	bw.write(uint64(size-1), 16)
+	bw.write(uint64(crc8(bw.bytes())), 8)
+
+	// Subframes, padding and footer
+	for _, subframe := range subframes {
+		bw.append(subframe)
+	}
+	bw.align()
+	bw.write(uint64(crc16(bw.bytes())), 16)
+	return bw.bytes()
+}
+
+func flacSampleSizeCode(bitDepth int) int {
+	switch bitDepth {
+	case 8:
+		return 0x1
+	case 16:
+		return 0x4
+	default: // 24
+		return 0x6
+	}
+}
+
+// encodeFLACSubframe encodes the samples of a channel, choosing the
+// most compact subframe type: constant, fixed predictor or verbatim.
+func encodeFLACSubframe(samples []int64, bitDepth int) *bitWriter {
+	var bw bitWriter
+	constant := true
+	for _, v := range samples {
+		if v != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		bw.write(0x00, 8) // type CONSTANT
+		bw.writeSigned(samples[0], uint(bitDepth))
+		return &bw
+	}
+
+	// Verbatim encoding, kept if no predictor is better
+	bw.write(0x02, 8) // type VERBATIM
+	for _, v := range samples {
+		bw.writeSigned(v, uint(bitDepth))
+	}
+	best := &bw
+
+	residual := make([]int64, len(samples))
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		fixedResidual(samples, order, residual)
+		var fw bitWriter
+		fw.write(uint64(0x10|order<<1), 8) // type FIXED of order
+		for _, v := range samples[:order] {
+			fw.writeSigned(v, uint(bitDepth))
+		}
+		if !encodeFLACResidual(&fw, residual[order:], len(samples), order, best.length()) {
+			continue
+		}
+		if fw.length() < best.length() {
+			best = &fw
+		}
+	}
+	return best
+}
+
+// fixedResidual computes the residual of the fixed predictor of the
+// specified order (the residual[:order] are not defined):
+//
+//	order 0: s[i]
+//	order 1: s[i] - s[i-1]
+//	order 2: s[i] - 2s[i-1] + s[i-2]
+//	order 3: s[i] - 3s[i-1] + 3s[i-2] - s[i-3]
+//	order 4: s[i] - 4s[i-1] + 6s[i-2] - 4s[i-3] + s[i-4]
+func fixedResidual(s []int64, order int, residual []int64) {
+	for i := order; i < len(s); i++ {
+		switch order {
+		case 0:
+			residual[i] = s[i]
+		case 1:
+			residual[i] = s[i] - s[i-1]
+		case 2:
+			residual[i] = s[i] - 2*s[i-1] + s[i-2]
+		case 3:
+			residual[i] = s[i] - 3*s[i-1] + 3*s[i-2] - s[i-3]
+		case 4:
+			residual[i] = s[i] - 4*s[i-1] + 6*s[i-2] - 4*s[i-3] + s[i-4]
+		}
+	}
+}
+
+// encodeFLACResidual writes the residual with partitioned Rice codes,
+// choosing the partition order that gives the shortest encoding. It
+// returns false (without writing anything) if the encoding would be
+// longer than the specified limit in bits.
+func encodeFLACResidual(bw *bitWriter, residual []int64, blockSize int, predictorOrder int, limit int) bool {
+	zigzag := make([]uint64, len(residual))
+	for i, r := range residual {
+		zigzag[i] = uint64(r<<1) ^ uint64(r>>63)
+	}
+
+	bestLength, bestOrder := -1, 0
+	var bestParameters []uint
+	for order := 0; order <= flacMaxPartitionOrder; order++ {
+		if blockSize%(1<<order) != 0 || blockSize>>order <= predictorOrder {
+			break
+		}
+		parameters, length := riceParameters(zigzag, blockSize>>order, predictorOrder, order)
+		if bestLength < 0 || length < bestLength {
+			bestLength, bestOrder, bestParameters = length, order, parameters
+		}
+	}
+	if bestLength < 0 || bw.length()+bestLength >= limit {
+		return false
+	}
+
+	// The 4 bits parameters are enough if they are lower than 15 (the
+	// value 15 is the escape code), otherwise 5 bits are used.
+	parameterBits := uint(4)
+	for _, k := range bestParameters {
+		if k >= 15 {
+			parameterBits = 5
+		}
+	}
+	bw.write(uint64(parameterBits-4), 2)
+	bw.write(uint64(bestOrder), 4)
+	start := 0
+	for p, k := range bestParameters {
+		count := blockSize >> bestOrder
+		if p == 0 {
+			count -= predictorOrder
+		}
+		bw.write(uint64(k), parameterBits)
+		for _, u := range zigzag[start : start+count] {
+			bw.writeUnary(u >> k)
+			bw.write(u&(1<<k-1), k)
+		}
+		start += count
+	}
+	return true
+}
+
+// riceParameters computes the best Rice parameter of each partition of
+// the residual, and the length in bits of the encoded residual (with
+// parameters on 5 bits, to be conservative).
+func riceParameters(zigzag []uint64, partitionSize int, predictorOrder int, order int) ([]uint, int) {
+	parameters := make([]uint, 1<<order)
+	length := 2 + 4
+	start := 0
+	for p := range parameters {
+		count := partitionSize
+		if p == 0 {
+			count -= predictorOrder
+		}
+		partition := zigzag[start : start+count]
+		start += count
+
+		// The best parameter is near log2 of the mean value
+		var sum uint64
+		for _, u := range partition {
+			sum += u
+		}
+		estimate := 0
+		if count > 0 && sum > uint64(count) {
+			estimate = bits.Len64(sum/uint64(count)) - 1
+		}
+		best, bestCost := uint(0), -1
+		for k := max(estimate-1, 0); k <= min(estimate+1, 30); k++ {
+			cost := count * (k + 1)
+			for _, u := range partition {
+				cost += int(u >> k)
+			}
+			if bestCost < 0 || cost < bestCost {
+				best, bestCost = uint(k), cost
+			}
+		}
+		parameters[p] = best
+		length += 5 + bestCost
+	}
+	return parameters, length
+}
+
+// utf8Number encodes the number with the UTF-8 scheme (extended to 36
+// bits), as required for the frame number.
+func utf8Number(v uint64) []byte {
+	if v < 0x80 {
+		return []byte{byte(v)}
+	}
+	// Number of continuation bytes (6 bits each)
+	n := 1
+	for v>>(6*n) >= 1<<(6-n) {
+		n++
+	}
+	b := make([]byte, n+1)
+	for i := n; i > 0; i-- {
+		b[i] = 0x80 | byte(v&0x3F)
+		v >>= 6
+	}
+	b[0] = byte(0xFF<<(7-n)) | byte(v)
+	return b
+}
+
+// crc8 is the CRC of the frame header (polynomial x^8 + x^2 + x + 1)
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 is the CRC of the frame (polynomial x^16 + x^15 + x^2 + 1)
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// -------------------------------------------------------------
+// Bit writer
+
+// bitWriter writes values on any number of bits, the most significant
+// bit first.
+type bitWriter struct {
+	buffer []byte
+	acc    uint64 // bits not yet written in the buffer
+	n      uint   // number of bits in acc (less than 8)
+}
+
+// write writes the lowest bits of the value (at most 32 bits)
+func (w *bitWriter) write(v uint64, bits uint) {
+	for bits > 32 {
+		w.write(v>>32, bits-32)
+		v &= math.MaxUint32
+		bits = 32
+	}
+	if bits == 0 {
+		return
+	}
+	w.acc = w.acc<<bits | v&(1<<bits-1)
+	w.n += bits
+	for w.n >= 8 {
+		w.buffer = append(w.buffer, byte(w.acc>>(w.n-8)))
+		w.n -= 8
+	}
+	w.acc &= 1<<w.n - 1
+}
+
+// writeSigned writes a signed value in two's complement
+func (w *bitWriter) writeSigned(v int64, bits uint) {
+	w.write(uint64(v), bits)
+}
+
+// writeUnary writes v zeros followed by a one
+func (w *bitWriter) writeUnary(v uint64) {
+	for v >= 32 {
+		w.write(0, 32)
+		v -= 32
+	}
+	w.write(1, uint(v)+1)
+}
+
+// append writes all the bits of another writer
+func (w *bitWriter) append(other *bitWriter) {
+	for _, b := range other.buffer {
+		w.write(uint64(b), 8)
+	}
+	w.write(other.acc, other.n)
+}
+
+// align writes zeros up to the next byte
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.write(0, 8-w.n)
+	}
+}
+
+// length returns the number of bits written
+func (w *bitWriter) length() int {
+	return 8*len(w.buffer) + int(w.n)
+}
+
+// bytes returns the complete bytes written
+func (w *bitWriter) bytes() []byte {
+	return w.buffer
+}
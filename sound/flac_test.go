@@ -0,0 +1,377 @@
+package sound
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// bitReader reads values on any number of bits, the most significant
+// bit first (the inverse of bitWriter).
+type bitReader struct {
+	data []byte
+	pos  int // position in bits
+}
+
+func (r *bitReader) read(bits int) (uint64, error) {
+	var v uint64
+	for range bits {
+		if r.pos >= 8*len(r.data) {
+			return 0, errors.New("unexpected end of data")
+		}
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+func (r *bitReader) readSigned(bits int) (int64, error) {
+	v, err := r.read(bits)
+	if err != nil {
+		return 0, err
+	}
+	// Sign extension
+	return int64(v<<(64-bits)) >> (64 - bits), nil
+}
+
+func (r *bitReader) readUnary() (uint64, error) {
+	var v uint64
+	for {
+		bit, err := r.read(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			return v, nil
+		}
+		v++
+	}
+}
+
+// decodeFLAC is a minimal FLAC decoder, that supports the features used
+// by the encoder, to check that the encoded stream is valid (CRCs, MD5
+// signature) and that the decoded samples are the quantized samples.
+func decodeFLAC(data []byte) (AudioFormat, [][]int64, error) {
+	var format AudioFormat
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return format, nil, errors.New("no stream marker")
+	}
+	r := &bitReader{data: data, pos: 32}
+
+	// Metadata blocks
+	var total uint64
+	var signature []byte
+	for last := uint64(0); last == 0; {
+		last, _ = r.read(1)
+		kind, _ := r.read(7)
+		length, _ := r.read(24)
+		end := r.pos + 8*int(length)
+		if kind == 0 {
+			r.read(16 + 16 + 24 + 24)
+			rate, _ := r.read(20)
+			channels, _ := r.read(3)
+			bps, _ := r.read(5)
+			total, _ = r.read(36)
+			format = AudioFormat{SampleRate: int(rate), Channels: int(channels) + 1, BitDepth: int(bps) + 1}
+			signature = data[r.pos/8 : r.pos/8+16]
+		}
+		r.pos = end
+	}
+
+	samples := make([][]int64, format.Channels)
+	for number := 0; uint64(len(samples[0])) < total; number++ {
+		start := r.pos / 8
+		sync, _ := r.read(16)
+		if sync != 0xFFF8 {
+			return format, nil, fmt.Errorf("frame %d: bad sync code %x", number, sync)
+		}
+		blockSizeCode, _ := r.read(4)
+		rateCode, _ := r.read(4)
+		assignment, _ := r.read(4)
+		sizeCode, _ := r.read(3)
+		r.read(1)
+		if blockSizeCode != 0x7 || rateCode != 0 {
+			return format, nil, fmt.Errorf("frame %d: unsupported header", number)
+		}
+		if codes := map[uint64]int{1: 8, 4: 16, 6: 24}; codes[sizeCode] != format.BitDepth {
+			return format, nil, fmt.Errorf("frame %d: bad sample size code %d", number, sizeCode)
+		}
+		// Frame number (UTF-8 coded)
+		first, _ := r.read(8)
+		continuation := 0
+		for mask := uint64(0x80); first&mask != 0; mask >>= 1 {
+			continuation++
+		}
+		if continuation > 0 {
+			continuation--
+		}
+		value := first & (0xFF >> (continuation + 2))
+		if continuation == 0 {
+			value = first
+		}
+		for range continuation {
+			b, _ := r.read(8)
+			value = value<<6 | b&0x3F
+		}
+		if value != uint64(number) {
+			return format, nil, fmt.Errorf("frame %d: bad frame number %d", number, value)
+		}
+		size, _ := r.read(16)
+		blockSize := int(size) + 1
+		if crc, _ := r.read(8); byte(crc) != crc8(data[start:r.pos/8-1]) {
+			return format, nil, fmt.Errorf("frame %d: bad header CRC", number)
+		}
+
+		// Subframes
+		block := make([][]int64, format.Channels)
+		for c := range block {
+			bps := format.BitDepth
+			if (assignment == 8 && c == 1) || (assignment == 9 && c == 0) || (assignment == 10 && c == 1) {
+				bps++ // side channel
+			}
+			channel, err := decodeFLACSubframe(r, blockSize, bps)
+			if err != nil {
+				return format, nil, fmt.Errorf("frame %d, channel %d: %w", number, c, err)
+			}
+			block[c] = channel
+		}
+		for i := range blockSize {
+			switch assignment {
+			case 8: // left, side
+				block[1][i] = block[0][i] - block[1][i]
+			case 9: // side, right
+				block[0][i] = block[0][i] + block[1][i]
+			case 10: // mid, side
+				mid := block[0][i]<<1 | block[1][i]&1
+				block[0][i] = (mid + block[1][i]) >> 1
+				block[1][i] = (mid - block[1][i]) >> 1
+			}
+		}
+		for c := range block {
+			samples[c] = append(samples[c], block[c]...)
+		}
+
+		// Padding and footer
+		if r.pos%8 != 0 {
+			r.pos += 8 - r.pos%8
+		}
+		crc, _ := r.read(16)
+		if uint16(crc) != crc16(data[start:r.pos/8-2]) {
+			return format, nil, fmt.Errorf("frame %d: bad frame CRC", number)
+		}
+	}
+	if r.pos != 8*len(data) {
+		return format, nil, errors.New("unexpected data after the last frame")
+	}
+
+	// MD5 signature of the samples
+	h := md5.New()
+	width := format.BitDepth / 8
+	for i := range samples[0] {
+		for c := range samples {
+			for k := range width {
+				h.Write([]byte{byte(samples[c][i] >> (8 * k))})
+			}
+		}
+	}
+	if !bytes.Equal(h.Sum(nil), signature) {
+		return format, nil, errors.New("bad MD5 signature")
+	}
+	return format, samples, nil
+}
+
+func decodeFLACSubframe(r *bitReader, blockSize int, bps int) ([]int64, error) {
+	header, _ := r.read(8)
+	kind := header >> 1 & 0x3F
+	samples := make([]int64, blockSize)
+	switch {
+	case kind == 0: // constant
+		v, err := r.readSigned(bps)
+		for i := range samples {
+			samples[i] = v
+		}
+		return samples, err
+	case kind == 1: // verbatim
+		for i := range samples {
+			v, err := r.readSigned(bps)
+			if err != nil {
+				return nil, err
+			}
+			samples[i] = v
+		}
+		return samples, nil
+	case kind >= 8 && kind <= 12: // fixed
+		order := int(kind - 8)
+		for i := range order {
+			samples[i], _ = r.readSigned(bps)
+		}
+		method, _ := r.read(2)
+		parameterBits := 4 + int(method)
+		partitionOrder, _ := r.read(4)
+		i := order
+		for p := range 1 << partitionOrder {
+			count := blockSize >> partitionOrder
+			if p == 0 {
+				count -= order
+			}
+			k, _ := r.read(parameterBits)
+			for range count {
+				q, err := r.readUnary()
+				if err != nil {
+					return nil, err
+				}
+				low, _ := r.read(int(k))
+				u := q<<k | low
+				residual := int64(u>>1) ^ -int64(u&1)
+				s := samples
+				switch order {
+				case 0:
+					s[i] = residual
+				case 1:
+					s[i] = residual + s[i-1]
+				case 2:
+					s[i] = residual + 2*s[i-1] - s[i-2]
+				case 3:
+					s[i] = residual + 3*s[i-1] - 3*s[i-2] + s[i-3]
+				case 4:
+					s[i] = residual + 4*s[i-1] - 6*s[i-2] + 4*s[i-3] - s[i-4]
+				}
+				i++
+			}
+		}
+		return samples, nil
+	default:
+		return nil, fmt.Errorf("unsupported subframe type %d", kind)
+	}
+}
+
+func TestEncodeFLAC(t *testing.T) {
+	r := 44100
+	n := 3*flacBlockSize + 100
+	sine := make([]float64, n)
+	noise := make([]float64, n)
+	ramp := make([]float64, n)
+	for i := range n {
+		sine[i] = 0.8 * math.Sin(2*math.Pi*440*float64(i)/float64(r))
+		noise[i] = math.Sin(float64(i*i) * 0.37) // not predictable
+		ramp[i] = -1 + 2*float64(i)/float64(n)
+	}
+	constant := make([]float64, n)
+	for i := range constant {
+		constant[i] = 0.25
+	}
+
+	tests := []struct {
+		name     string
+		channels [][]float64
+		bitDepth int
+		dither   bool
+	}{
+		{"mono16", [][]float64{sine}, 16, false},
+		{"mono8", [][]float64{noise}, 8, false},
+		{"mono24", [][]float64{ramp}, 24, true},
+		{"stereo-identical", [][]float64{sine, sine}, 16, false},
+		{"stereo-different", [][]float64{sine, noise}, 16, false},
+		{"stereo24", [][]float64{ramp, sine}, 24, false},
+		{"constant", [][]float64{constant, sine, noise}, 16, false},
+		{"short", [][]float64{sine[:3], noise[:3]}, 16, false},
+		{"single", [][]float64{sine[100:101]}, 16, false},
+		{"empty", [][]float64{{}, {}}, 16, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: r, BitDepth: tt.bitDepth}, Dither: tt.dither}
+			var buffer bytes.Buffer
+			if err := EncodeFLAC(&buffer, tt.channels, options); err != nil {
+				t.Fatal(err)
+			}
+			format, samples, err := decodeFLAC(buffer.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := AudioFormat{SampleRate: r, Channels: len(tt.channels), BitDepth: tt.bitDepth}
+			if format != exp {
+				t.Errorf("format is %+v (should be %+v)", format, exp)
+			}
+			scale := math.Ldexp(1, tt.bitDepth-1)
+			for c, channel := range tt.channels {
+				if len(samples[c]) != len(channel) {
+					t.Fatalf("number of samples is %d (should be %d)", len(samples[c]), len(channel))
+				}
+				for i, v := range channel {
+					// The dither adds at most 1 step to the rounding error
+					if math.Abs(float64(samples[c][i])-v*scale) > 1.5 {
+						t.Fatalf("sample %d of channel %d is %d (should be %.1f)", i, c, samples[c][i], v*scale)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeFLAC_Compression(t *testing.T) {
+	// A sine is very predictable, and the FLAC stream should be much
+	// smaller than the WAV stream
+	r := 44100
+	samples := make([]float64, r)
+	for i := range samples {
+		samples[i] = 0.5 * math.Sin(2*math.Pi*220*float64(i)/float64(r))
+	}
+	options := DefaultEncodeOptions(r)
+	var wav, flac bytes.Buffer
+	if err := EncodeWAV(&wav, [][]float64{samples, samples}, options.stereo()); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeFLAC(&flac, [][]float64{samples, samples}, options.stereo()); err != nil {
+		t.Fatal(err)
+	}
+	if ratio := float64(flac.Len()) / float64(wav.Len()); ratio > 0.3 {
+		t.Errorf("compression ratio is %.2f (should be lower than 0.3)", ratio)
+	}
+}
+
+func (o EncodeOptions) stereo() EncodeOptions {
+	o.Channels = 2
+	return o
+}
+
+func TestEncodeFLAC_Errors(t *testing.T) {
+	samples := [][]float64{{0, 0.5}}
+	for name, options := range map[string]EncodeOptions{
+		"float":    {AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 32, Float: true}},
+		"bitdepth": {AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 32}},
+		"rate":     {AudioFormat: AudioFormat{SampleRate: 1 << 20, BitDepth: 16}},
+	} {
+		var buffer bytes.Buffer
+		if err := EncodeFLAC(&buffer, samples, options); err == nil {
+			t.Errorf("encoding with %s should fail", name)
+		}
+	}
+	var buffer bytes.Buffer
+	if err := EncodeFLAC(&buffer, make([][]float64, 9), DefaultEncodeOptions(8000)); err == nil {
+		t.Errorf("encoding 9 channels should fail")
+	}
+}
+
+func TestUTF8Number(t *testing.T) {
+	tests := []struct {
+		v   uint64
+		exp []byte
+	}{
+		{0, []byte{0x00}},
+		{0x7F, []byte{0x7F}},
+		{0x80, []byte{0xC2, 0x80}},
+		{0x7FF, []byte{0xDF, 0xBF}},
+		{0x800, []byte{0xE0, 0xA0, 0x80}},
+		{0x10000, []byte{0xF0, 0x90, 0x80, 0x80}},
+	}
+	for _, tt := range tests {
+		if got := utf8Number(tt.v); !bytes.Equal(got, tt.exp) {
+			t.Errorf("utf8 of %x is % x (should be % x)", tt.v, got, tt.exp)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package sound
+
+// This file implements a multi-track mixer. Each track is a streamer
+// with a gain (in dB), a position in the stereo field, and the mute and
+// solo switches, as on a mixing desk. The tracks are summed on the
+// master bus, that applies a master gain and a limiter, so that the sum
+// of the tracks never exceeds the full scale (beep.Mix simply adds the
+// samples, and the sum is clipped by the speaker when it exceeds 1).
+//
+// Each track, and the master bus, can have an insert effect (see the
+// package effects), e.g. a compressor or a noise gate. The latency of
+// the effects (their lookahead) is not compensated by the mixer.
+//
+// The mixer is a beep.Streamer: it can be played live (see Play or
+// PlayAsync), or rendered offline (see Render, RenderFile). The levels
+// (peak and RMS) of each track and of the master bus are measured while
+// the mixer is streamed.
+
+import (
+	"math"
+
+	"github.com/gboulant/musicall/effects"
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+)
+
+// DBToGain converts a level in decibels to a linear gain (0 dB is a
+// gain of 1, -6 dB is about 0.5).
+func DBToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// GainToDB converts a linear gain to a level in decibels (-Inf for 0)
+func GainToDB(gain float64) float64 {
+	return 20 * math.Log10(math.Abs(gain))
+}
+
+// -------------------------------------------------------------
+// Meter measures the levels of a signal: the peak (the maximum of the
+// absolute values of the samples) and the RMS (root mean square, that
+// characterizes the power of the signal).
+type Meter struct {
+	peak  float64
+	sum   float64 // sum of the squares of the samples
+	count int
+}
+
+func (m *Meter) measure(samples [][2]float64) {
+	for _, s := range samples {
+		for _, v := range s {
+			m.peak = math.Max(m.peak, math.Abs(v))
+			m.sum += v * v
+		}
+	}
+	m.count += 2 * len(samples)
+}
+
+// Peak returns the peak level (linear)
+func (m Meter) Peak() float64 {
+	return m.peak
+}
+
+// RMS returns the RMS level (linear)
+func (m Meter) RMS() float64 {
+	if m.count == 0 {
+		return 0
+	}
+	return math.Sqrt(m.sum / float64(m.count))
+}
+
+// PeakDB returns the peak level in dB (relatively to the full scale)
+func (m Meter) PeakDB() float64 {
+	return GainToDB(m.Peak())
+}
+
+// RMSDB returns the RMS level in dB (relatively to the full scale)
+func (m Meter) RMSDB() float64 {
+	return GainToDB(m.RMS())
+}
+
+// Reset clears the levels measured so far
+func (m *Meter) Reset() {
+	*m = Meter{}
+}
+
+// -------------------------------------------------------------
+// Output limiter
+
+// OutputLimiter enables the brick-wall limiter of the output: the
+// streamers played (Play, PlayAsync) or rendered into files (Save,
+// RenderWAV, RenderFile) never exceed OutputCeiling, so that a sum of
+// notes can not be clipped. The samples below the ceiling are not
+// changed (the lookahead of the limiter is compensated). The in-memory
+// rendering (Render) is not limited.
+var OutputLimiter = true
+
+// OutputCeiling is the ceiling of the output limiter in dB (0 dB is the
+// full scale, the clipping level)
+var OutputCeiling = 0.
+
+// Parameters of the output limiter, in seconds
+const (
+	outputRelease   = 0.1
+	outputLookahead = effects.DefaultLookahead
+)
+
+// mixerCeiling is the ceiling of the limiter of the master bus of the
+// mixer in dB
+const mixerCeiling = -1
+
+// limitOutput returns the streamer through the output limiter, if it is
+// enabled
+func limitOutput(s beep.Streamer, sampleRate int) beep.Streamer {
+	if !OutputLimiter {
+		return s
+	}
+	l := effects.NewLimiter(OutputCeiling, outputRelease, outputLookahead, sampleRate)
+	return effects.Stream(s, l, 0, sampleRate)
+}
+
+// -------------------------------------------------------------
+// Track is a track of the mixer
+type Track struct {
+	Name  string
+	Gain  float64 // gain in dB (0 by default)
+	Pan   float64 // position in the stereo field, from -1 (left) to +1 (right)
+	Mute  bool
+	Solo  bool
+	Meter Meter // levels of the track, after the gain and the pan
+
+	// Effect is the insert effect of the track (nil by default), applied
+	// before the gain and the pan
+	Effect effects.Effect
+
+	streamer beep.Streamer
+	buffer   [][2]float64
+	done     bool
+}
+
+// panGains returns the gains of the channels for the pan of the track.
+// The gains of the constant power pan law are normalized so that a
+// track at the center is unchanged (both gains are 1).
+func (t *Track) panGains() (left, right float64) {
+	left, right = PanGains(t.Pan)
+	return math.Sqrt2 * left, math.Sqrt2 * right
+}
+
+// Mixer mixes several tracks on a master bus. By default, the master
+// bus has a brick-wall limiter (the limiter of the output, without
+// lookahead so that the mixer has no latency) with a ceiling of -1 dB,
+// so that the sum of the tracks can not be clipped. Set Limiter to nil
+// for disabling it.
+//
+// The parameters of the tracks and of the master bus can be changed
+// while the mixer is played, between the calls to Lock and Unlock.
+type Mixer struct {
+	SampleRate int
+	MasterGain float64 // gain of the master bus in dB
+	Limiter    *effects.Limiter
+	Master     Meter // levels of the master bus, after the limiter
+
+	// Effect is the insert effect of the master bus (nil by default),
+	// applied after the master gain and before the limiter
+	Effect effects.Effect
+
+	tracks []*Track
+	err    error
+}
+
+// NewMixer returns a mixer, with no track, for streamers at the
+// specified sample rate.
+func NewMixer(sampleRate int) *Mixer {
+	return &Mixer{
+		SampleRate: wave.SampleRate(sampleRate),
+		Limiter:    effects.NewLimiter(mixerCeiling, outputRelease, 0, sampleRate),
+	}
+}
+
+// AddTrack adds a track playing the streamer, and returns it so that
+// its parameters can be set.
+func (m *Mixer) AddTrack(name string, s beep.Streamer) *Track {
+	t := &Track{Name: name, streamer: s}
+	m.tracks = append(m.tracks, t)
+	return t
+}
+
+// Track returns the track with the specified name
+func (m *Mixer) Track(name string) (*Track, bool) {
+	for _, t := range m.tracks {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Tracks returns the tracks of the mixer, in the order of their
+// creation.
+func (m *Mixer) Tracks() []*Track {
+	return m.tracks
+}
+
+// Stream implements the beep.Streamer interface. The mixer streams as
+// long as one of its tracks is streaming. A track that returns no
+// samples but is not drained (a live source waiting for its events) is
+// kept in the mix.
+func (m *Mixer) Stream(samples [][2]float64) (int, bool) {
+	solo := false
+	for _, t := range m.tracks {
+		solo = solo || t.Solo
+	}
+
+	for i := range samples {
+		samples[i] = [2]float64{}
+	}
+	n := 0
+	active := false
+	for _, t := range m.tracks {
+		if t.done {
+			continue
+		}
+		if cap(t.buffer) < len(samples) {
+			t.buffer = make([][2]float64, len(samples))
+		}
+		buffer := t.buffer[:len(samples)]
+		tn, ok := t.streamer.Stream(buffer)
+		if !ok {
+			t.done = true
+			if err := t.streamer.Err(); err != nil && m.err == nil {
+				m.err = err
+			}
+			continue
+		}
+		active = true
+		if tn == 0 {
+			continue
+		}
+		n = max(n, tn)
+
+		// A muted track (or a track not in solo, when some tracks are in
+		// solo) is still streamed, so that it stays synchronized.
+		gain := DBToGain(t.Gain)
+		if t.Mute || (solo && !t.Solo) {
+			gain = 0
+		}
+		gl, gr := t.panGains()
+		for i := range buffer[:tn] {
+			if t.Effect != nil {
+				buffer[i] = t.Effect.Process(buffer[i])
+			}
+			buffer[i][0] *= gain * gl
+			buffer[i][1] *= gain * gr
+			samples[i][0] += buffer[i][0]
+			samples[i][1] += buffer[i][1]
+		}
+		t.Meter.measure(buffer[:tn])
+	}
+	if n == 0 {
+		return 0, active
+	}
+
+	gain := DBToGain(m.MasterGain)
+	for i := range samples[:n] {
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+		if m.Effect != nil {
+			samples[i] = m.Effect.Process(samples[i])
+		}
+		if m.Limiter != nil {
+			samples[i] = m.Limiter.Process(samples[i])
+		}
+	}
+	m.Master.measure(samples[:n])
+	return n, true
+}
+
+// Err returns the first error of the streamers of the tracks
+func (m *Mixer) Err() error {
+	return m.err
+}
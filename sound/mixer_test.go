@@ -0,0 +1,324 @@
+package sound
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/effects"
+	"github.com/gopxl/beep"
+)
+
+func constant(value float64, frames int) []float64 {
+	samples := make([]float64, frames)
+	for i := range samples {
+		samples[i] = value
+	}
+	return samples
+}
+
+func TestDBToGain(t *testing.T) {
+	tests := []struct {
+		db, gain float64
+	}{
+		{0, 1},
+		{-20, 0.1},
+		{20, 10},
+		{-6.0206, 0.5},
+	}
+	for _, tt := range tests {
+		if g := DBToGain(tt.db); !almostEqual(g, tt.gain, 1e-4) {
+			t.Errorf("gain of %v dB is %v (should be %v)", tt.db, g, tt.gain)
+		}
+		if db := GainToDB(tt.gain); !almostEqual(db, tt.db, 1e-4) {
+			t.Errorf("level of %v is %v dB (should be %v)", tt.gain, db, tt.db)
+		}
+	}
+	if db := GainToDB(0); !math.IsInf(db, -1) {
+		t.Errorf("level of 0 is %v dB (should be -Inf)", db)
+	}
+}
+
+func TestMeter(t *testing.T) {
+	var m Meter
+	m.measure([][2]float64{{0.5, -0.5}, {-1, 1}})
+	if m.Peak() != 1 {
+		t.Errorf("peak is %v (should be 1)", m.Peak())
+	}
+	if exp := math.Sqrt(0.625); !almostEqual(m.RMS(), exp, 1e-12) {
+		t.Errorf("RMS is %v (should be %v)", m.RMS(), exp)
+	}
+	if m.PeakDB() != 0 {
+		t.Errorf("peak is %v dB (should be 0)", m.PeakDB())
+	}
+	m.Reset()
+	if m.Peak() != 0 || m.RMS() != 0 {
+		t.Errorf("levels should be 0 after a reset")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	r := 1000
+	// The limiter of the mixer, without lookahead
+	l := effects.NewLimiter(-6.0206, 0.01, 0, r)
+	samples := make([][2]float64, 100)
+	for i := range samples {
+		samples[i] = [2]float64{0.25, 0.25}
+	}
+	samples[10] = [2]float64{1, -0.8}
+	for i := range samples {
+		samples[i] = l.Process(samples[i])
+	}
+
+	// Below the ceiling, the samples are unchanged
+	for i := range 10 {
+		if samples[i][0] != 0.25 {
+			t.Fatalf("sample %d is %v (should be 0.25)", i, samples[i][0])
+		}
+	}
+	// The peak is reduced to the ceiling
+	if !almostEqual(samples[10][0], 0.5, 1e-4) || !almostEqual(samples[10][1], -0.4, 1e-4) {
+		t.Errorf("limited sample is %v (should be [0.5 -0.4])", samples[10])
+	}
+	// Then the gain is released progressively
+	if samples[11][0] >= 0.25 || samples[11][0] <= samples[10][0]/4 {
+		t.Errorf("sample after the peak is %v (should be between 0.125 and 0.25)", samples[11][0])
+	}
+	if !almostEqual(samples[99][0], 0.25, 1e-3) {
+		t.Errorf("sample after the release is %v (should be 0.25)", samples[99][0])
+	}
+	if gr := l.GainReduction(); gr < 0 || gr > 0.01 {
+		t.Errorf("gain reduction is %v dB (should be near 0)", gr)
+	}
+}
+
+func TestMixer(t *testing.T) {
+	r := 8000
+	m := NewMixer(r)
+	m.Limiter = nil
+	a := m.AddTrack("a", NewSound(constant(0.5, 100)))
+	b := m.AddTrack("b", NewSound(constant(0.25, 50)))
+	b.Gain = -6.0206
+	b.Pan = 1
+
+	rec, err := Render(m, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != 100 {
+		t.Fatalf("number of frames is %d (should be 100)", rec.Frames())
+	}
+	// The track b is on the right, with a gain of √2 (constant power)
+	right := 0.5 + 0.125*math.Sqrt2
+	for i := range 100 {
+		expL, expR := 0.5, 0.5
+		if i < 50 {
+			expR = right
+		}
+		if !almostEqual(rec.Channels[0][i], expL, 1e-4) || !almostEqual(rec.Channels[1][i], expR, 1e-4) {
+			t.Fatalf("frame %d is (%v, %v) (should be (%v, %v))", i, rec.Channels[0][i], rec.Channels[1][i], expL, expR)
+		}
+	}
+
+	if !almostEqual(a.Meter.Peak(), 0.5, 1e-12) {
+		t.Errorf("peak of track a is %v (should be 0.5)", a.Meter.Peak())
+	}
+	if !almostEqual(b.Meter.Peak(), 0.125*math.Sqrt2, 1e-4) {
+		t.Errorf("peak of track b is %v (should be %v)", b.Meter.Peak(), 0.125*math.Sqrt2)
+	}
+	if !almostEqual(m.Master.Peak(), right, 1e-4) {
+		t.Errorf("peak of master is %v (should be %v)", m.Master.Peak(), right)
+	}
+	if track, ok := m.Track("b"); !ok || track != b {
+		t.Errorf("track b should be found")
+	}
+	if _, ok := m.Track("c"); ok {
+		t.Errorf("track c should not be found")
+	}
+}
+
+func TestMixer_MuteSolo(t *testing.T) {
+	r := 8000
+	render := func(mute, solo string) float64 {
+		m := NewMixer(r)
+		for i, name := range []string{"a", "b", "c"} {
+			track := m.AddTrack(name, NewSound(constant(math.Ldexp(1, -i-2), 10)))
+			track.Mute = name == mute
+			track.Solo = name == solo
+		}
+		rec, err := Render(m, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Frames() != 10 {
+			t.Fatalf("number of frames is %d (should be 10)", rec.Frames())
+		}
+		return rec.Channels[0][5]
+	}
+
+	tests := []struct {
+		mute, solo string
+		exp        float64
+	}{
+		{"", "", 0.25 + 0.125 + 0.0625},
+		{"a", "", 0.125 + 0.0625},
+		{"", "b", 0.125},
+		{"b", "b", 0},
+	}
+	for _, tt := range tests {
+		if v := render(tt.mute, tt.solo); !almostEqual(v, tt.exp, 1e-12) {
+			t.Errorf("mix with mute=%q solo=%q is %v (should be %v)", tt.mute, tt.solo, v, tt.exp)
+		}
+	}
+}
+
+func TestMixer_Limiter(t *testing.T) {
+	// The sum of the tracks exceeds 1, but is limited to -1 dB
+	r := 8000
+	m := NewMixer(r)
+	m.AddTrack("a", NewSound(constant(0.8, 1000)))
+	m.AddTrack("b", NewSound(constant(0.8, 1000)))
+	rec, err := Render(m, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ceiling := DBToGain(-1)
+	for i, v := range rec.Channels[0] {
+		if v > ceiling+1e-12 {
+			t.Fatalf("sample %d is %v (should be lower than %v)", i, v, ceiling)
+		}
+	}
+	if !almostEqual(m.Master.Peak(), ceiling, 1e-12) {
+		t.Errorf("peak of master is %v (should be %v)", m.Master.Peak(), ceiling)
+	}
+	if gr := m.Limiter.GainReduction(); !almostEqual(gr, GainToDB(1.6)+1, 1e-9) {
+		t.Errorf("gain reduction is %v dB (should be %v)", gr, GainToDB(1.6)+1)
+	}
+}
+
+func TestMixer_Effects(t *testing.T) {
+	// A track with a noise gate, a master bus with a compressor
+	r := 8000
+	m := NewMixer(r)
+	m.Limiter = nil
+	a := m.AddTrack("a", NewSound(constant(0.001, 1000)))
+	a.Effect = effects.NewGate(-40, 0, 0, r)
+	m.AddTrack("b", NewSound(constant(0.5, 1000)))
+	m.Effect = effects.NewCompressor(-12, 1000, 0, 0, r)
+	m.Effect.(*effects.Compressor).Knee = effects.Const(0)
+	rec, err := Render(m, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The track a is muted by its gate, the master is compressed to -12 dB
+	want := DBToGain(-12)
+	if v := rec.Channels[0][500]; !almostEqual(v, want, 1e-3) {
+		t.Errorf("sample is %v (should be %v)", v, want)
+	}
+}
+
+func TestOutputLimiter(t *testing.T) {
+	// The sum of two notes exceeds 1: it is limited when it is played
+	testBackend.Reset()
+	s := beep.Mix(NewSound(constant(0.8, 1000)), NewSound(constant(0.8, 1000)))
+	if err := Play(s); err != nil {
+		t.Fatal(err)
+	}
+	rec, _ := testBackend.Last()
+	if rec.Frames() != 1000 {
+		t.Errorf("number of samples played is %d (should be 1000)", rec.Frames())
+	}
+	for i, v := range rec.Channels[0] {
+		if v > 1 {
+			t.Fatalf("sample %d is %v (should be lower than 1)", i, v)
+		}
+	}
+
+	// The samples below the ceiling are not changed
+	samples := []float64{0.5, -0.25, 1, -1}
+	Play(NewSound(samples))
+	rec, _ = testBackend.Last()
+	checkChannels(t, rec, [][]float64{samples, samples}, 1e-15)
+
+	// Without the output limiter, the sum is played as is
+	OutputLimiter = false
+	defer func() { OutputLimiter = true }()
+	Play(beep.Mix(NewSound(constant(0.8, 10)), NewSound(constant(0.8, 10))))
+	rec, _ = testBackend.Last()
+	if v := rec.Channels[0][0]; v != 1.6 {
+		t.Errorf("sample is %v (should be 1.6)", v)
+	}
+}
+
+type errorStreamer struct{}
+
+func (errorStreamer) Stream(samples [][2]float64) (int, bool) { return 0, false }
+func (errorStreamer) Err() error                              { return errors.New("streamer error") }
+
+func TestMixer_Err(t *testing.T) {
+	m := NewMixer(8000)
+	m.AddTrack("a", beep.Seq(NewSound(constant(0.1, 10))))
+	m.AddTrack("b", errorStreamer{})
+	if _, err := Render(m, 8000); err == nil {
+		t.Errorf("the error of a track should be reported")
+	}
+
+	// A mixer without tracks is an empty streamer
+	rec, err := Render(NewMixer(8000), 8000)
+	if err != nil || rec.Frames() != 0 {
+		t.Errorf("empty mixer gives %d frames (should be 0)", rec.Frames())
+	}
+}
+
+// waitingStreamer returns no samples for the first calls (a live source
+// waiting for its events), then the samples
+type waitingStreamer struct {
+	wait    int
+	samples beep.Streamer
+}
+
+func (s *waitingStreamer) Stream(samples [][2]float64) (int, bool) {
+	if s.wait > 0 {
+		s.wait--
+		return 0, true
+	}
+	return s.samples.Stream(samples)
+}
+
+func (s *waitingStreamer) Err() error { return nil }
+
+func TestMixer_Waiting(t *testing.T) {
+	// A track that is waiting is not dropped from the mix
+	m := NewMixer(8000)
+	m.Limiter = nil
+	m.AddTrack("live", &waitingStreamer{wait: 3, samples: NewSound(constant(0.5, 10))})
+	buffer := make([][2]float64, 4)
+	if n, ok := m.Stream(buffer); n != 0 || !ok {
+		t.Errorf("the waiting mixer streams (%d, %v) (should be (0, true))", n, ok)
+	}
+	rec, err := Render(m, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != 10 {
+		t.Errorf("number of frames is %d (should be 10)", rec.Frames())
+	}
+}
+
+func TestOutputLimiter_Waiting(t *testing.T) {
+	// A waiting streamer is not ended by the output limiter
+	s := limitOutput(&waitingStreamer{wait: 3, samples: NewSound(constant(0.5, 1000))}, 8000)
+	rec, err := Render(s, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != 1000 {
+		t.Errorf("number of frames is %d (should be 1000)", rec.Frames())
+	}
+
+	m := NewMixer(8000)
+	m.AddTrack("live", &waitingStreamer{wait: 3, samples: NewSound(constant(0.5, 1000))})
+	if rec, _ = Render(limitOutput(m, 8000), 8000); rec.Frames() != 1000 {
+		t.Errorf("number of frames of the mixer is %d (should be 1000)", rec.Frames())
+	}
+}
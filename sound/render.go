@@ -0,0 +1,71 @@
+package sound
+
+// The rendering consists in pulling all the samples of a streamer (a
+// sound, a sequence or a mix of sounds) into memory or into a file,
+// without playing it. The rendering does not need any audio device and
+// is much faster than real time.
+
+import (
+	"github.com/gopxl/beep"
+)
+
+// Render pulls the streamer to its end and returns the samples of the
+// two channels (left and right) at the specified sample rate. The
+// streamer must be finite (see RenderDuration otherwise).
+func Render(s beep.Streamer, sampleRate int) (Recording, error) {
+	left := make([]float64, 0)
+	right := make([]float64, 0)
+	buffer := make([][2]float64, 4096)
+	for {
+		n, ok := s.Stream(buffer)
+		for _, frame := range buffer[:n] {
+			left = append(left, frame[0])
+			right = append(right, frame[1])
+		}
+		if !ok {
+			break
+		}
+	}
+	rec := Recording{
+		Format:   AudioFormat{SampleRate: sampleRate, Channels: 2, BitDepth: 64, Float: true},
+		Channels: [][]float64{left, right},
+	}
+	return rec, s.Err()
+}
+
+// RenderDuration renders the first seconds of the streamer (that could
+// be infinite, as a generator).
+func RenderDuration(s beep.Streamer, sampleRate int, duration float64) (Recording, error) {
+	return Render(beep.Take(int(duration*float64(sampleRate)), s), sampleRate)
+}
+
+// RenderWAV renders the streamer into a WAV file at the specified path,
+// at the sample rate of the options. If the options define a single
+// channel, the two channels of the streamer are mixed. The output
+// limiter is applied (see OutputLimiter).
+func RenderWAV(s beep.Streamer, path string, options EncodeOptions) error {
+	return renderWith(EncoderFunc(EncodeWAV), s, path, options)
+}
+
+// RenderFile renders the streamer into an audio file at the specified
+// path, as RenderWAV, but the encoder is selected by the extension of
+// the file (see EncoderFor).
+func RenderFile(s beep.Streamer, path string, options EncodeOptions) error {
+	e, err := EncoderFor(path)
+	if err != nil {
+		return err
+	}
+	return renderWith(e, s, path, options)
+}
+
+func renderWith(e Encoder, s beep.Streamer, path string, options EncodeOptions) error {
+	rec, err := Render(limitOutput(s, options.SampleRate), options.SampleRate)
+	if err != nil {
+		return err
+	}
+	if options.Channels == 1 {
+		return saveWith(e, path, [][]float64{rec.Mono()}, options)
+	}
+	options.Channels = len(rec.Channels)
+	return saveWith(e, path, rec.Channels, options)
+}
@@ -0,0 +1,83 @@
+package sound
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/generators"
+)
+
+func TestRender(t *testing.T) {
+	r := 8000
+	samples := wave.SineWaveSignal(440, 0.5, 1.5, r)
+	rec, err := Render(beep.Seq(NewSound(samples), Silence(0.5, r)), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != 2*r {
+		t.Errorf("number of samples is %d (should be %d)", rec.Frames(), 2*r)
+	}
+	if rec.Format.SampleRate != r || len(rec.Channels) != 2 {
+		t.Errorf("format is %+v", rec.Format)
+	}
+	for i, v := range samples {
+		if rec.Channels[0][i] != v || rec.Channels[1][i] != v {
+			t.Fatalf("sample %d is %v (should be %v)", i, rec.Channels[0][i], v)
+		}
+	}
+}
+
+func TestRenderDuration(t *testing.T) {
+	// A generator is an infinite streamer
+	r := 8000
+	rec, err := RenderDuration(generators.Silence(-1), r, 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames() != r/4 {
+		t.Errorf("number of samples is %d (should be %d)", rec.Frames(), r/4)
+	}
+}
+
+func TestRenderWAV(t *testing.T) {
+	path := "output.TestRenderWAV.wav"
+	samples := []float64{0.5, 0.25, -0.5}
+	options := DefaultEncodeOptions(16000)
+	if err := RenderWAV(NewSound(samples), path, options); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := LoadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChannels(t, rec, [][]float64{samples}, 1e-12)
+
+	options.Channels = 0
+	if err := RenderWAV(NewSound(samples), path, options); err != nil {
+		t.Fatal(err)
+	}
+	if rec, err = LoadWAV(path); err != nil {
+		t.Fatal(err)
+	}
+	checkChannels(t, rec, [][]float64{samples, samples}, 1e-12)
+}
+
+func TestRenderFile(t *testing.T) {
+	samples := []float64{0.5, 0.25, -0.5}
+	options := DefaultEncodeOptions(16000)
+	if err := RenderFile(NewSound(samples), "output.TestRenderFile.f32", options); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("output.TestRenderFile.f32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 4*len(samples) {
+		t.Errorf("size is %d (should be %d)", len(data), 4*len(samples))
+	}
+	if err := RenderFile(NewSound(samples), "output.TestRenderFile.xyz", options); err == nil {
+		t.Errorf("rendering in a file with an unknown extension should fail")
+	}
+}
@@ -2,22 +2,35 @@ package sound
 
 import (
 	"log"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/gboulant/musicall/wave"
 	"github.com/gopxl/beep"
 	"github.com/gopxl/beep/generators"
-	"github.com/gopxl/beep/speaker"
 )
 
 const testSampleRate = wave.DefaultSampleRate
 
+// testBackend is the backend used by the tests: the sounds are not
+// played but rendered in memory, so that the tests can run on the
+// machines without sound card. To listen to the tests, use the
+// speaker backend: MUSICALL_AUDIO=speaker go test
+var testBackend = recordingBackend()
+
+func recordingBackend() *NullBackend {
+	b := NewNullBackend()
+	b.Record = true
+	return b
+}
+
 func init() {
 	// Le speaker est initialisé avec un sample rate fixé. Tous les
 	// signaux ([]float64) joués par ce speaker seront considérés comme
 	// des sons avec ce sample rate. On doit donc générer des signaux
 	// avec ce sample rate.
+	SetBackend(testBackend)
 	if err := Init(testSampleRate); err != nil {
 		log.Fatal(err)
 	}
@@ -56,6 +69,21 @@ func TestNewSound(t *testing.T) {
 	if err := Play(streamer); err != nil {
 		t.Error(err)
 	}
+
+	// With the null backend, we can check the samples that were played
+	if CurrentBackend() != testBackend {
+		return
+	}
+	rec, _ := testBackend.Last()
+	r := float64(testSampleRate)
+	exp := 3*int(0.2*r) + 3*int(d*r)
+	if rec.Frames() != exp {
+		t.Errorf("number of samples played is %d (should be %d)", rec.Frames(), exp)
+	}
+	i := int(0.2*r) + int(0.25*r/f) // first maximum of the sine
+	if !almostEqual(rec.Channels[0][i], a, 1e-3) || !almostEqual(rec.Channels[1][i], a, 1e-3) {
+		t.Errorf("sample %d is %v (should be %v)", i, rec.Channels[0][i], a)
+	}
 }
 
 func TestSoundStruct(t *testing.T) {
@@ -254,7 +282,7 @@ func TestVolumeStreamer(t *testing.T) {
 func TestVolumeStreamerAsync(t *testing.T) {
 	// This test play one streamer sound and change the volume during
 	// playing. We use for that the low level Play function of the
-	// speaker with is asynchronous.
+	// speaker with is asynchronous (PlayAsync).
 	f := 440.
 	a := 1.
 	d := 2.
@@ -262,16 +290,44 @@ func TestVolumeStreamerAsync(t *testing.T) {
 	s := wave.NewSineWaveSynthesizer(f, a, r)
 	samples := s.Synthesize(d)
 
+	// With the null backend, the streamer is played in real time, and
+	// the volume change is checked on the samples played (without the
+	// output limiter, that would reduce the louder part).
+	if CurrentBackend() == testBackend {
+		testBackend.RealTime = true
+		OutputLimiter = false
+		defer func() {
+			testBackend.RealTime = false
+			OutputLimiter = true
+		}()
+	}
+
 	stream := VolumeStreamer(NewSound(samples))
 	stream.Volume = 0
-	speaker.Play(stream)
+	PlayAsync(stream)
 
 	time.Sleep(time.Duration(1 * time.Second))
-	speaker.Lock()
+	Lock()
 	stream.Volume += 1
-	speaker.Unlock()
+	Unlock()
 
 	time.Sleep(time.Duration(1 * time.Second))
+
+	if CurrentBackend() != testBackend {
+		return
+	}
+	testBackend.Wait()
+	rec, _ := testBackend.Last()
+	if rec.Frames() != len(samples) {
+		t.Fatalf("number of samples played is %d (should be %d)", rec.Frames(), len(samples))
+	}
+	// Volume +1 multiplies the amplitude by 2
+	n := len(samples) / 2
+	before := peak(rec.Channels[0][:n*3/4])
+	after := peak(rec.Channels[0][n*5/4:])
+	if !almostEqual(before, a, 1e-3) || !almostEqual(after, 2*a, 1e-3) {
+		t.Errorf("peaks are %v and %v (should be %v and %v)", before, after, a, 2*a)
+	}
 }
 
 func TestSaturation(t *testing.T) {
@@ -313,3 +369,12 @@ func TestSaturation(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// peak returns the maximal absolute value of the samples
+func peak(samples []float64) float64 {
+	p := 0.
+	for _, v := range samples {
+		p = math.Max(p, math.Abs(v))
+	}
+	return p
+}
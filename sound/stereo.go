@@ -0,0 +1,214 @@
+package sound
+
+// This file defines the stereo signals. The synthesizers of the package
+// wave create mono signals ([]float64), that are played identically on
+// the two channels by a Sound. A stereo signal is made of two signals,
+// one for each channel, and can be created from a mono signal placed in
+// the stereo field with a pan law (see Pan).
+
+import (
+	"math"
+
+	"github.com/gboulant/musicall/wave"
+	"github.com/gopxl/beep"
+)
+
+// Stereo is a stereo signal: the samples of the left channel and the
+// samples of the right channel. If the channels have not the same
+// number of samples, the shortest one is considered to be completed
+// with silence.
+type Stereo struct {
+	Left  []float64
+	Right []float64
+}
+
+// NewStereo returns a stereo signal whose two channels are a copy of
+// the specified mono signal.
+func NewStereo(samples []float64) Stereo {
+	return Stereo{
+		Left:  append([]float64(nil), samples...),
+		Right: append([]float64(nil), samples...),
+	}
+}
+
+// Frames returns the number of samples of the longest channel
+func (s Stereo) Frames() int {
+	return max(len(s.Left), len(s.Right))
+}
+
+// Frame returns the left and right samples at the index i (0 after the
+// end of a channel).
+func (s Stereo) Frame(i int) (left, right float64) {
+	if i < len(s.Left) {
+		left = s.Left[i]
+	}
+	if i < len(s.Right) {
+		right = s.Right[i]
+	}
+	return left, right
+}
+
+// Mono returns the average of the two channels
+func (s Stereo) Mono() []float64 {
+	samples := make([]float64, s.Frames())
+	for i := range samples {
+		l, r := s.Frame(i)
+		samples[i] = (l + r) / 2
+	}
+	return samples
+}
+
+// Channels returns the two channels with the same number of samples, as
+// required by the encoders (see SaveChannels).
+func (s Stereo) Channels() [][]float64 {
+	left := make([]float64, s.Frames())
+	right := make([]float64, s.Frames())
+	for i := range left {
+		left[i], right[i] = s.Frame(i)
+	}
+	return [][]float64{left, right}
+}
+
+// MixStereo returns the sum of the stereo signals
+func MixStereo(signals ...Stereo) Stereo {
+	frames := 0
+	for _, s := range signals {
+		frames = max(frames, s.Frames())
+	}
+	mix := Stereo{Left: make([]float64, frames), Right: make([]float64, frames)}
+	for _, s := range signals {
+		for i, v := range s.Left {
+			mix.Left[i] += v
+		}
+		for i, v := range s.Right {
+			mix.Right[i] += v
+		}
+	}
+	return mix
+}
+
+// -------------------------------------------------------------
+// Pan law
+
+// PanGains returns the gains of the left and right channels for placing
+// a mono signal at the position pan in the stereo field, from -1 (full
+// left) to +1 (full right), 0 being the center. This is the constant
+// power pan law: left² + right² = 1, so that the perceived loudness
+// does not depend on the position. At the center, both gains are
+// 1/√2 (-3 dB).
+//
+// La position est convertie en un angle entre 0 et π/2, et les gains
+// sont le cosinus et le sinus de cet angle.
+func PanGains(pan float64) (left, right float64) {
+	pan = math.Max(-1, math.Min(1, pan))
+	angle := (pan + 1) * math.Pi / 4
+	return math.Cos(angle), math.Sin(angle)
+}
+
+// Pan returns the stereo signal that places the mono signal at the
+// position pan in the stereo field (see PanGains).
+func Pan(samples []float64, pan float64) Stereo {
+	gl, gr := PanGains(pan)
+	s := Stereo{Left: make([]float64, len(samples)), Right: make([]float64, len(samples))}
+	for i, v := range samples {
+		s.Left[i] = gl * v
+		s.Right[i] = gr * v
+	}
+	return s
+}
+
+// PanStreamer returns a streamer that places the input streamer at the
+// position pan in the stereo field (see PanGains). The two channels of
+// the input streamer are first mixed in a mono signal.
+func PanStreamer(s beep.Streamer, pan float64) beep.Streamer {
+	left, right := PanGains(pan)
+	return &pannedStreamer{s, left, right}
+}
+
+type pannedStreamer struct {
+	beep.Streamer
+	left  float64
+	right float64
+}
+
+func (p *pannedStreamer) Stream(samples [][2]float64) (int, bool) {
+	n, ok := p.Streamer.Stream(samples)
+	for i := range samples[:n] {
+		mono := (samples[i][0] + samples[i][1]) / 2
+		samples[i][0] = p.left * mono
+		samples[i][1] = p.right * mono
+	}
+	return n, ok
+}
+
+// -------------------------------------------------------------
+// StereoSound implements the beep.Streamer interface for playing a
+// stereo signal (as Sound for a mono signal).
+type StereoSound struct {
+	Samples   Stereo
+	Processed int
+}
+
+func (s *StereoSound) Stream(samples [][2]float64) (int, bool) {
+	frames := s.Samples.Frames()
+	if s.Processed >= frames {
+		return 0, false
+	}
+
+	if frames-s.Processed < len(samples) {
+		samples = samples[:frames-s.Processed]
+	}
+
+	for i := range samples {
+		samples[i][0], samples[i][1] = s.Samples.Frame(s.Processed + i)
+	}
+
+	s.Processed += len(samples)
+
+	return len(samples), true
+}
+
+func (s *StereoSound) Err() error {
+	return nil
+}
+
+// NewStereoSound can be used to create a beep streamer playing the
+// given stereo signal.
+func NewStereoSound(samples Stereo) beep.Streamer {
+	return &StereoSound{samples, 0}
+}
+
+// -------------------------------------------------------------
+// Stereo synthesizers
+
+// StereoSynthesizer is the stereo counterpart of wave.Synthesizer: it
+// creates a stereo signal at a given sample rate for a given duration.
+type StereoSynthesizer interface {
+	SampleRate() int
+	SynthesizeStereo(duration float64) Stereo
+}
+
+// PannedSynthesizer is a stereo synthesizer that places the signal of
+// a mono synthesizer at the position Pan in the stereo field (see
+// PanGains).
+type PannedSynthesizer struct {
+	wave.Synthesizer
+	Pan float64
+}
+
+// NewPannedSynthesizer returns a stereo synthesizer that places the
+// signal of the synthesizer s at the position pan.
+func NewPannedSynthesizer(s wave.Synthesizer, pan float64) *PannedSynthesizer {
+	return &PannedSynthesizer{Synthesizer: s, Pan: pan}
+}
+
+func (p PannedSynthesizer) SynthesizeStereo(duration float64) Stereo {
+	return Pan(p.Synthesize(duration), p.Pan)
+}
+
+// StereoSynthSound can be used to synthesize a stereo sound, i.e.
+// create a beep streamer playing a stereo signal generated by the
+// specified synthesizer on the specified duration.
+func StereoSynthSound(duration float64, synthesizer StereoSynthesizer) beep.Streamer {
+	return &StereoSound{synthesizer.SynthesizeStereo(duration), 0}
+}
@@ -0,0 +1,100 @@
+package sound
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gboulant/musicall/wave"
+)
+
+func TestPanGains(t *testing.T) {
+	tests := []struct {
+		pan         float64
+		left, right float64
+	}{
+		{-1, 1, 0},
+		{0, math.Sqrt2 / 2, math.Sqrt2 / 2},
+		{1, 0, 1},
+		{-2, 1, 0}, // clipped
+		{0.5, math.Cos(3 * math.Pi / 8), math.Sin(3 * math.Pi / 8)},
+	}
+	for _, tt := range tests {
+		l, r := PanGains(tt.pan)
+		if !almostEqual(l, tt.left, 1e-12) || !almostEqual(r, tt.right, 1e-12) {
+			t.Errorf("gains of %v are (%v, %v) (should be (%v, %v))", tt.pan, l, r, tt.left, tt.right)
+		}
+	}
+
+	// Constant power
+	for pan := -1.; pan <= 1; pan += 0.1 {
+		l, r := PanGains(pan)
+		if p := l*l + r*r; !almostEqual(p, 1, 1e-12) {
+			t.Errorf("power at %.1f is %v (should be 1)", pan, p)
+		}
+	}
+}
+
+func TestPan(t *testing.T) {
+	s := Pan([]float64{1, -0.5}, -1)
+	checkChannels(t, Recording{Channels: s.Channels()}, [][]float64{{1, -0.5}, {0, 0}}, 1e-12)
+}
+
+func TestStereo(t *testing.T) {
+	s := Stereo{Left: []float64{1, 0.5, 0}, Right: []float64{-1, 0.5}}
+	if s.Frames() != 3 {
+		t.Errorf("number of frames is %d (should be 3)", s.Frames())
+	}
+	if l, r := s.Frame(2); l != 0 || r != 0 {
+		t.Errorf("frame 2 is (%v, %v) (should be (0, 0))", l, r)
+	}
+	checkChannels(t, Recording{Channels: [][]float64{s.Mono()}}, [][]float64{{0, 0.5, 0}}, 1e-12)
+
+	mix := MixStereo(s, NewStereo([]float64{1, 1, 1, 1}))
+	checkChannels(t, Recording{Channels: mix.Channels()}, [][]float64{{2, 1.5, 1, 1}, {0, 1.5, 1, 1}}, 1e-12)
+
+	// NewStereo copies the samples
+	samples := []float64{0.5}
+	n := NewStereo(samples)
+	n.Left[0] = 0
+	if samples[0] != 0.5 || n.Right[0] != 0.5 {
+		t.Errorf("the channels of NewStereo should be independent copies")
+	}
+}
+
+func TestStereoSound(t *testing.T) {
+	s := Stereo{Left: []float64{0.5, 0.25, -0.5}, Right: []float64{0, -0.25, 0.5}}
+	rec, err := Render(NewStereoSound(s), 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChannels(t, rec, s.Channels(), 1e-12)
+}
+
+func TestPanStreamer(t *testing.T) {
+	samples := []float64{0.5, 0.25, -0.5}
+	rec, err := Render(PanStreamer(NewSound(samples), 1), 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChannels(t, rec, [][]float64{{0, 0, 0}, samples}, 1e-12)
+}
+
+func TestPannedSynthesizer(t *testing.T) {
+	r := 8000
+	w := wave.NewSineWaveSynthesizer(440, 1, r)
+	p := NewPannedSynthesizer(w, -0.5)
+	rec, err := Render(StereoSynthSound(0.1, p), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mono := w.Synthesize(0.1)
+	gl, gr := PanGains(-0.5)
+	for i, v := range mono {
+		if !almostEqual(rec.Channels[0][i], gl*v, 1e-12) || !almostEqual(rec.Channels[1][i], gr*v, 1e-12) {
+			t.Fatalf("frame %d is (%v, %v) (should be (%v, %v))", i, rec.Channels[0][i], rec.Channels[1][i], gl*v, gr*v)
+		}
+	}
+	if p.SampleRate() != r {
+		t.Errorf("sample rate is %d (should be %d)", p.SampleRate(), r)
+	}
+}
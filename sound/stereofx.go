@@ -0,0 +1,71 @@
+package sound
+
+// This file implements the stereo effects: the width of the stereo
+// image, the ping-pong delay (echoes alternately on the left and on the
+// right), and the chorus (copies slightly delayed and modulated, with a
+// different modulation on each channel). The effects return a new
+// signal with the same number of samples as the input signal. The delay
+// and the chorus are those of the package effects, applied offline.
+
+import (
+	"github.com/gboulant/musicall/effects"
+	"github.com/gboulant/musicall/wave"
+)
+
+// Width changes the width of the stereo image. The signal is decomposed
+// into the mid signal M = (L+R)/2 (what is common to the two channels)
+// and the side signal S = (L-R)/2 (the difference), and the side signal
+// is multiplied by the width: 0 gives a mono signal, 1 leaves the
+// signal unchanged, and a width greater than 1 widens the image.
+func Width(s Stereo, width float64) Stereo {
+	out := Stereo{Left: make([]float64, s.Frames()), Right: make([]float64, s.Frames())}
+	for i := range out.Left {
+		l, r := s.Frame(i)
+		mid := (l + r) / 2
+		side := width * (l - r) / 2
+		out.Left[i] = mid + side
+		out.Right[i] = mid - side
+	}
+	return out
+}
+
+// PingPongDelay adds echoes of the signal that bounce alternately on the
+// left and on the right channel. The delay is the time (in seconds)
+// between two echoes, the feedback is the gain applied from one echo to
+// the next one (between 0 and 1), and mix is the level of the echoes
+// relative to the original signal (dry). It is the ping-pong delay of
+// the package effects (see effects.PingPong) applied to a signal.
+func PingPongDelay(s Stereo, delay, feedback, mix float64, sampleRate int) Stereo {
+	n := max(1, int(delay*float64(wave.SampleRate(sampleRate))))
+	time := float64(n) / float64(wave.SampleRate(sampleRate))
+	return addWet(s, effects.NewPingPong(time, feedback, 1, sampleRate), mix)
+}
+
+// Chorus mixes the signal with a copy delayed by a time that oscillates
+// around a few tens of milliseconds (20 ms plus the depth), which gives
+// the impression of several instruments playing together. The depth is
+// the amplitude of the oscillation of the delay (in seconds, typically
+// a few milliseconds), rate is its frequency (in Hz, typically below 1
+// Hz), and mix is the level of the delayed copy. The oscillations of
+// the left and right channels are in quadrature, which widens the
+// stereo image. It is the chorus of the package effects (see
+// effects.Chorus) applied to a signal.
+func Chorus(s Stereo, depth, rate, mix float64, sampleRate int) Stereo {
+	c := effects.NewChorus(depth, rate, 1, sampleRate)
+	c.Delay = effects.Const(effects.ChorusDelay + depth) // the delay never gets negative
+	return addWet(s, c, mix)
+}
+
+// addWet returns the signal plus the output of the effect (processed
+// fully wet) at the level mix. The effects of the package effects mix
+// the dry and the wet signals as a crossfade, while the level of the
+// stereo effects is added to the dry signal.
+func addWet(s Stereo, e effects.Effect, mix float64) Stereo {
+	channels := s.Channels()
+	left, right := effects.ApplyStereo(e, channels[0], channels[1])
+	for i := range left {
+		left[i] = channels[0][i] + mix*left[i]
+		right[i] = channels[1][i] + mix*right[i]
+	}
+	return Stereo{Left: left, Right: right}
+}
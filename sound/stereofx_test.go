@@ -0,0 +1,70 @@
+package sound
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWidth(t *testing.T) {
+	s := Stereo{Left: []float64{1, 0.5}, Right: []float64{0, 0.5}}
+
+	mono := Width(s, 0)
+	checkChannels(t, Recording{Channels: mono.Channels()}, [][]float64{{0.5, 0.5}, {0.5, 0.5}}, 1e-12)
+
+	same := Width(s, 1)
+	checkChannels(t, Recording{Channels: same.Channels()}, s.Channels(), 1e-12)
+
+	wide := Width(s, 2)
+	checkChannels(t, Recording{Channels: wide.Channels()}, [][]float64{{1.5, 0.5}, {-0.5, 0.5}}, 1e-12)
+}
+
+func TestPingPongDelay(t *testing.T) {
+	// An impulse gives echoes alternately on the left and on the right
+	r := 1000
+	impulse := make([]float64, 50)
+	impulse[0] = 1
+	s := PingPongDelay(NewStereo(impulse), 0.010, 0.5, 1, r)
+
+	expL := make([]float64, 50)
+	expR := make([]float64, 50)
+	expL[0], expR[0] = 1, 1
+	expL[10] = 1    // first echo on the left
+	expR[20] = 0.5  // second echo on the right
+	expL[30] = 0.25 // third echo on the left
+	expR[40] = 0.125
+	checkChannels(t, Recording{Channels: s.Channels()}, [][]float64{expL, expR}, 1e-12)
+}
+
+func TestChorus(t *testing.T) {
+	r := 8000
+	samples := make([]float64, r)
+	for i := range samples {
+		samples[i] = 0.5 * math.Sin(2*math.Pi*220*float64(i)/float64(r))
+	}
+	s := Chorus(NewStereo(samples), 0.005, 0.5, 0.5, r)
+	if s.Frames() != len(samples) {
+		t.Fatalf("number of frames is %d (should be %d)", s.Frames(), len(samples))
+	}
+
+	// Before the minimal delay (20 ms), the signal is unchanged
+	for i := range r / 50 {
+		if l, rr := s.Frame(i); l != samples[i] || rr != samples[i] {
+			t.Fatalf("frame %d is (%v, %v) (should be %v)", i, l, rr, samples[i])
+		}
+	}
+	// The modulations of the two channels are different, and the wet
+	// signal is bounded by (1+mix) times the dry signal
+	different := false
+	for i := range samples {
+		l, rr := s.Frame(i)
+		if l != rr {
+			different = true
+		}
+		if math.Abs(l) > 0.75+1e-12 || math.Abs(rr) > 0.75+1e-12 {
+			t.Fatalf("frame %d is (%v, %v) (should be bounded by 0.75)", i, l, rr)
+		}
+	}
+	if !different {
+		t.Errorf("the left and right channels should be different")
+	}
+}
@@ -2,15 +2,11 @@ package sound
 
 import (
 	"fmt"
-	"os"
-	"time"
 
 	"github.com/gboulant/musicall/wave"
 	"github.com/gopxl/beep"
 	"github.com/gopxl/beep/effects"
 	"github.com/gopxl/beep/generators"
-	"github.com/gopxl/beep/speaker"
-	"github.com/gopxl/beep/wav"
 )
 
 var samplerate int = wave.DefaultSampleRate
@@ -20,10 +16,14 @@ var samplerate int = wave.DefaultSampleRate
 // (samples) par seconde. Attention, on ne peux pas initialiser le
 // speaker deux fois dans un même programme. Tous les signaux ([]float64) joués par le
 // speaker ainsi initialisé seront considérés comme des sons avec ce
-// taux d'échantillonage.
+// taux d'échantillonage. Le speaker est le backend par défaut, qui peut
+// être remplacé par SetBackend ou par la variable d'environnement
+// MUSICALL_AUDIO (par exemple le backend "null", sans carte son).
 func Init(sampleRate int) error {
-	beepSampleRate := beep.SampleRate(sampleRate)
-	if err := speaker.Init(beepSampleRate, beepSampleRate.N(time.Second/10)); err != nil {
+	if err := backendFromEnv(); err != nil {
+		return err
+	}
+	if err := backend.Init(sampleRate); err != nil {
 		return err
 	}
 	samplerate = sampleRate
@@ -34,34 +34,63 @@ func Silence(duration float64, sampleRate int) beep.Streamer {
 	return generators.Silence(int(duration * float64(sampleRate)))
 }
 
+// Play plays the streamer and returns when it is over. The output is
+// limited (see OutputLimiter).
 func Play(s beep.Streamer) error {
-	// Note that speaker.Play is an asynchronous function, then we play
-	// 2 streamers, the second being a callback that triggers the
+	// Note that the backend Play is an asynchronous function, then we
+	// play 2 streamers, the second being a callback that triggers the
 	// channel, so that this Play function is synchronous
 	done := make(chan bool, 1)
-	speaker.Play(beep.Seq(s, beep.Callback(func() {
+	backend.Play(beep.Seq(limitOutput(s, samplerate), beep.Callback(func() {
 		done <- true
 	})))
 	<-done
 	return nil
 }
 
+// PlayAsync starts playing the streamer and returns immediately. Use
+// Lock and Unlock to change the streamer while it is played. The output
+// is limited (see OutputLimiter).
+func PlayAsync(s beep.Streamer) {
+	backend.Play(limitOutput(s, samplerate))
+}
+
+// Lock locks the streamers being played (see PlayAsync). As for the
+// speaker, it must not be called by the streamers themselves (e.g. in
+// a beep.Callback), since they are played with the lock held.
+func Lock() {
+	backend.Lock()
+}
+
+// Unlock unlocks the streamers being played (see PlayAsync)
+func Unlock() {
+	backend.Unlock()
+}
+
+// Format returns the format used by Save: 2 channels with samples of
+// 24 bits (3 bytes), at the specified sample rate.
 func Format(sampleRate int) beep.Format {
 	r := beep.SampleRate(sampleRate)
 	return beep.Format{SampleRate: r, NumChannels: 2, Precision: 3}
 }
 
+// Save writes the streamer in an audio file (2 channels, 24 bits) at
+// the sample rate of the speaker (see Init). The format of the file
+// (WAV, FLAC, AIFF, etc.) is selected by its extension, and is WAV if
+// the extension is unknown (or missing), as before the other formats.
+// Use RenderFile to choose the options of the file.
 func Save(s beep.Streamer, outpath string) error {
-	f, err := os.Create(outpath)
-	if err != nil {
-		return err
-	}
 	format := Format(samplerate)
-	if err = wav.Encode(f, s, format); err != nil {
-		return err
+	options := EncodeOptions{AudioFormat: AudioFormat{
+		SampleRate: int(format.SampleRate),
+		Channels:   format.NumChannels,
+		BitDepth:   8 * format.Precision,
+	}}
+	e, err := EncoderFor(outpath)
+	if err != nil {
+		e = EncoderFunc(EncodeWAV)
 	}
-	fmt.Printf("Sound saved to %s\n", outpath)
-	return nil
+	return renderWith(e, s, outpath, options)
 }
 
 // -------------------------------------------------------------
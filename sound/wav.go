@@ -0,0 +1,291 @@
+package sound
+
+// This file implements the reading and writing of the WAV files
+// (RIFF/WAVE format) from and to the []float64 signals used by the
+// package wave, so that the real recordings can be analyzed (spectrum,
+// pitch detection) and plotted as the synthesized signals, and the
+// synthesized signals can be saved without any speaker.
+//
+// A WAV file is a RIFF container made of chunks (an identifier of 4
+// bytes, a size on 4 bytes, and the content). The chunk "fmt " gives
+// the format of the samples, and the chunk "data" the samples
+// themselves, interleaved by channel (a frame is one sample of each
+// channel). The other chunks (LIST, fact, etc.) are ignored.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/gboulant/musicall/wave"
+)
+
+// Audio formats of the fmt chunk
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// maxFormatChunkSize is the maximal size of the fmt chunk (16, 18 or 40
+// bytes, with a margin for the extensions)
+const maxFormatChunkSize = 256
+
+// LoadWAV reads the WAV file at the specified path
+func LoadWAV(path string) (Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Recording{}, err
+	}
+	defer f.Close()
+	return DecodeWAV(f)
+}
+
+// DecodeWAV reads a WAV stream. The samples can be PCM integers of 8,
+// 16, 24 or 32 bits, or floating point values of 32 or 64 bits, with
+// any number of channels and any sample rate. If the size of the data
+// chunk is not known (a stream being recorded), the samples are read
+// until the end of the stream.
+func DecodeWAV(r io.Reader) (Recording, error) {
+	br := bufio.NewReader(r)
+	var header [12]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return Recording{}, fmt.Errorf("the WAV header can not be read: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return Recording{}, errors.New("the stream is not a RIFF/WAVE file")
+	}
+
+	var format AudioFormat
+	hasFormat := false
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(br, chunk[:]); err != nil {
+			if err == io.EOF && hasFormat {
+				return Recording{}, errors.New("the WAV file has no data chunk")
+			}
+			return Recording{}, fmt.Errorf("the WAV chunk can not be read: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			// The size is checked before the allocation, a corrupted
+			// header could announce up to 4 GiB
+			if size > maxFormatChunkSize {
+				return Recording{}, fmt.Errorf("the WAV format chunk is too large (%d bytes)", size)
+			}
+			content := make([]byte, size)
+			if _, err := io.ReadFull(br, content); err != nil {
+				return Recording{}, fmt.Errorf("the WAV format can not be read: %w", err)
+			}
+			f, err := parseAudioFormat(content)
+			if err != nil {
+				return Recording{}, err
+			}
+			format, hasFormat = f, true
+		case "data":
+			if !hasFormat {
+				return Recording{}, errors.New("the WAV data chunk is before the format chunk")
+			}
+			return decodeWAVData(br, format, size)
+		default:
+			if _, err := io.CopyN(io.Discard, br, int64(size)); err != nil {
+				return Recording{}, fmt.Errorf("the WAV chunk %q can not be read: %w", id, err)
+			}
+		}
+		// The chunks are aligned on 2 bytes
+		if size%2 == 1 {
+			if _, err := br.Discard(1); err != nil {
+				return Recording{}, fmt.Errorf("the WAV chunk %q can not be read: %w", id, err)
+			}
+		}
+	}
+}
+
+// parseAudioFormat reads the content of the fmt chunk
+func parseAudioFormat(content []byte) (AudioFormat, error) {
+	if len(content) < 16 {
+		return AudioFormat{}, fmt.Errorf("the WAV format chunk is too short (%d bytes)", len(content))
+	}
+	audioFormat := binary.LittleEndian.Uint16(content[0:2])
+	format := AudioFormat{
+		Channels:   int(binary.LittleEndian.Uint16(content[2:4])),
+		SampleRate: int(binary.LittleEndian.Uint32(content[4:8])),
+		BitDepth:   int(binary.LittleEndian.Uint16(content[14:16])),
+	}
+	if audioFormat == wavFormatExtensible {
+		// The real format is the first 2 bytes of the sub-format GUID
+		if len(content) < 26 {
+			return AudioFormat{}, errors.New("the WAV extensible format chunk is too short")
+		}
+		audioFormat = binary.LittleEndian.Uint16(content[24:26])
+	}
+
+	switch audioFormat {
+	case wavFormatPCM:
+		if format.BitDepth != 8 && format.BitDepth != 16 && format.BitDepth != 24 && format.BitDepth != 32 {
+			return AudioFormat{}, fmt.Errorf("the PCM bit depth %d is not supported", format.BitDepth)
+		}
+	case wavFormatFloat:
+		if format.BitDepth != 32 && format.BitDepth != 64 {
+			return AudioFormat{}, fmt.Errorf("the float bit depth %d is not supported", format.BitDepth)
+		}
+		format.Float = true
+	default:
+		return AudioFormat{}, fmt.Errorf("the WAV audio format 0x%04x is not supported", audioFormat)
+	}
+	if format.Channels < 1 {
+		return AudioFormat{}, errors.New("the WAV file has no channel")
+	}
+	if format.SampleRate < 1 {
+		return AudioFormat{}, fmt.Errorf("the WAV sample rate %d is not valid", format.SampleRate)
+	}
+	return format, nil
+}
+
+// decodeWAVData reads the samples of the data chunk. An incomplete
+// frame at the end of the data is ignored.
+func decodeWAVData(r io.Reader, format AudioFormat, size uint32) (Recording, error) {
+	// A size of 0 (or the maximal size) is written by the programs that
+	// record a stream without knowing its final size.
+	if size == 0 {
+		size = math.MaxUint32
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return Recording{}, fmt.Errorf("the WAV data can not be read: %w", err)
+	}
+
+	frames := len(data) / format.frameSize()
+	rec := Recording{Format: format, Channels: make([][]float64, format.Channels)}
+	for c := range rec.Channels {
+		rec.Channels[c] = make([]float64, frames)
+	}
+
+	width := format.BitDepth / 8
+	offset := 0
+	for i := range frames {
+		for c := range format.Channels {
+			rec.Channels[c][i] = decodeWAVSample(data[offset:offset+width], format)
+			offset += width
+		}
+	}
+	return rec, nil
+}
+
+// decodeWAVSample converts a sample to a value between -1 and 1
+func decodeWAVSample(b []byte, format AudioFormat) float64 {
+	if format.Float {
+		if format.BitDepth == 32 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	switch format.BitDepth {
+	case 8:
+		// The 8 bits samples are unsigned
+		return float64(int(b[0])-128) / 128.
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768.
+	case 24:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / 8388608.
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648.
+	}
+}
+
+// LoadImpulseResponse reads an impulse response (of a cabinet or of a
+// room) in the WAV file at the specified path, as a mono signal (the
+// average of the channels) at the specified sample rate: the response is
+// resampled (with a linear interpolation) if the file has another sample
+// rate.
+func LoadImpulseResponse(path string, sampleRate int) ([]float64, error) {
+	rec, err := LoadWAV(path)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Frames() == 0 {
+		return nil, fmt.Errorf("the impulse response %s is empty", path)
+	}
+	return resample(rec.Mono(), rec.Format.SampleRate, wave.SampleRate(sampleRate)), nil
+}
+
+// resample converts the samples from a sample rate to another one, with
+// a linear interpolation
+func resample(samples []float64, from, to int) []float64 {
+	if from == to || from <= 0 {
+		return samples
+	}
+	ratio := float64(from) / float64(to)
+	out := make([]float64, int(float64(len(samples))/ratio))
+	for i := range out {
+		x := float64(i) * ratio
+		k := int(x)
+		frac := x - float64(k)
+		out[i] = samples[k]
+		if k+1 < len(samples) {
+			out[i] += frac * (samples[k+1] - samples[k])
+		}
+	}
+	return out
+}
+
+// -------------------------------------------------------------
+// Writing of the WAV files
+
+// EncodeWAV writes the samples of each channel as a WAV stream. The
+// samples are values between -1 and 1 (the values out of this range
+// are clipped for the integer formats). All the channels must have the
+// same number of samples.
+func EncodeWAV(w io.Writer, channels [][]float64, options EncodeOptions) error {
+	format, frames, err := validateChannels(channels, options)
+	if err != nil {
+		return err
+	}
+	dataSize := uint64(frames) * uint64(format.frameSize())
+	if dataSize > math.MaxUint32-64 {
+		return fmt.Errorf("the WAV data is too large (%d bytes)", dataSize)
+	}
+
+	audioFormat := uint16(wavFormatPCM)
+	if format.Float {
+		audioFormat = wavFormatFloat
+	}
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize+dataSize%2))
+	copy(header[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], audioFormat)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(format.Channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(format.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(format.SampleRate*format.frameSize()))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(format.frameSize()))
+	binary.LittleEndian.PutUint16(header[34:36], uint16(format.BitDepth))
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// The 8 bits samples are unsigned in the WAV files
+	if frames > 0 {
+		if err := writeFrames(w, channels, format, options.Dither, binary.LittleEndian, true); err != nil {
+			return err
+		}
+	}
+	// The chunks are aligned on 2 bytes
+	if dataSize%2 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
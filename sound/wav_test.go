@@ -0,0 +1,402 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"testing"
+)
+
+// makeWAV creates the content of a WAV file with the specified format
+// and data. A LIST chunk of odd size is inserted before the data, to
+// check that the unknown chunks are skipped.
+func makeWAV(audioFormat uint16, channels, sampleRate, bitDepth int, data []byte) []byte {
+	var fmtChunk bytes.Buffer
+	binary.Write(&fmtChunk, binary.LittleEndian, audioFormat)
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(channels))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(sampleRate*channels*bitDepth/8))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(channels*bitDepth/8))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(bitDepth))
+	if audioFormat == wavFormatExtensible {
+		binary.Write(&fmtChunk, binary.LittleEndian, uint16(22))
+		binary.Write(&fmtChunk, binary.LittleEndian, uint16(bitDepth))
+		binary.Write(&fmtChunk, binary.LittleEndian, uint32(0))
+		binary.Write(&fmtChunk, binary.LittleEndian, uint16(wavFormatPCM))
+		fmtChunk.Write(make([]byte, 14))
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	chunk := func(id string, content []byte) {
+		body.WriteString(id)
+		binary.Write(&body, binary.LittleEndian, uint32(len(content)))
+		body.Write(content)
+		if len(content)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	chunk("fmt ", fmtChunk.Bytes())
+	chunk("LIST", []byte("INFOabc"))
+	chunk("data", data)
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func almostEqual(a, b float64, accuracy float64) bool {
+	return math.Abs(a-b) < accuracy
+}
+
+func checkChannels(t *testing.T, rec Recording, exp [][]float64, accuracy float64) {
+	t.Helper()
+	if len(rec.Channels) != len(exp) {
+		t.Fatalf("number of channels is %d (should be %d)", len(rec.Channels), len(exp))
+	}
+	for c := range exp {
+		if len(rec.Channels[c]) != len(exp[c]) {
+			t.Fatalf("number of samples is %d (should be %d)", len(rec.Channels[c]), len(exp[c]))
+		}
+		for i, v := range exp[c] {
+			if !almostEqual(rec.Channels[c][i], v, accuracy) {
+				t.Errorf("sample %d of channel %d is %f (should be %f)", i, c, rec.Channels[c][i], v)
+			}
+		}
+	}
+}
+
+func TestDecodeWAV_PCM(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   uint16
+		bitDepth int
+		data     []byte
+	}{
+		{"pcm8", wavFormatPCM, 8, []byte{128, 192, 0, 64}},
+		{"pcm16", wavFormatPCM, 16, []byte{0, 0, 0, 0x40, 0, 0x80, 0, 0xc0}},
+		{"pcm24", wavFormatPCM, 24, []byte{0, 0, 0, 0, 0, 0x40, 0, 0, 0x80, 0, 0, 0xc0}},
+		{"pcm32", wavFormatPCM, 32, []byte{0, 0, 0, 0, 0, 0, 0, 0x40, 0, 0, 0, 0x80, 0, 0, 0, 0xc0}},
+		{"extensible", wavFormatExtensible, 16, []byte{0, 0, 0, 0x40, 0, 0x80, 0, 0xc0, 0xff}},
+	}
+	// The frames are interleaved: (0, 0.5) and (-1, -0.5)
+	exp := [][]float64{{0, -1}, {0.5, -0.5}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := makeWAV(tt.format, 2, 22050, tt.bitDepth, tt.data)
+			rec, err := DecodeWAV(bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.Format.SampleRate != 22050 || rec.Format.BitDepth != tt.bitDepth || rec.Format.Float {
+				t.Errorf("format is %+v", rec.Format)
+			}
+			checkChannels(t, rec, exp, 1e-9)
+		})
+	}
+}
+
+func TestDecodeWAV_Float(t *testing.T) {
+	var data32, data64 bytes.Buffer
+	values := []float64{0.25, -0.75, 1.}
+	for _, v := range values {
+		binary.Write(&data32, binary.LittleEndian, float32(v))
+		binary.Write(&data64, binary.LittleEndian, v)
+	}
+	for _, content := range [][]byte{
+		makeWAV(wavFormatFloat, 1, 48000, 32, data32.Bytes()),
+		makeWAV(wavFormatFloat, 1, 48000, 64, data64.Bytes()),
+	} {
+		rec, err := DecodeWAV(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rec.Format.Float || rec.Format.Channels != 1 {
+			t.Errorf("format is %+v", rec.Format)
+		}
+		checkChannels(t, rec, [][]float64{values}, 1e-9)
+		if !almostEqual(rec.Duration(), 3./48000., 1e-12) {
+			t.Errorf("duration is %f (should be %f)", rec.Duration(), 3./48000.)
+		}
+	}
+}
+
+func TestDecodeWAV_Errors(t *testing.T) {
+	contents := map[string][]byte{
+		"empty":    {},
+		"notwave":  []byte("RIFF\x04\x00\x00\x00AVI "),
+		"nodata":   makeWAV(wavFormatPCM, 1, 44100, 16, nil)[:48],
+		"bitdepth": makeWAV(wavFormatPCM, 1, 44100, 12, nil),
+		"format":   makeWAV(0x0055, 1, 44100, 16, nil),
+		"fmtsize":  []byte("RIFF\x00\x00\x00\x00WAVEfmt \xff\xff\xff\xff"),
+	}
+	for name, content := range contents {
+		if _, err := DecodeWAV(bytes.NewReader(content)); err == nil {
+			t.Errorf("decoding %s should fail", name)
+		}
+	}
+}
+
+func TestRecording_Mono(t *testing.T) {
+	rec := Recording{
+		Format:   AudioFormat{SampleRate: 4, Channels: 2, BitDepth: 16},
+		Channels: [][]float64{{1, 0.5, 0}, {0, 0.5, -1}},
+	}
+	checkChannels(t, Recording{Channels: [][]float64{rec.Mono()}}, [][]float64{{0.5, 0.5, -0.5}}, 1e-12)
+	if rec.Duration() != 0.75 {
+		t.Errorf("duration is %f (should be 0.75)", rec.Duration())
+	}
+}
+
+func TestLoadWAV(t *testing.T) {
+	path := "output.test.wav"
+	content := makeWAV(wavFormatPCM, 1, 8000, 16, []byte{0, 0x40, 0, 0xc0})
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := LoadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChannels(t, rec, [][]float64{{0.5, -0.5}}, 1e-12)
+
+	if _, err := LoadWAV("output.notexist.wav"); err == nil {
+		t.Errorf("loading a file that does not exist should fail")
+	}
+}
+
+func TestLoadImpulseResponse(t *testing.T) {
+	path := "output.TestLoadImpulseResponse.wav"
+	options := DefaultEncodeOptions(16000)
+	options.Float = true
+	options.BitDepth = 32
+	options.Channels = 2
+	if err := SaveWAVChannels(path, [][]float64{{1, 0.5, 0, -0.5}, {0, 0.5, 0, 0.5}}, options); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mono, at the same sample rate
+	ir, err := LoadImpulseResponse(path, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.5, 0.5, 0, 0}
+	if len(ir) != len(want) {
+		t.Fatalf("len is %d (should be %d)", len(ir), len(want))
+	}
+	for i := range want {
+		if math.Abs(ir[i]-want[i]) > 1e-6 {
+			t.Errorf("sample %d is %v (should be %v)", i, ir[i], want[i])
+		}
+	}
+
+	// Resampled at twice the sample rate
+	if ir, err = LoadImpulseResponse(path, 32000); err != nil {
+		t.Fatal(err)
+	}
+	want = []float64{0.5, 0.5, 0.5, 0.25, 0, 0, 0, 0}
+	if len(ir) != len(want) {
+		t.Fatalf("len is %d (should be %d)", len(ir), len(want))
+	}
+	for i := range want {
+		if math.Abs(ir[i]-want[i]) > 1e-6 {
+			t.Errorf("resampled sample %d is %v (should be %v)", i, ir[i], want[i])
+		}
+	}
+
+	if _, err := LoadImpulseResponse("output.notexist.wav", 16000); err == nil {
+		t.Errorf("loading a file that does not exist should fail")
+	}
+}
+
+func TestEncodeWAV(t *testing.T) {
+	left := []float64{0, 0.5, -0.25, -1, 0.999}
+	right := []float64{0.875, -0.5, 0.125, 0.75, -0.001}
+	tests := []struct {
+		bitDepth int
+		float    bool
+		accuracy float64
+	}{
+		{8, false, 1. / 128},
+		{16, false, 1. / 32768},
+		{24, false, 1. / 8388608},
+		{32, false, 1e-9},
+		{32, true, 1e-7},
+		{64, true, 1e-15},
+	}
+	for _, tt := range tests {
+		options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 32000, BitDepth: tt.bitDepth, Float: tt.float}}
+		var buffer bytes.Buffer
+		if err := EncodeWAV(&buffer, [][]float64{left, right}, options); err != nil {
+			t.Fatal(err)
+		}
+		rec, err := DecodeWAV(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := AudioFormat{SampleRate: 32000, Channels: 2, BitDepth: tt.bitDepth, Float: tt.float}
+		if rec.Format != exp {
+			t.Errorf("format is %+v (should be %+v)", rec.Format, exp)
+		}
+		checkChannels(t, rec, [][]float64{left, right}, tt.accuracy)
+	}
+}
+
+func TestEncodeWAV_Clipping(t *testing.T) {
+	var buffer bytes.Buffer
+	options := DefaultEncodeOptions(8000)
+	if err := EncodeWAV(&buffer, [][]float64{{1.5, -2, 1}}, options); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := DecodeWAV(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChannels(t, rec, [][]float64{{32767. / 32768., -1, 32767. / 32768.}}, 1e-12)
+}
+
+func TestEncodeWAV_Padding(t *testing.T) {
+	// A data chunk of odd size is followed by a pad byte, that is
+	// included in the size of the RIFF chunk
+	var buffer bytes.Buffer
+	options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 8}}
+	if err := EncodeWAV(&buffer, [][]float64{{0, 0.5, -0.5}}, options); err != nil {
+		t.Fatal(err)
+	}
+	content := buffer.Bytes()
+	if len(content) != 44+4 {
+		t.Errorf("size is %d (should be %d)", len(content), 44+4)
+	}
+	if size := binary.LittleEndian.Uint32(content[4:8]); int(size) != len(content)-8 {
+		t.Errorf("RIFF size is %d (should be %d)", size, len(content)-8)
+	}
+	if size := binary.LittleEndian.Uint32(content[40:44]); size != 3 {
+		t.Errorf("data size is %d (should be 3)", size)
+	}
+}
+
+func TestEncodeWAV_Dither(t *testing.T) {
+	// A constant signal of a quarter of the quantization step is
+	// quantized to 0 without dither. With dither, the quantization
+	// error is a noise, and the mean of the signal is preserved.
+	n := 20000
+	step := 1. / 128
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = 0.25 * step
+	}
+	options := EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 8}}
+	for _, dither := range []bool{false, true} {
+		options.Dither = dither
+		var buffer bytes.Buffer
+		if err := EncodeWAV(&buffer, [][]float64{samples}, options); err != nil {
+			t.Fatal(err)
+		}
+		rec, err := DecodeWAV(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var mean float64
+		for _, v := range rec.Channels[0] {
+			if math.Abs(v-samples[0]) > 1.5*step {
+				t.Fatalf("quantization error of %f is larger than 1.5 step", v-samples[0])
+			}
+			mean += v / float64(n)
+		}
+		exp := 0.
+		if dither {
+			exp = samples[0]
+		}
+		if !almostEqual(mean, exp, 0.05*step) {
+			t.Errorf("mean with dither=%v is %f (should be %f)", dither, mean, exp)
+		}
+	}
+}
+
+func TestEncodeWAV_Errors(t *testing.T) {
+	tests := map[string]struct {
+		channels [][]float64
+		options  EncodeOptions
+	}{
+		"nochannel":  {nil, DefaultEncodeOptions(8000)},
+		"mismatch":   {[][]float64{{0}, {0}}, DefaultEncodeOptions(8000)},
+		"lengths":    {[][]float64{{0}, {0, 1}}, EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 16}}},
+		"samplerate": {[][]float64{{0}}, DefaultEncodeOptions(0)},
+		"bitdepth":   {[][]float64{{0}}, EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 12}}},
+		"float":      {[][]float64{{0}}, EncodeOptions{AudioFormat: AudioFormat{SampleRate: 8000, BitDepth: 16, Float: true}}},
+	}
+	for name, tt := range tests {
+		var buffer bytes.Buffer
+		if err := EncodeWAV(&buffer, tt.channels, tt.options); err == nil {
+			t.Errorf("encoding %s should fail", name)
+		}
+	}
+}
+
+func TestSaveWAV(t *testing.T) {
+	path := "output.TestSaveWAV.wav"
+	samples := []float64{0, 0.5, -0.5}
+	options := DefaultEncodeOptions(22050)
+	options.Channels = 2
+	if err := SaveWAV(path, samples, options); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := LoadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChannels(t, rec, [][]float64{samples, samples}, 1e-12)
+
+	// The recording is saved in its own format
+	rec.Format.BitDepth = 24
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if rec, err = LoadWAV(path); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Format.BitDepth != 24 {
+		t.Errorf("bit depth is %d (should be 24)", rec.Format.BitDepth)
+	}
+	checkChannels(t, rec, [][]float64{samples, samples}, 1e-12)
+
+	if err := SaveWAV("output.notexist/file.wav", samples, options); err == nil {
+		t.Errorf("saving in a folder that does not exist should fail")
+	}
+}
+
+func TestSave(t *testing.T) {
+	// Save does not need the speaker to be initialized
+	path := "output.TestSave.wav"
+	samples := []float64{0, 0.5, -0.5, 0.25}
+	if err := Save(NewSound(samples), path); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := LoadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := AudioFormat{SampleRate: samplerate, Channels: 2, BitDepth: 24}
+	if rec.Format != exp {
+		t.Errorf("format is %+v (should be %+v)", rec.Format, exp)
+	}
+	checkChannels(t, rec, [][]float64{samples, samples}, 1e-12)
+}
+
+func TestSave_UnknownExtension(t *testing.T) {
+	// A path without a known extension is written in WAV
+	samples := []float64{0, 0.5, -0.5, 0.25}
+	for _, path := range []string{"output.TestSave_UnknownExtension.out", "output.TestSave_UnknownExtension"} {
+		if err := Save(NewSound(samples), path); err != nil {
+			t.Fatal(err)
+		}
+		rec, err := LoadWAV(path)
+		if err != nil {
+			t.Fatalf("%s is not a WAV file: %v", path, err)
+		}
+		checkChannels(t, rec, [][]float64{samples, samples}, 1e-12)
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"log"
 )
 
-// LogError must be used to log error on the terminal. Indeed, for a
-// more clear code, some functions that manipulate the music data don't
-// return an error, even if an error occurs (for example if trying to
-// get the note of name "Ré" while it is registered with the name "Re",
-// without accent). Instead, we call the LogError function to print the
-// error message. By default, the print uses log.Fatal that interupts the
-// process (and then you know explicitly the error and can fix it).
+// LogError is used to log the errors on the terminal by the deprecated
+// functions that don't return an error, even if an error occurs:
+// music.Label2Index (for example if trying to get the note of name "Ra"
+// while it is not registered) and guitar.StandardChord. By default, the
+// print uses log.Fatal that interupts the process (and then you know
+// explicitly the error and can fix it).
+//
+// Deprecated: the packages of this module return errors instead (see
+// for example music.LookupIndex, music.ParseNote, guitar.LookupChord
+// and guitar.OpenStringNote), with Must* helpers for the programs.
 var LogError = log.Fatalf
 
 //var LogError = fmt.Printf
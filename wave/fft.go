@@ -2,85 +2,75 @@ package wave
 
 // This file provides functions to compute the spectrum of a timeseries,
 // i.e. the composition of the timeseries in terms of frequencies and
-// their proportions (amplitudes). The API (and part of the
-// implementation) is inspired from the Python scipy.fft package.
-// examples from github.com/xigh/spectrogram.git
+// their proportions (amplitudes). The API is inspired from the Python
+// scipy.fft package. The transforms are computed by the package fft,
+// for any number of samples: the functions of this file are kept for
+// the compatibility, the package fft provides the complete API (window
+// functions, real transforms, options of the spectrum).
 
 import (
-	"math"
-	"math/cmplx"
+	fourier "github.com/gboulant/musicall/fft"
 )
 
-func hfft(samples []float64, freqs []complex128, n, step int) {
-	if n == 1 {
-		freqs[0] = complex(samples[0], 0)
-		return
-	}
+// IsPowerOfTwo returns true if n is a power of two (1, 2, 4, 8, ...)
+func IsPowerOfTwo(n int) bool {
+	return fourier.IsPowerOfTwo(n)
+}
+
+// NextPowerOfTwo returns the smallest power of two greater or equal to
+// n, i.e. the size of the FFT of a signal of n samples padded with
+// zeros.
+func NextPowerOfTwo(n int) int {
+	return fourier.NextPowerOfTwo(n)
+}
 
-	half := n / 2
+// FFT computes the discrete Fourier transform of the values, of any
+// size (see fft.FFT). The powers of two are the fastest sizes.
+func FFT(values []complex128) []complex128 {
+	return fourier.FFT(values)
+}
 
-	hfft(samples, freqs, half, 2*step)
-	hfft(samples[step:], freqs[half:], half, 2*step)
+// IFFT computes the inverse discrete Fourier transform of the
+// frequencies (normalized by 1/n, so that IFFT(FFT(x)) is x).
+func IFFT(freqs []complex128) []complex128 {
+	return fourier.IFFT(freqs)
+}
 
-	for k := range half {
-		a := -2 * math.Pi * float64(k) / float64(n)
-		e := cmplx.Rect(1, a) * freqs[k+half]
+// RealFFT computes the FFT of real samples (see FFT). All the n
+// frequencies are returned, see fft.RFFT for the one-sided transform.
+func RealFFT(samples []float64) []complex128 {
+	values := make([]complex128, len(samples))
+	for i, x := range samples {
+		values[i] = complex(x, 0)
+	}
+	return fourier.FFT(values)
+}
 
-		freqs[k], freqs[k+half] = freqs[k]+e, freqs[k]-e
+// RealIFFT computes the inverse FFT of the frequencies of a real signal
+// (a symmetric spectrum), and returns the real part (see IFFT)
+func RealIFFT(freqs []complex128) []float64 {
+	values := fourier.IFFT(freqs)
+	samples := make([]float64, len(values))
+	for i, v := range values {
+		samples[i] = real(v)
 	}
+	return samples
 }
 
 // fft computes the FFT amplitudes (complex numbers) of the input signal
 func fft(samples []float64) []complex128 {
-	n := len(samples)
-	freqs := make([]complex128, n)
-	hfft(samples, freqs, n, 1)
-	return freqs
+	return RealFFT(samples)
 }
 
 // fftfreq computes the FFT frequencies
 func fftfreq(N int, d float64) []float64 {
-	// f = [0, 1, ...,   n/2-1,     -n/2, ..., -1] / (d*n)   if n is even
-	// f = [0, 1, ..., (n-1)/2, -(n-1)/2, ..., -1] / (d*n)   if n is odd
-	f := make([]float64, N)
-	dxN := d * float64(N)
-	var limit int
-	if N%2 == 0 { // N is even
-		limit = N/2 - 1
-	} else { // N is odd
-		limit = (N - 1) / 2
-	}
-	for i := 0; i <= limit; i++ {
-		f[i] = float64(i) / dxN
-	}
-	for i := limit + 1; i < N; i++ {
-		f[i] = -float64(N-i) / dxN
-	}
-	return f
+	return fourier.Freq(N, d)
 }
 
+// Spectrum returns the one-sided spectrum of the samples (from 0 to the
+// Nyquist frequency), without window, scaled in amplitude: a sinusoid
+// of amplitude a gives the value a at its frequency. See fft.Spectrum
+// for the options (window, scaling in power or dB, zero-padding).
 func Spectrum(samples []float64, samplerate int) (frequencies, amplitudes []float64) {
-	N := len(samples)
-	T := 1. / float64(samplerate)
-	fftf := fftfreq(N, T)
-	ffta := fft(samples)
-
-	// Pour les signaux à valeurs réelles, le spectre est symétrique et
-	// on ne garde que les valeurs de fréquences positives, c'est-à-dire
-	// jusque l'indice N/2 - 1 si N est pair, et (N-1)/2 si N est impair.
-	var lastidx int
-	if N%2 == 0 { // N is even
-		lastidx = N/2 - 1
-	} else { // N is odd
-		lastidx = (N - 1) / 2
-	}
-
-	// frequencies = fftf[:N//2-1]
-	// amplitudes  = 2.0/N * modulus(ffta[:N//2-1])
-	frequencies = fftf[:lastidx+1]
-	amplitudes = make([]float64, lastidx+1)
-	for i := 0; i <= lastidx; i++ {
-		amplitudes[i] = 2.0 * cmplx.Abs(ffta[i]) / float64(N)
-	}
-	return frequencies, amplitudes
+	return fourier.Spectrum(samples, samplerate, fourier.SpectrumOptions{})
 }
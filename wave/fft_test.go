@@ -1,6 +1,8 @@
 package wave
 
 import (
+	"math"
+	"math/cmplx"
 	"testing"
 )
 
@@ -69,3 +71,69 @@ func Test_spectrum(t *testing.T) {
 		t.Errorf("Max Amplitude Frequency is %.2f (should be %.2f)", maxamplfreq, f)
 	}
 }
+
+func TestNextPowerOfTwo(t *testing.T) {
+	tests := map[int]int{0: 1, 1: 1, 2: 2, 3: 4, 1000: 1024, 1024: 1024, 88200: 131072}
+	for n, exp := range tests {
+		if p := NextPowerOfTwo(n); p != exp {
+			t.Errorf("next power of two of %d is %d (should be %d)", n, p, exp)
+		}
+		if IsPowerOfTwo(n) != (n == exp) {
+			t.Errorf("IsPowerOfTwo(%d) is %v", n, IsPowerOfTwo(n))
+		}
+	}
+}
+
+func TestFFT(t *testing.T) {
+	// The FFT is compared to the direct computation of the DFT
+	N := 16
+	values := make([]complex128, N)
+	for i := range values {
+		values[i] = complex(math.Sin(float64(i)), math.Cos(float64(3*i)))
+	}
+	freqs := FFT(values)
+	for k := range N {
+		var exp complex128
+		for i, v := range values {
+			exp += v * cmplx.Rect(1, -2*math.Pi*float64(k*i)/float64(N))
+		}
+		if cmplx.Abs(freqs[k]-exp) > 1e-9 {
+			t.Errorf("frequency %d is %v (should be %v)", k, freqs[k], exp)
+		}
+	}
+
+	// The inverse transform gives back the values
+	inverse := IFFT(freqs)
+	for i := range values {
+		if cmplx.Abs(inverse[i]-values[i]) > 1e-12 {
+			t.Errorf("value %d is %v (should be %v)", i, inverse[i], values[i])
+		}
+	}
+
+	samples := []float64{1, 2, 3, 4, 0, 0, 0, 0}
+	back := RealIFFT(RealFFT(samples))
+	for i := range samples {
+		if !almostEqual(back[i], samples[i], 1e-12) {
+			t.Errorf("sample %d is %v (should be %v)", i, back[i], samples[i])
+		}
+	}
+}
+
+func TestFFT_Size(t *testing.T) {
+	// The sizes that are not powers of two are computed exactly
+	N := 10
+	values := make([]complex128, N)
+	for i := range values {
+		values[i] = complex(float64(i), 1)
+	}
+	freqs := FFT(values)
+	for k := range N {
+		var exp complex128
+		for i, v := range values {
+			exp += v * cmplx.Rect(1, -2*math.Pi*float64(k*i)/float64(N))
+		}
+		if cmplx.Abs(freqs[k]-exp) > 1e-9 {
+			t.Errorf("frequency %d is %v (should be %v)", k, freqs[k], exp)
+		}
+	}
+}
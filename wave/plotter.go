@@ -6,12 +6,14 @@ import (
 	"os"
 
 	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
 	"github.com/go-echarts/go-echarts/v2/opts"
 	"github.com/go-echarts/go-echarts/v2/types"
 )
 
 type WavePlotter struct {
-	chart *charts.Line
+	chart    *charts.Line
+	heatmaps []*charts.HeatMap // spectrograms
 }
 
 func applyDefaultOptions(chart *charts.Line) {
@@ -95,9 +97,20 @@ func (p *WavePlotter) AddLineSampledValues(samples []float64, samplerate int, la
 }
 
 // Plot draws the chart into the specified writer. The writer could be an html
-// file writer or an http writer
+// file writer or an http writer. The spectrograms, if any, are drawn
+// on the same page, below the chart of the lines.
 func (p *WavePlotter) Plot(w io.Writer) error {
-	return p.chart.Render(w)
+	if len(p.heatmaps) == 0 {
+		return p.chart.Render(w)
+	}
+	page := components.NewPage()
+	if len(p.chart.MultiSeries) > 0 {
+		page.AddCharts(p.chart)
+	}
+	for _, heatmap := range p.heatmaps {
+		page.AddCharts(heatmap)
+	}
+	return page.Render(w)
 }
 
 // Save creates an html file that display the chart. Technically speaking, it
@@ -0,0 +1,181 @@
+package wave
+
+// Le spectrogramme montre l'évolution du spectre dans le temps (une
+// carte de chaleur: le temps en abscisse, la fréquence en ordonnée, et
+// l'amplitude en couleur), calculé par la transformée de Fourier à court
+// terme du package fft. Le nombre de points du graphique est limité
+// (SpectrogramRows x SpectrogramColumns) pour qu'il reste affichable:
+// chaque point est le maximum des valeurs qu'il recouvre.
+
+import (
+	"fmt"
+	"math"
+
+	fourier "github.com/gboulant/musicall/fft"
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// Spectrogram returns the spectrogram of the samples in dB, computed
+// with the default options of the short-time transform (see
+// fft.NewSpectrogram for the options).
+func Spectrogram(samples []float64, samplerate int) *fourier.Spectrogram {
+	return fourier.NewSpectrogram(samples, samplerate, fourier.STFTOptions{}, fourier.Decibels)
+}
+
+// FrequencyAxis is the scale of the frequency axis of a spectrogram chart
+type FrequencyAxis int
+
+const (
+	LinearFrequency FrequencyAxis = iota
+	LogFrequency
+)
+
+// Maximal size of the spectrogram charts
+const (
+	SpectrogramRows    = 256 // frequencies
+	SpectrogramColumns = 512 // times
+)
+
+// SpectrogramRange is the range of the colors of a spectrogram in dB,
+// below its maximum
+const SpectrogramRange = 90.
+
+// colors of the spectrogram, from the lowest to the highest values
+var spectrogramColors = []string{"#000004", "#3b0f70", "#8c2981", "#de4968", "#fe9f6d", "#fcfdbf"}
+
+// AddSpectrogram adds the heatmap chart of the spectrogram, with a
+// linear or a logarithmic frequency axis. The chart is displayed below
+// the chart of the lines, if any.
+func (p *WavePlotter) AddSpectrogram(s *fourier.Spectrogram, axis FrequencyAxis, label string) {
+	rows := spectrogramRows(s.Frequencies, axis)
+	step := (len(s.Times) + SpectrogramColumns - 1) / SpectrogramColumns
+	step = max(step, 1)
+
+	times := make([]string, 0, len(s.Times)/step+1)
+	frequencies := make([]string, len(rows))
+	for j, f := range rows {
+		frequencies[j] = fmt.Sprintf("%.0f", f)
+	}
+	var data []opts.HeatMapData
+	vmin, vmax := math.Inf(1), math.Inf(-1)
+	for k := 0; k < len(s.Times); k += step {
+		column := len(times)
+		times = append(times, fmt.Sprintf("%.3f", s.Times[k]))
+		// Les trames regroupées dans une colonne, puis les fréquences
+		// regroupées dans une ligne, sont réduites à leur maximum.
+		values := make([]float64, len(s.Frequencies))
+		copy(values, s.Values[k])
+		for _, frame := range s.Values[k+1 : min(k+step, len(s.Values))] {
+			for i, v := range frame {
+				values[i] = math.Max(values[i], v)
+			}
+		}
+		for j, v := range resampleRows(s.Frequencies, values, rows) {
+			// Les valeurs sont arrondies, pour alléger la page HTML
+			v = math.Round(v*100) / 100
+			data = append(data, opts.HeatMapData{Value: [3]any{column, j, v}})
+			vmin, vmax = math.Min(vmin, v), math.Max(vmax, v)
+		}
+	}
+	if s.Scaling == fourier.Decibels {
+		vmin = math.Max(vmin, vmax-SpectrogramRange)
+	}
+
+	chart := charts.NewHeatMap()
+	chart.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: label}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Type:      "category",
+			Data:      times,
+			AxisLabel: &opts.AxisLabel{Formatter: "{value}s"},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Type:      "category",
+			Data:      frequencies,
+			AxisLabel: &opts.AxisLabel{Formatter: "{value} Hz"},
+		}),
+		charts.WithVisualMapOpts(opts.VisualMap{
+			Calculable: opts.Bool(true),
+			Min:        float32(vmin),
+			Max:        float32(vmax),
+			InRange:    &opts.VisualMapInRange{Color: spectrogramColors},
+		}),
+		charts.WithDataZoomOpts(
+			opts.DataZoom{Type: "inside", XAxisIndex: []int{0}},
+			opts.DataZoom{Type: "slider", XAxisIndex: []int{0}},
+		),
+	)
+	chart.AddSeries(label, data)
+	p.heatmaps = append(p.heatmaps, chart)
+}
+
+// spectrogramRows returns the frequencies of the rows of the chart: the
+// frequencies of the spectrogram if they are not too many and the axis
+// is linear, otherwise SpectrogramRows frequencies evenly spaced on the
+// axis (from the first non null frequency for a logarithmic axis).
+func spectrogramRows(frequencies []float64, axis FrequencyAxis) []float64 {
+	n := len(frequencies)
+	if n < 2 || (axis == LinearFrequency && n <= SpectrogramRows) {
+		return frequencies
+	}
+	rows := make([]float64, min(n, SpectrogramRows))
+	fmin, fmax := frequencies[0], frequencies[n-1]
+	if axis == LogFrequency {
+		fmin = frequencies[1]
+	}
+	for j := range rows {
+		x := float64(j) / float64(len(rows)-1)
+		if axis == LogFrequency {
+			rows[j] = fmin * math.Pow(fmax/fmin, x)
+		} else {
+			rows[j] = fmin + (fmax-fmin)*x
+		}
+	}
+	return rows
+}
+
+// resampleRows returns the values at the frequencies of the rows: the
+// maximum of the values between the middles of the neighbouring rows,
+// or the linear interpolation if there are none (the rows are closer
+// than the frequencies).
+func resampleRows(frequencies, values, rows []float64) []float64 {
+	out := make([]float64, len(rows))
+	i := 0
+	for j, f := range rows {
+		low, high := f, f
+		if j > 0 {
+			low = (rows[j-1] + f) / 2
+		}
+		if j < len(rows)-1 {
+			high = (f + rows[j+1]) / 2
+		}
+		for i < len(frequencies)-1 && frequencies[i+1] <= low {
+			i++
+		}
+		found := false
+		for k := i; k < len(frequencies) && frequencies[k] <= high; k++ {
+			if frequencies[k] >= low && (!found || values[k] > out[j]) {
+				out[j], found = values[k], true
+			}
+		}
+		if !found {
+			// frequencies[i] <= f < frequencies[i+1]
+			k := min(i+1, len(frequencies)-1)
+			x := 0.
+			if frequencies[k] > frequencies[i] {
+				x = (f - frequencies[i]) / (frequencies[k] - frequencies[i])
+			}
+			out[j] = values[i] + (values[k]-values[i])*x
+		}
+	}
+	return out
+}
+
+// PlotSpectrogramToFile creates a HTML file that displays the
+// spectrogram of the samples (see Spectrogram).
+func PlotSpectrogramToFile(htmlpath string, samples []float64, samplerate int, axis FrequencyAxis, label string) error {
+	p := NewPlotter()
+	p.AddSpectrogram(Spectrogram(samples, samplerate), axis, label)
+	return p.Save(htmlpath)
+}
@@ -0,0 +1,79 @@
+package wave
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSpectrogram(t *testing.T) {
+	r := 8000
+	samples := SweepFrequencySignal(200, 2000, 1, 2, r)
+	s := Spectrogram(samples, r)
+	if len(s.Times) != len(s.Values) || len(s.Frequencies) != 513 {
+		t.Errorf("the size of the spectrogram is %dx%d (should be %dx513)", len(s.Values), len(s.Frequencies), len(s.Times))
+	}
+
+	p := NewPlotter()
+	p.AddLineSampledValues(Decimate(samples, 10), r/10, "sweep")
+	p.AddSpectrogram(s, LinearFrequency, "linear")
+	p.AddSpectrogram(s, LogFrequency, "log")
+	var b bytes.Buffer
+	if err := p.Plot(&b); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(b.String(), `"type":"heatmap"`); n != 2 {
+		t.Errorf("the page has %d heatmaps (should be 2)", n)
+	}
+
+	samples = d01_KarplusStrong(DefaultSampleRate)
+	if err := PlotSpectrogramToFile("output.spectrogram.html", samples, DefaultSampleRate, LogFrequency, "KarplusStrong"); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_spectrogramRows(t *testing.T) {
+	frequencies := make([]float64, 1025)
+	for i := range frequencies {
+		frequencies[i] = float64(i) * 10
+	}
+	rows := spectrogramRows(frequencies, LinearFrequency)
+	if len(rows) != SpectrogramRows || rows[0] != 0 || rows[len(rows)-1] != 10240 {
+		t.Errorf("the linear rows are %d from %v to %v (should be %d from 0 to 10240)", len(rows), rows[0], rows[len(rows)-1], SpectrogramRows)
+	}
+	rows = spectrogramRows(frequencies, LogFrequency)
+	if len(rows) != SpectrogramRows || !almostEqual(rows[0], 10, 1e-9) || !almostEqual(rows[len(rows)-1], 10240, 1e-6) {
+		t.Errorf("the log rows are %d from %v to %v (should be %d from 10 to 10240)", len(rows), rows[0], rows[len(rows)-1], SpectrogramRows)
+	}
+	ratio := rows[1] / rows[0]
+	if !almostEqual(rows[100]/rows[99], ratio, 1e-9) {
+		t.Errorf("the ratio of the log rows is %v (should be %v)", rows[100]/rows[99], ratio)
+	}
+	if rows := spectrogramRows(frequencies[:100], LinearFrequency); len(rows) != 100 {
+		t.Errorf("the number of rows is %d (should be 100)", len(rows))
+	}
+}
+
+func Test_resampleRows(t *testing.T) {
+	frequencies := []float64{0, 10, 20, 30, 40}
+	values := []float64{1, 5, 2, 3, 4}
+
+	// Fewer rows: the maximum of the values they cover
+	res := resampleRows(frequencies, values, []float64{0, 20, 40})
+	exp := []float64{5, 5, 4}
+	for j, v := range exp {
+		if res[j] != v {
+			t.Errorf("value of row %d is %v (should be %v)", j, res[j], v)
+		}
+	}
+
+	// More rows: the interpolation
+	res = resampleRows(frequencies, values, []float64{2.5, 5, 7.5})
+	exp = []float64{2, 3, 4}
+	for j, v := range exp {
+		if math.Abs(res[j]-v) > 1e-12 {
+			t.Errorf("value of row %d is %v (should be %v)", j, res[j], v)
+		}
+	}
+}